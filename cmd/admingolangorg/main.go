@@ -4,33 +4,105 @@
 
 // The admingolangorg command serves an administrative interface for owners of
 // the golang-org Google Cloud project.
+//
+// Every request is authenticated and authorized by package auth,
+// configured by the JSON file named by $ADMIN_AUTH_CONFIG.
+// To accept TLS client certificates, the server must serve TLS itself:
+// set $ADMIN_TLS_CERT and $ADMIN_TLS_KEY to the names of its certificate
+// and key files.
+//
+// With -importlegacy, admingolangorg instead adds the named uploaders,
+// allowed to use the legacy keys of x/build/cmd/release for 30 days,
+// and exits. See README.md.
 package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"net/http"
 	"os"
 	"strings"
 
 	"cloud.google.com/go/datastore"
+	"golang.org/x/website/internal/auth"
+	"golang.org/x/website/internal/dl"
 	"golang.org/x/website/internal/memcache"
 	"golang.org/x/website/internal/short"
+	"golang.org/x/website/internal/storage"
 )
 
+var importLegacy = flag.String("importlegacy", "", "add the uploaders in this comma-separated list, allowed to use legacy keys for 30 days, and exit")
+
 func main() {
-	http.HandleFunc("/", short.AdminHandler(getClients()))
+	flag.Parse()
+	datastoreClient, memcacheClient := getClients()
+	db := storage.NewDatastore(datastoreClient)
+	dlDB := dl.NewDatastore(datastoreClient)
+
+	if *importLegacy != "" {
+		names := strings.Split(*importLegacy, ",")
+		if err := dl.ImportLegacyUploaders(context.Background(), dlDB, names); err != nil {
+			log.Fatalf("importing legacy uploaders: %v", err)
+		}
+		log.Printf("imported legacy uploaders %s", strings.Join(names, ", "))
+		return
+	}
+
+	authFile := os.Getenv("ADMIN_AUTH_CONFIG")
+	if authFile == "" {
+		log.Fatalf("ADMIN_AUTH_CONFIG not set; it must name the auth configuration file.")
+	}
+	authConfig, err := auth.LoadConfig(authFile)
+	if err != nil {
+		log.Fatalf("loading auth config: %v", err)
+	}
+	a, err := auth.New(authConfig, db)
+	if err != nil {
+		log.Fatalf("auth config: %v", err)
+	}
+	tlsConfig, err := authConfig.TLSConfig()
+	if err != nil {
+		log.Fatalf("auth config: %v", err)
+	}
+
+	// All admin handlers must be registered on mux,
+	// which is served only through a.Handler.
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", short.AdminHandler(db, memcacheClient))
+	api := short.APIHandler(db, memcacheClient)
+	mux.HandleFunc("/api/links", api)
+	mux.HandleFunc("/api/links/", api)
+	mux.HandleFunc("/dl/uploaders", dl.AdminHandler(dlDB))
+	upload := dl.UploadHandler(dlDB, memcacheClient)
+	mux.HandleFunc("/dl/upload", upload)
+	mux.HandleFunc("/dl/upload/log", upload)
+	mux.HandleFunc("/audit", auth.AuditHandler(db))
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 		log.Printf("Defaulting to port %s", port)
 	}
+	srv := &http.Server{
+		Addr:      ":" + port,
+		Handler:   a.Handler(mux),
+		TLSConfig: tlsConfig,
+	}
 
+	certFile, keyFile := os.Getenv("ADMIN_TLS_CERT"), os.Getenv("ADMIN_TLS_KEY")
+	if certFile != "" {
+		log.Printf("Listening on port %s (TLS)", port)
+		log.Fatal(srv.ListenAndServeTLS(certFile, keyFile))
+	}
+	if tlsConfig != nil {
+		log.Fatalf("auth config sets ClientCA, but ADMIN_TLS_CERT is not set.")
+	}
 	log.Printf("Listening on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	log.Fatal(srv.ListenAndServe())
 }
 
-func getClients() (*datastore.Client, *memcache.Client) {
+func getClients() (*datastore.Client, memcache.Client) {
 	ctx := context.Background()
 
 	datastoreClient, err := datastore.NewClient(ctx, "")
@@ -41,11 +113,14 @@ func getClients() (*datastore.Client, *memcache.Client) {
 		log.Fatalf("datastore.NewClient: %v.", err)
 	}
 
+	// The admin server uses the cache only to delete changed links,
+	// so without the Redis server shared with golangorg there is nothing to do.
 	redisAddr := os.Getenv("GOLANGORG_REDIS_ADDR")
 	if redisAddr == "" {
-		log.Fatalf("Missing redis server for golangorg in production mode. set GOLANGORG_REDIS_ADDR environment variable.")
+		log.Printf("GOLANGORG_REDIS_ADDR not set; golangorg may serve changed links from its cache for up to a minute.")
+		return datastoreClient, nil
 	}
-	memcacheClient := memcache.New(redisAddr)
+	memcacheClient := memcache.NewRedis(redisAddr)
 
 	return datastoreClient, memcacheClient
 }
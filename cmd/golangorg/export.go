@@ -0,0 +1,449 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+// This file implements the -export mode, which writes a static copy
+// of the site that can be served by any static file host.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/website/internal/redirect"
+)
+
+// exportSeeds lists the paths at which the export crawl starts,
+// in addition to the redirect table.
+var exportSeeds = []string{
+	"/",
+	"/cmd/",
+	"/doc/",
+	"/doc/codewalk/",
+	"/favicon.ico",
+	"/pkg/",
+	"/robots.txt",
+	"/src/",
+}
+
+// dynamicRoutes lists the routes whose responses depend on the request
+// and so cannot be exported as static files.
+var dynamicRoutes = []string{
+	"/change/",
+	"/cl/",
+	"/compile",
+	"/design/",
+	"/fmt",
+	"/issue/",
+	"/issues/",
+	"/play/",
+	"/search",
+	"/share",
+	"/src/pkg/",
+	"/talks/",
+	"/wiki/",
+	"/x/",
+}
+
+// exportQueries lists the query parameters whose variants are exported
+// as separate static pages: the code views shown in codewalks.
+// Links with other query parameters, such as ?m=text or ?refs=Name,
+// are rewritten to refer to the page without the query.
+var exportQueries = map[string]bool{
+	"fileprint": true,
+	"hi":        true,
+	"lo":        true,
+}
+
+// An exporter crawls the site served by h and writes
+// a static copy of it to dir.
+type exporter struct {
+	h   http.Handler
+	dir string
+
+	queue []string
+	seen  map[string]bool
+
+	pages     []string          // local URLs of exported HTML pages
+	links     map[string]string // local URL -> link to its static copy
+	redirects map[string]string // local URL -> redirect target
+	files     map[string]bool   // names of the written files
+
+	dynamic map[string]int // dynamic route -> number of URLs linked
+	dropped map[string]int // dropped query parameter -> number of links
+	broken  map[string]int // local URL -> HTTP status
+}
+
+// exportSite writes a static copy of the site served by h to dir
+// and prints a report of what could not be exported to w.
+//
+// HTML pages are written as name.html, or as index.html for directories,
+// and the links between them rewritten accordingly. Redirects are
+// written as pages with a meta refresh to the target.
+func exportSite(h http.Handler, dir string, w io.Writer) error {
+	e := &exporter{
+		h:         h,
+		dir:       dir,
+		seen:      make(map[string]bool),
+		links:     make(map[string]string),
+		redirects: make(map[string]string),
+		files:     make(map[string]bool),
+		dynamic:   make(map[string]int),
+		dropped:   make(map[string]int),
+		broken:    make(map[string]int),
+	}
+	for _, p := range exportSeeds {
+		e.enqueue(p)
+	}
+	var sources []string
+	for p := range redirect.Redirects() {
+		sources = append(sources, p)
+	}
+	sort.Strings(sources)
+	for _, p := range sources {
+		e.enqueue(p)
+	}
+
+	for len(e.queue) > 0 {
+		u := e.queue[0]
+		e.queue = e.queue[1:]
+		if err := e.fetch(u); err != nil {
+			return err
+		}
+	}
+	for u := range e.redirects {
+		_, e.links[u] = exportName(u, true)
+	}
+	for _, u := range e.pages {
+		if err := e.rewrite(u); err != nil {
+			return err
+		}
+	}
+	if err := e.writeRedirects(); err != nil {
+		return err
+	}
+	e.report(w)
+	return nil
+}
+
+// enqueue adds the local URL u to the crawl queue.
+func (e *exporter) enqueue(u string) {
+	if e.seen[u] {
+		return
+	}
+	e.seen[u] = true
+	for _, r := range dynamicRoutes {
+		if u == r || strings.HasSuffix(r, "/") && strings.HasPrefix(u, r) {
+			e.dynamic[r]++
+			return
+		}
+	}
+	e.queue = append(e.queue, u)
+}
+
+// fetch serves the local URL u and writes the response.
+func (e *exporter) fetch(u string) error {
+	ru, err := url.ParseRequestURI(u)
+	if err != nil {
+		e.broken[u] = http.StatusBadRequest
+		return nil
+	}
+	req := httptest.NewRequest("GET", "/", nil)
+	req.URL, req.RequestURI = ru, u
+	rec := httptest.NewRecorder()
+	e.h.ServeHTTP(rec, req)
+	resp := rec.Result()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// handled below
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		loc := resp.Header.Get("Location")
+		e.redirects[u] = loc
+		e.follow(u, loc)
+		return nil
+	default:
+		e.broken[u] = resp.StatusCode
+		return nil
+	}
+
+	body := rec.Body.Bytes()
+	ctype := resp.Header.Get("Content-Type")
+	isHTML := strings.HasPrefix(ctype, "text/html")
+	name, link := exportName(u, isHTML)
+	e.links[u] = link
+	if isHTML {
+		e.pages = append(e.pages, u)
+		for _, m := range linkAttr.FindAllSubmatch(body, -1) {
+			if ref, ok := attrRef(m); ok {
+				e.follow(u, ref)
+			}
+		}
+	} else if strings.HasPrefix(ctype, "text/css") {
+		for _, m := range cssURL.FindAllSubmatch(body, -1) {
+			e.follow(u, string(m[1]))
+		}
+	}
+	return e.write(name, body)
+}
+
+var (
+	linkAttr = regexp.MustCompile(`\b(href|src|value)="([^"]*)"`)
+	cssURL   = regexp.MustCompile(`url\(['"]?([^'")]*)['"]?\)`)
+)
+
+// attrRef returns the URL in the attribute matched by linkAttr.
+// Value attributes are only taken to be URLs, such as the
+// file choices in codewalks, if they are absolute paths.
+func attrRef(m [][]byte) (string, bool) {
+	ref := html.UnescapeString(string(m[2]))
+	if string(m[1]) == "value" && !strings.HasPrefix(ref, "/") {
+		return "", false
+	}
+	return ref, true
+}
+
+// local resolves the link ref on the page at local URL base and returns
+// the local URL it refers to, reporting whether it is a local URL at all.
+// Query parameters that are not exported are removed from the URL
+// and returned in dropped.
+func local(base, ref string) (target string, dropped []string, ok bool) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", nil, false
+	}
+	r, err := url.Parse(ref)
+	if err != nil || r.Scheme != "" || r.Host != "" || r.Opaque != "" {
+		return "", nil, false
+	}
+	if r.Path == "" && r.RawQuery == "" {
+		// Fragment within the page.
+		return "", nil, false
+	}
+	u := b.ResolveReference(r)
+	p := u.EscapedPath()
+	if !strings.HasPrefix(p, "/") {
+		return "", nil, false
+	}
+	var keep []string
+	for _, kv := range strings.Split(u.RawQuery, "&") {
+		if kv == "" {
+			continue
+		}
+		k := kv
+		if i := strings.Index(kv, "="); i >= 0 {
+			k = kv[:i]
+		}
+		if exportQueries[k] {
+			keep = append(keep, kv)
+		} else {
+			dropped = append(dropped, k)
+		}
+	}
+	if len(keep) > 0 {
+		return p + "?" + strings.Join(keep, "&"), dropped, true
+	}
+	return p, dropped, true
+}
+
+// follow adds the target of the link ref on the page at local URL base
+// to the crawl queue, if it is a local URL.
+func (e *exporter) follow(base, ref string) {
+	target, dropped, ok := local(base, ref)
+	if !ok {
+		return
+	}
+	for _, k := range dropped {
+		e.dropped[k]++
+	}
+	e.enqueue(target)
+}
+
+// exportName returns the name of the file to which the local URL u
+// is exported, and the link by which other pages refer to it.
+func exportName(u string, isHTML bool) (name, link string) {
+	p, query := u, ""
+	if i := strings.Index(u, "?"); i >= 0 {
+		p, query = u[:i], u[i+1:]
+	}
+	if query != "" {
+		// Variants of a page are written to dir/_/k=v&k=v.html.
+		var elems []string
+		for _, kv := range strings.Split(query, "&") {
+			if v, err := url.QueryUnescape(kv); err == nil && !strings.Contains(v, "..") && !strings.ContainsAny(v, "?#%") {
+				kv = v
+			} else {
+				kv = url.QueryEscape(kv)
+			}
+			elems = append(elems, kv)
+		}
+		dir := p
+		if !strings.HasSuffix(dir, "/") {
+			dir = pathpkg.Dir(dir) + "/"
+		}
+		link = dir + "_/" + strings.Join(elems, "&") + ".html"
+		return link, link
+	}
+	switch {
+	case strings.HasSuffix(p, "/"):
+		return p + "index.html", p
+	case isHTML && pathpkg.Ext(p) != ".html":
+		return p + ".html", p + ".html"
+	}
+	return p, p
+}
+
+// write writes data to the named file in the export directory.
+func (e *exporter) write(name string, data []byte) error {
+	file := filepath.Join(e.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+		return err
+	}
+	e.files[name] = true
+	return os.WriteFile(file, data, 0666)
+}
+
+// rewrite rewrites the links in the exported page at local URL u
+// to refer to the static copies of their targets.
+func (e *exporter) rewrite(u string) error {
+	name, _ := exportName(u, true)
+	file := filepath.Join(e.dir, filepath.FromSlash(name))
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	data = linkAttr.ReplaceAllFunc(data, func(m []byte) []byte {
+		sub := linkAttr.FindSubmatch(m)
+		ref, ok := attrRef(sub)
+		if !ok {
+			return m
+		}
+		link, ok := e.link(u, ref)
+		if !ok {
+			return m
+		}
+		return []byte(fmt.Sprintf(`%s="%s"`, sub[1], html.EscapeString(link)))
+	})
+	return os.WriteFile(file, data, 0666)
+}
+
+// link returns the link to the static copy of the target of ref,
+// a link on the page at local URL base.
+func (e *exporter) link(base, ref string) (string, bool) {
+	target, _, ok := local(base, ref)
+	if !ok {
+		return "", false
+	}
+	link, ok := e.links[target]
+	if !ok {
+		return "", false
+	}
+	if i := strings.Index(ref, "#"); i >= 0 {
+		link += ref[i:]
+	}
+	return link, true
+}
+
+// writeRedirects writes a page with a meta refresh for each redirect,
+// unless the page it would be written to has already been exported,
+// as when /doc/install.html redirects to /doc/install.
+func (e *exporter) writeRedirects() error {
+	var list []string
+	for u := range e.redirects {
+		list = append(list, u)
+	}
+	sort.Strings(list)
+	for _, u := range list {
+		name, _ := exportName(u, true)
+		if e.files[name] {
+			continue
+		}
+		target := e.redirects[u]
+		if l, ok := e.link(u, target); ok {
+			target = l
+		}
+		var buf bytes.Buffer
+		if err := redirectStub.Execute(&buf, target); err != nil {
+			return err
+		}
+		if err := e.write(name, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// report prints the export statistics and the routes
+// that could not be exported to w.
+func (e *exporter) report(w io.Writer) {
+	fmt.Fprintf(w, "exported %d files (%d pages, %d redirects) to %s\n", len(e.files), len(e.pages), len(e.redirects), e.dir)
+
+	fmt.Fprintf(w, "\nroutes that cannot be made static:\n")
+	for _, r := range dynamicRoutes {
+		fmt.Fprintf(w, "\t%s\t%d URLs\n", r, e.dynamic[r])
+	}
+
+	if len(e.dropped) > 0 {
+		fmt.Fprintf(w, "\nquery parameters removed from links:\n")
+		for _, k := range sortedKeys(e.dropped) {
+			fmt.Fprintf(w, "\t?%s=\t%d links\n", k, e.dropped[k])
+		}
+	}
+
+	if len(e.broken) > 0 {
+		fmt.Fprintf(w, "\nbroken links:\n")
+		for _, u := range sortedKeys(e.broken) {
+			fmt.Fprintf(w, "\t%s\t%d %s\n", u, e.broken[u], http.StatusText(e.broken[u]))
+		}
+	}
+}
+
+func sortedKeys(m map[string]int) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// export writes the static copy of the site served by mux to dir,
+// as requested by the -export flag.
+func export(mux http.Handler, dir string) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		log.Fatal(err)
+	}
+	if err := exportSite(mux, dir, os.Stderr); err != nil {
+		log.Fatalf("export: %v", err)
+	}
+}
+
+var redirectStub = template.Must(template.New("redirect").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta http-equiv="Content-Type" content="text/html; charset=utf-8"/>
+<meta http-equiv="refresh" content="0; url={{.}}">
+<link rel="canonical" href="{{.}}">
+</head>
+<body>
+<a href="{{.}}">Redirecting to {{.}}...</a>
+</body>
+</html>
+`))
@@ -0,0 +1,99 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	mux := http.NewServeMux()
+	page := func(path, body string) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != path {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(body))
+		})
+	}
+	page("/", `<a href="/doc/install#linux">install</a>
+<link rel="stylesheet" href="/lib/style.css">
+<a href="src/x.go?s=1:2#L1">x.go</a>
+<a href="/fmt">fmt</a>
+<a href="/old">old</a>
+<a href="/doc/walk/?fileprint=%2Fa.go&amp;lo=1">walk</a>
+<a href="https://example.com/">external</a>`)
+	page("/doc/install", `<a href="../">home</a>`)
+	page("/src/x.go", `<a href="#L1">1</a>`)
+	page("/doc/walk/", `<option value="/doc/walk/?fileprint=%2Fa.go">a.go</option>`)
+	mux.HandleFunc("/lib/style.css", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/css")
+		w.Write([]byte(`body { background: url("/lib/img.png"); }`))
+	})
+	mux.HandleFunc("/lib/img.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("PNG"))
+	})
+	mux.Handle("/old", http.RedirectHandler("/doc/install", http.StatusMovedPermanently))
+
+	dir, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var report strings.Builder
+	if err := exportSite(mux, dir, &report); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		file string
+		want []string
+	}{
+		{"index.html", []string{
+			`href="/doc/install.html#linux"`,
+			`href="/lib/style.css"`,
+			`href="/src/x.go.html#L1"`,
+			`href="/fmt"`,
+			`href="/old.html"`,
+			`href="/doc/walk/_/fileprint=/a.go&amp;lo=1.html"`,
+			`href="https://example.com/"`,
+		}},
+		{"doc/install.html", []string{`href="/"`}},
+		{"src/x.go.html", []string{`href="#L1"`}},
+		{"doc/walk/_/fileprint=/a.go&lo=1.html", []string{`value="/doc/walk/_/fileprint=/a.go.html"`}},
+		{"doc/walk/_/fileprint=/a.go.html", nil},
+		{"lib/style.css", nil},
+		{"lib/img.png", []string{"PNG"}},
+		{"old.html", []string{`<meta http-equiv="refresh" content="0; url=/doc/install.html">`}},
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(tt.file)))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		for _, s := range tt.want {
+			if !strings.Contains(string(data), s) {
+				t.Errorf("%s does not contain %s:\n%s", tt.file, s, data)
+			}
+		}
+	}
+
+	for _, s := range []string{"\t/fmt\t1 URLs\n", "\t?s=\t1 links\n", "\t/pkg/\t404 Not Found\n"} {
+		if !strings.Contains(report.String(), s) {
+			t.Errorf("report does not contain %q:\n%s", s, report.String())
+		}
+	}
+}
@@ -90,6 +90,7 @@ func registerHandlers(pres *godoc.Presentation) *http.ServeMux {
 	mux.Handle("/fmt", http.HandlerFunc(fmtHandler))
 	mux.Handle("/pkg/C/", redirect.Handler("/cmd/cgo/"))
 	mux.Handle("/robots.txt", pres.FileServer())
+	mux.Handle("/translations/", http.HandlerFunc(translationsHandler))
 	mux.Handle("/x/", http.HandlerFunc(xHandler))
 	redirect.Register(mux)
 
@@ -121,12 +122,19 @@ func readTemplate(name string) *template.Template {
 func readTemplates(p *godoc.Presentation) {
 	codewalkHTML = readTemplate("codewalk.html")
 	codewalkdirHTML = readTemplate("codewalkdir.html")
+	p.APIChangesHTML = readTemplate("apichanges.html")
+	p.APIDiffHTML = readTemplate("apidiff.html")
 	p.DirlistHTML = readTemplate("dirlist.html")
 	p.ErrorHTML = readTemplate("error.html")
 	p.ExampleHTML = readTemplate("example.html")
 	p.GodocHTML = readTemplate("godoc.html")
+	p.ImportsHTML = readTemplate("imports.html")
 	p.PackageHTML = readTemplate("package.html")
 	p.PackageRootHTML = readTemplate("packageroot.html")
+	p.PlatformsHTML = readTemplate("platforms.html")
+	p.RefsHTML = readTemplate("refs.html")
+	p.SearchHTML = readTemplate("search.html")
+	translationsHTML = readTemplate("translations.html")
 }
 
 type fmtResponse struct {
@@ -10,14 +10,21 @@ package main
 import (
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"time"
 
-	// This package registers "/compile" and "/share" handlers
-	// that redirect to the golang.org playground.
-	_ "golang.org/x/tools/playground"
+	"golang.org/x/website/internal/dl"
+	"golang.org/x/website/internal/memcache"
+	"golang.org/x/website/internal/modproxy"
+	"golang.org/x/website/internal/pkgdoc"
+	"golang.org/x/website/internal/proxy"
+	"golang.org/x/website/internal/short"
+	"golang.org/x/website/internal/storage"
 )
 
 func earlySetup() {
@@ -32,9 +39,105 @@ func earlySetup() {
 		return
 	}
 	*templateDir = dir
+
+	// Index the blog articles from a sibling blog checkout, if present.
+	blog := filepath.Join(file, "../../../blog/_content")
+	if *blogDir == "" {
+		if _, err := os.Stat(blog); err == nil {
+			*blogDir = blog
+		}
+	}
 }
 
 func lateSetup(mux *http.ServeMux) {
-	// Register a redirect handler for /dl/ to the golang.org download page.
-	mux.Handle("/dl/", http.RedirectHandler("https://golang.org/dl/", http.StatusFound))
+	var db storage.DB
+	if *dataDir != "" {
+		var err error
+		db, err = storage.OpenDir(*dataDir)
+		if err != nil {
+			log.Fatalf("opening -data directory: %v", err)
+		}
+	}
+
+	// Serve /compile and /share by proxying to a playground
+	// or by running programs locally, keeping shares in *dataDir if set.
+	// Compile results are cached in memory.
+	var play proxy.Backend
+	if *playServer == "local" {
+		l, err := proxy.NewLocal(proxy.LocalConfig{DB: db})
+		if err != nil {
+			log.Fatalf("-play=local: %v", err)
+		}
+		// Unless isolated, programs run as this process's user,
+		// so only serve them to users who could run them on this
+		// machine anyway.
+		if !l.Isolated() {
+			if !isLoopback(*httpAddr) {
+				log.Fatalf("-play=local: -http=%s listens beyond this machine, and programs cannot be isolated on this system; use a loopback address, like localhost:6060", *httpAddr)
+			}
+			log.Printf("warning: -play=local: programs cannot be isolated on this system and run as this user")
+		}
+		play = l
+	} else {
+		play = proxy.Remote(*playServer)
+	}
+	proxy.RegisterHandlers(mux, play, memcache.NewLRU(32<<20, time.Hour))
+
+	// Serve the golang.org/x modules at /modproxy/, for use as GOPROXY.
+	if *modProxy != "" {
+		mux.Handle("/modproxy/", http.StripPrefix("/modproxy", modproxy.Handler(modProxyConfig())))
+	}
+
+	// Serve the documentation of the modules in *modCache at /mod/.
+	if *modCache != "" {
+		pres.Modules = pkgdoc.NewModuleCache(*modCache)
+	}
+
+	if db == nil {
+		// Register a redirect handler for /dl/ to the golang.org download page.
+		mux.Handle("/dl/", http.RedirectHandler("https://golang.org/dl/", http.StatusFound))
+		return
+	}
+
+	// Serve the download page and short links from files in *dataDir,
+	// without a cache.
+	var files dl.Backend
+	switch {
+	case strings.HasPrefix(*dlFilesDir, "https://"), strings.HasPrefix(*dlFilesDir, "http://"):
+		files = dl.Redirect(*dlFilesDir)
+	case *dlFilesDir != "":
+		files = dl.Mirror(db, dl.Dir(*dlFilesDir))
+	}
+	dl.RegisterHandlers(mux, db, nil, files)
+	pres.Downloads = dl.Downloads(db, nil)
+	flushVisits := short.RegisterHandlers(mux, db, nil)
+	atShutdown = append(atShutdown, flushVisits)
+}
+
+// isLoopback reports whether the server address addr,
+// as for http.ListenAndServe, listens only on a loopback interface.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// modProxyConfig returns the module proxy configuration
+// set by the -modproxy and -modsums flags.
+func modProxyConfig() *modproxy.Config {
+	var prefixes []string
+	for _, p := range xConfig.Paths {
+		prefixes = append(prefixes, p.Prefix)
+	}
+	cfg, err := modproxy.NewConfig(prefixes, *modProxy, *modSums)
+	if err != nil {
+		log.Fatalf("-modsums: %v", err)
+	}
+	return cfg
 }
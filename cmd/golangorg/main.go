@@ -21,16 +21,21 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io/fs"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"runtime"
+	"syscall"
+	"time"
 
 	"golang.org/x/website"
 	"golang.org/x/website/internal/godoc"
+	"golang.org/x/website/internal/translation"
 )
 
 var (
@@ -38,8 +43,31 @@ var (
 	verbose     = flag.Bool("v", false, "verbose mode")
 	goroot      = flag.String("goroot", runtime.GOROOT(), "Go root directory")
 	templateDir = flag.String("templates", "", "load templates/JS/CSS from disk in this directory (usually /path-to-website/content)")
+	transDir    = flag.String("translations", "", "serve translated content from this directory in preference to the English content")
+	blogDir     = flag.String("blog", "", "include the blog articles in this directory in the search index")
+	xrefIndex   = flag.Bool("xref", false, "type-check all of GOROOT/src at startup to link identifiers in Go source files to their declarations and uses")
+	exportDir   = flag.String("export", "", "write a static copy of the site to this directory and exit")
+	versionList = flag.String("versions", "", "comma-separated list of version=goroot pairs whose package documentation can be selected with ?v=version")
+	versionDir  = flag.String("versiondir", "", "serve package documentation for ?v=version from the Go source tree in this directory's version subdirectory")
+	fetchList   = flag.String("fetchversions", "", "comma-separated list of Go versions whose source archives to download into -versiondir")
+	dataDir     = flag.String("data", "", "serve the download page and short links from data stored in this directory, instead of redirecting to golang.org")
+	dlFilesDir  = flag.String("dlfiles", "", "with -data, serve the release files listed on the download page from this directory, or redirect to this base URL, instead of redirecting to dl.google.com")
+	playServer  = flag.String("play", "https://play.golang.org", "run playground programs by proxying to this playground, or \"local\" to build and run them on this machine")
+	modProxy    = flag.String("modproxy", "", "serve the golang.org/x modules at /modproxy/ from this module cache download directory, or from this upstream proxy URL")
+	modSums     = flag.String("modsums", "https://sum.golang.org", "with -modproxy, verify modules against this sum.golang.org checksum database URL, or against the checksums in this go.sum file")
+	modCache    = flag.String("modcache", "", "serve the documentation of the modules in this module cache or directory of module zips at /mod/")
+	typeCheck   = flag.Bool("typecheck", false, "type-check the packages documented, to link identifiers precisely and list promoted methods and implemented interfaces")
 )
 
+// shutdownTimeout bounds how long the server takes to finish
+// the requests in progress and run the atShutdown functions
+// once it is asked to stop.
+const shutdownTimeout = 5 * time.Second
+
+// atShutdown lists the functions to call when the server stops,
+// after it has finished serving requests.
+var atShutdown []func(context.Context)
+
 func usage() {
 	fmt.Fprintf(os.Stderr, "usage: golangorg\n")
 	flag.PrintDefaults()
@@ -69,101 +97,88 @@ func main() {
 		usage()
 	}
 
-	// Serve files from _content, falling back to GOROOT.
-	var content fs.FS
+	// Serve files from the translations, then _content, falling back to GOROOT.
+	var content, trans fs.FS
 	if *templateDir != "" {
 		content = os.DirFS(*templateDir)
 	} else {
 		content = website.Content
 	}
-	fsys = unionFS{content, os.DirFS(*goroot)}
+	if *transDir != "" {
+		trans = os.DirFS(*transDir)
+	}
+	fsys = translation.NewFS(trans, content, os.DirFS(*goroot))
 
 	corpus := godoc.NewCorpus(fsys)
+	corpus.Xref = *xrefIndex
 	// Initialize the version info before readTemplates, which saves
 	// the map value in a method value.
 	corpus.InitVersionInfo()
+	corpus.InitReleases()
 
 	pres = godoc.NewPresentation(corpus)
 	pres.GoogleCN = googleCN
+	pres.TypeCheck = *typeCheck
+	if err := initVersions(pres); err != nil {
+		log.Fatal(err)
+	}
 
 	readTemplates(pres)
 	mux := registerHandlers(pres)
 	lateSetup(mux)
 
+	if *exportDir != "" {
+		if *xrefIndex {
+			corpus.InitXref()
+		}
+		export(mux, *exportDir)
+		return
+	}
+
+	// Build the indexes in the background; the pages that use them
+	// report that indexing is in progress until they are done.
+	var blog fs.FS
+	if *blogDir != "" {
+		blog = os.DirFS(*blogDir)
+	}
+	go pres.InitSearch(blog)
+	if *xrefIndex {
+		go corpus.InitXref()
+	}
+
 	var handler http.Handler = http.DefaultServeMux
 	if *verbose {
 		log.Printf("golang.org server:")
 		log.Printf("\tversion = %s", runtime.Version())
 		log.Printf("\taddress = %s", *httpAddr)
 		log.Printf("\tgoroot = %s", *goroot)
+		if *transDir != "" {
+			log.Printf("\ttranslations = %s", *transDir)
+		}
 		handler = loggingHandler(handler)
 	}
 
 	// Start http server.
-	fmt.Fprintf(os.Stderr, "serving http://%s\n", *httpAddr)
-	if err := http.ListenAndServe(*httpAddr, handler); err != nil {
-		log.Fatalf("ListenAndServe %s: %v", *httpAddr, err)
-	}
-}
-
-var _ fs.ReadDirFS = unionFS{}
-
-// A unionFS is an FS presenting the union of the file systems in the slice.
-// If multiple file systems provide a particular file, Open uses the FS listed earlier in the slice.
-// If multiple file systems provide a particular directory, ReadDir presents the
-// concatenation of all the directories listed in the slice (with duplicates removed).
-type unionFS []fs.FS
-
-func (fsys unionFS) Open(name string) (fs.File, error) {
-	var errOut error
-	for _, sub := range fsys {
-		f, err := sub.Open(name)
-		if err == nil {
-			// Note: Should technically check for directory
-			// and return a synthetic directory that merges
-			// reads from all the matching directories,
-			// but all the directory reads in internal/godoc
-			// come from fsys.ReadDir, which does that for us.
-			// So we can ignore direct f.ReadDir calls.
-			return f, nil
-		}
-		if errOut == nil {
-			errOut = err
-		}
-	}
-	return nil, errOut
-}
-
-func (fsys unionFS) ReadDir(name string) ([]fs.DirEntry, error) {
-	var all []fs.DirEntry
-	var seen map[string]bool // seen[name] is true if name is listed in all; lazily initialized
-	var errOut error
-	for _, sub := range fsys {
-		list, err := fs.ReadDir(sub, toFS(name))
-		if err != nil {
-			errOut = err
+	srv := &http.Server{Addr: *httpAddr, Handler: handler}
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+		sig := <-c
+		log.Printf("%v: shutting down", sig)
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Printf("ERROR shutting down: %v", err)
 		}
-		if len(all) == 0 {
-			all = append(all, list...)
-		} else {
-			if seen == nil {
-				// Initialize seen only after we get two different directory listings.
-				seen = make(map[string]bool)
-				for _, d := range all {
-					seen[d.Name()] = true
-				}
-			}
-			for _, d := range list {
-				name := d.Name()
-				if !seen[name] {
-					seen[name] = true
-					all = append(all, d)
-				}
-			}
+		for _, f := range atShutdown {
+			f(ctx)
 		}
+	}()
+	fmt.Fprintf(os.Stderr, "serving http://%s\n", *httpAddr)
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatalf("ListenAndServe %s: %v", *httpAddr, err)
 	}
-	if len(all) > 0 {
-		return all, nil
-	}
-	return nil, errOut
+	<-stopped
 }
@@ -14,6 +14,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"golang.org/x/website/internal/dl"
 	"golang.org/x/website/internal/proxy"
@@ -22,6 +23,7 @@ import (
 
 	"cloud.google.com/go/datastore"
 	"golang.org/x/website/internal/memcache"
+	"golang.org/x/website/internal/storage"
 )
 
 func earlySetup() {
@@ -40,13 +42,16 @@ func lateSetup(mux *http.ServeMux) {
 
 	datastoreClient, memcacheClient := getClients()
 
-	dl.RegisterHandlers(mux, datastoreClient, memcacheClient)
-	short.RegisterHandlers(mux, datastoreClient, memcacheClient)
+	dlDB := dl.NewDatastore(datastoreClient)
+	dl.RegisterHandlers(mux, dlDB, memcacheClient, nil)
+	pres.Downloads = dl.Downloads(dlDB, memcacheClient)
+	flushVisits := short.RegisterHandlers(mux, storage.NewDatastore(datastoreClient), memcacheClient)
+	atShutdown = append(atShutdown, flushVisits)
 
 	// Register /compile and /share handlers against the default serve mux
 	// so that other app modules can make plain HTTP requests to those
 	// hosts. (For reasons, HTTPS communication between modules is broken.)
-	proxy.RegisterHandlers(http.DefaultServeMux)
+	proxy.RegisterHandlers(http.DefaultServeMux, nil, memcacheClient)
 
 	http.HandleFunc("/_ah/health", func(w http.ResponseWriter, r *http.Request) {
 		io.WriteString(w, "ok")
@@ -63,7 +68,7 @@ func lateSetup(mux *http.ServeMux) {
 	log.Println("godoc initialization complete")
 }
 
-func getClients() (*datastore.Client, *memcache.Client) {
+func getClients() (*datastore.Client, memcache.Client) {
 	ctx := context.Background()
 
 	datastoreClient, err := datastore.NewClient(ctx, "")
@@ -74,10 +79,15 @@ func getClients() (*datastore.Client, *memcache.Client) {
 		log.Fatalf("datastore.NewClient: %v.", err)
 	}
 
+	// Keep recently used values in memory, in front of Redis if there is one.
+	// The short maximum age bounds how long a value changed
+	// by another instance can be served from this one.
+	lru := memcache.NewLRU(64<<20, time.Minute)
 	redisAddr := os.Getenv("GOLANGORG_REDIS_ADDR")
 	if redisAddr == "" {
-		log.Fatalf("Missing redis server for golangorg in production mode. set GOLANGORG_REDIS_ADDR environment variable.")
+		log.Printf("GOLANGORG_REDIS_ADDR not set; caching in memory only.")
+		return datastoreClient, lru
 	}
-	memcacheClient := memcache.New(redisAddr)
+	memcacheClient := memcache.Tiered(lru, memcache.NewRedis(redisAddr))
 	return datastoreClient, memcacheClient
 }
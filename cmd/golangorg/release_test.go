@@ -27,7 +27,9 @@ func TestReleaseHistory(t *testing.T) {
 	origFS, origPres := fsys, pres
 	defer func() { fsys, pres = origFS, origPres }()
 	fsys = website.Content
-	pres = godoc.NewPresentation(godoc.NewCorpus(fsys))
+	corpus := godoc.NewCorpus(fsys)
+	corpus.InitReleases()
+	pres = godoc.NewPresentation(corpus)
 	readTemplates(pres)
 	mux := registerHandlers(pres)
 
@@ -0,0 +1,46 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package main
+
+import (
+	"io/fs"
+	"log"
+	"net/http"
+	"text/template"
+
+	"golang.org/x/website/internal/godoc"
+	"golang.org/x/website/internal/translation"
+)
+
+var translationsHTML *template.Template
+
+// translationsHandler serves the translation status dashboard at /translations/.
+// The report is read from translation.ReportFile at the root of the served content,
+// as written by "transtatus -json".
+func translationsHandler(w http.ResponseWriter, r *http.Request) {
+	if redir(w, r) {
+		return
+	}
+	const relpath = translation.ReportFile
+	data, err := fs.ReadFile(fsys, relpath)
+	if err != nil {
+		pres.ServeError(w, r, relpath, err)
+		return
+	}
+	report, err := translation.ReadReport(data)
+	if err != nil {
+		log.Printf("reading %s: %v", relpath, err)
+		pres.ServeError(w, r, relpath, err)
+		return
+	}
+	pres.ServePage(w, godoc.Page{
+		Title:    "Translation Status",
+		Body:     applyTemplate(translationsHTML, "translations", report),
+		GoogleCN: googleCN(r),
+	})
+}
@@ -0,0 +1,202 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+// This file contains the setup of the Go versions whose package
+// documentation can be selected with /pkg/path/?v=version.
+
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/website/internal/dl"
+	"golang.org/x/website/internal/godoc"
+)
+
+const (
+	downloadsJSON = "https://golang.org/dl/?mode=json&include=all"
+	downloadsBase = "https://dl.google.com/go/"
+)
+
+// initVersions adds the Go versions named by the -versions and
+// -versiondir flags to pres, first downloading the source archives
+// of the versions named by -fetchversions into -versiondir.
+func initVersions(pres *godoc.Presentation) error {
+	if *versionList != "" {
+		for _, kv := range strings.Split(*versionList, ",") {
+			i := strings.Index(kv, "=")
+			if i < 0 {
+				return fmt.Errorf("-versions: %q is not version=goroot", kv)
+			}
+			pres.Versions.Add(kv[:i], os.DirFS(kv[i+1:]))
+		}
+	}
+	if *versionDir == "" {
+		if *fetchList != "" {
+			return fmt.Errorf("-fetchversions requires -versiondir")
+		}
+		return nil
+	}
+
+	if *fetchList != "" {
+		var missing []string
+		for _, v := range strings.Split(*fetchList, ",") {
+			if _, err := os.Stat(filepath.Join(*versionDir, v)); os.IsNotExist(err) {
+				missing = append(missing, v)
+			}
+		}
+		if len(missing) > 0 {
+			if err := fetchSources(*versionDir, missing); err != nil {
+				return err
+			}
+		}
+	}
+
+	list, err := ioutil.ReadDir(*versionDir)
+	if err != nil {
+		return err
+	}
+	for _, fi := range list {
+		if !fi.IsDir() || strings.HasPrefix(fi.Name(), ".") {
+			continue
+		}
+		// Source archives unpack to a go directory.
+		goroot := filepath.Join(*versionDir, fi.Name())
+		if _, err := os.Stat(filepath.Join(goroot, "go", "src")); err == nil {
+			goroot = filepath.Join(goroot, "go")
+		}
+		pres.Versions.Add(fi.Name(), os.DirFS(goroot))
+	}
+	return nil
+}
+
+// fetchSources downloads the source archives of the Go versions
+// in the download list and unpacks each into dir/version.
+func fetchSources(dir string, versions []string) error {
+	resp, err := http.Get(downloadsJSON)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", downloadsJSON, resp.Status)
+	}
+	var releases []dl.Release
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return fmt.Errorf("%s: %v", downloadsJSON, err)
+	}
+
+	for _, v := range versions {
+		var src *dl.File
+		for _, r := range releases {
+			for i, f := range r.Files {
+				if f.Version == v && f.Kind == "source" {
+					src = &r.Files[i]
+				}
+			}
+		}
+		if src == nil {
+			return fmt.Errorf("no source archive for %s in the download list", v)
+		}
+		log.Printf("fetching %s", src.Filename)
+		if err := fetchSource(filepath.Join(dir, v), *src); err != nil {
+			return fmt.Errorf("fetching %s: %v", src.Filename, err)
+		}
+	}
+	return nil
+}
+
+// fetchSource downloads the source archive f, checks its SHA256
+// checksum and unpacks it into dir.
+func fetchSource(dir string, f dl.File) error {
+	resp, err := http.Get(downloadsBase + f.Filename)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s", resp.Status)
+	}
+
+	// Unpack into a temporary directory, and only move it
+	// into place once the checksum has been verified.
+	tmp, err := ioutil.TempDir(filepath.Dir(dir), ".fetch")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	h := sha256.New()
+	if err := untar(tmp, io.TeeReader(resp.Body, h)); err != nil {
+		return err
+	}
+	// Drain the rest of the archive to complete the checksum.
+	if _, err := io.Copy(h, resp.Body); err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != f.ChecksumSHA256 {
+		return fmt.Errorf("SHA256 checksum mismatch: got %s, want %s", sum, f.ChecksumSHA256)
+	}
+	return os.Rename(tmp, dir)
+}
+
+// untar unpacks the directories and regular files
+// of the gzipped tar archive r into dir.
+func untar(dir string, r io.Reader) error {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := filepath.FromSlash(hdr.Name)
+		if filepath.IsAbs(name) || strings.HasPrefix(filepath.Clean(name), "..") {
+			return fmt.Errorf("invalid file name %q", hdr.Name)
+		}
+		name = filepath.Join(dir, name)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(name, 0777); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+				return err
+			}
+			w, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(w, tr)
+			if cerr := w.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -12,52 +12,36 @@
 package main
 
 import (
-	"html/template"
-	"log"
 	"net/http"
 	"strings"
 
 	"golang.org/x/build/repos"
+	"golang.org/x/website/internal/vanity"
 )
 
-func xHandler(w http.ResponseWriter, r *http.Request) {
-	if !strings.HasPrefix(r.URL.Path, "/x/") {
-		// Shouldn't happen if handler is registered correctly.
-		http.Redirect(w, r, "https://pkg.go.dev/search?q=golang.org/x", http.StatusTemporaryRedirect)
-		return
+// xConfig maps golang.org/x/PROJ to the Gerrit project PROJ,
+// for every sub-repository listed in x/build/repos.
+var xConfig = func() *vanity.Config {
+	cfg := &vanity.Config{
+		Host:  "golang.org/x",
+		Index: "https://pkg.go.dev/search?q=golang.org/x",
 	}
-	proj, suffix := strings.TrimPrefix(r.URL.Path, "/x/"), ""
-	if i := strings.Index(proj, "/"); i != -1 {
-		proj, suffix = proj[:i], proj[i:]
+	for proj, repo := range repos.ByGerritProject {
+		if !strings.HasPrefix(repo.ImportPath, "golang.org/x/") {
+			continue
+		}
+		cfg.Paths = append(cfg.Paths, &vanity.Path{
+			Prefix: "golang.org/x/" + proj,
+			VCS:    "git",
+			Repo:   "https://go.googlesource.com/" + proj,
+			Source: &vanity.Source{
+				Home: "https://github.com/golang/" + proj + "/",
+				Dir:  "https://github.com/golang/" + proj + "/tree/master{/dir}",
+				File: "https://github.com/golang/" + proj + "/blob/master{/dir}/{file}#L{line}",
+			},
+		})
 	}
-	if proj == "" {
-		http.Redirect(w, r, "https://pkg.go.dev/search?q=golang.org/x", http.StatusTemporaryRedirect)
-		return
-	}
-	repo, ok := repos.ByGerritProject[proj]
-	if !ok || !strings.HasPrefix(repo.ImportPath, "golang.org/x/") {
-		http.NotFound(w, r)
-		return
-	}
-	data := struct {
-		Proj   string // Gerrit project ("net", "sys", etc)
-		Suffix string // optional "/path" for requests like /x/PROJ/path
-	}{proj, suffix}
-	if err := xTemplate.Execute(w, data); err != nil {
-		log.Println("xHandler:", err)
-	}
-}
+	return cfg
+}()
 
-var xTemplate = template.Must(template.New("x").Parse(`<!DOCTYPE html>
-<html>
-<head>
-<meta http-equiv="Content-Type" content="text/html; charset=utf-8"/>
-<meta name="go-import" content="golang.org/x/{{.Proj}} git https://go.googlesource.com/{{.Proj}}">
-<meta name="go-source" content="golang.org/x/{{.Proj}} https://github.com/golang/{{.Proj}}/ https://github.com/golang/{{.Proj}}/tree/master{/dir} https://github.com/golang/{{.Proj}}/blob/master{/dir}/{file}#L{line}">
-<meta http-equiv="refresh" content="0; url=https://pkg.go.dev/golang.org/x/{{.Proj}}{{.Suffix}}">
-</head>
-<body>
-<a href="https://pkg.go.dev/golang.org/x/{{.Proj}}{{.Suffix}}">Redirecting to documentation...</a>
-</body>
-</html>
-`))
+var xHandler = http.StripPrefix("/x", vanity.Handler(xConfig)).ServeHTTP
@@ -103,3 +103,9 @@ func TestXHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestXConfig(t *testing.T) {
+	if err := xConfig.Check(); err != nil {
+		t.Error(err)
+	}
+}
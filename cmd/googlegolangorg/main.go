@@ -1,84 +1,45 @@
-// A trivial redirector for google.golang.org.
+// A trivial redirector for google.golang.org,
+// serving the import paths configured in vanity.yaml.
 package main
 
 import (
+	"flag"
 	"fmt"
-	"html/template"
+	"log"
 	"net/http"
 	"os"
-	"strings"
-)
-
-var repoMap = map[string]*repoImport{
-	"api": {
-		VCS: "git",
-		URL: "https://github.com/googleapis/google-api-go-client",
-		Src: github("googleapis/google-api-go-client"),
-	},
-	"appengine": {
-		VCS: "git",
-		URL: "https://github.com/golang/appengine",
-		Src: github("golang/appengine"),
-	},
-	"cloud": {
-		// This repo is now at "cloud.google.com/go", but still specifying the repo
-		// here gives nicer errors in the go tool.
-		VCS: "git",
-		URL: "https://github.com/googleapis/google-cloud-go",
-		Src: github("googleapis/google-cloud-go"),
-	},
-	"genproto": {
-		VCS: "git",
-		URL: "https://github.com/googleapis/go-genproto",
-		Src: github("googleapis/go-genproto"),
-	},
-	"grpc": {
-		VCS: "git",
-		URL: "https://github.com/grpc/grpc-go",
-		Src: github("grpc/grpc-go"),
-	},
-	"protobuf": {
-		VCS: "git",
-		URL: "https://go.googlesource.com/protobuf",
-		Src: github("protocolbuffers/protobuf-go"),
-	},
-}
 
-// repoImport represents an import meta-tag, as per
-// https://golang.org/cmd/go/#hdr-Import_path_syntax
-type repoImport struct {
-	VCS string
-	URL string
-	Src *src
-}
+	"golang.org/x/website/internal/modproxy"
+	"golang.org/x/website/internal/vanity"
+)
 
-// src represents a pkg.go.dev source redirect.
-// https://github.com/golang/gddo/search?utf8=%E2%9C%93&q=sourceMeta
-type src struct {
-	URL     string
-	DirTpl  string
-	FileTpl string
-}
+var (
+	configFile = flag.String("config", "vanity.yaml", "read import path mappings from `file` (YAML or JSON)")
+	check      = flag.Bool("check", false, "check the mappings and exit")
+	modProxy   = flag.String("modproxy", "", "serve the modules at /modproxy/ from this module cache download directory, or from this upstream proxy URL")
+	modSums    = flag.String("modsums", "https://sum.golang.org", "with -modproxy, verify modules against this sum.golang.org checksum database URL, or against the checksums in this go.sum file")
+)
 
-// github returns the *src representing a github repo.
-func github(base string) *src {
-	return &src{
-		URL:     fmt.Sprintf("https://github.com/%s", base),
-		DirTpl:  fmt.Sprintf("https://github.com/%s/tree/master{/dir}", base),
-		FileTpl: fmt.Sprintf("https://github.com/%s/tree/master{/dir}/{file}#L{line}", base),
+func main() {
+	flag.Parse()
+	cfg, err := vanity.LoadConfig(*configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
 	}
-}
-
-func googsource(repo, base string) *src {
-	return &src{
-		URL:     fmt.Sprintf("https://%s.googlesource.com/%s", repo, base),
-		DirTpl:  fmt.Sprintf("https://%s.googlesource.com/%s/+/master{/dir}", repo, base),
-		FileTpl: fmt.Sprintf("https://%s.googlesource.com/%s/+/master{/dir}/{file}#{line}", repo, base),
+	if err := cfg.Check(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s:\n%v\n", *configFile, err)
+		os.Exit(1)
+	}
+	if *check {
+		fmt.Printf("%s: %d paths OK\n", *configFile, len(cfg.Paths))
+		return
 	}
-}
 
-func main() {
-	http.HandleFunc("/", handler)
+	http.Handle("/", vanity.Handler(cfg))
+	if *modProxy != "" {
+		http.Handle("/modproxy/", http.StripPrefix("/modproxy", modproxy.Handler(modProxyConfig(cfg))))
+	}
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -93,47 +54,16 @@ func main() {
 	}
 }
 
-func handler(w http.ResponseWriter, r *http.Request) {
-	head, tail := strings.TrimPrefix(r.URL.Path, "/"), ""
-	if i := strings.Index(head, "/"); i != -1 {
-		head, tail = head[:i], head[i:]
+// modProxyConfig returns the configuration for serving the modules
+// under the import paths in cfg, as set by the -modproxy and -modsums flags.
+func modProxyConfig(cfg *vanity.Config) *modproxy.Config {
+	var prefixes []string
+	for _, p := range cfg.Paths {
+		prefixes = append(prefixes, p.Prefix)
 	}
-	if head == "" {
-		http.Redirect(w, r, "https://cloud.google.com/go/google.golang.org", http.StatusFound)
-		return
-	}
-	repo, ok := repoMap[head]
-	if !ok {
-		http.NotFound(w, r)
-		return
-	}
-	godoc := "https://pkg.go.dev/google.golang.org/" + head + tail
-	// For users visting in a browser, redirect straight to pkg.go.dev.
-	if isBrowser := r.FormValue("go-get") == ""; isBrowser {
-		http.Redirect(w, r, godoc, http.StatusFound)
-		return
-	}
-	data := struct {
-		Head, GoDoc string
-		Repo        *repoImport
-	}{head, godoc, repo}
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if err := tmpl.Execute(w, data); err != nil {
-		fmt.Fprintf(os.Stderr, "tmpl.Execute: %v\n", err)
+	mcfg, err := modproxy.NewConfig(prefixes, *modProxy, *modSums)
+	if err != nil {
+		log.Fatalf("-modsums: %v", err)
 	}
+	return mcfg
 }
-
-var tmpl = template.Must(template.New("redir").Parse(`<!DOCTYPE html>
-<html>
-<head>
-<meta name="go-import" content="google.golang.org/{{.Head}} {{.Repo.VCS}} {{.Repo.URL}}">
-{{if .Repo.Src}}
-<meta name="go-source" content="google.golang.org/{{.Head}} {{.Repo.Src.URL}} {{.Repo.Src.DirTpl}} {{.Repo.Src.FileTpl}}">
-{{end}}
-<meta http-equiv="refresh" content="0; url={{.GoDoc}}">
-</head>
-<body>
-Nothing to see here. Please <a href="{{.GoDoc}}">move along</a>.
-</body>
-</html>
-`))
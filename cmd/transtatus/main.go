@@ -0,0 +1,102 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+// Transtatus reports the translation status of the website documents.
+//
+// Usage:
+//
+//	transtatus [-repo dir] [-json] transdir
+//
+// Transtatus walks the English documents in the website repository
+// (_content, tour/content/*.article and blog/_content/*.article)
+// and looks for a translation of each at the same path under transdir.
+// For each translated document it reads the UpstreamRev recorded in its
+// metadata and uses git to count the lines of the English original
+// that have changed since that revision.
+//
+// The -json output is served as a dashboard by golangorg at /translations/
+// when it is saved as translations.json next to the translated _content:
+//
+//	transtatus -json transdir >transdir/_content/translations.json
+//	golangorg -translations=transdir/_content
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"golang.org/x/website/internal/translation"
+)
+
+var (
+	repoDir = flag.String("repo", ".", "root of the English website repository")
+	jsonOut = flag.Bool("json", false, "write the report as JSON")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: transtatus [-repo dir] [-json] transdir\n")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	log.SetPrefix("transtatus: ")
+	log.SetFlags(0)
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() != 1 {
+		usage()
+	}
+
+	r, err := translation.Scan(os.DirFS(*repoDir), os.DirFS(flag.Arg(0)), gitDiff)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+		err = enc.Encode(r)
+	} else {
+		err = r.WriteText(os.Stdout)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// gitDiff returns the number of lines added or deleted in the named file
+// of the repository between rev and HEAD.
+func gitDiff(rev, name string) (int, error) {
+	cmd := exec.Command("git", "diff", "--numstat", rev, "HEAD", "--", name)
+	cmd.Dir = *repoDir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("git diff %s: %v\n%s", rev, err, stderr.Bytes())
+	}
+	total := 0
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		f := strings.Fields(line)
+		if len(f) < 3 {
+			continue
+		}
+		// Binary files report "-" for both counts.
+		added, _ := strconv.Atoi(f[0])
+		deleted, _ := strconv.Atoi(f[1])
+		total += added + deleted
+	}
+	return total, nil
+}
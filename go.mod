@@ -8,8 +8,10 @@ require (
 	github.com/gomodule/redigo v2.0.0+incompatible
 	github.com/yuin/goldmark v1.2.1
 	golang.org/x/build v0.0.0-20210422214718-6469a76194d9
+	golang.org/x/mod v0.4.1
 	golang.org/x/net v0.0.0-20201110031124-69a78807bb2b
 	golang.org/x/tools v0.1.1-0.20210215123931-123adc86bcb6
 	google.golang.org/api v0.27.0 // indirect
 	google.golang.org/genproto v0.0.0-20200617032506-f1bdc9086088 // indirect
+	gopkg.in/yaml.v2 v2.3.0
 )
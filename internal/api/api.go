@@ -33,21 +33,28 @@ type DB map[string]PkgDB // keyed by Go package ("net/http")
 //
 // Only things added after Go1 are tracked. Version strings are of the
 // form "1.1", "1.2", etc.
+//
+// A symbol that exists only on some operating systems or architectures
+// is recorded with the earliest version that added it on any of them,
+// and not at all if it was present on any of them in Go 1.
 type PkgDB struct {
 	Type   map[string]string            // "Server" -> "1.7"
 	Method map[string]map[string]string // "*Server" ->"Shutdown"->1.8
 	Func   map[string]string            // "NewServer" -> "1.7"
 	Field  map[string]map[string]string // "ClientTrace" -> "Got1xxResponse" -> "1.11"
+	Const  map[string]string            // "StatusEarlyHints" -> "1.13"
+	Var    map[string]string            // "ErrAbortHandler" -> "1.8"
 }
 
 // Func returns a string (such as "1.7") specifying which Go
 // version introduced a symbol, unless it was introduced in Go1, in
 // which case it returns the empty string.
 //
-// The kind is one of "type", "method", or "func".
+// The kind is one of "type", "method", "func", "field", "const", or "var".
 //
 // The receiver is only used for "methods" and specifies the receiver type,
-// such as "*Server".
+// such as "*Server". For "field", it specifies the struct type, such as
+// "ClientTrace".
 //
 // The name is the symbol name ("Server") and the pkg is the package
 // ("net/http").
@@ -60,11 +67,35 @@ func (v DB) Func(kind, receiver, name, pkg string) string {
 		return pv.Type[name]
 	case "method":
 		return pv.Method[receiver][name]
+	case "field":
+		return pv.Field[receiver][name]
+	case "const":
+		return pv.Const[name]
+	case "var":
+		return pv.Var[name]
 	}
 	return ""
 }
 
+// Load parses the $GOROOT/api/go*.txt files and returns
+// the version of Go that added each symbol.
 func Load() (DB, error) {
+	files, err := apiFiles()
+	if err != nil {
+		return nil, err
+	}
+	vp := new(parser)
+	for _, f := range files {
+		if err := vp.parseFile(f); err != nil {
+			return nil, err
+		}
+	}
+	return vp.res, nil
+}
+
+// apiFiles returns the names of the $GOROOT/api/go*.txt files,
+// newest version first.
+func apiFiles() ([]string, error) {
 	var apiGlob string
 	if os.Getenv("GOROOT") == "" {
 		apiGlob = filepath.Join(build.Default.GOROOT, "api", "go*.txt")
@@ -86,23 +117,34 @@ func Load() (DB, error) {
 	// order means we end up with the earliest version of Go
 	// when the symbol was added. See golang.org/issue/44081.
 	//
-	ver := func(name string) int {
-		base := filepath.Base(name)
-		ver := strings.TrimPrefix(strings.TrimSuffix(base, ".txt"), "go1.")
-		if ver == "go1" {
-			return 0
-		}
-		v, _ := strconv.Atoi(ver)
-		return v
+	sort.Slice(files, func(i, j int) bool {
+		return minor(fileVersion(files[i])) > minor(fileVersion(files[j]))
+	})
+	return files, nil
+}
+
+// fileVersion returns the version ("1", "1.16") of the named
+// $GOROOT/api/goVERSION.txt file.
+func fileVersion(name string) string {
+	base := filepath.Base(name)
+	return strings.TrimPrefix(strings.TrimSuffix(base, ".txt"), "go")
+}
+
+// minor returns the minor version number of the Go version v,
+// such as 16 for "1.16" or 0 for "1". It returns -1 if v is not
+// a Go 1 version.
+func minor(v string) int {
+	if v == "1" {
+		return 0
 	}
-	sort.Slice(files, func(i, j int) bool { return ver(files[i]) > ver(files[j]) })
-	vp := new(parser)
-	for _, f := range files {
-		if err := vp.parseFile(f); err != nil {
-			return nil, err
-		}
+	if !strings.HasPrefix(v, "1.") {
+		return -1
 	}
-	return vp.res, nil
+	n, err := strconv.Atoi(v[len("1."):])
+	if err != nil {
+		return -1
+	}
+	return n
 }
 
 // parser parses $GOROOT/api/go*.txt files and stores them in in its rows field.
@@ -123,8 +165,7 @@ func (vp *parser) parseFile(name string) error {
 	}
 	defer f.Close()
 
-	base := filepath.Base(name)
-	ver := strings.TrimPrefix(strings.TrimSuffix(base, ".txt"), "go")
+	ver := fileVersion(name)
 
 	sc := bufio.NewScanner(f)
 	for sc.Scan() {
@@ -142,6 +183,8 @@ func (vp *parser) parseFile(name string) error {
 				Method: make(map[string]map[string]string),
 				Func:   make(map[string]string),
 				Field:  make(map[string]map[string]string),
+				Const:  make(map[string]string),
+				Var:    make(map[string]string),
 			}
 			vp.res[row.pkg] = pkgi
 		}
@@ -176,6 +219,18 @@ func (vp *parser) parseFile(name string) error {
 				pkgi.Field[row.structName] = make(map[string]string)
 			}
 			pkgi.Field[row.structName][row.name] = ver
+		case "const":
+			if ver == "1" {
+				delete(pkgi.Const, row.name)
+				break
+			}
+			pkgi.Const[row.name] = ver
+		case "var":
+			if ver == "1" {
+				delete(pkgi.Var, row.name)
+				break
+			}
+			pkgi.Var[row.name] = ver
 		}
 	}
 	return sc.Err()
@@ -185,7 +240,9 @@ func (vp *parser) parseFile(name string) error {
 // $GOROOT/api/go.*txt file.
 type row struct {
 	pkg        string // "net/http"
-	kind       string // "type", "func", "method", "field" TODO: "const", "var"
+	platform   string // "darwin-amd64", or "" if on all platforms
+	kind       string // "type", "func", "method", "field", "const", "var"
+	feature    string // the line after the package and platform: "func NewServer() *Server"
 	recv       string // for methods, the receiver type ("Server", "*Server")
 	name       string // name of type, (struct) field, func, method
 	structName string // for struct fields, the outer struct name
@@ -202,13 +259,20 @@ func parseRow(s string) (vr row, ok bool) {
 		return
 	}
 	vr.pkg, rest = rest[:endPkg], rest[endPkg:]
-	if !strings.HasPrefix(rest, ", ") {
-		// If the part after the pkg name isn't ", ", then it's a OS/ARCH-dependent line of the form:
+	if strings.HasPrefix(rest, " (") {
+		// An OS/ARCH-dependent line of the form:
 		//   pkg syscall (darwin-amd64), const ImplementsGetwd = false
-		// We skip those for now.
+		end := strings.IndexByte(rest, ')')
+		if end == -1 {
+			return
+		}
+		vr.platform, rest = rest[len(" ("):end], rest[end+1:]
+	}
+	if !strings.HasPrefix(rest, ", ") {
 		return
 	}
 	rest = rest[len(", "):]
+	vr.feature = rest
 
 	switch {
 	case strings.HasPrefix(rest, "type "):
@@ -223,6 +287,15 @@ func parseRow(s string) (vr row, ok bool) {
 			return vr, true
 		}
 		rest = rest[len("struct, "):]
+		if strings.HasPrefix(rest, "embedded ") { // "embedded *bufio.Reader"
+			vr.kind = "field"
+			vr.structName = vr.name
+			vr.name = strings.TrimLeft(rest[len("embedded "):], "*")
+			if i := strings.LastIndexByte(vr.name, '.'); i != -1 {
+				vr.name = vr.name[i+1:]
+			}
+			return vr, true
+		}
 		if i := strings.IndexByte(rest, ' '); i != -1 {
 			vr.kind = "field"
 			vr.structName = vr.name
@@ -251,6 +324,20 @@ func parseRow(s string) (vr row, ok bool) {
 		}
 		vr.name = rest[:paren]
 		return vr, true
+	case strings.HasPrefix(rest, "const "): // "const MaxInt8 = 127", "const MaxInt8 ideal-int"
+		vr.kind = "const"
+		rest = rest[len("const "):]
+		if i := strings.IndexByte(rest, ' '); i != -1 {
+			vr.name = rest[:i]
+			return vr, true
+		}
+	case strings.HasPrefix(rest, "var "): // "var ErrProcessDone error"
+		vr.kind = "var"
+		rest = rest[len("var "):]
+		if i := strings.IndexByte(rest, ' '); i != -1 {
+			vr.name = rest[:i]
+			return vr, true
+		}
 	}
-	return // TODO: handle more cases
+	return
 }
@@ -26,14 +26,16 @@ func TestParseVersionRow(t *testing.T) {
 		{
 			row: "pkg archive/tar, type Writer struct",
 			want: row{
-				pkg:  "archive/tar",
-				kind: "type",
-				name: "Writer",
+				feature: "type Writer struct",
+				pkg:     "archive/tar",
+				kind:    "type",
+				name:    "Writer",
 			},
 		},
 		{
 			row: "pkg archive/tar, type Header struct, AccessTime time.Time",
 			want: row{
+				feature:    "type Header struct, AccessTime time.Time",
 				pkg:        "archive/tar",
 				kind:       "field",
 				structName: "Header",
@@ -43,27 +45,77 @@ func TestParseVersionRow(t *testing.T) {
 		{
 			row: "pkg archive/tar, method (*Reader) Read([]uint8) (int, error)",
 			want: row{
-				pkg:  "archive/tar",
-				kind: "method",
-				name: "Read",
-				recv: "*Reader",
+				feature: "method (*Reader) Read([]uint8) (int, error)",
+				pkg:     "archive/tar",
+				kind:    "method",
+				name:    "Read",
+				recv:    "*Reader",
 			},
 		},
 		{
 			row: "pkg archive/zip, func FileInfoHeader(os.FileInfo) (*FileHeader, error)",
 			want: row{
-				pkg:  "archive/zip",
-				kind: "func",
-				name: "FileInfoHeader",
+				feature: "func FileInfoHeader(os.FileInfo) (*FileHeader, error)",
+				pkg:     "archive/zip",
+				kind:    "func",
+				name:    "FileInfoHeader",
 			},
 		},
 		{
 			row: "pkg encoding/base32, method (Encoding) WithPadding(int32) *Encoding",
 			want: row{
-				pkg:  "encoding/base32",
-				kind: "method",
-				name: "WithPadding",
-				recv: "Encoding",
+				feature: "method (Encoding) WithPadding(int32) *Encoding",
+				pkg:     "encoding/base32",
+				kind:    "method",
+				name:    "WithPadding",
+				recv:    "Encoding",
+			},
+		},
+		{
+			row: "pkg bufio, type ReadWriter struct, embedded *Reader",
+			want: row{
+				pkg:        "bufio",
+				kind:       "field",
+				feature:    "type ReadWriter struct, embedded *Reader",
+				structName: "ReadWriter",
+				name:       "Reader",
+			},
+		},
+		{
+			row: "pkg math, const MaxInt8 = 127",
+			want: row{
+				pkg:     "math",
+				kind:    "const",
+				feature: "const MaxInt8 = 127",
+				name:    "MaxInt8",
+			},
+		},
+		{
+			row: "pkg math, const MaxInt8 ideal-int",
+			want: row{
+				pkg:     "math",
+				kind:    "const",
+				feature: "const MaxInt8 ideal-int",
+				name:    "MaxInt8",
+			},
+		},
+		{
+			row: "pkg os, var ErrProcessDone error",
+			want: row{
+				pkg:     "os",
+				kind:    "var",
+				feature: "var ErrProcessDone error",
+				name:    "ErrProcessDone",
+			},
+		},
+		{
+			row: "pkg syscall (darwin-amd64-cgo), const ImplementsGetwd = true",
+			want: row{
+				pkg:      "syscall",
+				platform: "darwin-amd64-cgo",
+				kind:     "const",
+				feature:  "const ImplementsGetwd = true",
+				name:     "ImplementsGetwd",
 			},
 		},
 	}
@@ -116,6 +168,11 @@ func TestAPIVersion(t *testing.T) {
 		{"type", "strings", "Builder", "", "1.10"},
 		{"method", "strings", "WriteString", "*Builder", "1.10"},
 
+		{"const", "net/http", "StatusEarlyHints", "", "1.13"},
+		{"var", "net/http", "ErrAbortHandler", "", "1.8"},
+		{"field", "net/http/httptrace", "Got1xxResponse", "ClientTrace", "1.11"},
+		{"const", "syscall", "SizeofInet4Pktinfo", "", ""}, // per-platform, Go 1 era
+
 		// Should get the earliest Go version when an identifier
 		// was initially added, rather than a later version when
 		// it may have been updated. See issue 44081.
@@ -140,3 +197,41 @@ func TestAPIVersion(t *testing.T) {
 		}
 	}
 }
+
+func TestFeaturesDiff(t *testing.T) {
+	if !hasTag("go1.16") {
+		t.Skip("needs Go 1.16 API files")
+	}
+	fs, err := LoadFeatures()
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := fs.Diff("1.14", "1.16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	have := make(map[string]string)
+	for _, pc := range list {
+		for _, f := range pc.Features {
+			if f.Pkg != pc.Pkg {
+				t.Errorf("feature %q of %s listed under %s", f.Text, f.Pkg, pc.Pkg)
+			}
+			have[f.Pkg+", "+f.Text] = f.Version
+		}
+	}
+	for feature, want := range map[string]string{
+		"os, func ReadFile(string) ([]uint8, error)":              "1.16",
+		"io/fs, type FS interface { Open }":                       "1.16",
+		"crypto/tls, method (*Conn) VerifyHostname(string) error": "",
+		"strings, type Builder struct":                            "",
+		"time, method (*Ticker) Reset(Duration)":                  "1.15",
+	} {
+		if got := have[feature]; got != want {
+			t.Errorf("Diff(1.14, 1.16) has %q in %q; want %q", feature, got, want)
+		}
+	}
+
+	if _, err := fs.Diff("1.14", "2.0"); err == nil {
+		t.Errorf("Diff(1.14, 2.0) succeeded; want error")
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Features lists the API features of the standard library
+// together with the Go version that added them.
+type Features struct {
+	Versions []string   // "1", "1.1", ..., newest last
+	list     []*Feature // sorted by package, then feature
+}
+
+// A Feature is one line of a $GOROOT/api/goVERSION.txt file,
+// merged across all platforms on which it was added in the same version.
+type Feature struct {
+	Pkg       string   // "net/http"
+	Version   string   // "1.16"
+	Text      string   // "func NewServer() *Server"
+	Platforms []string // "darwin-amd64", ...; nil if on all platforms
+}
+
+// LoadFeatures parses the $GOROOT/api/go*.txt files and returns
+// the features listed in them.
+func LoadFeatures() (*Features, error) {
+	files, err := apiFiles()
+	if err != nil {
+		return nil, err
+	}
+	type key struct{ pkg, ver, text string }
+	seen := make(map[key]*Feature)
+	fs := new(Features)
+	for i := len(files) - 1; i >= 0; i-- {
+		ver := fileVersion(files[i])
+		if minor(ver) < 0 {
+			continue
+		}
+		fs.Versions = append(fs.Versions, ver)
+		err := readRows(files[i], func(row row) {
+			k := key{row.pkg, ver, row.feature}
+			f := seen[k]
+			if f == nil {
+				f = &Feature{Pkg: row.pkg, Version: ver, Text: row.feature}
+				seen[k] = f
+				fs.list = append(fs.list, f)
+				if row.platform != "" {
+					f.Platforms = []string{}
+				}
+			}
+			if row.platform == "" {
+				f.Platforms = nil
+			} else if f.Platforms != nil {
+				f.Platforms = append(f.Platforms, row.platform)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(fs.list, func(i, j int) bool {
+		if fs.list[i].Pkg != fs.list[j].Pkg {
+			return fs.list[i].Pkg < fs.list[j].Pkg
+		}
+		return fs.list[i].Text < fs.list[j].Text
+	})
+	return fs, nil
+}
+
+// readRows calls f for each API feature in the named file.
+func readRows(name string, f func(row)) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	sc := bufio.NewScanner(file)
+	for sc.Scan() {
+		if row, ok := parseRow(sc.Text()); ok {
+			f(row)
+		}
+	}
+	return sc.Err()
+}
+
+// Latest returns the newest version in fs, or "" if fs is empty.
+func (fs *Features) Latest() string {
+	if fs == nil || len(fs.Versions) == 0 {
+		return ""
+	}
+	return fs.Versions[len(fs.Versions)-1]
+}
+
+// A PkgChanges lists the features added to a package.
+type PkgChanges struct {
+	Pkg      string
+	Features []*Feature
+}
+
+// Diff returns the features added after version from, up to and
+// including version to, grouped by package.
+// The versions are of the form "1.14"; "1" means Go 1.
+func (fs *Features) Diff(from, to string) ([]*PkgChanges, error) {
+	lo, hi := minor(from), minor(to)
+	if lo < 0 {
+		return nil, fmt.Errorf("invalid version %q", from)
+	}
+	if hi < 0 {
+		return nil, fmt.Errorf("invalid version %q", to)
+	}
+	var list []*PkgChanges
+	for _, f := range fs.list {
+		if v := minor(f.Version); v <= lo || v > hi {
+			continue
+		}
+		if len(list) == 0 || list[len(list)-1].Pkg != f.Pkg {
+			list = append(list, &PkgChanges{Pkg: f.Pkg})
+		}
+		pc := list[len(list)-1]
+		pc.Features = append(pc.Features, f)
+	}
+	return list, nil
+}
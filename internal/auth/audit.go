@@ -0,0 +1,197 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/website/internal/storage"
+)
+
+// An AuditEntry records one request that may have changed something.
+// Entries are stored as "AuditLog" entities keyed by time,
+// so that listing them returns them oldest first.
+type AuditEntry struct {
+	Time       time.Time
+	User       string
+	AuthMethod string // "oidc", "token" or "mtls"
+	Role       string
+	Method     string // HTTP method
+	URL        string // path and query
+	Body       string `datastore:",noindex"` // form values, URL-encoded, or the start of another body
+	Status     int    // HTTP status of the response
+}
+
+const (
+	// maxAuditBody is the maximum length of AuditEntry.Body.
+	maxAuditBody = 2000
+
+	maxAuditEntries = 500
+)
+
+// audit records in the audit log that id made the request r,
+// receiving a response with the given status. If body is not nil,
+// it holds the part of r's body read by the handler.
+func (a *Auth) audit(r *http.Request, id *Identity, body *bodyRecorder, status int) {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	t := timeNow().UTC()
+	e := &AuditEntry{
+		Time:       t,
+		User:       id.User,
+		AuthMethod: id.Method,
+		Role:       id.Role.String(),
+		Method:     r.Method,
+		URL:        r.URL.RequestURI(),
+		Body:       auditBody(r, body),
+		Status:     status,
+	}
+	log.Printf("audit: %s (%s) %s %s: %d", e.User, e.AuthMethod, e.Method, e.URL, e.Status)
+	if a.DB == nil {
+		return
+	}
+	// The random suffix keeps entries from overwriting each other
+	// if they are made at the same time.
+	key := fmt.Sprintf("%s %s", t.Format("2006-01-02T15:04:05.000000000Z"), randomHex(4))
+	if err := a.DB.Put(r.Context(), "AuditLog", key, e); err != nil {
+		log.Printf("ERROR AuditLog entity: %v", err)
+	}
+}
+
+// auditBody returns the body of r for the audit log: the form values
+// parsed by the handler that served it, with uploaded files replaced
+// by their names, or else the start of the body read by the handler.
+func auditBody(r *http.Request, body *bodyRecorder) string {
+	if len(r.PostForm) == 0 && r.MultipartForm == nil && body != nil {
+		s := body.buf.String()
+		if body.truncated {
+			s += "..."
+		}
+		return s
+	}
+	vals := url.Values{}
+	for k, v := range r.PostForm {
+		vals[k] = v
+	}
+	if r.MultipartForm != nil {
+		for k, v := range r.MultipartForm.Value {
+			vals[k] = v
+		}
+		for k, files := range r.MultipartForm.File {
+			for _, f := range files {
+				vals.Add(k, "file:"+f.Filename)
+			}
+		}
+	}
+	s := vals.Encode()
+	if len(s) > maxAuditBody {
+		s = s[:maxAuditBody] + "..."
+	}
+	return s
+}
+
+// A bodyRecorder records the start of a request body as it is read.
+type bodyRecorder struct {
+	io.ReadCloser
+	buf       bytes.Buffer
+	truncated bool
+}
+
+func (b *bodyRecorder) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := maxAuditBody - b.buf.Len(); n > room {
+		b.buf.Write(p[:room])
+		b.truncated = true
+	} else {
+		b.buf.Write(p[:n])
+	}
+	return n, err
+}
+
+// AuditHandler serves the most recent entries of the audit log in db,
+// newest first. The user parameter limits the list to one user's requests.
+// The handler must itself be protected by Auth.Handler.
+func AuditHandler(db storage.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.FormValue("user")
+		field := ""
+		if user != "" {
+			field = "User"
+		}
+		var out []*AuditEntry
+		if err := db.GetLast(r.Context(), "AuditLog", field, user, maxAuditEntries, &out); err != nil {
+			log.Printf("ERROR listing AuditLog entities: %v", err)
+			http.Error(w, "could not list audit log", http.StatusInternalServerError)
+			return
+		}
+
+		data := struct {
+			User    string
+			Entries []*AuditEntry
+		}{user, out}
+		if err := auditTemplate.Execute(w, &data); err != nil {
+			log.Printf("ERROR auditTemplate: %v", err)
+		}
+	}
+}
+
+var auditTemplate = template.Must(template.New("audit").Parse(auditTemplateHTML))
+
+const auditTemplateHTML = `
+<!doctype html>
+<html>
+<head>
+<title>Admin audit log</title>
+<style>
+body {
+	font-family: sans-serif;
+}
+table {
+	border-collapse: collapse;
+}
+th, td {
+	border-bottom: 1px solid #ccc;
+	padding: 0.2em 0.5em;
+	text-align: left;
+	vertical-align: top;
+}
+.denied {
+	color: #a00;
+}
+</style>
+</head>
+<body>
+<h1>Admin audit log</h1>
+<form>
+Filter by user: <input type="text" name="user" value="{{.User}}">
+<input type="submit" value="Filter">
+{{if .User}}<a href="?">Show all</a>{{end}}
+</form>
+<table>
+<tr><th>Time (UTC)</th><th>User</th><th>Auth</th><th>Request</th><th>Body</th><th>Status</th></tr>
+{{range .Entries}}
+<tr{{if ge .Status 400}} class="denied"{{end}}>
+<td>{{.Time.Format "2006-01-02 15:04:05"}}</td>
+<td><a href="?user={{.User}}">{{.User}}</a> ({{.Role}})</td>
+<td>{{.AuthMethod}}</td>
+<td>{{.Method}} {{.URL}}</td>
+<td>{{.Body}}</td>
+<td>{{.Status}}</td>
+</tr>
+{{else}}
+<tr><td colspan="6">No entries.</td></tr>
+{{end}}
+</table>
+</body>
+</html>
+`
@@ -0,0 +1,218 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package auth authenticates and authorizes requests to the
+// administrative handlers served by cmd/admingolangorg.
+//
+// A request is authenticated by one of:
+//
+//   - a session cookie set after signing in with an OpenID Connect provider,
+//     or an ID token from that provider sent as an Authorization: Bearer header;
+//   - a static token sent as an Authorization: Bearer header,
+//     configured by the SHA256 of its value;
+//   - a TLS client certificate signed by a configured CA.
+//
+// Each of these yields a user name: the email address of an OpenID Connect
+// or certificate user, or the configured name of a token. The configuration
+// maps user names to roles. A Viewer may only make GET and HEAD requests;
+// an Editor may also make changes. Every request that may make a change is
+// recorded in the audit log, whether or not it is allowed.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/website/internal/storage"
+)
+
+// A Role is the level of access granted to a user.
+type Role int
+
+const (
+	None   Role = iota // no access
+	Viewer             // may view pages and lists
+	Editor             // may also make changes
+)
+
+func (r Role) String() string {
+	switch r {
+	case Viewer:
+		return "viewer"
+	case Editor:
+		return "editor"
+	}
+	return "none"
+}
+
+// ParseRole parses the name of a role, as returned by Role.String.
+func ParseRole(s string) (Role, error) {
+	switch s {
+	case "viewer":
+		return Viewer, nil
+	case "editor":
+		return Editor, nil
+	case "none":
+		return None, nil
+	}
+	return None, fmt.Errorf("unknown role %q", s)
+}
+
+// An Identity describes the authenticated user making a request.
+type Identity struct {
+	User   string // email address or token name
+	Method string // "oidc", "token" or "mtls"
+	Role   Role
+}
+
+// ErrNoCredentials is returned by an Authenticator for a request that
+// carries no credentials of the kind it checks.
+var ErrNoCredentials = errors.New("no credentials")
+
+// An Authenticator identifies the user making a request
+// from one kind of credentials.
+type Authenticator interface {
+	// Authenticate returns the user making the request r and the name of
+	// the authentication method. It returns ErrNoCredentials if r carries
+	// no credentials of its kind, and another error if they are invalid.
+	Authenticate(r *http.Request) (user, method string, err error)
+}
+
+type contextKey struct{}
+
+// WithIdentity returns a copy of ctx carrying id.
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the identity of the user making the request
+// with context ctx, or nil if the request was not authenticated.
+func FromContext(ctx context.Context) *Identity {
+	id, _ := ctx.Value(contextKey{}).(*Identity)
+	return id
+}
+
+// An Auth checks the requests to the handlers it protects.
+type Auth struct {
+	// Authenticators are tried in order until one finds credentials.
+	Authenticators []Authenticator
+
+	// Roles maps user names to their roles.
+	// Users not listed have no access.
+	Roles map[string]Role
+
+	// DB stores the audit log.
+	DB storage.DB
+
+	oidc *oidcAuth // if configured, also in Authenticators
+}
+
+var timeNow = time.Now // for testing
+
+// Handler returns a handler that serves authorized requests using h.
+// GET and HEAD requests require the Viewer role, all others Editor.
+// The identity of the user is available to h using FromContext.
+//
+// If an OpenID Connect provider is configured, the handler also serves
+// the sign-in flow under /auth/, and browsers making unauthenticated
+// requests are sent there.
+func (a *Auth) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.oidc != nil && strings.HasPrefix(r.URL.Path, authPrefix) {
+			a.oidc.serveAuth(w, r)
+			return
+		}
+
+		need := Editor
+		if r.Method == "GET" || r.Method == "HEAD" {
+			need = Viewer
+		}
+
+		id, err := a.authenticate(r)
+		if err != nil {
+			log.Printf("auth: %s %s: %v", r.Method, r.URL.Path, err)
+			http.Error(w, "authentication failed: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if id == nil {
+			if a.oidc != nil && need == Viewer && strings.Contains(r.Header.Get("Accept"), "text/html") {
+				http.Redirect(w, r, loginPath+"?return="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+
+		r = r.WithContext(WithIdentity(r.Context(), id))
+		if id.Role < need {
+			if need == Editor {
+				a.audit(r, id, nil, http.StatusForbidden)
+			}
+			http.Error(w, fmt.Sprintf("%s has role %v; %v required", id.User, id.Role, need), http.StatusForbidden)
+			return
+		}
+		if need == Viewer {
+			h.ServeHTTP(w, r)
+			return
+		}
+		sw := &statusWriter{ResponseWriter: w}
+		body := &bodyRecorder{ReadCloser: r.Body}
+		r.Body = body
+		h.ServeHTTP(sw, r)
+		a.audit(r, id, body, sw.status)
+	})
+}
+
+// authenticate returns the identity of the user making the request r,
+// or nil if r carries no credentials.
+func (a *Auth) authenticate(r *http.Request) (*Identity, error) {
+	for _, au := range a.Authenticators {
+		user, method, err := au.Authenticate(r)
+		if err == ErrNoCredentials {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &Identity{User: user, Method: method, Role: a.Roles[user]}, nil
+	}
+	return nil, nil
+}
+
+// A statusWriter records the status code of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// randomHex returns n random bytes in hexadecimal.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%x", b)
+}
@@ -0,0 +1,133 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/website/internal/storage"
+)
+
+func TestHandler(t *testing.T) {
+	db := storage.NewMemory()
+	a := &Auth{
+		Authenticators: []Authenticator{
+			Tokens(map[[sha256.Size]byte]string{
+				sha256.Sum256([]byte("editor-token")): "bot",
+				sha256.Sum256([]byte("viewer-token")): "dashboard",
+				sha256.Sum256([]byte("nobody-token")): "nobody",
+			}),
+			ClientCerts(),
+		},
+		Roles: map[string]Role{
+			"bot":               Editor,
+			"dashboard":         Viewer,
+			"gopher@golang.org": Editor,
+		},
+		DB: db,
+	}
+	h := a.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			ioutil.ReadAll(r.Body)
+		} else {
+			r.FormValue("key")
+		}
+		id := FromContext(r.Context())
+		fmt.Fprintf(w, "%s %s", id.User, id.Method)
+	}))
+
+	cert := &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "Gopher"},
+		EmailAddresses: []string{"gopher@golang.org"},
+	}
+	for _, tc := range []struct {
+		method, token, body string
+		cert                *x509.Certificate
+		code                int
+		out                 string
+	}{
+		{"GET", "", "", nil, 401, ""},
+		{"GET", "wrong-token", "", nil, 401, ""},
+		{"GET", "viewer-token", "", nil, 200, "dashboard token"},
+		{"POST", "viewer-token", "key=x", nil, 403, ""},
+		{"GET", "nobody-token", "", nil, 403, ""},
+		{"POST", "editor-token", "key=go1&target=https://golang.org/", nil, 200, "bot token"},
+		{"PUT", "editor-token", `{"target": "https://golang.org/"}`, nil, 200, "bot token"},
+		{"DELETE", "", "", cert, 200, "gopher@golang.org mtls"},
+	} {
+		req := httptest.NewRequest(tc.method, "/api/links/go1", strings.NewReader(tc.body))
+		if tc.method == "POST" {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		if tc.cert != nil {
+			req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{tc.cert}}}
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tc.code || tc.code == 200 && w.Body.String() != tc.out {
+			t.Errorf("%s as %q: %d %q, want %d %q", tc.method, tc.token, w.Code, w.Body.String(), tc.code, tc.out)
+		}
+	}
+
+	var log []*AuditEntry
+	if err := db.GetAll(context.Background(), "AuditLog", &log); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range log {
+		got = append(got, fmt.Sprintf("%s %s %s %s %d %s", e.User, e.Role, e.AuthMethod, e.Method, e.Status, e.Body))
+	}
+	want := []string{
+		"dashboard viewer token POST 403 ",
+		"bot editor token POST 200 key=go1&target=https%3A%2F%2Fgolang.org%2F",
+		`bot editor token PUT 200 {"target": "https://golang.org/"}`,
+		"gopher@golang.org editor mtls DELETE 200 ",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("audit log:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	w := httptest.NewRecorder()
+	AuditHandler(db).ServeHTTP(w, httptest.NewRequest("GET", "/audit?user=bot", nil))
+	if body := w.Body.String(); strings.Count(body, `<a href="?user=bot">bot</a>`) != 2 {
+		t.Errorf("audit page for bot does not list 2 entries:\n%s", body)
+	}
+}
+
+func TestNew(t *testing.T) {
+	var cfg Config
+	if _, err := New(&cfg, nil); err == nil {
+		t.Errorf("New with no authentication methods succeeded")
+	}
+	cfg.Roles = map[string]string{"bot": "owner"}
+	cfg.Tokens = append(cfg.Tokens, TokenConfig{"bot", fmt.Sprintf("%x", sha256.Sum256([]byte("secret")))})
+	if _, err := New(&cfg, nil); err == nil || !strings.Contains(err.Error(), "owner") {
+		t.Errorf("New with bad role: %v", err)
+	}
+	cfg.Roles["bot"] = "editor"
+	a, err := New(&cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "bearer secret")
+	id, err := a.authenticate(req)
+	if err != nil || id == nil || id.User != "bot" || id.Role != Editor {
+		t.Errorf("authenticate = %+v, %v, want bot editor", id, err)
+	}
+}
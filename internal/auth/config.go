@@ -0,0 +1,138 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"golang.org/x/website/internal/storage"
+)
+
+// A Config configures authentication. It is usually loaded from
+// a JSON file by LoadConfig, such as:
+//
+//	{
+//		"Roles": {
+//			"gopher@golang.org": "editor",
+//			"release-bot": "editor",
+//			"dashboard": "viewer"
+//		},
+//		"Tokens": [
+//			{"Name": "release-bot", "SHA256": "9f86d08..."}
+//		],
+//		"OIDC": {
+//			"Issuer": "https://accounts.google.com",
+//			"ClientID": "1234.apps.googleusercontent.com",
+//			"ClientSecret": "$ADMIN_OIDC_SECRET",
+//			"RedirectURL": "https://admin.example.com/auth/callback",
+//			"SessionKey": "$ADMIN_SESSION_KEY"
+//		},
+//		"ClientCA": "clientca.pem"
+//	}
+type Config struct {
+	// Roles maps user names to role names ("viewer" or "editor").
+	Roles map[string]string
+
+	// Tokens lists the static bearer tokens.
+	Tokens []TokenConfig
+
+	// OIDC, if set, configures sign-in with an OpenID Connect provider.
+	OIDC *OIDCConfig
+
+	// ClientCA, if set, names a file of PEM-encoded CA certificates
+	// that sign accepted TLS client certificates.
+	ClientCA string
+}
+
+// A TokenConfig configures a static bearer token.
+type TokenConfig struct {
+	Name   string // user name
+	SHA256 string // hex SHA256 of the token
+}
+
+// LoadConfig reads a Config from the named JSON file.
+// References to environment variables in the file, like $VAR or ${VAR},
+// are replaced by their values, so that secrets can be kept out of the file.
+func LoadConfig(file string) (*Config, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	cfg := new(Config)
+	if err := json.Unmarshal([]byte(os.ExpandEnv(string(data))), cfg); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return cfg, nil
+}
+
+// New returns an Auth using the authentication methods configured by cfg,
+// recording the audit log in db.
+func New(cfg *Config, db storage.DB) (*Auth, error) {
+	a := &Auth{Roles: make(map[string]Role), DB: db}
+	for user, name := range cfg.Roles {
+		role, err := ParseRole(name)
+		if err != nil {
+			return nil, fmt.Errorf("user %s: %v", user, err)
+		}
+		a.Roles[user] = role
+	}
+	if cfg.OIDC != nil {
+		o, err := newOIDC(*cfg.OIDC)
+		if err != nil {
+			return nil, err
+		}
+		a.oidc = o
+		a.Authenticators = append(a.Authenticators, o)
+	}
+	if len(cfg.Tokens) > 0 {
+		tokens := make(map[[sha256.Size]byte]string)
+		for _, t := range cfg.Tokens {
+			b, err := hex.DecodeString(t.SHA256)
+			if err != nil || len(b) != sha256.Size {
+				return nil, fmt.Errorf("token %s: SHA256 must be %d hex digits", t.Name, 2*sha256.Size)
+			}
+			var sum [sha256.Size]byte
+			copy(sum[:], b)
+			tokens[sum] = t.Name
+		}
+		a.Authenticators = append(a.Authenticators, Tokens(tokens))
+	}
+	if cfg.ClientCA != "" {
+		a.Authenticators = append(a.Authenticators, ClientCerts())
+	}
+	if len(a.Authenticators) == 0 {
+		return nil, errors.New("no authentication methods configured")
+	}
+	return a, nil
+}
+
+// TLSConfig returns the TLS server configuration needed to accept
+// the client certificates signed by cfg.ClientCA, or nil if it is not set.
+// Clients without certificates can still authenticate in other ways.
+func (cfg *Config) TLSConfig() (*tls.Config, error) {
+	if cfg.ClientCA == "" {
+		return nil, nil
+	}
+	pem, err := ioutil.ReadFile(cfg.ClientCA)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s: no PEM certificates", cfg.ClientCA)
+	}
+	return &tls.Config{
+		ClientCAs:  pool,
+		ClientAuth: tls.VerifyClientCertIfGiven,
+	}, nil
+}
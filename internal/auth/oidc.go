@@ -0,0 +1,472 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	authPrefix   = "/auth/"
+	loginPath    = "/auth/login"
+	callbackPath = "/auth/callback"
+	logoutPath   = "/auth/logout"
+
+	sessionCookie = "admin_session"
+	stateCookie   = "admin_oidc_state"
+
+	sessionTTL = 12 * time.Hour
+	stateTTL   = 10 * time.Minute
+
+	// jwksRefresh is how long to wait before fetching the provider's keys
+	// again when a token is signed by an unknown key.
+	jwksRefresh = time.Minute
+)
+
+// An OIDCConfig configures sign-in with an OpenID Connect provider.
+type OIDCConfig struct {
+	Issuer       string // for example, "https://accounts.google.com"
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string // must end in /auth/callback
+
+	// SessionKey is the base64-encoded key used to sign session cookies.
+	// It must be at least 32 bytes and shared by all server instances.
+	SessionKey string
+}
+
+// oidcAuth authenticates users signed in with an OpenID Connect provider,
+// either by the session cookie set by the sign-in flow or by an ID token
+// sent as a bearer token.
+type oidcAuth struct {
+	cfg        OIDCConfig
+	sessionKey []byte
+	client     *http.Client
+
+	mu          sync.Mutex
+	provider    *provider
+	keys        map[string]*rsa.PublicKey // JWKS key ID -> key
+	keysFetched time.Time
+}
+
+// provider is the provider metadata published at
+// Issuer + "/.well-known/openid-configuration".
+type provider struct {
+	Issuer        string `json:"issuer"`
+	AuthEndpoint  string `json:"authorization_endpoint"`
+	TokenEndpoint string `json:"token_endpoint"`
+	JWKSURI       string `json:"jwks_uri"`
+}
+
+func newOIDC(cfg OIDCConfig) (*oidcAuth, error) {
+	if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, errors.New("oidc: issuer, client ID and redirect URL are required")
+	}
+	if !strings.HasSuffix(cfg.RedirectURL, callbackPath) {
+		return nil, fmt.Errorf("oidc: redirect URL must end in %s", callbackPath)
+	}
+	key, err := base64.StdEncoding.DecodeString(cfg.SessionKey)
+	if err != nil || len(key) < 32 {
+		return nil, errors.New("oidc: session key must be at least 32 base64-encoded bytes")
+	}
+	return &oidcAuth{
+		cfg:        cfg,
+		sessionKey: key,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (o *oidcAuth) Authenticate(r *http.Request) (string, string, error) {
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		user, err := o.checkSession(c.Value)
+		if err != nil {
+			return "", "", err
+		}
+		return user, "oidc", nil
+	}
+	if tok := bearerToken(r); strings.Count(tok, ".") == 2 {
+		user, err := o.verifyIDToken(r.Context(), tok, "")
+		if err != nil {
+			return "", "", err
+		}
+		return user, "oidc", nil
+	}
+	return "", "", ErrNoCredentials
+}
+
+// serveAuth serves the sign-in flow:
+//
+//	/auth/login?return=P  redirect to the provider, returning to path P
+//	/auth/callback        receive the provider's answer and set the session cookie
+//	/auth/logout          clear the session cookie
+func (o *oidcAuth) serveAuth(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case loginPath:
+		o.login(w, r)
+	case callbackPath:
+		o.callback(w, r)
+	case logoutPath:
+		o.setCookie(w, sessionCookie, "", "/", -1)
+		io.WriteString(w, "Signed out.\n")
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (o *oidcAuth) login(w http.ResponseWriter, r *http.Request) {
+	p, err := o.discover(r.Context())
+	if err != nil {
+		log.Printf("ERROR oidc: %v", err)
+		http.Error(w, "could not reach identity provider", http.StatusBadGateway)
+		return
+	}
+	ret := r.FormValue("return")
+	if !strings.HasPrefix(ret, "/") || strings.HasPrefix(ret, "//") {
+		ret = "/"
+	}
+	state := randomHex(16)
+	o.setCookie(w, stateCookie, state+"|"+ret, authPrefix, int(stateTTL/time.Second))
+
+	q := url.Values{
+		"response_type": {"code"},
+		"client_id":     {o.cfg.ClientID},
+		"redirect_uri":  {o.cfg.RedirectURL},
+		"scope":         {"openid email"},
+		"state":         {state},
+		"nonce":         {state},
+	}
+	http.Redirect(w, r, p.AuthEndpoint+"?"+q.Encode(), http.StatusFound)
+}
+
+func (o *oidcAuth) callback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	c, err := r.Cookie(stateCookie)
+	if err != nil {
+		http.Error(w, "missing sign-in state; try again", http.StatusBadRequest)
+		return
+	}
+	o.setCookie(w, stateCookie, "", authPrefix, -1)
+	i := strings.Index(c.Value, "|")
+	if i < 0 || subtle.ConstantTimeCompare([]byte(c.Value[:i]), []byte(r.FormValue("state"))) != 1 {
+		http.Error(w, "bad sign-in state; try again", http.StatusBadRequest)
+		return
+	}
+	state, ret := c.Value[:i], c.Value[i+1:]
+	if e := r.FormValue("error"); e != "" {
+		http.Error(w, "sign-in failed: "+e, http.StatusForbidden)
+		return
+	}
+
+	raw, err := o.exchange(ctx, r.FormValue("code"))
+	if err != nil {
+		log.Printf("ERROR oidc: %v", err)
+		http.Error(w, "could not complete sign-in", http.StatusBadGateway)
+		return
+	}
+	user, err := o.verifyIDToken(ctx, raw, state)
+	if err != nil {
+		log.Printf("ERROR oidc: %v", err)
+		http.Error(w, "invalid ID token", http.StatusForbidden)
+		return
+	}
+	log.Printf("auth: %s signed in", user)
+	o.setCookie(w, sessionCookie, o.newSession(user), "/", int(sessionTTL/time.Second))
+	http.Redirect(w, r, ret, http.StatusFound)
+}
+
+func (o *oidcAuth) setCookie(w http.ResponseWriter, name, value, path string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		MaxAge:   maxAge,
+		Secure:   strings.HasPrefix(o.cfg.RedirectURL, "https:"),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// newSession returns a session cookie value for user, valid for sessionTTL.
+// It has the form base64(user)|expiry|base64(signature).
+func (o *oidcAuth) newSession(user string) string {
+	v := base64.RawURLEncoding.EncodeToString([]byte(user)) + "|" + strconv.FormatInt(timeNow().Add(sessionTTL).Unix(), 10)
+	return v + "|" + base64.RawURLEncoding.EncodeToString(o.sign(v))
+}
+
+// checkSession returns the user of a session cookie value made by newSession.
+func (o *oidcAuth) checkSession(v string) (string, error) {
+	i := strings.LastIndex(v, "|")
+	if i < 0 {
+		return "", errors.New("malformed session cookie")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(v[i+1:])
+	if err != nil || !hmac.Equal(sig, o.sign(v[:i])) {
+		return "", errors.New("invalid session cookie; sign in again")
+	}
+	f := strings.Split(v[:i], "|")
+	user, err1 := base64.RawURLEncoding.DecodeString(f[0])
+	exp, err2 := strconv.ParseInt(f[len(f)-1], 10, 64)
+	if len(f) != 2 || err1 != nil || err2 != nil {
+		return "", errors.New("malformed session cookie")
+	}
+	if timeNow().Unix() > exp {
+		return "", errors.New("session expired; sign in again")
+	}
+	return string(user), nil
+}
+
+func (o *oidcAuth) sign(v string) []byte {
+	mac := hmac.New(sha256.New, o.sessionKey)
+	io.WriteString(mac, v)
+	return mac.Sum(nil)
+}
+
+// discover returns the provider's metadata, fetching it the first time.
+func (o *oidcAuth) discover(ctx context.Context) (*provider, error) {
+	o.mu.Lock()
+	p := o.provider
+	o.mu.Unlock()
+	if p != nil {
+		return p, nil
+	}
+	p = new(provider)
+	if err := o.getJSON(ctx, strings.TrimSuffix(o.cfg.Issuer, "/")+"/.well-known/openid-configuration", p); err != nil {
+		return nil, err
+	}
+	if p.Issuer != o.cfg.Issuer {
+		return nil, fmt.Errorf("provider issuer is %q, want %q", p.Issuer, o.cfg.Issuer)
+	}
+	o.mu.Lock()
+	o.provider = p
+	o.mu.Unlock()
+	return p, nil
+}
+
+// exchange exchanges an authorization code for an ID token.
+func (o *oidcAuth) exchange(ctx context.Context, code string) (string, error) {
+	p, err := o.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {o.cfg.RedirectURL},
+		"client_id":     {o.cfg.ClientID},
+		"client_secret": {o.cfg.ClientSecret},
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", p.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var tok struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := o.doJSON(req, &tok); err != nil {
+		return "", fmt.Errorf("token exchange: %v", err)
+	}
+	if tok.IDToken == "" {
+		return "", errors.New("token exchange: no id_token in response")
+	}
+	return tok.IDToken, nil
+}
+
+// verifyIDToken verifies the signature and claims of an ID token
+// and returns the user it identifies: its email address if verified,
+// or else its subject. If nonce is not empty, the token must contain it.
+func (o *oidcAuth) verifyIDToken(ctx context.Context, raw, nonce string) (string, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return "", errors.New("malformed ID token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return "", err
+	}
+	if header.Alg != "RS256" {
+		return "", fmt.Errorf("unsupported ID token algorithm %q", header.Alg)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", errors.New("malformed ID token signature")
+	}
+	key, err := o.key(ctx, header.Kid)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return "", errors.New("bad ID token signature")
+	}
+
+	var claims struct {
+		Issuer        string          `json:"iss"`
+		Subject       string          `json:"sub"`
+		Audience      json.RawMessage `json:"aud"`
+		Expires       int64           `json:"exp"`
+		Nonce         string          `json:"nonce"`
+		Email         string          `json:"email"`
+		EmailVerified *bool           `json:"email_verified"`
+	}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return "", err
+	}
+	if claims.Issuer != o.cfg.Issuer {
+		return "", fmt.Errorf("ID token issuer is %q", claims.Issuer)
+	}
+	if !hasAudience(claims.Audience, o.cfg.ClientID) {
+		return "", errors.New("ID token is for another client")
+	}
+	if timeNow().Unix() > claims.Expires {
+		return "", errors.New("ID token expired")
+	}
+	if nonce != "" && claims.Nonce != nonce {
+		return "", errors.New("ID token has wrong nonce")
+	}
+	// Trust the email address only if the provider says it has verified it.
+	if claims.Email != "" && claims.EmailVerified != nil && *claims.EmailVerified {
+		return claims.Email, nil
+	}
+	if claims.Subject == "" {
+		return "", errors.New("ID token has no subject")
+	}
+	return claims.Subject, nil
+}
+
+// hasAudience reports whether the aud claim, a string or list of strings,
+// contains id.
+func hasAudience(aud json.RawMessage, id string) bool {
+	var list []string
+	if err := json.Unmarshal(aud, &list); err != nil {
+		var s string
+		if err := json.Unmarshal(aud, &s); err != nil {
+			return false
+		}
+		list = []string{s}
+	}
+	for _, a := range list {
+		if a == id {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err == nil {
+		err = json.Unmarshal(data, v)
+	}
+	if err != nil {
+		return errors.New("malformed ID token")
+	}
+	return nil
+}
+
+// key returns the provider's signing key with the given ID,
+// fetching the provider's key set if it is not known.
+func (o *oidcAuth) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	o.mu.Lock()
+	key, ok := o.keys[kid]
+	stale := timeNow().Sub(o.keysFetched) > jwksRefresh
+	o.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+	if !stale {
+		return nil, fmt.Errorf("unknown ID token key %q", kid)
+	}
+
+	p, err := o.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := o.getJSON(ctx, p.JWKSURI, &jwks); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err1 := base64.RawURLEncoding.DecodeString(k.N)
+		e, err2 := base64.RawURLEncoding.DecodeString(k.E)
+		if err1 != nil || err2 != nil || len(e) > 4 {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	o.mu.Lock()
+	o.keys = keys
+	o.keysFetched = timeNow()
+	o.mu.Unlock()
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown ID token key %q", kid)
+}
+
+func (o *oidcAuth) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	return o.doJSON(req, v)
+}
+
+func (o *oidcAuth) doJSON(req *http.Request, v interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", req.URL, resp.Status)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %v", req.URL, err)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeProvider is an OpenID Connect provider issuing ID tokens
+// for a single user.
+type fakeProvider struct {
+	*httptest.Server
+	key   *rsa.PrivateKey
+	email string
+	nonce string // nonce of the last authorization request
+
+	// unverified omits the email_verified claim from ID tokens.
+	unverified bool
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &fakeProvider{key: key, email: "gopher@golang.org"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.URL,
+			"authorization_endpoint": p.URL + "/authorize",
+			"token_endpoint":         p.URL + "/token",
+			"jwks_uri":               p.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "k1",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "good-code" || r.FormValue("client_secret") != "secret" {
+			http.Error(w, "bad code", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"id_token": p.token(t, "client", p.nonce, time.Hour),
+		})
+	})
+	p.Server = httptest.NewServer(mux)
+	return p
+}
+
+// token returns an ID token for p.email.
+func (p *fakeProvider) token(t *testing.T, aud, nonce string, ttl time.Duration) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "k1"})
+	c := map[string]interface{}{
+		"iss":            p.URL,
+		"sub":            "1234",
+		"aud":            aud,
+		"exp":            timeNow().Add(ttl).Unix(),
+		"nonce":          nonce,
+		"email":          p.email,
+		"email_verified": true,
+	}
+	if p.unverified {
+		delete(c, "email_verified")
+	}
+	claims, _ := json.Marshal(c)
+	msg := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(msg))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestOIDC(t *testing.T) {
+	p := newFakeProvider(t)
+	defer p.Close()
+
+	a, err := New(&Config{
+		Roles: map[string]string{"gopher@golang.org": "editor", "1234": "viewer"},
+		OIDC: &OIDCConfig{
+			Issuer:       p.URL,
+			ClientID:     "client",
+			ClientSecret: "secret",
+			RedirectURL:  "https://admin.example.com/auth/callback",
+			SessionKey:   base64.StdEncoding.EncodeToString(make([]byte, 32)),
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := a.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, FromContext(r.Context()).User)
+	}))
+	do := func(path string, cookies []*http.Cookie, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		for i := 0; i < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	// A browser is sent to sign in, then to the provider.
+	w := do("/links?q=go", nil, "Accept", "text/html")
+	if loc := w.Header().Get("Location"); w.Code != 302 || loc != "/auth/login?return=%2Flinks%3Fq%3Dgo" {
+		t.Fatalf("unauthenticated browser: %d %q", w.Code, loc)
+	}
+	w = do(w.Header().Get("Location"), nil)
+	loc, _ := url.Parse(w.Header().Get("Location"))
+	if w.Code != 302 || !strings.HasPrefix(loc.String(), p.URL+"/authorize?") || loc.Query().Get("client_id") != "client" {
+		t.Fatalf("login: %d %q", w.Code, loc)
+	}
+	state := loc.Query().Get("state")
+	p.nonce = loc.Query().Get("nonce")
+	stateCookies := w.Result().Cookies()
+
+	// The provider returns to the callback with a code.
+	if w := do("/auth/callback?code=good-code&state=wrong", stateCookies); w.Code != 400 {
+		t.Errorf("callback with wrong state: %d, want 400", w.Code)
+	}
+	w = do("/auth/callback?code=good-code&state="+state, stateCookies)
+	if loc := w.Header().Get("Location"); w.Code != 302 || loc != "/links?q=go" {
+		t.Fatalf("callback: %d %q %s", w.Code, loc, w.Body)
+	}
+	var session []*http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == sessionCookie {
+			session = append(session, c)
+		}
+	}
+	if w := do("/links", session); w.Code != 200 || w.Body.String() != "gopher@golang.org" {
+		t.Errorf("with session: %d %q", w.Code, w.Body)
+	}
+
+	// Sessions expire.
+	defer func() { timeNow = time.Now }()
+	timeNow = func() time.Time { return time.Now().Add(sessionTTL + time.Minute) }
+	if w := do("/links", session); w.Code != 401 {
+		t.Errorf("with expired session: %d, want 401", w.Code)
+	}
+	timeNow = time.Now
+
+	// ID tokens are accepted as bearer tokens.
+	for _, tc := range []struct {
+		token string
+		code  int
+	}{
+		{p.token(t, "client", "", time.Hour), 200},
+		{p.token(t, "other-client", "", time.Hour), 401},
+		{p.token(t, "client", "", -time.Hour), 401},
+		{p.token(t, "client", "", time.Hour)[:100] + "x.y", 401},
+	} {
+		if w := do("/links", nil, "Authorization", "Bearer "+tc.token); w.Code != tc.code {
+			t.Errorf("bearer %s...: %d %s, want %d", tc.token[:20], w.Code, w.Body, tc.code)
+		}
+	}
+
+	// Without email_verified, the user is the token's subject,
+	// not its email address.
+	p.unverified = true
+	if w := do("/links", nil, "Authorization", "Bearer "+p.token(t, "client", "", time.Hour)); w.Code != 200 || w.Body.String() != "1234" {
+		t.Errorf("bearer without email_verified: %d %q, want 200 1234", w.Code, w.Body)
+	}
+}
+
+func TestCheckSession(t *testing.T) {
+	o, err := newOIDC(OIDCConfig{
+		Issuer:      "https://example.com",
+		ClientID:    "client",
+		RedirectURL: "http://localhost/auth/callback",
+		SessionKey:  base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := o.newSession("a|b@golang.org")
+	if user, err := o.checkSession(v); err != nil || user != "a|b@golang.org" {
+		t.Errorf("checkSession(newSession) = %q, %v", user, err)
+	}
+	for _, bad := range []string{"", "x", v + "x", strings.Replace(v, "|", "|1", 1), fmt.Sprintf("%s|1|%s", v[:4], v[len(v)-43:])} {
+		if user, err := o.checkSession(bad); err == nil {
+			t.Errorf("checkSession(%q) = %q, want error", bad, user)
+		}
+	}
+}
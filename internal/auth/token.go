@@ -0,0 +1,74 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Tokens returns an Authenticator accepting static bearer tokens.
+// The map tokens maps the SHA256 of each token's value to its user name,
+// so that the configuration does not contain the tokens themselves.
+//
+// Bearer values that look like JSON Web Tokens are left for
+// the OpenID Connect authenticator.
+func Tokens(tokens map[[sha256.Size]byte]string) Authenticator {
+	return tokenAuth(tokens)
+}
+
+type tokenAuth map[[sha256.Size]byte]string
+
+func (t tokenAuth) Authenticate(r *http.Request) (string, string, error) {
+	tok := bearerToken(r)
+	if tok == "" || strings.Count(tok, ".") == 2 {
+		return "", "", ErrNoCredentials
+	}
+	user, ok := t[sha256.Sum256([]byte(tok))]
+	if !ok {
+		return "", "", errors.New("unknown bearer token")
+	}
+	return user, "token", nil
+}
+
+// bearerToken returns the token in r's Authorization header, if any.
+func bearerToken(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if len(h) > len("Bearer ") && strings.EqualFold(h[:len("Bearer ")], "Bearer ") {
+		return strings.TrimSpace(h[len("Bearer "):])
+	}
+	return ""
+}
+
+// ClientCerts returns an Authenticator accepting TLS client certificates.
+// The server's TLS configuration must verify them (see Config.TLSConfig);
+// the user name is the certificate's first email address, if any,
+// or else its subject common name.
+func ClientCerts() Authenticator {
+	return certAuth{}
+}
+
+type certAuth struct{}
+
+func (certAuth) Authenticate(r *http.Request) (string, string, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return "", "", ErrNoCredentials
+	}
+	user := certUser(r.TLS.VerifiedChains[0][0])
+	if user == "" {
+		return "", "", errors.New("client certificate has no email address or common name")
+	}
+	return user, "mtls", nil
+}
+
+func certUser(cert *x509.Certificate) string {
+	if len(cert.EmailAddresses) > 0 {
+		return cert.EmailAddresses[0]
+	}
+	return cert.Subject.CommonName
+}
@@ -0,0 +1,140 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dl
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"regexp"
+
+	"golang.org/x/website/internal/storage"
+)
+
+// AdminHandler serves an administrative interface for managing the uploaders
+// allowed to use /dl/upload and for reviewing the log of their changes.
+// Be careful. It is the caller’s responsibility to ensure that the handler is
+// only exposed to authorized users, for example by serving it through
+// auth.Auth.Handler, which also records changes in the audit log.
+func AdminHandler(db storage.DB) http.HandlerFunc {
+	s := server{db: db}
+	return s.adminHandler
+}
+
+var adminTemplate = template.Must(template.New("admin").Parse(adminTemplateHTML))
+
+var validUploader = regexp.MustCompile(`^[a-zA-Z0-9-_.@]+$`)
+
+// ImportLegacyUploaders adds to db an Uploader for each of names that
+// is not already one, allowed to use a legacy key for maxLegacyKeyAge.
+// It is a one-off migration for the users who uploaded with
+// x/build/cmd/release before uploaders were Uploader entities;
+// see the -importlegacy flag of cmd/admingolangorg.
+// Existing uploaders are left as they are.
+func ImportLegacyUploaders(ctx context.Context, db storage.DB, names []string) error {
+	expires := timeNow().Add(maxLegacyKeyAge)
+	for _, name := range names {
+		if !validUploader.MatchString(name) {
+			return fmt.Errorf("invalid uploader name %q; must match %s", name, validUploader)
+		}
+		var u Uploader
+		err := db.Get(ctx, "Uploader", name, &u)
+		if err == nil {
+			continue
+		}
+		if err != storage.ErrNotFound {
+			return err
+		}
+		if err := db.Put(ctx, "Uploader", name, &Uploader{Name: name, LegacyKeyExpires: expires}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// adminHandler serves an administrative interface.
+// Be careful. Ensure that this handler is only be exposed to authorized users.
+func (h server) adminHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var newKey string // HMAC key of a new uploader, shown only once
+	var doErr error
+	if r.Method == "POST" {
+		name := r.FormValue("name")
+		var u Uploader
+		err := h.db.Get(ctx, "Uploader", name, &u)
+		switch r.FormValue("do") {
+		case "Add":
+			if err == nil {
+				doErr = errors.New("uploader already exists")
+				break
+			}
+			if !validUploader.MatchString(name) {
+				doErr = errors.New("invalid name; must match " + validUploader.String())
+				break
+			}
+			u = Uploader{Name: name}
+			if pub := r.FormValue("publickey"); pub != "" {
+				u.PublicKey, err = base64.StdEncoding.DecodeString(pub)
+				if err != nil || len(u.PublicKey) != ed25519.PublicKeySize {
+					doErr = errors.New("public key must be a base64-encoded ed25519 public key")
+					break
+				}
+			} else {
+				u.HMACKey = make([]byte, 32)
+				if _, err := rand.Read(u.HMACKey); err != nil {
+					doErr = err
+					break
+				}
+				newKey = base64.StdEncoding.EncodeToString(u.HMACKey)
+			}
+			doErr = h.db.Put(ctx, "Uploader", name, &u)
+		case "Disable", "Enable":
+			if err != nil {
+				doErr = err
+				break
+			}
+			u.Disabled = r.FormValue("do") == "Disable"
+			doErr = h.db.Put(ctx, "Uploader", name, &u)
+		case "Delete":
+			doErr = h.db.Delete(ctx, "Uploader", name)
+		default:
+			http.Error(w, "unknown action", http.StatusBadRequest)
+			return
+		}
+		if doErr != nil {
+			newKey = ""
+		}
+	}
+
+	var uploaders []*Uploader
+	if err := h.db.GetAll(ctx, "Uploader", &uploaders); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Printf("ERROR %v", err)
+		return
+	}
+	var logs []UploadLog
+	if err := h.db.GetAll(ctx, "UploadLog", &logs); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Printf("ERROR %v", err)
+		return
+	}
+
+	var data = struct {
+		Uploaders []*Uploader
+		NewKey    string
+		Log       []UploadLog
+		Error     error
+	}{uploaders, newKey, newestLogs(logs, maxLogEntries), doErr}
+	if err := adminTemplate.Execute(w, &data); err != nil {
+		log.Printf("ERROR adminTemplate: %v", err)
+	}
+}
@@ -4,7 +4,8 @@
 
 // Package dl implements a simple downloads frontend server.
 //
-// It accepts HTTP POST requests to create a new download metadata entity, and
+// It accepts signed HTTP requests to /dl/upload to create, edit and delete
+// download metadata entities (see Uploader and SignHMAC), and
 // lists entities with sorting and filtering.
 // It is designed to run only on the instance of godoc that serves golang.org.
 //
@@ -20,6 +21,23 @@
 // query param, will serve a full list of available downloads, including
 // stable, unstable, and archived releases in JSON format:
 //     https://golang.org/dl/?mode=json&include=all
+//
+// The os, arch, kind and version query params filter the listed files,
+// where version=go1.16 matches go1.16 and all its minor releases, and
+// latest=1 lists only the newest matching release:
+//     https://golang.org/dl/?mode=json&os=linux&arch=amd64&version=go1.16&latest=1
+//
+// The newest stable archive for a platform, or the source archive,
+// redirects from:
+//     https://golang.org/dl/latest/{os}-{arch}
+//     https://golang.org/dl/latest/src
+//
+// The SHA256 checksum of each file is served as hexadecimal text at:
+//     https://golang.org/dl/{file}.sha256
+//
+// An Atom feed lists the most recently uploaded releases
+// with the URL and SHA256 checksum of each file:
+//     https://golang.org/dl/feed.atom
 package dl
 
 import (
@@ -33,7 +51,7 @@ import (
 )
 
 const (
-	cacheKey      = "download_list_4" // increment if listTemplateData changes
+	cacheKey      = "download_list_5" // increment if listTemplateData changes
 	cacheDuration = time.Hour
 )
 
@@ -178,6 +196,7 @@ type listTemplateData struct {
 	Featured                  []Feature
 	Stable, Unstable, Archive []Release
 	GoogleCN                  bool
+	ETag                      string // of the JSON download index; see listETag
 }
 
 var (
@@ -333,16 +352,6 @@ func parseVersion(v string) (maj, min int, tail string) {
 	return
 }
 
-// validUser controls whether the named gomote user is allowed to upload
-// Go release binaries via the /dl/upload endpoint.
-func validUser(user string) bool {
-	switch user {
-	case "amedee", "cherryyz", "dmitshur", "drchase", "katiehockman", "mknyszek", "rakoczy", "valsorda":
-		return true
-	}
-	return false
-}
-
 var (
 	fileRe  = regexp.MustCompile(`^go[0-9a-z.]+\.[0-9a-z.-]+\.(tar\.gz|tar\.gz\.asc|pkg|msi|zip)$`)
 	goGetRe = regexp.MustCompile(`^go[0-9a-z.]+\.[0-9a-z.-]+$`)
@@ -0,0 +1,151 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dl
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/blog/atom"
+	"golang.org/x/website/internal/history"
+	"golang.org/x/website/internal/memcache"
+	"golang.org/x/website/internal/storage"
+)
+
+const (
+	baseURL = "https://golang.org"
+
+	// maxFeedEntries is the number of releases listed in /dl/feed.atom.
+	maxFeedEntries = 25
+)
+
+func (h server) feedHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	d, err := h.listData(r.Context())
+	if err != nil {
+		http.Error(w, "Could not get download feed. Try again in a few minutes.", 500)
+		return
+	}
+	var releases []Release
+	releases = append(releases, d.Stable...)
+	releases = append(releases, d.Unstable...)
+	releases = append(releases, d.Archive...)
+	data, err := xml.Marshal(releasesFeed(releases))
+	if err != nil {
+		log.Printf("ERROR rendering feed: %v", err)
+		http.Error(w, "Something broke", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	w.Write(data)
+}
+
+// releasesFeed returns an Atom feed listing the most recently
+// uploaded releases, with the URL and SHA256 checksum of each file.
+func releasesFeed(releases []Release) *atom.Feed {
+	uploaded := func(r Release) time.Time {
+		var t time.Time
+		for _, f := range r.Files {
+			if f.Uploaded.After(t) {
+				t = f.Uploaded
+			}
+		}
+		return t
+	}
+	releases = append([]Release(nil), releases...)
+	sort.SliceStable(releases, func(i, j int) bool {
+		ti, tj := uploaded(releases[i]), uploaded(releases[j])
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return versionLess(releases[i].Version, releases[j].Version) // newest first
+	})
+	if len(releases) > maxFeedEntries {
+		releases = releases[:maxFeedEntries]
+	}
+
+	feed := &atom.Feed{
+		Title: "Go Downloads",
+		ID:    "tag:golang.org,2015:/dl/",
+		Link: []atom.Link{
+			{Rel: "self", Href: baseURL + "/dl/feed.atom"},
+			{Rel: "alternate", Href: baseURL + "/dl/"},
+		},
+		Author: &atom.Person{Name: "The Go Authors"},
+	}
+	for _, r := range releases {
+		t := atom.Time(uploaded(r))
+		if feed.Updated == "" {
+			feed.Updated = t
+		}
+		e := &atom.Entry{
+			Title:     r.Version,
+			ID:        "tag:golang.org,2015:/dl/" + r.Version,
+			Link:      []atom.Link{{Rel: "alternate", Href: baseURL + "/dl/#" + r.Version}},
+			Published: t,
+			Updated:   t,
+		}
+		var buf strings.Builder
+		buf.WriteString("<ul>\n")
+		for _, f := range r.Files {
+			url := baseURL + f.URL()
+			e.Link = append(e.Link, atom.Link{Rel: "enclosure", Href: url, Length: uint(f.Size)})
+			fmt.Fprintf(&buf, "<li><a href=\"%s\">%s</a> %s %s</li>\n",
+				html.EscapeString(url), html.EscapeString(f.Filename), f.ChecksumType(), html.EscapeString(f.PrettyChecksum()))
+		}
+		buf.WriteString("</ul>\n")
+		e.Content = &atom.Text{Type: "html", Body: buf.String()}
+		feed.Entry = append(feed.Entry, e)
+	}
+	return feed
+}
+
+// Downloads returns a function that reports the files available
+// for download for each Go version, such as "1.16.4", for listing
+// in the release feeds served by other packages.
+func Downloads(db storage.DB, mc memcache.Client) func(ctx context.Context) map[history.Version][]history.Download {
+	h := server{db: db, memcache: memcache.WithCodec(mc, memcache.Gob)}
+	return func(ctx context.Context) map[history.Version][]history.Download {
+		d, err := h.listData(ctx)
+		if err != nil {
+			return nil
+		}
+		return releaseDownloads(d)
+	}
+}
+
+// releaseDownloads returns the files of the releases in d, by version.
+// Releases whose versions history.Version cannot represent,
+// such as betas, are omitted.
+func releaseDownloads(d listTemplateData) map[history.Version][]history.Download {
+	m := make(map[history.Version][]history.Download)
+	for _, list := range [][]Release{d.Stable, d.Unstable, d.Archive} {
+		for _, r := range list {
+			var v history.Version
+			if err := v.UnmarshalText([]byte(strings.TrimPrefix(r.Version, "go"))); err != nil {
+				continue
+			}
+			for _, f := range r.Files {
+				m[v] = append(m[v], history.Download{
+					Filename: f.Filename,
+					URL:      baseURL + f.URL(),
+					SHA256:   f.ChecksumSHA256,
+				})
+			}
+		}
+	}
+	return m
+}
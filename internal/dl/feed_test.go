@@ -0,0 +1,67 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dl
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/website/internal/history"
+)
+
+func TestReleasesFeed(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2021, 5, d, 0, 0, 0, 0, time.UTC) }
+	fs := []File{
+		{Filename: "go1.16.4.src.tar.gz", Version: "go1.16.4", ChecksumSHA256: "ae4f6b6e2a1677d31817984655a762074b5356da50fb58722b99104870d43503", Size: 20 << 20, Kind: "source", Uploaded: day(6)},
+		{Filename: "go1.16.4.linux-amd64.tar.gz", Version: "go1.16.4", OS: "linux", Arch: "amd64", ChecksumSHA256: "7154e88f5a8047aad4b80ebace58a059e36e7e2e4eb3b383127a28c711b4ff59", Kind: "archive", Uploaded: day(6)},
+		{Filename: "go1.15.12.src.tar.gz", Version: "go1.15.12", ChecksumSHA256: "1c6911937df4a277fa74e7b7efc3d08594498c4c4adc0b6c4ae3566137528091", Kind: "source", Uploaded: day(6)},
+		{Filename: "go1.17beta1.src.tar.gz", Version: "go1.17beta1", Kind: "source", Uploaded: day(10)},
+		{Filename: "go1.16.3.src.tar.gz", Version: "go1.16.3", Kind: "source", Uploaded: day(1)},
+	}
+	stable, unstable, archive := filesToReleases(fs)
+	feed := releasesFeed(append(append(stable, unstable...), archive...))
+
+	var titles []string
+	for _, e := range feed.Entry {
+		titles = append(titles, e.Title)
+	}
+	if got, want := strings.Join(titles, " "), "go1.17beta1 go1.16.4 go1.15.12 go1.16.3"; got != want {
+		t.Errorf("feed entries = %s; want %s", got, want)
+	}
+	if got, want := string(feed.Updated), "2021-05-10T00:00:00+00:00"; got != want {
+		t.Errorf("feed updated = %s; want %s", got, want)
+	}
+
+	data, err := xml.Marshal(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<link rel="enclosure" href="https://golang.org/dl/go1.16.4.src.tar.gz" length="20971520">`,
+		`&lt;a href=&#34;https://golang.org/dl/go1.16.4.linux-amd64.tar.gz&#34;&gt;go1.16.4.linux-amd64.tar.gz&lt;/a&gt; SHA256 7154e88f5a8047aad4b80ebace58a059e36e7e2e4eb3b383127a28c711b4ff59`,
+		`<link rel="alternate" href="https://golang.org/dl/#go1.15.12">`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("feed does not contain %s:\n%s", want, data)
+		}
+	}
+}
+
+func TestReleaseDownloads(t *testing.T) {
+	d := listTemplateData{
+		Stable:   []Release{{Version: "go1.16.4", Files: []File{{Filename: "go1.16.4.src.tar.gz", ChecksumSHA256: "ae4f"}}}},
+		Unstable: []Release{{Version: "go1.17beta1", Files: []File{{Filename: "go1.17beta1.src.tar.gz"}}}},
+	}
+	m := releaseDownloads(d)
+	files := m[history.Version{X: 1, Y: 16, Z: 4}]
+	if len(files) != 1 || files[0].URL != "https://golang.org/dl/go1.16.4.src.tar.gz" || files[0].SHA256 != "ae4f" {
+		t.Errorf("releaseDownloads: go1.16.4 files = %+v", files)
+	}
+	if len(m) != 1 {
+		t.Errorf("releaseDownloads lists %d versions, want 1: %+v", len(m), m)
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dl
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/website/internal/storage"
+)
+
+// A Backend serves the contents of the release files at /dl/{name}.
+// The name has already been checked to be a valid release file name.
+type Backend interface {
+	ServeFile(w http.ResponseWriter, r *http.Request, name string)
+}
+
+// defaultBackend is the Backend used by golang.org.
+//
+// The redirect target is an internal implementation detail and may change
+// if there is a good reason to do so. Last time was in CL 76971 (in 2017).
+var defaultBackend = Redirect("https://dl.google.com/go/")
+
+// Redirect returns a Backend that redirects each request to baseURL+name,
+// leaving it to another host, which serves the bytes more efficiently.
+func Redirect(baseURL string) Backend {
+	return redirectBackend(baseURL)
+}
+
+type redirectBackend string
+
+func (b redirectBackend) ServeFile(w http.ResponseWriter, r *http.Request, name string) {
+	http.Redirect(w, r, string(b)+name, http.StatusFound)
+}
+
+// A Store holds the contents of release files,
+// such as a local directory or an object store bucket.
+type Store interface {
+	// Open opens the named file for reading.
+	// If there is no such file, the error satisfies errors.Is(err, os.ErrNotExist).
+	Open(ctx context.Context, name string) (Object, error)
+}
+
+// An Object is an open release file.
+type Object interface {
+	io.ReadSeeker
+	io.Closer
+	ModTime() time.Time
+}
+
+// Dir returns a Store holding release files in the local directory dir.
+func Dir(dir string) Store {
+	return dirStore(dir)
+}
+
+type dirStore string
+
+func (d dirStore) Open(ctx context.Context, name string) (Object, error) {
+	f, err := os.Open(filepath.Join(string(d), filepath.FromSlash(name)))
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &dirObject{f, info.ModTime()}, nil
+}
+
+type dirObject struct {
+	*os.File
+	modTime time.Time
+}
+
+func (o *dirObject) ModTime() time.Time { return o.modTime }
+
+// Mirror returns a Backend that serves release files from store itself,
+// supporting range requests and conditional requests by ETag.
+// Only files with a File entity in db are served, and only after
+// checking that their size and SHA256 checksum match the entity.
+func Mirror(db storage.DB, store Store) Backend {
+	return &mirror{
+		db:       db,
+		store:    store,
+		verified: make(map[string]verified),
+		checks:   make(map[check]*checkCall),
+	}
+}
+
+type mirror struct {
+	db    storage.DB
+	store Store
+
+	mu       sync.Mutex
+	verified map[string]verified // file name -> last verified contents
+	checks   map[check]*checkCall
+}
+
+// verified describes release file contents whose checksum has been verified,
+// to avoid rereading the file for every request.
+type verified struct {
+	sha256  string
+	size    int64
+	modTime time.Time
+}
+
+// A check identifies the verification of one version of a release file.
+type check struct {
+	name string
+	v    verified
+}
+
+// A checkCall is a verification in progress,
+// shared by the requests waiting for it.
+type checkCall struct {
+	done chan bool // closed when err is set
+	err  error
+}
+
+func (m *mirror) ServeFile(w http.ResponseWriter, r *http.Request, name string) {
+	ctx := r.Context()
+	var f File
+	if err := m.db.Get(ctx, "File", name, &f); err == storage.ErrNotFound {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		log.Printf("ERROR reading File entity: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	obj, err := m.store.Open(ctx, name)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Printf("ERROR %s has a File entity but is missing from the store", name)
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR opening %s: %v", name, err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer obj.Close()
+
+	if err := m.verify(ctx, name, f, obj); err != nil {
+		log.Printf("ERROR verifying %s: %v", name, err)
+		http.Error(w, "file failed verification", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if f.ChecksumSHA256 != "" {
+		w.Header().Set("ETag", `"`+f.ChecksumSHA256+`"`)
+	}
+	http.ServeContent(w, r, name, obj.ModTime(), obj)
+}
+
+// verify checks that the contents of obj match the size and checksum in f,
+// rereading the file only if it has changed since it was last verified.
+// Concurrent requests for the same file share a single reading,
+// which continues for the others if ctx is canceled.
+// verify leaves obj positioned at the start.
+func (m *mirror) verify(ctx context.Context, name string, f File, obj Object) error {
+	size, err := obj.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := obj.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if f.Size != 0 && size != f.Size {
+		return fmt.Errorf("size is %d, want %d", size, f.Size)
+	}
+	if f.ChecksumSHA256 == "" {
+		return errors.New("File entity has no SHA256 checksum")
+	}
+
+	c := check{name, verified{f.ChecksumSHA256, size, obj.ModTime()}}
+	m.mu.Lock()
+	if m.verified[name] == c.v {
+		m.mu.Unlock()
+		return nil
+	}
+	call, ok := m.checks[c]
+	if !ok {
+		call = &checkCall{done: make(chan bool)}
+		m.checks[c] = call
+		go func() {
+			call.err = m.check(c)
+			m.mu.Lock()
+			if call.err == nil {
+				m.verified[name] = c.v
+			}
+			delete(m.checks, c)
+			m.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	m.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// check reads the release file c.name from the store
+// and checks that it has the contents described by c.v.
+func (m *mirror) check(c check) error {
+	obj, err := m.store.Open(context.Background(), c.name)
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, obj)
+	if err != nil {
+		return err
+	}
+	if size != c.v.size || !obj.ModTime().Equal(c.v.modTime) {
+		return errors.New("file changed while being verified")
+	}
+	if sum := fmt.Sprintf("%x", hash.Sum(nil)); sum != c.v.sha256 {
+		return fmt.Errorf("SHA256 is %s, want %s", sum, c.v.sha256)
+	}
+	return nil
+}
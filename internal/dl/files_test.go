@@ -0,0 +1,192 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dl
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/website/internal/storage"
+)
+
+func TestMirror(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db := storage.NewMemory()
+	content := []byte("0123456789 release contents")
+	sum := fmt.Sprintf("%x", sha256.Sum256(content))
+	for _, f := range []File{
+		{Filename: "go1.16.5.src.tar.gz", Version: "go1.16.5", Kind: "source", ChecksumSHA256: sum, Size: int64(len(content))},
+		{Filename: "go1.16.4.src.tar.gz", Version: "go1.16.4", Kind: "source", ChecksumSHA256: testSHA256, Size: int64(len(content))},
+		{Filename: "go1.16.3.src.tar.gz", Version: "go1.16.3", Kind: "source", ChecksumSHA256: sum, Size: int64(len(content))},
+	} {
+		f := f
+		if err := db.Put(ctx, "File", f.Filename, &f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// go1.16.4 has the wrong checksum; go1.16.3 is missing from the store;
+	// go1.16.2 has no File entity.
+	for _, name := range []string{"go1.16.5.src.tar.gz", "go1.16.4.src.tar.gz", "go1.16.2.src.tar.gz"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), content, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, db, nil, Mirror(db, Dir(dir)))
+	etag := `"` + sum + `"`
+
+	for _, tc := range []struct {
+		desc   string
+		path   string
+		header map[string]string
+		code   int
+		body   string
+	}{
+		{desc: "full", path: "/dl/go1.16.5.src.tar.gz", code: 200, body: string(content)},
+		{desc: "range", path: "/dl/go1.16.5.src.tar.gz", header: map[string]string{"Range": "bytes=2-5"}, code: 206, body: "2345"},
+		{desc: "etag match", path: "/dl/go1.16.5.src.tar.gz", header: map[string]string{"If-None-Match": etag}, code: 304},
+		{desc: "etag mismatch", path: "/dl/go1.16.5.src.tar.gz", header: map[string]string{"If-None-Match": `"other"`}, code: 200, body: string(content)},
+		{desc: "bad checksum", path: "/dl/go1.16.4.src.tar.gz", code: 500},
+		{desc: "missing from store", path: "/dl/go1.16.3.src.tar.gz", code: 404},
+		{desc: "no entity", path: "/dl/go1.16.2.src.tar.gz", code: 404},
+	} {
+		req := httptest.NewRequest("GET", tc.path, nil)
+		for k, v := range tc.header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != tc.code {
+			t.Errorf("%s: GET %s = %d, want %d", tc.desc, tc.path, w.Code, tc.code)
+			continue
+		}
+		if tc.body != "" && w.Body.String() != tc.body {
+			t.Errorf("%s: GET %s body = %q, want %q", tc.desc, tc.path, w.Body.String(), tc.body)
+		}
+		if w.Code/100 == 2 && w.Header().Get("ETag") != etag {
+			t.Errorf("%s: GET %s ETag = %q, want %q", tc.desc, tc.path, w.Header().Get("ETag"), etag)
+		}
+	}
+
+	// Changing the file after it has been verified must be noticed.
+	name := filepath.Join(dir, "go1.16.5.src.tar.gz")
+	if err := ioutil.WriteFile(name, []byte("9876543210 release contents"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Now().Add(time.Hour) // distinct even on file systems with coarse times
+	if err := os.Chtimes(name, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/dl/go1.16.5.src.tar.gz", nil))
+	if w.Code != 500 {
+		t.Errorf("GET of modified file = %d, want 500", w.Code)
+	}
+}
+
+// gatedStore counts the files opened in a Store
+// and blocks reading them until release is closed.
+type gatedStore struct {
+	Store
+	opens   int32
+	release chan bool
+}
+
+func (s *gatedStore) Open(ctx context.Context, name string) (Object, error) {
+	atomic.AddInt32(&s.opens, 1)
+	obj, err := s.Store.Open(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return gatedObject{obj, s.release}, nil
+}
+
+type gatedObject struct {
+	Object
+	release chan bool
+}
+
+func (o gatedObject) Read(b []byte) (int, error) {
+	<-o.release
+	return o.Object.Read(b)
+}
+
+func TestMirrorConcurrentVerify(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db := storage.NewMemory()
+	content := []byte("0123456789 release contents")
+	f := File{Filename: "go1.16.5.src.tar.gz", Version: "go1.16.5", Kind: "source", ChecksumSHA256: fmt.Sprintf("%x", sha256.Sum256(content)), Size: int64(len(content))}
+	if err := db.Put(ctx, "File", f.Filename, &f); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, f.Filename), content, 0666); err != nil {
+		t.Fatal(err)
+	}
+	store := &gatedStore{Store: Dir(dir), release: make(chan bool)}
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, db, nil, Mirror(db, store))
+
+	// Each request opens the file to serve it; verifying it
+	// must open it only once more, however many requests wait.
+	const n = 5
+	var wg sync.WaitGroup
+	codes := make([]int, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest("GET", "/dl/"+f.Filename, nil))
+			codes[i] = w.Code
+		}(i)
+	}
+	for deadline := time.Now().Add(10 * time.Second); atomic.LoadInt32(&store.opens) < n+1; {
+		if time.Now().After(deadline) {
+			t.Fatalf("opened %d files, want %d", atomic.LoadInt32(&store.opens), n+1)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(store.release)
+	wg.Wait()
+	for i, code := range codes {
+		if code != 200 {
+			t.Errorf("request %d = %d, want 200", i, code)
+		}
+	}
+	if opens := atomic.LoadInt32(&store.opens); opens != n+1 {
+		t.Errorf("opened %d files for %d requests, want %d", opens, n, n+1)
+	}
+}
+
+func TestRedirectBackend(t *testing.T) {
+	for _, tc := range []struct {
+		files Backend
+		want  string
+	}{
+		{nil, "https://dl.google.com/go/go1.16.5.src.tar.gz"},
+		{Redirect("https://mirror.example.com/go/"), "https://mirror.example.com/go/go1.16.5.src.tar.gz"},
+	} {
+		mux := http.NewServeMux()
+		RegisterHandlers(mux, storage.NewMemory(), nil, tc.files)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/dl/go1.16.5.src.tar.gz", nil))
+		if w.Code != http.StatusFound || w.Header().Get("Location") != tc.want {
+			t.Errorf("GET = %d %q, want 302 %q", w.Code, w.Header().Get("Location"), tc.want)
+		}
+	}
+}
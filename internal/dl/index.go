@@ -0,0 +1,207 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dl
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// indexCacheControl is the Cache-Control header of the machine-readable
+// download index, the .sha256 files and the /dl/latest/ redirects.
+const indexCacheControl = "public, max-age=300"
+
+// listETag returns the ETag of the download index listing d's releases.
+// It changes whenever the JSON form of any release changes.
+func listETag(d listTemplateData) string {
+	data, err := json.Marshal([][]Release{d.Stable, d.Unstable, d.Archive})
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf(`"%x"`, sum[:16])
+}
+
+// checkETag sets the caching headers for a response with the given ETag.
+// It reports whether the request's If-None-Match header matches etag,
+// in which case it has replied with 304 Not Modified.
+func checkETag(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("Cache-Control", indexCacheControl)
+	if etag == "" {
+		return false
+	}
+	w.Header().Set("ETag", etag)
+	for _, tag := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		if tag = strings.TrimSpace(tag); tag == etag || tag == "*" {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// indexReleases returns the releases selected by the query q for the
+// JSON download index: the stable releases on the download page by default,
+// all stable releases when filtering by version, and all releases if
+// include=all. The os, arch, kind and version parameters filter the
+// files listed, dropping releases left with no files, and latest=1
+// keeps only the newest remaining release, preferring stable releases.
+func indexReleases(d listTemplateData, q url.Values) []Release {
+	var releases []Release
+	switch {
+	case q.Get("include") == "all":
+		releases = append(append(d.Stable, d.Archive...), d.Unstable...)
+	case q.Get("version") != "":
+		releases = stableReleases(d)
+	default:
+		releases = d.Stable
+	}
+	return filterReleases(releases, q)
+}
+
+// stableReleases returns all the stable releases in d.
+func stableReleases(d listTemplateData) []Release {
+	releases := append([]Release(nil), d.Stable...)
+	for _, r := range d.Archive {
+		if r.Stable {
+			releases = append(releases, r)
+		}
+	}
+	return releases
+}
+
+// filterReleases applies the os, arch, kind, version and latest
+// filters in q to releases, as described for indexReleases.
+func filterReleases(releases []Release, q url.Values) []Release {
+	goos, goarch, kind, version := q.Get("os"), q.Get("arch"), q.Get("kind"), q.Get("version")
+	if goos == "" && goarch == "" && kind == "" && version == "" && q.Get("latest") == "" {
+		return releases
+	}
+	var out []Release
+	for _, r := range releases {
+		if version != "" && !versionMatch(r.Version, version) {
+			continue
+		}
+		var files []File
+		for _, f := range r.Files {
+			if (goos == "" || f.OS == goos) && (goarch == "" || f.Arch == goarch) && (kind == "" || f.Kind == kind) {
+				files = append(files, f)
+			}
+		}
+		if len(files) == 0 {
+			continue
+		}
+		r.Files = files
+		out = append(out, r)
+	}
+	if q.Get("latest") == "1" && len(out) > 1 {
+		sort.SliceStable(out, func(i, j int) bool {
+			return versionLess(out[i].Version, out[j].Version)
+		})
+		out = out[:1]
+	}
+	return out
+}
+
+// versionMatch reports whether the release version v matches pattern,
+// which is either a full version, like go1.16.5, or a prefix of one
+// ending at a dot, like go1.16 (matching go1.16, go1.16.1 and so on).
+func versionMatch(v, pattern string) bool {
+	return v == pattern || strings.HasPrefix(v, pattern+".")
+}
+
+// findFile returns the file with the given name listed in d.
+func findFile(d listTemplateData, name string) (File, bool) {
+	for _, list := range [][]Release{d.Stable, d.Unstable, d.Archive} {
+		for _, r := range list {
+			for _, f := range r.Files {
+				if f.Filename == name {
+					return f, true
+				}
+			}
+		}
+	}
+	return File{}, false
+}
+
+// latestHandler serves /dl/latest/{os}-{arch} and /dl/latest/src,
+// redirecting to the file of that platform in the newest stable release
+// having one. The kind parameter selects the kind of file,
+// by default "archive" (or "source" for src).
+// The redirects change only with the download list,
+// so they carry its ETag.
+func (h server) latestHandler(w http.ResponseWriter, r *http.Request, platform string) {
+	d, err := h.listData(r.Context())
+	if err != nil {
+		http.Error(w, "Could not get download list. Try again in a few minutes.", 500)
+		return
+	}
+	if checkETag(w, r, d.ETag) {
+		return
+	}
+	var goos, goarch string
+	kind := r.FormValue("kind")
+	if platform == "src" {
+		if kind == "" {
+			kind = "source"
+		}
+	} else {
+		i := strings.Index(platform, "-")
+		if i < 0 {
+			http.NotFound(w, r)
+			return
+		}
+		goos, goarch = platform[:i], platform[i+1:]
+		if kind == "" {
+			kind = "archive"
+		}
+	}
+
+	releases := filterReleases(stableReleases(d), url.Values{"latest": {"1"}, "os": {goos}, "arch": {goarch}, "kind": {kind}})
+	if len(releases) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	files := releases[0].Files
+	if kind == "archive" || kind == "source" {
+		// Prefer .tar.gz over .zip and skip signatures.
+		sort.SliceStable(files, func(i, j int) bool {
+			return strings.HasSuffix(files[i].Filename, ".tar.gz") && !strings.HasSuffix(files[j].Filename, ".tar.gz")
+		})
+	}
+	for _, f := range files {
+		if !strings.HasSuffix(f.Filename, ".asc") {
+			http.Redirect(w, r, f.URL(), http.StatusFound)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+// checksumHandler serves /dl/{file}.sha256, the SHA256 checksum of file
+// in hexadecimal, as served for the files on dl.google.com.
+func (h server) checksumHandler(w http.ResponseWriter, r *http.Request, name string) {
+	d, err := h.listData(r.Context())
+	if err != nil {
+		http.Error(w, "Could not get download list. Try again in a few minutes.", 500)
+		return
+	}
+	f, ok := findFile(d, name)
+	if !ok || f.ChecksumSHA256 == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if checkETag(w, r, `"`+f.ChecksumSHA256+`"`) {
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, f.ChecksumSHA256)
+}
@@ -0,0 +1,139 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dl
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/website/internal/storage"
+)
+
+func testIndexMux(t *testing.T) *http.ServeMux {
+	ctx := context.Background()
+	db := storage.NewMemory()
+	for _, f := range []File{
+		{Filename: "go1.16.5.linux-amd64.tar.gz", OS: "linux", Arch: "amd64", Version: "go1.16.5", Kind: "archive", ChecksumSHA256: testSHA256},
+		{Filename: "go1.16.5.darwin-amd64.pkg", OS: "darwin", Arch: "amd64", Version: "go1.16.5", Kind: "installer"},
+		{Filename: "go1.16.5.windows-amd64.zip", OS: "windows", Arch: "amd64", Version: "go1.16.5", Kind: "archive"},
+		{Filename: "go1.16.5.src.tar.gz", Version: "go1.16.5", Kind: "source"},
+		{Filename: "go1.16.4.linux-amd64.tar.gz", OS: "linux", Arch: "amd64", Version: "go1.16.4", Kind: "archive"},
+		{Filename: "go1.15.13.linux-amd64.tar.gz", OS: "linux", Arch: "amd64", Version: "go1.15.13", Kind: "archive"},
+		{Filename: "go1.14.15.linux-amd64.tar.gz", OS: "linux", Arch: "amd64", Version: "go1.14.15", Kind: "archive"},
+		{Filename: "go1.14.15.darwin-386.tar.gz", OS: "darwin", Arch: "386", Version: "go1.14.15", Kind: "archive"},
+		{Filename: "go1.17beta1.linux-amd64.tar.gz", OS: "linux", Arch: "amd64", Version: "go1.17beta1", Kind: "archive"},
+	} {
+		f := f
+		if err := db.Put(ctx, "File", f.Filename, &f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, db, nil, nil)
+	return mux
+}
+
+func TestIndexFilters(t *testing.T) {
+	mux := testIndexMux(t)
+	for _, tc := range []struct {
+		query string
+		want  string // releases and their files
+	}{
+		{"", "go1.16.5: src.tar.gz darwin-amd64.pkg linux-amd64.tar.gz windows-amd64.zip; go1.15.13: linux-amd64.tar.gz"},
+		{"os=linux", "go1.16.5: linux-amd64.tar.gz; go1.15.13: linux-amd64.tar.gz"},
+		{"os=darwin&kind=installer", "go1.16.5: darwin-amd64.pkg"},
+		{"kind=source&latest=1", "go1.16.5: src.tar.gz"},
+		{"version=go1.16&os=linux", "go1.16.5: linux-amd64.tar.gz; go1.16.4: linux-amd64.tar.gz"},
+		{"version=go1.14.15&arch=386", "go1.14.15: darwin-386.tar.gz"},
+		{"version=go1.1", ""},
+		{"include=all&os=linux&latest=1", "go1.16.5: linux-amd64.tar.gz"},
+		{"include=all&version=go1.17beta1", "go1.17beta1: linux-amd64.tar.gz"},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/dl/?mode=json&"+tc.query, nil))
+		var rs []Release
+		if err := json.Unmarshal(w.Body.Bytes(), &rs); err != nil || rs == nil {
+			t.Errorf("%s: invalid JSON list %q: %v", tc.query, w.Body.String(), err)
+			continue
+		}
+		var list []string
+		for _, r := range rs {
+			var files []string
+			for _, f := range r.Files {
+				files = append(files, strings.TrimPrefix(f.Filename, r.Version+"."))
+			}
+			list = append(list, r.Version+": "+strings.Join(files, " "))
+		}
+		if got := strings.Join(list, "; "); got != tc.want {
+			t.Errorf("%s:\nhave %s\nwant %s", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestIndexETag(t *testing.T) {
+	mux := testIndexMux(t)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/dl/?mode=json", nil))
+	etag := w.Header().Get("ETag")
+	if w.Code != 200 || etag == "" || w.Header().Get("Cache-Control") == "" {
+		t.Fatalf("GET = %d, ETag %q, Cache-Control %q; want 200 and caching headers", w.Code, etag, w.Header().Get("Cache-Control"))
+	}
+	req := httptest.NewRequest("GET", "/dl/?mode=json", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("GET with If-None-Match = %d, want 304", w.Code)
+	}
+}
+
+func TestLatestAndChecksum(t *testing.T) {
+	mux := testIndexMux(t)
+	for _, tc := range []struct {
+		path string
+		code int
+		want string // Location or body
+	}{
+		{"/dl/latest/linux-amd64", 302, "/dl/go1.16.5.linux-amd64.tar.gz"},
+		{"/dl/latest/windows-amd64", 302, "/dl/go1.16.5.windows-amd64.zip"},
+		{"/dl/latest/darwin-amd64?kind=installer", 302, "/dl/go1.16.5.darwin-amd64.pkg"},
+		{"/dl/latest/darwin-386", 302, "/dl/go1.14.15.darwin-386.tar.gz"},
+		{"/dl/latest/src", 302, "/dl/go1.16.5.src.tar.gz"},
+		{"/dl/latest/plan9-arm", 404, ""},
+		{"/dl/latest/linux", 404, ""},
+		{"/dl/go1.16.5.linux-amd64.tar.gz.sha256", 200, testSHA256},
+		{"/dl/go1.16.5.src.tar.gz.sha256", 404, ""},
+		{"/dl/go1.2.linux-amd64.tar.gz.sha256", 404, ""},
+	} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", tc.path, nil))
+		got := w.Header().Get("Location")
+		if w.Code == 200 {
+			got = w.Body.String()
+		}
+		if w.Code != tc.code || tc.want != "" && got != tc.want {
+			t.Errorf("GET %s = %d %q, want %d %q", tc.path, w.Code, got, tc.code, tc.want)
+		}
+	}
+
+	// The latest redirects carry the download list's ETag.
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/dl/latest/linux-amd64", nil))
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusFound || etag == "" || w.Header().Get("Cache-Control") == "" {
+		t.Fatalf("GET /dl/latest/linux-amd64 = %d, ETag %q, Cache-Control %q; want 302 and caching headers", w.Code, etag, w.Header().Get("Cache-Control"))
+	}
+	req := httptest.NewRequest("GET", "/dl/latest/linux-amd64", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("GET /dl/latest/linux-amd64 with If-None-Match = %d, want 304", w.Code)
+	}
+}
@@ -16,23 +16,34 @@ import (
 	"net/http"
 	"strings"
 	"sync"
-	"time"
 
 	"cloud.google.com/go/datastore"
 	"golang.org/x/website/internal/env"
 	"golang.org/x/website/internal/memcache"
+	"golang.org/x/website/internal/storage"
 )
 
 type server struct {
-	datastore *datastore.Client
-	memcache  *memcache.CodecClient
+	db       storage.DB
+	memcache *memcache.CodecClient
+	files    Backend
 }
 
-func RegisterHandlers(mux *http.ServeMux, dc *datastore.Client, mc *memcache.Client) {
-	s := server{dc, mc.WithCodec(memcache.Gob)}
+// RegisterHandlers registers the download handlers on mux,
+// storing File entities in db and caching the list in mc.
+// The memcache client mc may be nil, to disable caching.
+// The files backend serves the release files themselves;
+// if it is nil, requests are redirected to dl.google.com.
+func RegisterHandlers(mux *http.ServeMux, db storage.DB, mc memcache.Client, files Backend) {
+	if files == nil {
+		files = defaultBackend
+	}
+	s := server{db, memcache.WithCodec(mc, memcache.Gob), files}
 	mux.HandleFunc("/dl", s.getHandler)
 	mux.HandleFunc("/dl/", s.getHandler) // also serves listHandler
 	mux.HandleFunc("/dl/upload", s.uploadHandler)
+	mux.HandleFunc("/dl/upload/log", s.uploadHandler)
+	mux.HandleFunc("/dl/feed.atom", s.feedHandler)
 
 	// NOTE(cbro): this only needs to be run once per project,
 	// and should be behind an admin login.
@@ -40,51 +51,66 @@ func RegisterHandlers(mux *http.ServeMux, dc *datastore.Client, mc *memcache.Cli
 	// mux.HandleFunc("/dl/init", initHandler)
 }
 
-// rootKey is the ancestor of all File entities.
+// rootKey is the ancestor of all File entities in Datastore.
 var rootKey = datastore.NameKey("FileRoot", "root", nil)
 
+// NewDatastore returns a DB storing entities in dc,
+// keeping File entities under rootKey as the production server does.
+func NewDatastore(dc *datastore.Client) storage.DB {
+	return &storage.Datastore{
+		Client:  dc,
+		Parents: map[string]*datastore.Key{"File": rootKey},
+	}
+}
+
 func (h server) listHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" && r.Method != "OPTIONS" {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	ctx := r.Context()
-	d := listTemplateData{
-		GoogleCN: googleCN(r),
+	d, err := h.listData(r.Context())
+	if err != nil {
+		http.Error(w, "Could not get download page. Try again in a few minutes.", 500)
+		return
+	}
+	d.GoogleCN = googleCN(r)
+
+	if r.URL.Query().Get("mode") == "json" {
+		serveJSON(w, r, d)
+		return
+	}
+
+	if err := listTemplate.ExecuteTemplate(w, "root", d); err != nil {
+		log.Printf("ERROR executing template: %v", err)
 	}
+}
 
+// listData returns the downloads to list, from the cache if possible.
+func (h server) listData(ctx context.Context) (listTemplateData, error) {
+	var d listTemplateData
 	if err := h.memcache.Get(ctx, cacheKey, &d); err != nil {
 		if err != memcache.ErrCacheMiss {
 			log.Printf("ERROR cache get error: %v", err)
-			// NOTE(cbro): continue to hit datastore if the memcache is down.
+			// NOTE(cbro): continue to hit storage if the memcache is down.
 		}
 
 		var fs []File
-		q := datastore.NewQuery("File").Ancestor(rootKey)
-		if _, err := h.datastore.GetAll(ctx, q, &fs); err != nil {
+		if err := h.db.GetAll(ctx, "File", &fs); err != nil {
 			log.Printf("ERROR error listing: %v", err)
-			http.Error(w, "Could not get download page. Try again in a few minutes.", 500)
-			return
+			return listTemplateData{}, err
 		}
 		d.Stable, d.Unstable, d.Archive = filesToReleases(fs)
 		if len(d.Stable) > 0 {
 			d.Featured = filesToFeatured(d.Stable[0].Files)
 		}
+		d.ETag = listETag(d)
 
 		item := &memcache.Item{Key: cacheKey, Object: &d, Expiration: cacheDuration}
 		if err := h.memcache.Set(ctx, item); err != nil {
 			log.Printf("ERROR cache set error: %v", err)
 		}
 	}
-
-	if r.URL.Query().Get("mode") == "json" {
-		serveJSON(w, r, d)
-		return
-	}
-
-	if err := listTemplate.ExecuteTemplate(w, "root", d); err != nil {
-		log.Printf("ERROR executing template: %v", err)
-	}
+	return d, nil
 }
 
 // serveJSON serves a JSON representation of d. It assumes that requests are
@@ -98,12 +124,12 @@ func serveJSON(w http.ResponseWriter, r *http.Request, d listTemplateData) {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
-	var releases []Release
-	switch r.URL.Query().Get("include") {
-	case "all":
-		releases = append(append(d.Stable, d.Archive...), d.Unstable...)
-	default:
-		releases = d.Stable
+	if checkETag(w, r, d.ETag) {
+		return
+	}
+	releases := indexReleases(d, r.URL.Query())
+	if releases == nil {
+		releases = []Release{} // encode as [], not null
 	}
 	w.Header().Set("Content-Type", "application/json")
 	enc := json.NewEncoder(w)
@@ -133,50 +159,6 @@ func googleCN(r *http.Request) bool {
 	return false
 }
 
-func (h server) uploadHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-	ctx := r.Context()
-
-	// Authenticate using a user token (same as gomote).
-	user := r.FormValue("user")
-	if !validUser(user) {
-		http.Error(w, "bad user", http.StatusForbidden)
-		return
-	}
-	if r.FormValue("key") != h.userKey(ctx, user) {
-		http.Error(w, "bad key", http.StatusForbidden)
-		return
-	}
-
-	var f File
-	defer r.Body.Close()
-	if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
-		log.Printf("ERROR decoding upload JSON: %v", err)
-		http.Error(w, "Something broke", http.StatusInternalServerError)
-		return
-	}
-	if f.Filename == "" {
-		http.Error(w, "Must provide Filename", http.StatusBadRequest)
-		return
-	}
-	if f.Uploaded.IsZero() {
-		f.Uploaded = time.Now()
-	}
-	k := datastore.NameKey("File", f.Filename, rootKey)
-	if _, err := h.datastore.Put(ctx, k, &f); err != nil {
-		log.Printf("ERROR File entity: %v", err)
-		http.Error(w, "could not put File entity", http.StatusInternalServerError)
-		return
-	}
-	if err := h.memcache.Delete(ctx, cacheKey); err != nil {
-		log.Printf("ERROR delete error: %v", err)
-	}
-	io.WriteString(w, "OK")
-}
-
 func (h server) getHandler(w http.ResponseWriter, r *http.Request) {
 	isGoGet := (r.Method == "GET" || r.Method == "HEAD") && r.FormValue("go-get") == "1"
 	// For go get, we need to serve the same meta tags at /dl for cmd/go to
@@ -200,13 +182,14 @@ func (h server) getHandler(w http.ResponseWriter, r *http.Request) {
 		h.listHandler(w, r)
 		return
 	case fileRe.MatchString(name):
-		// This is a /dl/{file} request to download a file. It's implemented by
-		// redirecting to another host, which serves the bytes more efficiently.
-		//
-		// The redirect target is an internal implementation detail and may change
-		// if there is a good reason to do so. Last time was in CL 76971 (in 2017).
-		const downloadBaseURL = "https://dl.google.com/go/"
-		http.Redirect(w, r, downloadBaseURL+name, http.StatusFound)
+		// This is a /dl/{file} request to download a file.
+		h.files.ServeFile(w, r, name)
+		return
+	case strings.HasPrefix(name, "latest/"):
+		h.latestHandler(w, r, strings.TrimPrefix(name, "latest/"))
+		return
+	case strings.HasSuffix(name, ".sha256") && fileRe.MatchString(strings.TrimSuffix(name, ".sha256")):
+		h.checksumHandler(w, r, strings.TrimSuffix(name, ".sha256"))
 		return
 	case name == "gotip":
 		redirectURL = "https://pkg.go.dev/golang.org/dl/gotip"
@@ -238,15 +221,10 @@ func (h server) initHandler(w http.ResponseWriter, r *http.Request) {
 		Root string
 	}
 	ctx := r.Context()
-	k := rootKey
-	_, err := h.datastore.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
-		err := tx.Get(k, &fileRoot)
-		if err != nil && err != datastore.ErrNoSuchEntity {
-			return err
-		}
-		_, err = tx.Put(k, &fileRoot)
-		return err
-	}, nil)
+	err := h.db.Get(ctx, "FileRoot", "root", &fileRoot)
+	if err == storage.ErrNotFound {
+		err = h.db.Put(ctx, "FileRoot", "root", &fileRoot)
+	}
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 		return
@@ -271,10 +249,6 @@ type builderKey struct {
 	Secret string
 }
 
-func (k *builderKey) Key() *datastore.Key {
-	return datastore.NameKey("BuilderKey", "root", nil)
-}
-
 func (h server) secret(ctx context.Context) string {
 	// check with rlock
 	theKey.RLock()
@@ -292,9 +266,9 @@ func (h server) secret(ctx context.Context) string {
 	}
 
 	// fill
-	if err := h.datastore.Get(ctx, theKey.Key(), &theKey.builderKey); err != nil {
-		if err == datastore.ErrNoSuchEntity {
-			// If the key is not stored in datastore, write it.
+	if err := h.db.Get(ctx, "BuilderKey", "root", &theKey.builderKey); err != nil {
+		if err == storage.ErrNotFound {
+			// If the key is not stored, write it.
 			// This only happens at the beginning of a new deployment.
 			// The code is left here for SDK use and in case a fresh
 			// deployment is ever needed.  "gophers rule" is not the
@@ -303,7 +277,7 @@ func (h server) secret(ctx context.Context) string {
 				panic("lost key from datastore")
 			}
 			theKey.Secret = "gophers rule"
-			h.datastore.Put(ctx, theKey.Key(), &theKey.builderKey)
+			h.db.Put(ctx, "BuilderKey", "root", &theKey.builderKey)
 			return theKey.Secret
 		}
 		panic("cannot load builder key: " + err.Error())
@@ -5,10 +5,15 @@
 package dl
 
 import (
+	"context"
 	"encoding/json"
 	"net/http/httptest"
 	"sort"
 	"testing"
+	"time"
+
+	"golang.org/x/website/internal/memcache"
+	"golang.org/x/website/internal/storage"
 )
 
 func TestServeJSON(t *testing.T) {
@@ -90,3 +95,34 @@ func TestServeJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestListData(t *testing.T) {
+	ctx := context.Background()
+	db := storage.NewMemory()
+	uploaded := time.Date(2021, 6, 3, 0, 0, 0, 0, time.UTC)
+	for _, f := range []File{
+		{Filename: "go1.16.5.linux-amd64.tar.gz", OS: "linux", Arch: "amd64", Version: "go1.16.5", Kind: "archive", Uploaded: uploaded},
+		{Filename: "go1.16.5.src.tar.gz", Version: "go1.16.5", Kind: "source", Uploaded: uploaded},
+		{Filename: "go1.17beta1.src.tar.gz", Version: "go1.17beta1", Kind: "source", Uploaded: uploaded},
+	} {
+		f := f
+		if err := db.Put(ctx, "File", f.Filename, &f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := server{db: db, memcache: memcache.WithCodec(nil, memcache.Gob)}
+	d, err := h.listData(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Stable) != 1 || d.Stable[0].Version != "go1.16.5" || len(d.Stable[0].Files) != 2 {
+		t.Fatalf("Stable = %+v, want go1.16.5 with 2 files", d.Stable)
+	}
+	if len(d.Unstable) != 1 || d.Unstable[0].Version != "go1.17beta1" {
+		t.Errorf("Unstable = %+v, want go1.17beta1", d.Unstable)
+	}
+	if got := d.Stable[0].Files[0].Uploaded; !got.Equal(uploaded) {
+		t.Errorf("Uploaded = %v, want %v", got, uploaded)
+	}
+}
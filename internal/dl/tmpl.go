@@ -296,3 +296,107 @@ go get golang.org/dl/{{.Version}}
 </a>
 {{end}}
 `
+
+const adminTemplateHTML = `
+<!doctype HTML>
+<html lang="en">
+<title>golang.org download uploaders</title>
+<style>
+* {
+	box-sizing: border-box;
+}
+body {
+	font-family: system-ui, sans-serif;
+}
+input {
+	border: 1px solid #ccc;
+}
+input[type=text] {
+	width: 400px;
+}
+input, td, th {
+	color: #333;
+}
+td, th {
+	padding: 2px 8px;
+	text-align: left;
+}
+th {
+	padding-top: 10px;
+}
+.error {
+	color: #900;
+}
+.key {
+	font-family: monospace;
+}
+table {
+	margin-left: auto;
+	margin-right: auto;
+}
+</style>
+<table>
+
+{{with .Error}}
+	<tr>
+		<th colspan="4">Error</th>
+	</tr>
+	<tr>
+		<td class="error" colspan="4">{{.}}</td>
+	</tr>
+{{end}}
+
+{{with .NewKey}}
+	<tr>
+		<th colspan="4">New HMAC key (shown only once)</th>
+	</tr>
+	<tr>
+		<td class="key" colspan="4">{{.}}</td>
+	</tr>
+{{end}}
+
+<tr>
+	<th>Uploader</th>
+	<th>ed25519 public key (base64; empty for a new HMAC key)</th>
+	<th>Legacy key until</th>
+	<th></th>
+</tr>
+
+<form method="POST">
+<tr>
+	<td><input type="text" name="name" required></td>
+	<td><input type="text" name="publickey"></td>
+	<td></td>
+	<td><input type="submit" name="do" value="Add"></td>
+</tr>
+</form>
+
+{{range .Uploaders}}
+	<tr>
+		<td>{{.Name}}</td>
+		<td>{{if .PublicKey}}ed25519{{else if .HMACKey}}HMAC{{end}}{{if .Disabled}} (disabled){{end}}</td>
+		<td>{{if not .LegacyKeyExpires.IsZero}}{{.LegacyKeyExpires.Format "2006-01-02 15:04 MST"}}{{end}}</td>
+		<td>
+			<form method="POST">
+				<input type="hidden" name="name" value="{{.Name}}">
+				<input type="submit" name="do" value="{{if .Disabled}}Enable{{else}}Disable{{end}}">
+				<input type="submit" name="do" value="Delete">
+			</form>
+		</td>
+	</tr>
+{{end}}
+
+{{with .Log}}
+	<tr>
+		<th colspan="4">Recent changes</th>
+	</tr>
+	{{range .}}
+		<tr>
+			<td>{{.Time.Format "2006-01-02 15:04:05"}} {{.User}}</td>
+			<td>{{.Action}} {{.Filename}}</td>
+			<td colspan="2" class="key">{{.SHA256}}</td>
+		</tr>
+	{{end}}
+{{end}}
+</table>
+`
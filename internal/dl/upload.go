@@ -0,0 +1,432 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dl
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/website/internal/auth"
+	"golang.org/x/website/internal/memcache"
+	"golang.org/x/website/internal/storage"
+)
+
+// An Uploader is a person or program allowed to change the downloads list
+// through /dl/upload. Uploaders are stored as "Uploader" entities keyed by Name.
+//
+// Each request is signed either with HMACKey, using HMAC-SHA256,
+// or with the ed25519 private key corresponding to PublicKey.
+// See SignHMAC and SignEd25519.
+type Uploader struct {
+	Name      string
+	HMACKey   []byte `datastore:",noindex"`
+	PublicKey []byte `datastore:",noindex"` // ed25519.PublicKey
+
+	// LegacyKeyExpires, if set, allows the uploader to authenticate
+	// until that time with the user and key parameters used by
+	// x/build/cmd/release, where key is derived from the builder secret
+	// (see userKey). Legacy keys are weaker than signatures: they are
+	// sent in the URL and do not cover the body. So they are granted only
+	// by ImportLegacyUploaders, for at most maxLegacyKeyAge.
+	LegacyKeyExpires time.Time
+
+	Disabled bool
+}
+
+// An UploadLog records one change to the downloads list.
+// Changes are stored as "UploadLog" entities keyed by time,
+// so that listing them returns them oldest first.
+type UploadLog struct {
+	Time     time.Time
+	User     string
+	Action   string // "upload", "edit", or "delete"
+	Filename string
+	Version  string
+	SHA256   string
+	Size     int64
+}
+
+const (
+	// Headers carrying the signature of an upload request.
+	userHeader      = "X-Go-Upload-User"
+	timeHeader      = "X-Go-Upload-Time"
+	signatureHeader = "X-Go-Upload-Signature"
+
+	// maxClockSkew is how far the signed time of a request
+	// may be from the server's time.
+	maxClockSkew = 5 * time.Minute
+
+	// maxLegacyKeyAge is how long an imported uploader
+	// may keep using a legacy key.
+	maxLegacyKeyAge = 30 * 24 * time.Hour
+
+	maxUploadBody = 1 << 20
+	maxLogEntries = 100
+)
+
+var timeNow = time.Now // for testing
+
+// UploadHandler returns a handler serving the upload API at /dl/upload
+// and /dl/upload/log, as RegisterHandlers does, for use behind
+// auth.Auth.Handler. Requests authenticated by that handler need not be
+// signed and are logged as made by the authenticated user.
+// The memcache client mc may be nil, to disable caching.
+func UploadHandler(db storage.DB, mc memcache.Client) http.HandlerFunc {
+	s := server{db: db, memcache: memcache.WithCodec(mc, memcache.Gob)}
+	return s.uploadHandler
+}
+
+// uploadHandler serves the upload API:
+//
+//	POST /dl/upload                 create or replace the File in the JSON body
+//	DELETE /dl/upload?filename=F    delete the file F
+//	DELETE /dl/upload?version=V     delete all files of the release V
+//	GET /dl/upload/log              list recent changes, newest first, as JSON
+//
+// Every request must be signed by a configured Uploader,
+// unless it is served by UploadHandler.
+func (h server) uploadHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxUploadBody+1))
+	if err != nil {
+		http.Error(w, "error reading request", http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxUploadBody {
+		http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	user, err := h.authenticate(ctx, r, body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/dl/upload/log" && r.Method == "GET":
+		h.serveUploadLog(w, r)
+	case r.URL.Path == "/dl/upload" && r.Method == "POST":
+		h.putFile(w, r, user, body)
+	case r.URL.Path == "/dl/upload" && r.Method == "DELETE":
+		h.deleteFiles(w, r, user)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// putFile creates or replaces the File encoded in body.
+func (h server) putFile(w http.ResponseWriter, r *http.Request, user string, body []byte) {
+	ctx := r.Context()
+	var f File
+	if err := json.Unmarshal(body, &f); err != nil {
+		http.Error(w, "invalid File JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := checkFile(f); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if f.Uploaded.IsZero() {
+		f.Uploaded = timeNow()
+	}
+
+	action := "upload"
+	var old File
+	switch err := h.db.Get(ctx, "File", f.Filename, &old); err {
+	case nil:
+		action = "edit"
+	case storage.ErrNotFound:
+		// new file
+	default:
+		log.Printf("ERROR reading File entity: %v", err)
+		http.Error(w, "could not read File entity", http.StatusInternalServerError)
+		return
+	}
+	if err := h.db.Put(ctx, "File", f.Filename, &f); err != nil {
+		log.Printf("ERROR File entity: %v", err)
+		http.Error(w, "could not put File entity", http.StatusInternalServerError)
+		return
+	}
+	h.logUpload(ctx, user, action, f)
+	h.clearCache(ctx)
+	io.WriteString(w, "OK")
+}
+
+// deleteFiles deletes the file named by the filename parameter
+// or all the files of the release named by the version parameter.
+func (h server) deleteFiles(w http.ResponseWriter, r *http.Request, user string) {
+	ctx := r.Context()
+	name, version := r.FormValue("filename"), r.FormValue("version")
+	if (name == "") == (version == "") {
+		http.Error(w, "must provide one of filename or version", http.StatusBadRequest)
+		return
+	}
+
+	var del []File
+	if name != "" {
+		var f File
+		if err := h.db.Get(ctx, "File", name, &f); err == storage.ErrNotFound {
+			http.Error(w, "no such file", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Printf("ERROR reading File entity: %v", err)
+			http.Error(w, "could not read File entity", http.StatusInternalServerError)
+			return
+		}
+		del = append(del, f)
+	} else {
+		var fs []File
+		if err := h.db.GetAll(ctx, "File", &fs); err != nil {
+			log.Printf("ERROR listing File entities: %v", err)
+			http.Error(w, "could not list File entities", http.StatusInternalServerError)
+			return
+		}
+		for _, f := range fs {
+			if f.Version == version {
+				del = append(del, f)
+			}
+		}
+		if len(del) == 0 {
+			http.Error(w, "no such release", http.StatusNotFound)
+			return
+		}
+	}
+
+	for _, f := range del {
+		if err := h.db.Delete(ctx, "File", f.Filename); err != nil {
+			log.Printf("ERROR deleting File entity: %v", err)
+			http.Error(w, "could not delete File entity", http.StatusInternalServerError)
+			h.clearCache(ctx)
+			return
+		}
+		h.logUpload(ctx, user, "delete", f)
+	}
+	h.clearCache(ctx)
+	io.WriteString(w, "OK")
+}
+
+// serveUploadLog serves the most recent changes, newest first, as JSON.
+func (h server) serveUploadLog(w http.ResponseWriter, r *http.Request) {
+	var list []UploadLog
+	if err := h.db.GetAll(r.Context(), "UploadLog", &list); err != nil {
+		log.Printf("ERROR listing UploadLog entities: %v", err)
+		http.Error(w, "could not list UploadLog entities", http.StatusInternalServerError)
+		return
+	}
+	list = newestLogs(list, maxLogEntries)
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", " ")
+	if err := enc.Encode(list); err != nil {
+		log.Printf("ERROR rendering JSON for upload log: %v", err)
+	}
+}
+
+// newestLogs returns the last n entries of list, which is oldest first,
+// in reverse order.
+func newestLogs(list []UploadLog, n int) []UploadLog {
+	var out []UploadLog
+	for i := len(list) - 1; i >= 0 && len(out) < n; i-- {
+		out = append(out, list[i])
+	}
+	return out
+}
+
+// logUpload records in the audit log that user applied action to f.
+func (h server) logUpload(ctx context.Context, user, action string, f File) {
+	t := timeNow().UTC()
+	e := &UploadLog{
+		Time:     t,
+		User:     user,
+		Action:   action,
+		Filename: f.Filename,
+		Version:  f.Version,
+		SHA256:   f.ChecksumSHA256,
+		Size:     f.Size,
+	}
+	// The random suffix keeps entries from overwriting each other
+	// if they are made at the same time.
+	var suffix [4]byte
+	rand.Read(suffix[:])
+	key := fmt.Sprintf("%s %s %x", t.Format("2006-01-02T15:04:05.000000000Z"), f.Filename, suffix)
+	if err := h.db.Put(ctx, "UploadLog", key, e); err != nil {
+		log.Printf("ERROR UploadLog entity: %v", err)
+	}
+	log.Printf("dl: %s %s by %s", action, f.Filename, user)
+}
+
+func (h server) clearCache(ctx context.Context) {
+	if err := h.memcache.Delete(ctx, cacheKey); err != nil {
+		log.Printf("ERROR delete error: %v", err)
+	}
+}
+
+// authenticate checks that the request r, with the given body,
+// comes from a configured uploader and returns the uploader's name.
+// A request already authenticated by package auth (see UploadHandler)
+// needs no signature; auth has checked the user's role.
+func (h server) authenticate(ctx context.Context, r *http.Request, body []byte) (string, error) {
+	if id := auth.FromContext(ctx); id != nil {
+		return id.User, nil
+	}
+
+	name := r.Header.Get(userHeader)
+	legacy := false
+	if name == "" {
+		// Authenticate using a user token (same as gomote).
+		name = r.URL.Query().Get("user")
+		legacy = true
+	}
+	if name == "" {
+		return "", errors.New("missing " + userHeader)
+	}
+	var u Uploader
+	if err := h.db.Get(ctx, "Uploader", name, &u); err != nil {
+		if err != storage.ErrNotFound {
+			log.Printf("ERROR reading Uploader entity: %v", err)
+		}
+		return "", errors.New("bad user")
+	}
+	if u.Disabled {
+		return "", errors.New("bad user")
+	}
+
+	if legacy {
+		key := r.URL.Query().Get("key")
+		if !timeNow().Before(u.LegacyKeyExpires) {
+			return "", errors.New("legacy key not allowed; sign the request")
+		}
+		if !hmac.Equal([]byte(key), []byte(h.userKey(ctx, name))) {
+			return "", errors.New("bad key")
+		}
+		return name, nil
+	}
+
+	ts := r.Header.Get(timeHeader)
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return "", errors.New("bad " + timeHeader)
+	}
+	if d := timeNow().Sub(time.Unix(sec, 0)); d > maxClockSkew || d < -maxClockSkew {
+		return "", errors.New("request time too far from server time")
+	}
+	msg := signedMessage(r.Method, r.URL.RequestURI(), ts, body)
+
+	sig := r.Header.Get(signatureHeader)
+	switch {
+	case strings.HasPrefix(sig, "hmac-sha256=") && len(u.HMACKey) > 0:
+		got, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sig, "hmac-sha256="))
+		if err == nil && hmac.Equal(got, hmacSum(u.HMACKey, msg)) {
+			return name, nil
+		}
+	case strings.HasPrefix(sig, "ed25519=") && len(u.PublicKey) == ed25519.PublicKeySize:
+		got, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sig, "ed25519="))
+		if err == nil && ed25519.Verify(ed25519.PublicKey(u.PublicKey), msg, got) {
+			return name, nil
+		}
+	}
+	return "", errors.New("bad signature")
+}
+
+// signedMessage returns the message signed for a request.
+// It covers the method, the path and query, the signing time
+// and the SHA256 of the body.
+func signedMessage(method, uri, time string, body []byte) []byte {
+	return []byte(fmt.Sprintf("%s\n%s\n%s\n%x", method, uri, time, sha256.Sum256(body)))
+}
+
+func hmacSum(key, msg []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(msg)
+	return mac.Sum(nil)
+}
+
+// SignHMAC signs the upload request req, whose body is body,
+// as coming from the named uploader, using its HMAC key.
+func SignHMAC(req *http.Request, body []byte, user string, key []byte) {
+	sign(req, body, user, func(msg []byte) string {
+		return "hmac-sha256=" + base64.StdEncoding.EncodeToString(hmacSum(key, msg))
+	})
+}
+
+// SignEd25519 signs the upload request req, whose body is body,
+// as coming from the named uploader, using its ed25519 private key.
+func SignEd25519(req *http.Request, body []byte, user string, key ed25519.PrivateKey) {
+	sign(req, body, user, func(msg []byte) string {
+		return "ed25519=" + base64.StdEncoding.EncodeToString(ed25519.Sign(key, msg))
+	})
+}
+
+func sign(req *http.Request, body []byte, user string, sig func([]byte) string) {
+	ts := strconv.FormatInt(timeNow().Unix(), 10)
+	req.Header.Set(userHeader, user)
+	req.Header.Set(timeHeader, ts)
+	req.Header.Set(signatureHeader, sig(signedMessage(req.Method, req.URL.RequestURI(), ts, body)))
+}
+
+var (
+	versionRe = regexp.MustCompile(`^go[0-9]+(\.[0-9]+)*((beta|rc)[0-9]+)?$`)
+	sha256Re  = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+// checkFile checks that the fields of f are consistent with its file name,
+// such as go1.16.5.linux-amd64.tar.gz or go1.16.5.src.tar.gz.
+func checkFile(f File) error {
+	if !fileRe.MatchString(f.Filename) {
+		return fmt.Errorf("invalid Filename %q", f.Filename)
+	}
+	if !versionRe.MatchString(f.Version) || !strings.HasPrefix(f.Filename, f.Version+".") {
+		return fmt.Errorf("Version %q does not match Filename %q", f.Version, f.Filename)
+	}
+	if !sha256Re.MatchString(f.ChecksumSHA256) {
+		return fmt.Errorf("ChecksumSHA256 must be 64 lower-case hex digits")
+	}
+	if f.Size <= 0 {
+		return fmt.Errorf("Size must be positive")
+	}
+
+	rest := strings.TrimPrefix(f.Filename, f.Version+".")
+	rest = strings.TrimSuffix(rest, ".asc") // signature of the file named by the rest
+	var kind, goos, goarch string
+	switch {
+	case rest == "src.tar.gz":
+		kind = "source"
+	case strings.HasSuffix(rest, ".tar.gz"), strings.HasSuffix(rest, ".zip"):
+		kind = "archive"
+	case strings.HasSuffix(rest, ".pkg"), strings.HasSuffix(rest, ".msi"):
+		kind = "installer"
+	default:
+		return fmt.Errorf("unknown kind of file %q", f.Filename)
+	}
+	if kind != "source" {
+		// The platform is the first dot-separated element, like linux-amd64
+		// or darwin-amd64-osx10.8 for some old releases.
+		platform := strings.SplitN(rest, ".", 2)[0]
+		parts := strings.Split(platform, "-")
+		if len(parts) < 2 {
+			return fmt.Errorf("missing os-arch in Filename %q", f.Filename)
+		}
+		goos, goarch = parts[0], parts[1]
+	}
+	if f.Kind != kind || f.OS != goos || f.Arch != goarch {
+		return fmt.Errorf("Kind, OS, Arch = %q, %q, %q; want %q, %q, %q for Filename %q", f.Kind, f.OS, f.Arch, kind, goos, goarch, f.Filename)
+	}
+	return nil
+}
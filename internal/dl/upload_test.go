@@ -0,0 +1,265 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dl
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/website/internal/auth"
+	"golang.org/x/website/internal/memcache"
+	"golang.org/x/website/internal/storage"
+)
+
+const testSHA256 = "bbd1c2a3cc8db4a3e0ae6a1ab5f8b4b3a6e6b5c8f1d6b7d1e5a4d5e3c6b2a1f0"
+
+func TestCheckFile(t *testing.T) {
+	ok := func(name, version, os, arch, kind string) File {
+		return File{Filename: name, Version: version, OS: os, Arch: arch, Kind: kind, ChecksumSHA256: testSHA256, Size: 1}
+	}
+	good := []File{
+		ok("go1.16.5.linux-amd64.tar.gz", "go1.16.5", "linux", "amd64", "archive"),
+		ok("go1.16.5.src.tar.gz", "go1.16.5", "", "", "source"),
+		ok("go1.17beta1.windows-386.msi", "go1.17beta1", "windows", "386", "installer"),
+		ok("go1.16.darwin-arm64.pkg", "go1.16", "darwin", "arm64", "installer"),
+		ok("go1.4.2.darwin-amd64-osx10.8.pkg", "go1.4.2", "darwin", "amd64", "installer"),
+		ok("go1.16.5.windows-amd64.zip", "go1.16.5", "windows", "amd64", "archive"),
+		ok("go1.16.5.linux-armv6l.tar.gz.asc", "go1.16.5", "linux", "armv6l", "archive"),
+	}
+	for _, f := range good {
+		if err := checkFile(f); err != nil {
+			t.Errorf("checkFile(%s): %v", f.Filename, err)
+		}
+	}
+
+	bad := []File{
+		ok("go1.16.5.linux-amd64.exe", "go1.16.5", "linux", "amd64", "archive"),
+		ok("go1.16.5.linux-amd64.tar.gz", "go1.16.4", "linux", "amd64", "archive"),
+		ok("go1.16.5.linux-amd64.tar.gz", "go1.16.5", "linux", "arm64", "archive"),
+		ok("go1.16.5.linux-amd64.tar.gz", "go1.16.5", "linux", "amd64", "installer"),
+		ok("go1.16.5.src.tar.gz", "go1.16.5", "linux", "", "source"),
+		ok("go1.16.5.linux.tar.gz", "go1.16.5", "linux", "", "archive"),
+		{Filename: "go1.16.5.src.tar.gz", Version: "go1.16.5", Kind: "source", ChecksumSHA256: "xyz", Size: 1},
+		{Filename: "go1.16.5.src.tar.gz", Version: "go1.16.5", Kind: "source", ChecksumSHA256: testSHA256},
+	}
+	for _, f := range bad {
+		if err := checkFile(f); err == nil {
+			t.Errorf("checkFile(%+v) succeeded, want error", f)
+		}
+	}
+}
+
+func TestUpload(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2021, 6, 3, 12, 0, 0, 0, time.UTC)
+	defer func(old func() time.Time) { timeNow = old }(timeNow)
+	timeNow = func() time.Time { return now }
+
+	db := storage.NewMemory()
+	hmacKey := []byte("secret")
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range []*Uploader{
+		{Name: "alice", HMACKey: hmacKey},
+		{Name: "bob", PublicKey: pub},
+		{Name: "carol", HMACKey: hmacKey, Disabled: true},
+		{Name: "dave", LegacyKeyExpires: now.Add(time.Hour)},
+		{Name: "erin", LegacyKeyExpires: now.Add(-time.Hour)},
+	} {
+		if err := db.Put(ctx, "Uploader", u.Name, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, db, memcache.NewLRU(1<<20, 0), nil)
+	legacyKey := server{db: db}.userKey(ctx, "dave")
+	aliceKey := server{db: db}.userKey(ctx, "alice")
+	erinKey := server{db: db}.userKey(ctx, "erin")
+
+	do := func(method, url string, body []byte, sign func(*http.Request, []byte)) int {
+		t.Helper()
+		now = now.Add(time.Second)
+		req := httptest.NewRequest(method, url, bytes.NewReader(body))
+		if sign != nil {
+			sign(req, body)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w.Code
+	}
+	asAlice := func(req *http.Request, body []byte) { SignHMAC(req, body, "alice", hmacKey) }
+	asBob := func(req *http.Request, body []byte) { SignEd25519(req, body, "bob", priv) }
+	asCarol := func(req *http.Request, body []byte) { SignHMAC(req, body, "carol", hmacKey) }
+	asMallory := func(req *http.Request, body []byte) { SignHMAC(req, body, "alice", []byte("guess")) }
+
+	file := func(name, version, os, arch, kind string) []byte {
+		data, err := json.Marshal(File{Filename: name, Version: version, OS: os, Arch: arch, Kind: kind, ChecksumSHA256: testSHA256, Size: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	linux := file("go1.16.5.linux-amd64.tar.gz", "go1.16.5", "linux", "amd64", "archive")
+	src := file("go1.16.5.src.tar.gz", "go1.16.5", "", "", "source")
+
+	for _, tc := range []struct {
+		desc   string
+		method string
+		url    string
+		body   []byte
+		sign   func(*http.Request, []byte)
+		code   int
+	}{
+		{"unsigned", "POST", "/dl/upload", linux, nil, 403},
+		{"wrong key", "POST", "/dl/upload", linux, asMallory, 403},
+		{"disabled uploader", "POST", "/dl/upload", linux, asCarol, 403},
+		{"invalid file", "POST", "/dl/upload", file("go1.16.5.linux-amd64.tar.gz", "go1.16.5", "linux", "arm64", "archive"), asAlice, 400},
+		{"hmac upload", "POST", "/dl/upload", linux, asAlice, 200},
+		{"ed25519 upload", "POST", "/dl/upload", src, asBob, 200},
+		{"edit", "POST", "/dl/upload", linux, asBob, 200},
+		{"delete missing", "DELETE", "/dl/upload?filename=go1.2.src.tar.gz", nil, asAlice, 404},
+		{"delete unsigned", "DELETE", "/dl/upload?version=go1.16.5", nil, nil, 403},
+		{"delete file", "DELETE", "/dl/upload?filename=go1.16.5.src.tar.gz", nil, asAlice, 200},
+		{"log", "GET", "/dl/upload/log", nil, asAlice, 200},
+		{"legacy key", "POST", "/dl/upload?user=dave&key=" + legacyKey, src, nil, 200},
+		{"legacy key not allowed", "POST", "/dl/upload?user=alice&key=" + aliceKey, src, nil, 403},
+		{"legacy wrong key", "POST", "/dl/upload?user=dave&key=" + aliceKey, src, nil, 403},
+		{"legacy key expired", "POST", "/dl/upload?user=erin&key=" + erinKey, src, nil, 403},
+	} {
+		if code := do(tc.method, tc.url, tc.body, tc.sign); code != tc.code {
+			t.Errorf("%s: %s %s = %d, want %d", tc.desc, tc.method, tc.url, code, tc.code)
+		}
+	}
+
+	var fs []File
+	if err := db.GetAll(ctx, "File", &fs); err != nil {
+		t.Fatal(err)
+	}
+	if len(fs) != 2 || fs[0].Filename != "go1.16.5.linux-amd64.tar.gz" || fs[0].Uploaded.IsZero() {
+		t.Errorf("files after upload = %+v, want linux and source files with upload times", fs)
+	}
+
+	// A signature is valid only for the signed request and time.
+	req := httptest.NewRequest("DELETE", "/dl/upload?version=go1.16.5", nil)
+	asAlice(req, nil)
+	req.URL.RawQuery = "version=go1.16.4"
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != 403 {
+		t.Errorf("DELETE with altered query = %d, want 403", w.Code)
+	}
+	req = httptest.NewRequest("DELETE", "/dl/upload?version=go1.16.5", nil)
+	asAlice(req, nil)
+	now = now.Add(10 * time.Minute)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != 403 {
+		t.Errorf("DELETE with stale signature = %d, want 403", w.Code)
+	}
+
+	if code := do("DELETE", "/dl/upload?version=go1.16.5", nil, asBob); code != 200 {
+		t.Errorf("DELETE release = %d, want 200", code)
+	}
+	var logs []UploadLog
+	if err := db.GetAll(ctx, "UploadLog", &logs); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range logs {
+		got = append(got, e.User+" "+e.Action+" "+e.Filename)
+	}
+	want := []string{
+		"alice upload go1.16.5.linux-amd64.tar.gz",
+		"bob upload go1.16.5.src.tar.gz",
+		"bob edit go1.16.5.linux-amd64.tar.gz",
+		"alice delete go1.16.5.src.tar.gz",
+		"dave upload go1.16.5.src.tar.gz",
+		"bob delete go1.16.5.linux-amd64.tar.gz",
+		"bob delete go1.16.5.src.tar.gz",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("upload log:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestUploadHandlerAuth(t *testing.T) {
+	ctx := context.Background()
+	db := storage.NewMemory()
+	a := &auth.Auth{
+		Authenticators: []auth.Authenticator{auth.Tokens(map[[sha256.Size]byte]string{
+			sha256.Sum256([]byte("secret")): "release-bot",
+		})},
+		Roles: map[string]auth.Role{"release-bot": auth.Editor},
+	}
+	h := a.Handler(UploadHandler(db, nil))
+
+	body, err := json.Marshal(File{Filename: "go1.16.5.src.tar.gz", Version: "go1.16.5", Kind: "source", ChecksumSHA256: testSHA256, Size: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []string{"", "secret"} {
+		req := httptest.NewRequest("POST", "/dl/upload", bytes.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if want := map[string]int{"": 401, "secret": 200}[token]; w.Code != want {
+			t.Errorf("upload with token %q = %d, want %d", token, w.Code, want)
+		}
+	}
+
+	var logs []UploadLog
+	if err := db.GetAll(ctx, "UploadLog", &logs); err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 1 || logs[0].User != "release-bot" {
+		t.Errorf("upload log = %+v, want one upload by release-bot", logs)
+	}
+}
+
+func TestImportLegacyUploaders(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2021, 6, 3, 12, 0, 0, 0, time.UTC)
+	defer func(old func() time.Time) { timeNow = old }(timeNow)
+	timeNow = func() time.Time { return now }
+
+	db := storage.NewMemory()
+	disabled := &Uploader{Name: "alice", Disabled: true}
+	if err := db.Put(ctx, "Uploader", disabled.Name, disabled); err != nil {
+		t.Fatal(err)
+	}
+	if err := ImportLegacyUploaders(ctx, db, []string{"alice", "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ImportLegacyUploaders(ctx, db, []string{"bad name"}); err == nil {
+		t.Errorf("imported invalid name")
+	}
+
+	var list []*Uploader
+	if err := db.GetAll(ctx, "Uploader", &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("have %d uploaders, want 2", len(list))
+	}
+	// Existing uploaders are left alone.
+	if u := list[0]; !u.Disabled || !u.LegacyKeyExpires.IsZero() {
+		t.Errorf("import changed existing uploader: %+v", u)
+	}
+	if u := list[1]; u.Name != "bob" || !u.LegacyKeyExpires.Equal(now.Add(maxLegacyKeyAge)) || u.Disabled {
+		t.Errorf("imported uploader %+v, want legacy key for %v", u, maxLegacyKeyAge)
+	}
+}
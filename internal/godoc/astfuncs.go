@@ -18,7 +18,6 @@ import (
 	"log"
 	"unicode"
 
-	"golang.org/x/website/internal/api"
 	"golang.org/x/website/internal/pkgdoc"
 	"golang.org/x/website/internal/texthtml"
 )
@@ -40,8 +39,19 @@ func (p *Presentation) node_htmlFunc(info *pkgdoc.Page, node interface{}, linkif
 	if linkify {
 		n, _ = node.(ast.Node)
 	}
+	var identURL texthtml.IdentURL
+	if info.Uses != nil {
+		identURL = func(id *ast.Ident) (string, bool) {
+			ref, ok := info.Uses[id]
+			if !ok {
+				return "", false
+			}
+			return refLinkFunc(info, ref), true
+		}
+	}
 	buf2.Write(texthtml.Format(buf1.Bytes(), texthtml.Config{
 		AST:        n,
+		IdentURL:   identURL,
 		GoComments: true,
 	}))
 	return buf2.String()
@@ -52,8 +62,8 @@ const TabWidth = 4
 // writeNode writes the AST node x to w.
 //
 // The provided fset must be non-nil. The pageInfo is optional. If
-// present, the pageInfo is used to add comments to struct fields to
-// say which version of Go introduced them.
+// present, the pageInfo is used to add comments to struct fields,
+// constants and variables to say which version of Go introduced them.
 func (p *Presentation) writeNode(w io.Writer, pageInfo *pkgdoc.Page, fset *token.FileSet, x interface{}) {
 	// convert trailing tabs into spaces using a tconv filter
 	// to ensure a good outcome in most browsers (there may still
@@ -64,23 +74,38 @@ func (p *Presentation) writeNode(w io.Writer, pageInfo *pkgdoc.Page, fset *token
 	//           with an another printer mode (which is more efficiently
 	//           implemented in the printer than here with another layer)
 
-	var pkgName, structName string
-	var apiInfo api.PkgDB
+	// since returns the version of Go that added the named
+	// field, constant or variable declared by x, if it should be noted.
+	var since func(name string) string
 	if gd, ok := x.(*ast.GenDecl); ok && pageInfo != nil && pageInfo.PDoc != nil &&
-		p.Corpus != nil &&
-		gd.Tok == token.TYPE && len(gd.Specs) != 0 {
-		pkgName = pageInfo.PDoc.ImportPath
-		if ts, ok := gd.Specs[0].(*ast.TypeSpec); ok {
-			if _, ok := ts.Type.(*ast.StructType); ok {
-				structName = ts.Name.Name
+		p.Corpus != nil && len(gd.Specs) != 0 {
+		apiInfo := p.Corpus.pkgAPIInfo[pageInfo.PDoc.ImportPath]
+		switch gd.Tok {
+		case token.TYPE:
+			if ts, ok := gd.Specs[0].(*ast.TypeSpec); ok {
+				if _, ok := ts.Type.(*ast.StructType); ok {
+					fieldSince := apiInfo.Field[ts.Name.Name]
+					typeSince := apiInfo.Type[ts.Name.Name]
+					since = func(field string) string {
+						// Don't highlight field versions if they were the
+						// same as the struct itself.
+						if v := fieldSince[field]; v != typeSince {
+							return v
+						}
+						return ""
+					}
+				}
 			}
+		case token.CONST:
+			since = func(name string) string { return apiInfo.Const[name] }
+		case token.VAR:
+			since = func(name string) string { return apiInfo.Var[name] }
 		}
-		apiInfo = p.Corpus.pkgAPIInfo[pkgName]
 	}
 
 	var out = w
 	var buf bytes.Buffer
-	if structName != "" {
+	if since != nil {
 		out = &buf
 	}
 
@@ -90,27 +115,19 @@ func (p *Presentation) writeNode(w io.Writer, pageInfo *pkgdoc.Page, fset *token
 		log.Print(err)
 	}
 
-	// Add comments to struct fields saying which Go version introduced them.
-	if structName != "" {
-		fieldSince := apiInfo.Field[structName]
-		typeSince := apiInfo.Type[structName]
-		// Add/rewrite comments on struct fields to note which Go version added them.
+	// Add/rewrite comments on struct fields, constants and variables
+	// to note which Go version added them.
+	if since != nil {
 		var buf2 bytes.Buffer
 		buf2.Grow(buf.Len() + len(" // Added in Go 1.n")*10)
 		bs := bufio.NewScanner(&buf)
 		for bs.Scan() {
 			line := bs.Bytes()
-			field := firstIdent(line)
-			var since string
-			if field != "" {
-				since = fieldSince[field]
-				if since != "" && since == typeSince {
-					// Don't highlight field versions if they were the
-					// same as the struct itself.
-					since = ""
-				}
+			var version string
+			if name := firstIdent(declLine(line)); name != "" {
+				version = since(name)
 			}
-			if since == "" {
+			if version == "" {
 				buf2.Write(line)
 			} else {
 				if bytes.Contains(line, slashSlash) {
@@ -121,7 +138,7 @@ func (p *Presentation) writeNode(w io.Writer, pageInfo *pkgdoc.Page, fset *token
 					buf2.Write(line)
 					buf2.WriteString(" // Go ")
 				}
-				buf2.WriteString(since)
+				buf2.WriteString(version)
 			}
 			buf2.WriteByte('\n')
 		}
@@ -129,6 +146,17 @@ func (p *Presentation) writeNode(w io.Writer, pageInfo *pkgdoc.Page, fset *token
 	}
 }
 
+// declLine returns the line of a declaration without a leading
+// const or var keyword, as in "const Pi = 3.14".
+func declLine(line []byte) []byte {
+	for _, kw := range []string{"const ", "var "} {
+		if bytes.HasPrefix(line, []byte(kw)) {
+			return line[len(kw):]
+		}
+	}
+	return line
+}
+
 // firstIdent returns the first identifier in x.
 // This actually parses "identifiers" that begin with numbers too, but we
 // never feed it such input, so it's fine.
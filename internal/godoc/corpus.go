@@ -9,8 +9,11 @@ package godoc
 
 import (
 	"io/fs"
+	"sync/atomic"
 
 	"golang.org/x/website/internal/api"
+	"golang.org/x/website/internal/history"
+	"golang.org/x/website/internal/translation"
 )
 
 // A Corpus holds all the state related to serving and indexing a
@@ -21,9 +24,24 @@ import (
 type Corpus struct {
 	fs fs.FS
 
+	// Xref reports whether the identifier cross-reference index
+	// is built, by InitXref. Without it, ?refs= is ignored.
+	Xref bool
+
 	// pkgAPIInfo contains the information about which package API
 	// features were added in which version of Go.
 	pkgAPIInfo api.DB
+
+	// apiFeatures lists the API features added in each version of Go,
+	// for /api/diff.
+	apiFeatures *api.Features
+
+	// releases lists the Go releases, newest first,
+	// and releaseMajors groups them by major release.
+	releases      []*history.Release
+	releaseMajors []*history.Major
+
+	xrefIndex atomic.Value // *xref.Index; set by InitXref
 }
 
 // NewCorpus returns a new Corpus from a filesystem.
@@ -35,3 +53,22 @@ func NewCorpus(fsys fs.FS) *Corpus {
 	}
 	return c
 }
+
+// englishFS returns the file system of c without any translated overlay.
+func (c *Corpus) englishFS() fs.FS {
+	if tfs, ok := c.fs.(*translation.FS); ok {
+		return tfs.English()
+	}
+	return c.fs
+}
+
+// translationStatus returns the translation status of the named file,
+// or nil if the corpus is not serving a translated overlay.
+func (c *Corpus) translationStatus(name string) *translation.Status {
+	tfs, ok := c.fs.(*translation.FS)
+	if !ok || !tfs.HasTranslation() {
+		return nil
+	}
+	st := tfs.Status(name)
+	return &st
+}
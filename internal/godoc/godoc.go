@@ -43,12 +43,13 @@ func (p *Presentation) initFuncMap() {
 	}
 	p.templateFuncs = template.FuncMap{
 		"code":     p.code,
-		"releases": func() []*history.Major { return history.Majors },
+		"releases": func() []*history.Major { return p.Corpus.releaseMajors },
 	}
 	p.funcMap = template.FuncMap{
 		// various helpers
-		"filename": filenameFunc,
-		"since":    p.Corpus.pkgAPIInfo.Func,
+		"filename":  filenameFunc,
+		"since":     p.Corpus.pkgAPIInfo.Func,
+		"platforms": platformsFunc,
 
 		// formatting of AST nodes
 		"node":         p.nodeFunc,
@@ -61,6 +62,8 @@ func (p *Presentation) initFuncMap() {
 		"srcLink":       srcLinkFunc,
 		"posLink_url":   posLink_urlFunc,
 		"docLink":       docLinkFunc,
+		"dirLink":       dirLinkFunc,
+		"refLink":       refLinkFunc,
 		"queryLink":     queryLinkFunc,
 		"srcBreadcrumb": srcBreadcrumbFunc,
 		"srcToPkgLink":  srcToPkgLinkFunc,
@@ -192,7 +195,7 @@ func srcPosLinkFunc(s string, line, low, high int) string {
 
 func srcLinkFunc(s string) string {
 	s = path.Clean("/" + s)
-	if !strings.HasPrefix(s, "/src/") {
+	if !strings.HasPrefix(s, "/src/") && !strings.HasPrefix(s, "/mod/") {
 		s = "/src" + s
 	}
 	return s
@@ -211,6 +214,39 @@ func queryLinkFunc(s, query string, line int) string {
 	return url
 }
 
+// refLinkFunc returns the URL documenting ref, as linked from
+// the documentation in info, or "" if there is none.
+func refLinkFunc(info *pkgdoc.Page, ref pkgdoc.Ref) string {
+	if ref.Path == "" {
+		return ""
+	}
+	if ref.Path != "builtin" && ref.Name != "" {
+		// Only exported names have anchors.
+		for _, name := range strings.Split(ref.Name, ".") {
+			if !ast.IsExported(name) {
+				return ""
+			}
+		}
+	}
+	frag := ""
+	if ref.Name != "" {
+		frag = "#" + ref.Name
+	}
+	if info.PDoc != nil && ref.Path == strings.TrimPrefix(info.PDoc.ImportPath, "/") && frag != "" {
+		return frag
+	}
+	if m := info.Module; m != "" {
+		if ref.Path == m || strings.HasPrefix(ref.Path, m+"/") {
+			return path.Join("/mod", m+"@"+info.Version, strings.TrimPrefix(ref.Path, m)) + "/" + frag
+		}
+		if strings.Contains(strings.Split(ref.Path, "/")[0], ".") {
+			// Not in the standard library, and maybe not in the cache.
+			return ""
+		}
+	}
+	return "/pkg/" + ref.Path + "/" + frag
+}
+
 func docLinkFunc(s string, ident string) string {
 	return path.Clean("/pkg/"+s) + "/#" + ident
 }
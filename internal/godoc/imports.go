@@ -0,0 +1,84 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package godoc
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/website/internal/pkgdoc"
+)
+
+// importsPage is the data for the ImportsHTML template.
+type importsPage struct {
+	*pkgdoc.ImportList
+	Query string // query parameters to keep in links, ending in & if not empty
+}
+
+// serveImports serves the packages imported by the package importPath
+// in d, documented by info, or the packages importing it if mode has
+// ModeImportedBy, as a page, as JSON, or as a DOT or SVG graph.
+func (p *Presentation) serveImports(w http.ResponseWriter, r *http.Request, d *pkgdoc.Docs, info *pkgdoc.Page, importPath string, mode pkgdoc.Mode) {
+	if info.PDoc == nil {
+		p.serveDocError(w, r, importPath, mode, fmt.Errorf("no package in %s", importPath))
+		return
+	}
+	g := d.ImportGraph(info.Platform)
+	if g == nil {
+		p.serveDocError(w, r, importPath, mode, fmt.Errorf("unknown platform %s", info.Platform))
+		return
+	}
+	list, title := g.Imports(importPath), "Imports of "
+	if mode&pkgdoc.ModeImportedBy != 0 {
+		list, title = g.ImportedBy(importPath), "Packages importing "
+	}
+	switch {
+	case mode&pkgdoc.ModeJSON != 0:
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(marshalJSON(list))
+		return
+	case mode&pkgdoc.ModeDOT != 0:
+		w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		w.Write(list.DOT())
+		return
+	case mode&pkgdoc.ModeSVG != 0:
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Write(list.SVG(dirLinkFunc))
+		return
+	}
+
+	q := make(url.Values)
+	for _, k := range []string{"v", "GOOS", "GOARCH"} {
+		if v := r.FormValue(k); v != "" {
+			q.Set(k, v)
+		}
+	}
+	query := q.Encode()
+	if query != "" {
+		query += "&"
+	}
+	p.ServePage(w, Page{
+		Title:    title + importPath,
+		Tabtitle: importPath,
+		Subtitle: fmt.Sprintf("%d packages, built for %s", len(list.Packages), info.Platform),
+		Body:     applyTemplate(p.ImportsHTML, "importsHTML", &importsPage{list, query}),
+		GoogleCN: p.googleCN(r),
+	})
+}
+
+// dirLinkFunc returns the URL of the documentation for the package
+// in the absolute directory dir, like "/src/net/http" or
+// "/mod/golang.org/x/net@v0.1.0/http2".
+func dirLinkFunc(dir string) string {
+	if strings.HasPrefix(dir, "/src/") {
+		return "/pkg/" + strings.TrimPrefix(dir, "/src/") + "/"
+	}
+	return dir + "/"
+}
@@ -8,19 +8,16 @@
 package godoc
 
 import (
-	"bytes"
-	"encoding/json"
 	"io/fs"
 	"log"
 	"strings"
-)
 
-var (
-	doctype   = []byte("<!DOCTYPE ")
-	jsonStart = []byte("<!--{")
-	jsonEnd   = []byte("}-->")
+	"golang.org/x/website/internal/history"
+	"golang.org/x/website/internal/metadata"
 )
 
+var doctype = []byte("<!DOCTYPE ")
+
 type Metadata struct {
 	// Copied from document metadata
 	Title    string
@@ -32,30 +29,23 @@ type Metadata struct {
 }
 
 type MetaJSON struct {
-	Title    string
-	Subtitle string
-	Template bool
-	Redirect string // if set, redirect to other URL
+	Title       string
+	Subtitle    string
+	Template    bool
+	Redirect    string // if set, redirect to other URL
+	UpstreamRev string // for translations, the English revision last synced
+
+	// For release notes, the major release and its minor revisions.
+	Release *history.Release
+	Minor   []*history.Release
 }
 
 // extractMetadata extracts the MetaJSON from a byte slice.
 // It returns the Metadata value and the remaining data.
 // If no metadata is present the original byte slice is returned.
-//
+// See package metadata for the forms the metadata may take.
 func extractMetadata(b []byte) (meta MetaJSON, tail []byte, err error) {
-	tail = b
-	if !bytes.HasPrefix(b, jsonStart) {
-		return
-	}
-	end := bytes.Index(b, jsonEnd)
-	if end < 0 {
-		return
-	}
-	b = b[len(jsonStart)-1 : end+1] // drop leading <!-- and include trailing }
-	if err = json.Unmarshal(b, &meta); err != nil {
-		return
-	}
-	tail = tail[end+len(jsonEnd):]
+	tail, err = metadata.Parse(b, &meta)
 	return
 }
 
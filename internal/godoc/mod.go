@@ -0,0 +1,245 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package godoc
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+
+	"golang.org/x/website/internal/pkgdoc"
+)
+
+// modServer serves the documentation of the modules in p.Modules:
+// /mod/ lists the modules, /mod/path/ redirects to the latest version,
+// and /mod/path@version/dir/ documents the package in dir,
+// with its source files at /mod/path@version/dir/file.go.
+type modServer struct {
+	p *Presentation
+}
+
+func (h *modServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mods := h.p.Modules
+	if mods == nil {
+		http.NotFound(w, r)
+		return
+	}
+	relpath := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+	mode := pkgdoc.ParseMode(r.FormValue("m"))
+
+	modPath, version, dir, ok := pkgdoc.ModulePath("/" + relpath)
+	if !ok {
+		h.serveIndex(w, r, strings.TrimPrefix(strings.TrimPrefix(relpath, "mod"), "/"), mode)
+		return
+	}
+	if version == "latest" {
+		if version = mods.Latest(modPath); version != "" {
+			redirectModule(w, r, modPath, version, dir)
+			return
+		}
+	}
+	if v := r.FormValue("v"); v != "" && v != version {
+		redirectModule(w, r, modPath, v, dir)
+		return
+	}
+	m, ok := h.module(w, r, modPath, version, relpath, mode)
+	if !ok {
+		return
+	}
+
+	if fi, err := fs.Stat(m.FS, toFS(dir)); err == nil && !fi.IsDir() {
+		if redirectFile(w, r) {
+			return
+		}
+		src, err := fs.ReadFile(m.FS, dir)
+		if err != nil {
+			log.Printf("ERROR mod: %s: %v", relpath, err)
+			h.p.ServeError(w, r, relpath, errors.New("error reading file"))
+			return
+		}
+		h.p.serveText(w, r, src, "/"+relpath, relpath, "Source file")
+		return
+	}
+	if redirect(w, r) {
+		return
+	}
+
+	abspath := path.Join(m.Root(), dir)
+	importPath := path.Join(modPath, dir)
+	if from := r.FormValue("diff"); from != "" {
+		h.serveAPIDiff(w, r, m, dir, from, relpath)
+		return
+	}
+	info := pkgdoc.Doc(m.Docs, abspath, importPath, h.p.docMode(mode), r.FormValue("GOOS"), r.FormValue("GOARCH"))
+	info.Mode = mode // keep links from adding ?m=types
+	if info.Err != nil {
+		log.Print(info.Err)
+		h.p.serveDocError(w, r, relpath, mode, info.Err)
+		return
+	}
+	info.Module = modPath
+	info.Version = version
+	if list := mods.Versions(modPath); len(list) > 1 {
+		info.Versions = list
+	}
+	if mode&(pkgdoc.ModeImports|pkgdoc.ModeImportedBy) != 0 {
+		h.p.serveImports(w, r, m.Docs, info, importPath, mode)
+		return
+	}
+	if mode&pkgdoc.ModeJSON != 0 {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(marshalJSON(pkgdoc.JSON(info, importPath, nil)))
+		return
+	}
+	addPlatforms(m.Docs, abspath, info, mode)
+	if mode&pkgdoc.ModePlatforms != 0 {
+		info.GoogleCN = h.p.googleCN(r)
+		h.p.servePlatforms(w, r, info, importPath)
+		return
+	}
+
+	var title, tabtitle string
+	switch {
+	case info.PDoc != nil && info.IsMain:
+		tabtitle = path.Base(importPath)
+		title = "Command " + tabtitle
+	case info.PDoc != nil:
+		tabtitle = info.PDoc.Name
+		title = "Package " + tabtitle
+	default:
+		tabtitle = importPath
+		title = "Directory " + tabtitle
+	}
+	info.GoogleCN = h.p.googleCN(r)
+	h.p.ServePage(w, Page{
+		Title:    title,
+		Tabtitle: tabtitle,
+		Subtitle: "Module " + modPath + "@" + version,
+		Body:     applyTemplate(h.p.PackageHTML, "packageHTML", info),
+		GoogleCN: info.GoogleCN,
+	})
+}
+
+// module returns the module path at version.
+// If the module cannot be read, module serves an error
+// and returns ok == false.
+func (h *modServer) module(w http.ResponseWriter, r *http.Request, modPath, version, relpath string, mode pkgdoc.Mode) (m *pkgdoc.Module, ok bool) {
+	m, err := h.p.Modules.Module(modPath, version)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("ERROR mod: %s@%s: %v", modPath, version, err)
+		}
+		// Don't reveal file system paths.
+		h.p.serveDocError(w, r, relpath, mode, fmt.Errorf("module %s@%s not found", modPath, version))
+		return nil, false
+	}
+	return m, true
+}
+
+// redirectModule redirects to the directory dir in the module path at version,
+// keeping the query parameters other than v.
+func redirectModule(w http.ResponseWriter, r *http.Request, modPath, version, dir string) {
+	url := *r.URL
+	url.Path = path.Join("/mod", modPath+"@"+version, dir) + "/"
+	q := url.Query()
+	q.Del("v")
+	url.RawQuery = q.Encode()
+	http.Redirect(w, r, url.String(), http.StatusFound)
+}
+
+// serveIndex serves the list of modules whose paths start with prefix,
+// or redirects to the latest version if prefix is itself a module.
+func (h *modServer) serveIndex(w http.ResponseWriter, r *http.Request, prefix string, mode pkgdoc.Mode) {
+	mods := h.p.Modules
+	if v := mods.Latest(prefix); prefix != "" && v != "" {
+		redirectModule(w, r, prefix, v, "")
+		return
+	}
+	if redirect(w, r) {
+		return
+	}
+
+	var list []pkgdoc.DirEntry
+	for _, p := range mods.Modules() {
+		rel := p
+		if prefix != "" {
+			if !strings.HasPrefix(p, prefix+"/") {
+				continue
+			}
+			rel = strings.TrimPrefix(p, prefix+"/")
+		}
+		list = append(list, pkgdoc.DirEntry{
+			Depth:    1,
+			Path:     rel,
+			HasPkg:   true,
+			Synopsis: "latest " + mods.Latest(p),
+		})
+	}
+	relpath := path.Join("mod", prefix)
+	if len(list) == 0 && prefix != "" {
+		h.p.serveDocError(w, r, relpath, mode, fmt.Errorf("no modules in %s", prefix))
+		return
+	}
+	info := &pkgdoc.Page{
+		Dirname: "/" + relpath,
+		Mode:    mode,
+		DirFlat: true,
+	}
+	if list != nil {
+		info.Dirs = &pkgdoc.DirList{List: list}
+	}
+	if mode&pkgdoc.ModeJSON != 0 {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(marshalJSON(pkgdoc.JSON(info, prefix, nil)))
+		return
+	}
+
+	title := "Modules"
+	if prefix != "" {
+		title += " in " + prefix
+	}
+	info.GoogleCN = h.p.googleCN(r)
+	h.p.ServePage(w, Page{
+		Title:    title,
+		Tabtitle: title,
+		Body:     applyTemplate(h.p.PackageHTML, "packageHTML", info),
+		GoogleCN: info.GoogleCN,
+	})
+}
+
+// serveAPIDiff serves the list of changes to the exported API of the
+// package in dir between the version from and module m.
+func (h *modServer) serveAPIDiff(w http.ResponseWriter, r *http.Request, m *pkgdoc.Module, dir, from, relpath string) {
+	fromMod, ok := h.module(w, r, m.Path, from, relpath, 0)
+	if !ok {
+		return
+	}
+
+	// A package missing from either version has an empty API.
+	importPath := path.Join(m.Path, dir)
+	doc := func(m *pkgdoc.Module) *pkgdoc.Page {
+		info := pkgdoc.Doc(m.Docs, path.Join(m.Root(), dir), importPath, 0, "", "")
+		if info.Err != nil {
+			return nil
+		}
+		return info
+	}
+	diff := pkgdoc.DiffAPI(from, doc(fromMod), m.Version, doc(m))
+
+	h.p.ServePage(w, Page{
+		Title:    "API changes in " + importPath,
+		Tabtitle: importPath,
+		Subtitle: from + " to " + m.Version,
+		Body:     applyTemplate(h.p.APIDiffHTML, "apiDiffHTML", diff),
+		GoogleCN: h.p.googleCN(r),
+	})
+}
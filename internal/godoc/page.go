@@ -12,6 +12,8 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+
+	"golang.org/x/website/internal/translation"
 )
 
 // Page describes the contents of the top-level godoc webpage.
@@ -24,6 +26,11 @@ type Page struct {
 	Body     []byte
 	GoogleCN bool // page is being served from golang.google.cn
 
+	// Translation reports where a document page came from
+	// when serving a translated site; nil otherwise.
+	Translation *translation.Status
+	UpstreamRev string // for a translated document, the English revision it translates
+
 	// filled in by ServePage
 	Version         string
 	GoogleAnalytics string
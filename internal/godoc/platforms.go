@@ -0,0 +1,57 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package godoc
+
+import (
+	"fmt"
+	"net/http"
+
+	"golang.org/x/website/internal/pkgdoc"
+)
+
+// addPlatforms records in info the platforms on which the package
+// in abspath in d and its exported identifiers exist,
+// if info documents a package for which that matters.
+func addPlatforms(d *pkgdoc.Docs, abspath string, info *pkgdoc.Page, mode pkgdoc.Mode) {
+	if info.PDoc == nil || info.IsMain || mode&(pkgdoc.ModeSrc|pkgdoc.ModeBuiltin) != 0 {
+		return
+	}
+	info.Platforms = d.Platforms(abspath)
+}
+
+// platformsFunc returns the note on the platforms on which the identifier
+// or identifiers names exist, like "darwin, linux only", or "" if they
+// exist wherever the package documented by info does.
+func platformsFunc(info *pkgdoc.Page, names interface{}) string {
+	if info.Platforms == nil {
+		return ""
+	}
+	switch names := names.(type) {
+	case string:
+		return info.Platforms.Note(names)
+	case []string:
+		return info.Platforms.Note(names...)
+	}
+	return ""
+}
+
+// servePlatforms serves the table of the platforms on which
+// the exported identifiers of the package in info exist.
+func (p *Presentation) servePlatforms(w http.ResponseWriter, r *http.Request, info *pkgdoc.Page, importPath string) {
+	if info.Platforms == nil {
+		p.ServeError(w, r, importPath, fmt.Errorf("no package documentation for %s", importPath))
+		return
+	}
+	p.ServePage(w, Page{
+		Title:    "Platforms for " + importPath,
+		Tabtitle: importPath,
+		Subtitle: fmt.Sprintf("%d platforms", len(info.Platforms.Platforms)),
+		Body:     applyTemplate(p.PlatformsHTML, "platformsHTML", info),
+		GoogleCN: info.GoogleCN,
+	})
+}
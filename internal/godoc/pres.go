@@ -8,10 +8,15 @@
 package godoc
 
 import (
+	"context"
+	"io/fs"
 	"net/http"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"text/template"
 
+	"golang.org/x/website/internal/history"
 	"golang.org/x/website/internal/pkgdoc"
 )
 
@@ -21,13 +26,41 @@ type Presentation struct {
 
 	mux        *http.ServeMux
 	fileServer http.Handler
+	pkgDocs    *pkgdoc.Docs
 
+	searchIndex atomic.Value // *search.Index; set by InitSearch
+
+	// Versions holds the package documentation of the Go versions
+	// that can be selected with ?v=version, including DefaultVersion,
+	// the version of the corpus.
+	Versions       *pkgdoc.VersionSet
+	DefaultVersion string
+
+	// TypeCheck reports whether to type-check the packages documented.
+	// Requests setting ?m=types are documented without it otherwise.
+	TypeCheck bool
+
+	// Modules optionally holds the modules whose documentation
+	// is served at /mod/path@version/.
+	Modules *pkgdoc.ModuleCache
+
+	// Downloads optionally reports the files available for download
+	// for each Go release, by version, to list in the release feeds.
+	// It is called once for each feed served.
+	Downloads func(ctx context.Context) map[history.Version][]history.Download
+
+	APIChangesHTML,
+	APIDiffHTML,
 	DirlistHTML,
 	ErrorHTML,
 	ExampleHTML,
 	GodocHTML,
+	ImportsHTML,
 	PackageHTML,
-	PackageRootHTML *template.Template
+	PackageRootHTML,
+	PlatformsHTML,
+	RefsHTML,
+	SearchHTML *template.Template
 
 	// GoogleCN reports whether this request should be marked GoogleCN.
 	// If the function is nil, no requests are marked GoogleCN.
@@ -52,12 +85,23 @@ func NewPresentation(c *Corpus) *Presentation {
 		mux:        http.NewServeMux(),
 		fileServer: http.FileServer(http.FS(c.fs)),
 	}
+	p.pkgDocs = pkgdoc.NewDocs(c.fs)
+	p.DefaultVersion = goVersion(c.fs)
+	p.Versions = new(pkgdoc.VersionSet)
+	p.Versions.AddDocs(p.DefaultVersion, p.pkgDocs)
 	docs := &docServer{
 		p: p,
-		d: pkgdoc.NewDocs(c.fs),
+		d: p.pkgDocs,
 	}
 	p.mux.Handle("/cmd/", docs)
 	p.mux.Handle("/pkg/", docs)
+	p.mux.Handle("/mod/", &modServer{p})
+	p.mux.HandleFunc("/api/diff", p.serveAPIChanges)
+	p.mux.HandleFunc("/doc/devel/release", p.serveReleaseHistory)
+	p.mux.HandleFunc("/doc/devel/release.atom", p.serveReleaseFeed)
+	p.mux.HandleFunc("/doc/devel/security.atom", p.serveSecurityFeed)
+	p.mux.HandleFunc("/doc/devel/security.json", p.serveSecurityJSON)
+	p.mux.HandleFunc("/search", p.serveSearch)
 	p.mux.HandleFunc("/", p.ServeFile)
 	return p
 }
@@ -70,6 +114,33 @@ func (p *Presentation) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	p.mux.ServeHTTP(w, r)
 }
 
+// goVersion returns the Go version of the GOROOT in fsys, like "go1.16.4",
+// as recorded in its VERSION file, or "devel" for a development tree,
+// which has none.
+func goVersion(fsys fs.FS) string {
+	data, err := fs.ReadFile(fsys, "VERSION")
+	if err != nil {
+		return "devel"
+	}
+	v := strings.TrimSpace(strings.SplitN(string(data), "\n", 2)[0])
+	if !strings.HasPrefix(v, "go") {
+		return "devel"
+	}
+	return v
+}
+
+// docMode returns the mode in which to compute
+// the documentation requested with mode.
+// Packages are type-checked if and only if p.TypeCheck is set,
+// whatever the request asks for, since type checking a package
+// and the packages it imports is too costly to allow on demand.
+func (p *Presentation) docMode(mode pkgdoc.Mode) pkgdoc.Mode {
+	if p.TypeCheck {
+		return mode | pkgdoc.ModeTypes
+	}
+	return mode &^ pkgdoc.ModeTypes
+}
+
 func (p *Presentation) googleCN(r *http.Request) bool {
 	return p.GoogleCN != nil && p.GoogleCN(r)
 }
@@ -0,0 +1,259 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package godoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io/fs"
+	"log"
+	"net/http"
+	"path"
+
+	"golang.org/x/tools/blog/atom"
+	"golang.org/x/website/internal/history"
+)
+
+// releaseNotesGlob matches the release notes of the major Go releases,
+// whose metadata records the release history.
+const releaseNotesGlob = "doc/go1*.*"
+
+// InitReleases reads the release history from the metadata
+// of the release notes, for the /doc/devel/release page and feeds.
+func (c *Corpus) InitReleases() {
+	// Translated release notes need not repeat the release metadata,
+	// so read it from the English originals.
+	releases, err := loadReleases(c.englishFS())
+	if err != nil {
+		log.Printf("godoc: error loading release history: %v", err)
+	}
+	c.releases = releases
+	c.releaseMajors = history.Majors(releases)
+}
+
+// loadReleases reads the releases recorded in the metadata
+// of the release notes in fsys, sorted newest first.
+func loadReleases(fsys fs.FS) ([]*history.Release, error) {
+	files, err := fs.Glob(fsys, releaseNotesGlob)
+	if err != nil {
+		return nil, err
+	}
+	var releases []*history.Release
+	for _, file := range files {
+		if ext := path.Ext(file); ext != ".html" && ext != ".md" {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		meta, _, err := extractMetadata(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		if meta.Release == nil {
+			continue
+		}
+		releases = append(releases, meta.Release)
+		releases = append(releases, meta.Minor...)
+	}
+	history.Sort(releases)
+	return releases, nil
+}
+
+// englishRelease returns the release and minor revisions recorded
+// in the English original of the release notes at abspath,
+// for a translation that does not record them itself.
+func (c *Corpus) englishRelease(abspath string) (*history.Release, []*history.Release) {
+	if ok, _ := path.Match("/"+releaseNotesGlob, abspath); !ok {
+		return nil, nil
+	}
+	data, err := fs.ReadFile(c.englishFS(), toFS(abspath))
+	if err != nil {
+		return nil, nil
+	}
+	meta, _, err := extractMetadata(data)
+	if err != nil {
+		log.Printf("decoding metadata %s: %v", abspath, err)
+		return nil, nil
+	}
+	return meta.Release, meta.Minor
+}
+
+// serveReleaseHistory serves the release history page at /doc/devel/release,
+// or the list of releases as JSON for /doc/devel/release?mode=json.
+func (p *Presentation) serveReleaseHistory(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("mode") != "json" {
+		p.ServeFile(w, r)
+		return
+	}
+	releases := p.Corpus.releases
+	if releases == nil {
+		releases = []*history.Release{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", " ")
+	if err := enc.Encode(releases); err != nil {
+		log.Printf("ERROR rendering JSON for releases: %v", err)
+	}
+}
+
+// serveReleaseFeed serves an Atom feed of the Go releases
+// at /doc/devel/release.atom.
+func (p *Presentation) serveReleaseFeed(w http.ResponseWriter, r *http.Request) {
+	p.serveFeed(w, r, "Go Releases", "/doc/devel/release", p.Corpus.releases)
+}
+
+// serveSecurityFeed serves an Atom feed of the Go security releases
+// at /doc/devel/security.atom.
+func (p *Presentation) serveSecurityFeed(w http.ResponseWriter, r *http.Request) {
+	p.serveFeed(w, r, "Go Security Releases", "/doc/devel/security", p.Corpus.securityReleases())
+}
+
+// A securityRelease is an entry in the JSON security feed.
+type securityRelease struct {
+	*history.Release
+	Files []history.Download `json:",omitempty"`
+}
+
+// serveSecurityJSON serves the Go security releases that have happened,
+// newest first, as JSON at /doc/devel/security.json.
+// If p.Downloads is set, each release lists its files.
+func (p *Presentation) serveSecurityJSON(w http.ResponseWriter, r *http.Request) {
+	downloads := p.downloads(r)
+	list := []securityRelease{}
+	for _, rel := range p.Corpus.securityReleases() {
+		if !rel.Future {
+			list = append(list, securityRelease{rel, downloads[rel.Version]})
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", " ")
+	if err := enc.Encode(list); err != nil {
+		log.Printf("ERROR rendering JSON for security releases: %v", err)
+	}
+}
+
+// securityReleases returns the Go security releases, newest first.
+func (c *Corpus) securityReleases() []*history.Release {
+	var security []*history.Release
+	for _, rel := range c.releases {
+		if rel.Security {
+			security = append(security, rel)
+		}
+	}
+	return security
+}
+
+// downloads returns the files of the Go releases, by version,
+// for the feeds served in response to r, or nil if p.Downloads is not set.
+func (p *Presentation) downloads(r *http.Request) map[history.Version][]history.Download {
+	if p.Downloads == nil {
+		return nil
+	}
+	return p.Downloads(r.Context())
+}
+
+// serveFeed serves an Atom feed with the given title
+// listing releases, which are sorted newest first.
+// The feed is published at page + ".atom".
+// If p.Downloads is set, each entry lists the files of the release.
+func (p *Presentation) serveFeed(w http.ResponseWriter, req *http.Request, title, page string, releases []*history.Release) {
+	const baseURL = "https://golang.org"
+	downloads := p.downloads(req)
+	feed := &atom.Feed{
+		Title: title,
+		ID:    "tag:golang.org,2012:" + page,
+		Link: []atom.Link{
+			{Rel: "self", Href: baseURL + page + ".atom"},
+			{Rel: "alternate", Href: baseURL + page},
+		},
+		Author: &atom.Person{Name: "The Go Authors"},
+	}
+	for _, r := range releases {
+		if r.Future {
+			continue
+		}
+		if feed.Updated == "" {
+			feed.Updated = atom.Time(r.Date.Time())
+		}
+		e := &atom.Entry{
+			Title:     "Go " + r.Version.String(),
+			ID:        "tag:golang.org,2012:go" + r.Version.String(),
+			Published: atom.Time(r.Date.Time()),
+			Updated:   atom.Time(r.Date.Time()),
+		}
+		var summary string
+		if r.Version.IsMajor() {
+			e.Link = []atom.Link{{Rel: "alternate", Href: baseURL + "/doc/go" + r.Version.String()}}
+			summary = fmt.Sprintf("Go %s is a major release of Go.", r.Version)
+		} else {
+			major := r.Version
+			major.Z = 0
+			e.Link = []atom.Link{{Rel: "alternate", Href: fmt.Sprintf("%s/doc/devel/release#go%s.minor", baseURL, major)}}
+			summary = fmt.Sprintf("go%s (released %s) %s", r.Version, r.Date, r.Summary())
+		}
+		if r.Security {
+			e.Title += " (security)"
+		}
+		e.Summary = &atom.Text{Type: "html", Body: summary}
+		e.Link = append(e.Link, atom.Link{Rel: "related", Href: baseURL + "/dl/#go" + r.Version.String()})
+		if files := downloads[r.Version]; len(files) > 0 {
+			e.Content = &atom.Text{Type: "html", Body: downloadsHTML(files)}
+			for _, f := range files {
+				e.Link = append(e.Link, atom.Link{Rel: "enclosure", Href: f.URL})
+			}
+		}
+		feed.Entry = append(feed.Entry, e)
+	}
+	data, err := xml.Marshal(feed)
+	if err != nil {
+		log.Printf("ERROR rendering release feed: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	w.Write(data)
+}
+
+// downloadsHTML returns the list of release files
+// with their checksums shown in a feed entry.
+func downloadsHTML(files []history.Download) string {
+	var buf bytes.Buffer
+	buf.WriteString("<ul>\n")
+	for _, f := range files {
+		fmt.Fprintf(&buf, "<li><a href=\"%s\">%s</a> SHA256 %s</li>\n",
+			html.EscapeString(f.URL), html.EscapeString(f.Filename), html.EscapeString(f.SHA256))
+	}
+	buf.WriteString("</ul>\n")
+	return buf.String()
+}
+
+// minorReleasesHTML returns the list of minor revisions
+// to show at the end of the release notes for a major release.
+func minorReleasesHTML(major *history.Release, minor []*history.Release) []byte {
+	if len(minor) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "\n<h2 id=\"go%s.minor\">Minor revisions</h2>\n", major.Version)
+	for _, r := range minor {
+		when := "released"
+		if r.Future {
+			when = "planned for"
+		}
+		fmt.Fprintf(&buf, "\n<p>\ngo%s (%s %s) %s\n</p>\n", r.Version, when, r.Date, r.Summary())
+	}
+	return buf.Bytes()
+}
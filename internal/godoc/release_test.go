@@ -0,0 +1,191 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package godoc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"text/template"
+
+	"golang.org/x/website"
+	"golang.org/x/website/internal/history"
+	"golang.org/x/website/internal/translation"
+)
+
+// TestReleaseHistory checks the release history
+// recorded in the metadata of the release notes.
+func TestReleaseHistory(t *testing.T) {
+	releases, err := loadReleases(website.Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(releases) == 0 {
+		t.Fatal("no releases")
+	}
+	seen := make(map[history.Version]bool)
+	lastMinor := make(map[history.Version]int)
+	for i, r := range releases {
+		if seen[r.Version] {
+			t.Errorf("version %v duplicated", r.Version)
+			continue
+		}
+		seen[r.Version] = true
+		if i > 0 && r.Date.Time().After(releases[i-1].Date.Time()) {
+			t.Errorf("version %v out of order: %v vs %v", r.Version, r.Date, releases[i-1].Date)
+		}
+		major := r.Version
+		major.Z = 0
+		if z, ok := lastMinor[major]; ok && r.Version.Z != z-1 {
+			old := major
+			old.Z = z
+			t.Errorf("jumped from %v to %v", old, r.Version)
+		}
+		lastMinor[major] = r.Version.Z
+	}
+}
+
+func TestReleaseFeeds(t *testing.T) {
+	c := NewCorpus(fstest.MapFS{
+		"doc/go1.15.html": {Data: []byte(`<!--{
+	"Release": {"Version": "1.15", "Date": "2020-08-11"},
+	"Minor": [
+		{"Version": "1.15.1", "Date": "2020-09-01", "Security": true, "Packages": ["net/http/cgi"]}
+	]
+}-->
+Notes.`)},
+		"doc/go1.14.md": {Data: []byte(`<!--{"Release": {"Version": "1.14", "Date": "2020-02-25"}}-->`)},
+		"doc/go1.html":  {Data: []byte(`<!--{"Title": "Go 1 Release Notes"}-->`)},
+	})
+	c.InitReleases()
+	p := NewPresentation(c)
+	p.GodocHTML = template.Must(template.New("").Parse(`{{.Title}}: {{printf "%s" .Body}}`))
+
+	get := func(path string) string {
+		t.Helper()
+		rw := httptest.NewRecorder()
+		p.ServeHTTP(rw, httptest.NewRequest("GET", path, nil))
+		if rw.Code != 200 {
+			t.Fatalf("GET %s: %d", path, rw.Code)
+		}
+		return rw.Body.String()
+	}
+
+	var list []*history.Release
+	if err := json.Unmarshal([]byte(get("/doc/devel/release?mode=json")), &list); err != nil {
+		t.Fatal(err)
+	}
+	var versions []string
+	for _, r := range list {
+		versions = append(versions, r.Version.String())
+	}
+	if got, want := strings.Join(versions, " "), "1.15.1 1.15 1.14"; got != want {
+		t.Errorf("?mode=json versions = %s; want %s", got, want)
+	}
+
+	feed := get("/doc/devel/release.atom")
+	for _, want := range []string{
+		"<title>Go 1.15.1 (security)</title>",
+		`href="https://golang.org/doc/devel/release#go1.15.minor"`,
+		`href="https://golang.org/doc/go1.14"`,
+		"includes security fixes to the &lt;code&gt;net/http/cgi&lt;/code&gt; package.",
+	} {
+		if !strings.Contains(feed, want) {
+			t.Errorf("feed does not contain %q:\n%s", want, feed)
+		}
+	}
+
+	calls := 0
+	p.Downloads = func(ctx context.Context) map[history.Version][]history.Download {
+		calls++
+		m := make(map[history.Version][]history.Download)
+		for _, r := range list {
+			v := r.Version.String()
+			m[r.Version] = []history.Download{{
+				Filename: "go" + v + ".src.tar.gz",
+				URL:      "https://golang.org/dl/go" + v + ".src.tar.gz",
+				SHA256:   "2f0ab8b5",
+			}}
+		}
+		return m
+	}
+	feed = get("/doc/devel/security.atom")
+	for _, want := range []string{
+		"<title>Go 1.15.1 (security)</title>",
+		`<link rel="enclosure" href="https://golang.org/dl/go1.15.1.src.tar.gz">`,
+		"go1.15.1.src.tar.gz&lt;/a&gt; SHA256 2f0ab8b5",
+	} {
+		if !strings.Contains(feed, want) {
+			t.Errorf("security feed does not contain %q:\n%s", want, feed)
+		}
+	}
+	if strings.Contains(feed, "<title>Go 1.15</title>") {
+		t.Errorf("security feed lists Go 1.15:\n%s", feed)
+	}
+	if calls != 1 {
+		t.Errorf("security feed called Downloads %d times, want 1", calls)
+	}
+
+	var security []struct {
+		Version history.Version
+		Files   []history.Download
+	}
+	if err := json.Unmarshal([]byte(get("/doc/devel/security.json")), &security); err != nil {
+		t.Fatal(err)
+	}
+	if len(security) != 1 || security[0].Version.String() != "1.15.1" ||
+		len(security[0].Files) != 1 || security[0].Files[0].SHA256 != "2f0ab8b5" {
+		t.Errorf("security.json = %+v; want 1.15.1 with its file", security)
+	}
+
+	notes := get("/doc/go1.15")
+	for _, want := range []string{
+		"Go 1.15 Release Notes: ",
+		`<h2 id="go1.15.minor">Minor revisions</h2>`,
+		"go1.15.1 (released 2020-09-01) includes security fixes",
+	} {
+		if !strings.Contains(notes, want) {
+			t.Errorf("/doc/go1.15 does not contain %q:\n%s", want, notes)
+		}
+	}
+}
+
+// TestTranslatedReleaseNotes checks that translated release notes
+// without the release metadata keep their release in the history.
+func TestTranslatedReleaseNotes(t *testing.T) {
+	c := NewCorpus(translation.NewFS(
+		fstest.MapFS{
+			"doc/go1.15.html": {Data: []byte(`<!--{"Title": "Заметки о выпуске Go 1.15"}-->Заметки.`)},
+			"doc/go1.14.html": {Data: []byte("Заметки.")},
+		},
+		fstest.MapFS{
+			"doc/go1.15.html": {Data: []byte(`<!--{
+	"Release": {"Version": "1.15", "Date": "2020-08-11"},
+	"Minor": [{"Version": "1.15.1", "Date": "2020-09-01"}]
+}-->Notes.`)},
+			"doc/go1.14.html": {Data: []byte(`<!--{"Release": {"Version": "1.14", "Date": "2020-02-25"}}-->Notes.`)},
+		},
+		nil,
+	))
+	c.InitReleases()
+	var versions []string
+	for _, r := range c.releases {
+		versions = append(versions, r.Version.String())
+	}
+	if got, want := strings.Join(versions, " "), "1.15.1 1.15 1.14"; got != want {
+		t.Errorf("releases = %s; want %s", got, want)
+	}
+
+	p := NewPresentation(c)
+	p.GodocHTML = template.Must(template.New("").Parse(`{{.Title}}: {{printf "%s" .Body}}`))
+	testServeBody(t, p, "/doc/go1.15", `<h2 id="go1.15.minor">Minor revisions</h2>`)
+	testServeBody(t, p, "/doc/go1.14", "Go 1.14 Release Notes: Заметки.")
+}
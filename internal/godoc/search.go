@@ -0,0 +1,279 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package godoc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"go/doc"
+	"html"
+	"io/fs"
+	"log"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/website/internal/pkgdoc"
+	"golang.org/x/website/internal/search"
+)
+
+// maxSearchResults is the maximum number of results shown for a query.
+const maxSearchResults = 100
+
+// InitSearch builds the full-text search index over the site documents,
+// the package documentation and, if blog is non-nil, the blog articles
+// in the blog directory. Building the index takes a while; until InitSearch
+// returns, /search reports that indexing is in progress.
+func (p *Presentation) InitSearch(blog fs.FS) {
+	start := time.Now()
+	x := search.NewIndex()
+	p.indexContent(x)
+	p.indexPackages(x)
+	if blog != nil {
+		indexBlog(x, blog)
+	}
+	p.searchIndex.Store(x)
+	log.Printf("search index: %d documents in %v", x.Len(), time.Since(start).Round(time.Millisecond))
+}
+
+// indexContent adds the HTML and Markdown documents served from
+// the root, /doc and /ref directories to x.
+func (p *Presentation) indexContent(x *search.Index) {
+	seen := make(map[string]bool)
+	for _, dir := range []string{".", "doc", "ref"} {
+		err := fs.WalkDir(p.Corpus.fs, dir, func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if dir == "." && name != "." {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			ext := path.Ext(name)
+			if ext != ".html" && ext != ".md" {
+				return nil
+			}
+			src, err := fs.ReadFile(p.Corpus.fs, name)
+			if err != nil || bytes.HasPrefix(src, doctype) {
+				return nil
+			}
+			meta, src, err := extractMetadata(src)
+			if err != nil || meta.Redirect != "" {
+				return nil
+			}
+
+			url := "/" + strings.TrimSuffix(name, ext)
+			if path.Base(url) == "index" {
+				url = strings.TrimSuffix(url, "index")
+			} else if m := p.Corpus.MetadataFor(url); m != nil {
+				url = m.Path
+			}
+			if seen[url] {
+				return nil
+			}
+			seen[url] = true
+
+			if meta.Template {
+				src = templateActions.ReplaceAll(src, nil)
+			}
+			if ext == ".md" {
+				if src, err = renderMarkdown(src); err != nil {
+					return nil
+				}
+			}
+			title := meta.Title
+			if title == "" {
+				title = url
+			}
+			x.Add(&search.Doc{
+				Kind:  search.KindDoc,
+				Title: title,
+				URL:   url,
+				Text:  htmlText(src),
+			})
+			return nil
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("search index: walking %s: %v", dir, err)
+		}
+	}
+}
+
+var (
+	templateActions = regexp.MustCompile(`{{[^}]*}}`)
+	htmlTags        = regexp.MustCompile(`(?s)<script.*?</script>|<style.*?</style>|<!--.*?-->|<[^>]*>`)
+	whitespace      = regexp.MustCompile(`\s+`)
+)
+
+// htmlText returns the plain text content of the HTML src.
+func htmlText(src []byte) string {
+	text := htmlTags.ReplaceAllString(string(src), " ")
+	text = html.UnescapeString(text)
+	return strings.TrimSpace(whitespace.ReplaceAllString(text, " "))
+}
+
+// indexPackages adds the documentation of every package and command
+// in the /src tree, and of their exported identifiers, to x.
+func (p *Presentation) indexPackages(x *search.Index) {
+	root := p.pkgDocs.Lookup("/src")
+	if root == nil {
+		return
+	}
+	list := root.List(nil)
+	if list == nil {
+		return
+	}
+	for _, e := range list.List {
+		if !e.HasPkg || isInternal(e.Path) {
+			continue
+		}
+		info := pkgdoc.Doc(p.pkgDocs, path.Join("/src", e.Path), e.Path, 0, "", "")
+		if info.Err != nil || info.PDoc == nil {
+			continue
+		}
+		pdoc := info.PDoc
+		url := "/pkg/" + e.Path + "/"
+		if info.IsMain {
+			url = "/" + e.Path + "/"
+		}
+		x.Add(&search.Doc{
+			Kind:  search.KindPkg,
+			Title: e.Path,
+			URL:   url,
+			Text:  pdoc.Doc,
+		})
+		if info.IsMain {
+			continue
+		}
+
+		ident := func(name, text string) {
+			x.Add(&search.Doc{
+				Kind:  search.KindIdent,
+				Title: pdoc.Name + "." + name,
+				URL:   url + "#" + name,
+				Text:  text,
+			})
+		}
+		values := func(list []*doc.Value) {
+			for _, v := range list {
+				for _, name := range v.Names {
+					ident(name, v.Doc)
+				}
+			}
+		}
+		funcs := func(list []*doc.Func) {
+			for _, f := range list {
+				ident(f.Name, f.Doc)
+			}
+		}
+		values(pdoc.Consts)
+		values(pdoc.Vars)
+		funcs(pdoc.Funcs)
+		for _, t := range pdoc.Types {
+			ident(t.Name, t.Doc)
+			values(t.Consts)
+			values(t.Vars)
+			funcs(t.Funcs)
+			for _, m := range t.Methods {
+				ident(t.Name+"."+m.Name, m.Doc)
+			}
+		}
+	}
+}
+
+// isInternal reports whether the package path contains
+// an internal, vendor or testdata element.
+func isInternal(path string) bool {
+	for _, elem := range strings.Split(path, "/") {
+		switch elem {
+		case "internal", "vendor", "testdata":
+			return true
+		}
+	}
+	return false
+}
+
+// indexBlog adds the blog articles (*.article) in blog to x.
+func indexBlog(x *search.Index, blog fs.FS) {
+	list, err := fs.ReadDir(blog, ".")
+	if err != nil {
+		log.Printf("search index: reading blog: %v", err)
+		return
+	}
+	for _, d := range list {
+		name := d.Name()
+		if d.IsDir() || path.Ext(name) != ".article" {
+			continue
+		}
+		data, err := fs.ReadFile(blog, name)
+		if err != nil {
+			continue
+		}
+		title, text := articleText(data)
+		x.Add(&search.Doc{
+			Kind:  search.KindBlog,
+			Title: title,
+			URL:   "/blog/" + strings.TrimSuffix(name, ".article"),
+			Text:  text,
+		})
+	}
+}
+
+// articleText returns the title and the plain text body
+// of an article in present format.
+func articleText(data []byte) (title, text string) {
+	var body []string
+	inHeader := true
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case title == "":
+			title = strings.TrimSpace(strings.TrimPrefix(line, "#"))
+		case inHeader:
+			// The header ends at the first section heading.
+			if strings.HasPrefix(line, "*") || strings.HasPrefix(line, "##") {
+				inHeader = false
+				body = append(body, strings.TrimLeft(line, "*# "))
+			}
+		case strings.HasPrefix(line, "."):
+			// Present command, such as .image or .code.
+		default:
+			body = append(body, strings.TrimLeft(line, "*# "))
+		}
+	}
+	return title, strings.TrimSpace(whitespace.ReplaceAllString(strings.Join(body, " "), " "))
+}
+
+// serveSearch serves the search results page at /search?q=query.
+func (p *Presentation) serveSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.FormValue("q"))
+	data := struct {
+		Query    string
+		Indexing bool // index not yet available
+		Results  []*search.Result
+	}{Query: query}
+	if x, _ := p.searchIndex.Load().(*search.Index); x == nil {
+		data.Indexing = true
+	} else if query != "" {
+		data.Results = x.Search(query, maxSearchResults)
+	}
+
+	p.ServePage(w, Page{
+		Title:    "Search",
+		Tabtitle: query,
+		Query:    query,
+		Body:     applyTemplate(p.SearchHTML, "searchHTML", data),
+		GoogleCN: p.googleCN(r),
+	})
+}
@@ -0,0 +1,80 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package godoc
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"golang.org/x/website/internal/search"
+)
+
+func TestIndexContent(t *testing.T) {
+	p := &Presentation{
+		Corpus: NewCorpus(fstest.MapFS{
+			"index.html":       {Data: []byte("<!--{\n\t\"Title\": \"Home\"\n}-->\n<p>Welcome gophers</p>")},
+			"doc/install.html": {Data: []byte("<!--{\n\t\"Title\": \"Установка\"\n}-->\n<p>Установите <b>пакеты</b>.</p>")},
+			"doc/old.html":     {Data: []byte("<!--{\n\t\"Redirect\": \"/doc/install\"\n}-->\n")},
+			"doc/tmpl.html":    {Data: []byte("<!--{\n\t\"Title\": \"T\",\n\t\"Template\": true\n}-->\n{{code \"x.go\"}} gophers")},
+			"doc/notes.md":     {Data: []byte("<!--{\n\t\"Title\": \"Notes\"\n}-->\n# Release *notes*")},
+			"src/x/y.html":     {Data: []byte("<p>gophers</p>")},
+		}),
+	}
+	x := search.NewIndex()
+	p.indexContent(x)
+
+	for _, tt := range []struct {
+		query string
+		urls  []string
+	}{
+		{"gophers", []string{"/", "/doc/tmpl"}},
+		{"пакетов", []string{"/doc/install"}},
+		{"note", []string{"/doc/notes"}},
+		{"code", nil},
+		{"redirect", nil},
+	} {
+		var got []string
+		for _, r := range x.Search(tt.query, 10) {
+			got = append(got, r.URL)
+		}
+		if len(got) != len(tt.urls) {
+			t.Errorf("Search(%q) = %v; want %v", tt.query, got, tt.urls)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.urls[i] {
+				t.Errorf("Search(%q) = %v; want %v", tt.query, got, tt.urls)
+				break
+			}
+		}
+	}
+}
+
+func TestArticleText(t *testing.T) {
+	title, text := articleText([]byte(`# Go at 10
+10 Nov 2019
+Tags: community
+
+Russ Cox
+
+## Introduction
+
+Happy birthday, Go!
+
+.image 10years/gopher.png
+
+* Thanks
+Thank you all.
+`))
+	if title != "Go at 10" {
+		t.Errorf("title = %q; want %q", title, "Go at 10")
+	}
+	if want := "Introduction Happy birthday, Go! Thanks Thank you all."; text != want {
+		t.Errorf("text = %q; want %q", text, want)
+	}
+}
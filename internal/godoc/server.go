@@ -16,6 +16,7 @@ import (
 	"io/fs"
 	"log"
 	"net/http"
+	"os"
 	"path"
 	"regexp"
 	"strconv"
@@ -50,17 +51,52 @@ func (h *docServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	relpath := path.Clean(strings.TrimPrefix(r.URL.Path, "/pkg/"))
 
 	abspath := path.Join("/src", relpath)
+
 	mode := pkgdoc.ParseMode(r.FormValue("m"))
+
+	// Serve the documentation for another Go version if requested.
+	d, version := h.d, h.p.DefaultVersion
+	if v := r.FormValue("v"); v != "" && v != version {
+		if d = h.p.Versions.Docs(v); d == nil {
+			h.p.serveDocError(w, r, relpath, mode, fmt.Errorf("unknown Go version %q", v))
+			return
+		}
+		version = v
+	}
+	if from := r.FormValue("diff"); from != "" {
+		h.serveAPIDiff(w, r, abspath, relpath, from, version, d)
+		return
+	}
+
 	if relpath == "builtin" {
 		// The fake built-in package contains unexported identifiers,
 		// but we want to show them. Also, disable type association,
 		// since it's not helpful for this fake package (see issue 6645).
 		mode |= pkgdoc.ModeAll | pkgdoc.ModeBuiltin
 	}
-	info := pkgdoc.Doc(h.d, abspath, relpath, mode, r.FormValue("GOOS"), r.FormValue("GOARCH"))
+	info := pkgdoc.Doc(d, abspath, relpath, h.p.docMode(mode), r.FormValue("GOOS"), r.FormValue("GOARCH"))
+	info.Mode = mode // keep links from adding ?m=types
 	if info.Err != nil {
 		log.Print(info.Err)
-		h.p.ServeError(w, r, relpath, info.Err)
+		h.p.serveDocError(w, r, relpath, mode, info.Err)
+		return
+	}
+	if names := h.p.Versions.Names(); len(names) > 1 {
+		info.Version = version
+		info.Versions = names
+	}
+	if mode&(pkgdoc.ModeImports|pkgdoc.ModeImportedBy) != 0 {
+		h.p.serveImports(w, r, d, info, strings.TrimPrefix(relpath, "/"), mode)
+		return
+	}
+	if mode&pkgdoc.ModeJSON != 0 {
+		h.serveJSON(w, relpath, info)
+		return
+	}
+	addPlatforms(d, abspath, info, mode)
+	if mode&pkgdoc.ModePlatforms != 0 {
+		info.GoogleCN = h.p.googleCN(r)
+		h.p.servePlatforms(w, r, info, relpath)
 		return
 	}
 
@@ -114,6 +150,68 @@ func (h *docServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// serveJSON serves the documentation in info as a pkgdoc.JSONPage.
+func (h *docServer) serveJSON(w http.ResponseWriter, relpath string, info *pkgdoc.Page) {
+	importPath := strings.TrimPrefix(relpath, "/")
+	if importPath == "." {
+		importPath = ""
+	}
+	var since func(kind, receiver, name, pkg string) string
+	if h.p.Corpus != nil {
+		since = h.p.Corpus.pkgAPIInfo.Func
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(marshalJSON(pkgdoc.JSON(info, importPath, since)))
+}
+
+// serveDocError serves a 404 error for relpath, as JSON if requested by mode.
+func (p *Presentation) serveDocError(w http.ResponseWriter, r *http.Request, relpath string, mode pkgdoc.Mode, err error) {
+	if mode&pkgdoc.ModeJSON == 0 {
+		p.ServeError(w, r, relpath, err)
+		return
+	}
+	if _, ok := err.(*os.PathError); ok {
+		// Don't reveal file system paths.
+		err = fmt.Errorf("%s not found", relpath)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusNotFound)
+	w.Write(marshalJSON(struct {
+		Error string `json:"error"`
+	}{err.Error()}))
+}
+
+// serveAPIDiff serves the list of changes to the exported API of the
+// package in abspath between the Go version from and version,
+// whose documentation tree is d.
+func (h *docServer) serveAPIDiff(w http.ResponseWriter, r *http.Request, abspath, relpath, from, version string, d *pkgdoc.Docs) {
+	fromDocs := h.d
+	if from != h.p.DefaultVersion {
+		if fromDocs = h.p.Versions.Docs(from); fromDocs == nil {
+			h.p.ServeError(w, r, relpath, fmt.Errorf("unknown Go version %q", from))
+			return
+		}
+	}
+
+	// A package missing from either version has an empty API.
+	doc := func(d *pkgdoc.Docs) *pkgdoc.Page {
+		info := pkgdoc.Doc(d, abspath, relpath, 0, "", "")
+		if info.Err != nil {
+			return nil
+		}
+		return info
+	}
+	diff := pkgdoc.DiffAPI(from, doc(fromDocs), version, doc(d))
+
+	h.p.ServePage(w, Page{
+		Title:    "API changes in " + relpath,
+		Tabtitle: relpath,
+		Subtitle: from + " to " + version,
+		Body:     applyTemplate(h.p.APIDiffHTML, "apiDiffHTML", diff),
+		GoogleCN: h.p.googleCN(r),
+	})
+}
+
 func modeQueryString(m pkgdoc.Mode) string {
 	s := m.String()
 	if s == "" {
@@ -211,17 +309,31 @@ func (p *Presentation) serveTextFile(w http.ResponseWriter, r *http.Request, abs
 		return
 	}
 
+	if name := r.FormValue("refs"); name != "" && p.Corpus.Xref && path.Ext(abspath) == ".go" {
+		p.serveRefs(w, r, path.Dir(abspath), name)
+		return
+	}
+	p.serveText(w, r, src, abspath, relpath, title)
+}
+
+// serveText serves src, the contents of the file abspath,
+// as HTML with line numbers or, with ?m=text, as plain text.
+func (p *Presentation) serveText(w http.ResponseWriter, r *http.Request, src []byte, abspath, relpath, title string) {
 	if r.FormValue("m") == "text" {
 		p.ServeText(w, src)
 		return
 	}
 
+	isGo := path.Ext(abspath) == ".go"
 	cfg := texthtml.Config{
-		GoComments: path.Ext(abspath) == ".go",
+		GoComments: isGo,
 		Highlight:  r.FormValue("h"),
 		Selection:  rangeSelection(r.FormValue("s")),
 		Line:       1,
 	}
+	if x := p.Corpus.xref(); x != nil && isGo {
+		cfg.Links = x.Links(abspath)
+	}
 
 	var buf bytes.Buffer
 	buf.WriteString("<pre>")
@@ -244,6 +356,11 @@ func (p *Presentation) serveDirectory(w http.ResponseWriter, r *http.Request, ab
 		return
 	}
 
+	if name := r.FormValue("refs"); name != "" && p.Corpus.Xref {
+		p.serveRefs(w, r, path.Clean(abspath), name)
+		return
+	}
+
 	list, err := fs.ReadDir(p.Corpus.fs, toFS(abspath))
 	if err != nil {
 		p.ServeError(w, r, relpath, err)
@@ -291,9 +408,19 @@ func (p *Presentation) ServeHTMLDoc(w http.ResponseWriter, r *http.Request, absp
 	}
 
 	page := Page{
-		Title:    meta.Title,
-		Subtitle: meta.Subtitle,
-		GoogleCN: p.googleCN(r),
+		Title:       meta.Title,
+		Subtitle:    meta.Subtitle,
+		GoogleCN:    p.googleCN(r),
+		Translation: p.Corpus.translationStatus(abspath),
+	}
+	if page.Translation != nil && page.Translation.Translated {
+		page.UpstreamRev = meta.UpstreamRev
+		if meta.Release == nil {
+			meta.Release, meta.Minor = p.Corpus.englishRelease(abspath)
+		}
+	}
+	if meta.Release != nil && page.Title == "" {
+		page.Title = "Go " + meta.Release.Version.String() + " Release Notes"
 	}
 
 	// evaluate as template if indicated
@@ -332,6 +459,11 @@ func (p *Presentation) ServeHTMLDoc(w http.ResponseWriter, r *http.Request, absp
 		src = buf.Bytes()
 	}
 
+	// if they are release notes, list the minor revisions
+	if meta.Release != nil {
+		src = append(src, minorReleasesHTML(meta.Release, meta.Minor)...)
+	}
+
 	page.Body = src
 	p.ServePage(w, page)
 }
@@ -8,13 +8,20 @@
 package godoc
 
 import (
+	"archive/zip"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"testing/fstest"
 	"text/template"
+
+	"golang.org/x/website/internal/pkgdoc"
+	"golang.org/x/website/internal/translation"
 )
 
 func testServeBody(t *testing.T, p *Presentation, path, body string) {
@@ -64,3 +71,197 @@ func TestMarkdown(t *testing.T) {
 	testServeBody(t, p, "/doc/test", "<strong>bold</strong>")
 	testServeBody(t, p, "/doc/test2", "<em>template</em>")
 }
+
+func TestFrontMatter(t *testing.T) {
+	p := &Presentation{
+		Corpus: NewCorpus(fstest.MapFS{
+			"doc/go1.9.md": {Data: []byte("---\nTemplate: true\nRelease:\n  Version: \"1.9\"\n  Date: \"2017-08-24\"\n---\n{{.Title}} **notes**")},
+		}),
+		GodocHTML: template.Must(template.New("").Parse(`{{.Title}}: {{printf "%s" .Body}}`)),
+	}
+
+	testServeBody(t, p, "/doc/go1.9", "Go 1.9 Release Notes: <p>Go 1.9 Release Notes <strong>notes</strong>")
+
+	// An unquoted version is a YAML number and is rejected
+	// rather than read as 1.1.
+	if _, _, err := extractMetadata([]byte("---\nRelease:\n  Version: 1.10\n---\n")); err == nil {
+		t.Errorf("extractMetadata accepted unquoted version 1.10")
+	}
+}
+
+func TestTranslationStatus(t *testing.T) {
+	p := &Presentation{
+		Corpus: NewCorpus(translation.NewFS(
+			fstest.MapFS{
+				"doc/ru.html":       {Data: []byte("Привет.")},
+				"doc/old.html":      {Data: []byte(`<!--{"UpstreamRev": "abc123"}-->Старый.`)},
+				"translations.json": {Data: []byte(`{"docs": [{"path": "_content/doc/old.html", "translated": true, "upstreamRev": "abc123", "diffLines": 2}]}`)},
+			},
+			fstest.MapFS{
+				"doc/ru.html":  {Data: []byte("Hello.")},
+				"doc/en.html":  {Data: []byte("Hello.")},
+				"doc/old.html": {Data: []byte("New.")},
+			},
+			nil,
+		)),
+		GodocHTML: template.Must(template.New("").Parse(`{{printf "%s" .Body}}{{with .Translation}}{{if .Untranslated}} [untranslated]{{else if .Stale}} [stale since {{$.UpstreamRev}}]{{end}}{{end}}`)),
+	}
+
+	testServeBody(t, p, "/doc/ru", "Привет.")
+	testServeBody(t, p, "/doc/en", "Hello. [untranslated]")
+	testServeBody(t, p, "/doc/old", "Старый. [stale since abc123]")
+}
+
+func TestDocJSON(t *testing.T) {
+	p := NewPresentation(NewCorpus(fstest.MapFS{
+		"src/p/p.go":     {Data: []byte("// Package p is a test.\npackage p\n\n// F does nothing.\nfunc F() {}\n")},
+		"src/cmd/c/c.go": {Data: []byte("// C is a command.\npackage main\n\nfunc main() {}\n")},
+	}))
+	for _, tc := range []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/pkg/p/?m=json", 200, `{"path":"p","package":{"name":"p","importPath":"p","synopsis":"Package p is a test.","doc":"Package p is a test.\n","funcs":[{"name":"F","doc":"F does nothing.\n","decl":"func F()"}]}}`},
+		{"/cmd/c/?m=json", 200, `{"path":"cmd/c","package":{"name":"main","importPath":"cmd/c","isCommand":true,"synopsis":"C is a command.","doc":"C is a command.\n"}}`},
+		{"/pkg/?m=json", 200, `{"path":"","subdirs":[{"path":"cmd","depth":1,"hasPkg":false},{"path":"cmd/c","depth":2,"hasPkg":true,"synopsis":"C is a command."},{"path":"p","depth":1,"hasPkg":true,"synopsis":"Package p is a test."}]}`},
+		{"/pkg/p/?m=json&v=go0", 404, `{"error":"unknown Go version \"go0\""}`},
+	} {
+		w := httptest.NewRecorder()
+		p.ServeHTTP(w, httptest.NewRequest("GET", tc.path, nil))
+		if w.Code != tc.code || w.Body.String() != tc.body {
+			t.Errorf("GET %s: %d %s\nwant %d %s", tc.path, w.Code, w.Body, tc.code, tc.body)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+			t.Errorf("GET %s: Content-Type %q", tc.path, ct)
+		}
+	}
+}
+
+func TestDocMode(t *testing.T) {
+	p := &Presentation{}
+	if m := p.docMode(pkgdoc.ModeJSON | pkgdoc.ModeTypes); m != pkgdoc.ModeJSON {
+		t.Errorf("docMode(json,types) = %v without TypeCheck, want %v", m, pkgdoc.ModeJSON)
+	}
+	p.TypeCheck = true
+	if m := p.docMode(pkgdoc.ModeJSON); m != pkgdoc.ModeJSON|pkgdoc.ModeTypes {
+		t.Errorf("docMode(json) = %v with TypeCheck, want %v", m, pkgdoc.ModeJSON|pkgdoc.ModeTypes)
+	}
+}
+
+func TestGoVersion(t *testing.T) {
+	for _, tc := range []struct {
+		data string
+		want string
+	}{
+		{"go1.16.4\ntime 2021-05-06T15:53:07Z\n", "go1.16.4"},
+		{"go1.17rc1", "go1.17rc1"},
+		{"devel +0123456789", "devel"},
+	} {
+		if v := goVersion(fstest.MapFS{"VERSION": {Data: []byte(tc.data)}}); v != tc.want {
+			t.Errorf("goVersion(VERSION %q) = %q, want %q", tc.data, v, tc.want)
+		}
+	}
+	if v := goVersion(fstest.MapFS{}); v != "devel" {
+		t.Errorf("goVersion without VERSION = %q, want devel", v)
+	}
+}
+
+func TestModuleDocs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "modcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, vers := range []string{"v1.0.0", "v1.1.0"} {
+		v := filepath.Join(dir, "example.com/m/@v")
+		if err := os.MkdirAll(v, 0777); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Create(filepath.Join(v, vers+".zip"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		z := zip.NewWriter(f)
+		for name, data := range map[string]string{
+			"go.mod":   "module example.com/m\n",
+			"p/p.go":   "// Package p is a test.\npackage p\n\n// F does nothing.\nfunc F() {}\n",
+			"p/new.go": map[string]string{"v1.1.0": "package p\n\nfunc G() {}\n"}[vers],
+		} {
+			if data == "" {
+				continue
+			}
+			w, err := z.Create("example.com/m@" + vers + "/" + name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write([]byte(data))
+		}
+		if err := z.Close(); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+
+	p := NewPresentation(NewCorpus(fstest.MapFS{}))
+	p.Modules = pkgdoc.NewModuleCache(dir)
+	p.GodocHTML = template.Must(template.New("").Parse(`{{.Title}}: {{printf "%s" .Body}}`))
+	p.PackageHTML = template.Must(template.New("").Funcs(p.FuncMap()).Parse(
+		`{{with .Version}}{{.}} of {{$.Versions}} {{end}}{{with .PDoc}}{{range .Filenames}}{{srcLink .}} {{end}}{{end}}{{with .Dirs}}{{range .List}}{{.Path}} {{.Synopsis}}{{end}}{{end}}`))
+	p.APIDiffHTML = template.Must(template.New("").Parse(`{{.Added}}`))
+	p.ErrorHTML = template.Must(template.New("").Parse(`{{.}}`))
+	for _, tc := range []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/mod/", 200, "Modules: example.com/m latest v1.1.0"},
+		{"/mod/example.com/", 200, "Modules in example.com: m latest v1.1.0"},
+		{"/mod/example.com/m/", 302, "/mod/example.com/m@v1.1.0/"},
+		{"/mod/example.com/m@latest/p/", 302, "/mod/example.com/m@v1.1.0/p/"},
+		{"/mod/example.com/m@v1.1.0/p/?v=v1.0.0&m=all", 302, "/mod/example.com/m@v1.0.0/p/?m=all"},
+		{"/mod/example.com/m@v1.0.0/p", 301, "/mod/example.com/m@v1.0.0/p/"},
+		{"/mod/example.com/m@v1.0.0/p/", 200, "Package p: v1.0.0 of [v1.1.0 v1.0.0] /mod/example.com/m@v1.0.0/p/p.go "},
+		{"/mod/example.com/m@v1.0.0/", 200, "Directory example.com/m: v1.0.0 of [v1.1.0 v1.0.0] p Package p is a test."},
+		{"/mod/example.com/m@v1.1.0/p/?diff=v1.0.0", 200, "API changes in example.com/m/p: [func G()]"},
+		{"/mod/example.com/m@v1.0.0/p/p.go?m=text", 200, "// Package p is a test."},
+		{"/mod/example.com/m@v1.0.0/p/?m=json", 200, `{"path":"example.com/m/p","module":"example.com/m","version":"v1.0.0"`},
+		{"/mod/example.com/m@v1.0.0/q/", 404, ""},
+		{"/mod/example.com/m@v2.0.0/p/", 404, "module example.com/m@v2.0.0 not found"},
+		{"/mod/example.org/", 404, ""},
+	} {
+		w := httptest.NewRecorder()
+		p.ServeHTTP(w, httptest.NewRequest("GET", tc.path, nil))
+		body := w.Body.String()
+		if w.Code == http.StatusFound || w.Code == http.StatusMovedPermanently {
+			body = w.Header().Get("Location")
+		}
+		if w.Code != tc.code || !strings.Contains(body, tc.body) {
+			t.Errorf("GET %s: %d %s\nwant %d %s", tc.path, w.Code, body, tc.code, tc.body)
+		}
+	}
+}
+
+func TestRefsWithoutXref(t *testing.T) {
+	p := NewPresentation(NewCorpus(fstest.MapFS{
+		"src/a/a.go": {Data: []byte("package a\n\ntype T int\n\nvar V T\n")},
+	}))
+	p.GodocHTML = template.Must(template.New("").Parse(`{{.Title}}: {{printf "%s" .Body}}`))
+	p.RefsHTML = template.Must(template.New("").Parse(`{{.Count}} uses of {{.Name}}`))
+	get := func() string {
+		w := httptest.NewRecorder()
+		p.ServeHTTP(w, httptest.NewRequest("GET", "/src/a/a.go?refs=T", nil))
+		return w.Body.String()
+	}
+
+	// Without the index, ?refs= shows the file.
+	if body := get(); !strings.Contains(body, "type T int") {
+		t.Errorf("?refs= without Xref:\n%s\nwant the source file", body)
+	}
+
+	p.Corpus.Xref = true
+	p.Corpus.InitXref()
+	if body := get(); !strings.Contains(body, "References to T: 1 uses of T") {
+		t.Errorf("?refs= with Xref:\n%s\nwant the references", body)
+	}
+}
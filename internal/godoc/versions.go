@@ -6,12 +6,14 @@
 // +build go1.16
 
 // This file caches information about which standard library types, methods,
-// and functions appeared in what version of Go
+// functions, fields, constants and variables appeared in what version of Go
 
 package godoc
 
 import (
+	"errors"
 	"log"
+	"net/http"
 
 	"golang.org/x/website/internal/api"
 )
@@ -25,4 +27,48 @@ func (c *Corpus) InitVersionInfo() {
 		// TODO: consider making this fatal, after the Go 1.11 cycle.
 		log.Printf("godoc: error parsing API version files: %v", err)
 	}
+	c.apiFeatures, err = api.LoadFeatures()
+	if err != nil {
+		log.Printf("godoc: error parsing API version files: %v", err)
+	}
+}
+
+// serveAPIChanges serves the list of API features added between
+// two releases, at /api/diff?from=1.14&to=1.16.
+// The default is the changes in the latest release.
+func (p *Presentation) serveAPIChanges(w http.ResponseWriter, r *http.Request) {
+	fs := p.Corpus.apiFeatures
+	if fs == nil || len(fs.Versions) < 2 {
+		p.ServeError(w, r, r.URL.Path, errors.New("API version information not available"))
+		return
+	}
+	to := r.FormValue("to")
+	if to == "" {
+		to = fs.Latest()
+	}
+	from := r.FormValue("from")
+	if from == "" {
+		from = fs.Versions[len(fs.Versions)-2]
+		for i, v := range fs.Versions {
+			if v == to && i > 0 {
+				from = fs.Versions[i-1]
+			}
+		}
+	}
+	pkgs, err := fs.Diff(from, to)
+	if err != nil {
+		p.ServeError(w, r, r.URL.Path, err)
+		return
+	}
+	data := struct {
+		From, To string
+		Versions []string
+		Pkgs     []*api.PkgChanges
+	}{from, to, fs.Versions, pkgs}
+	p.ServePage(w, Page{
+		Title:    "API changes from Go " + from + " to Go " + to,
+		Tabtitle: "API changes",
+		Body:     applyTemplate(p.APIChangesHTML, "apiChangesHTML", data),
+		GoogleCN: p.googleCN(r),
+	})
 }
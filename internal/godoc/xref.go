@@ -0,0 +1,131 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package godoc
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"time"
+
+	"golang.org/x/website/internal/texthtml"
+	"golang.org/x/website/internal/xref"
+)
+
+// InitXref type-checks the Go packages in /src and builds the
+// identifier cross-reference index used to link the identifiers in
+// /src/*.go views to their declarations and to list their uses (?refs=).
+// Building the index takes a while; until InitXref returns,
+// source files are served without identifier links.
+// Set c.Xref before calling InitXref, so that ?refs= is served.
+func (c *Corpus) InitXref() {
+	start := time.Now()
+	x := xref.Build(c.fs, "/src")
+	c.xrefIndex.Store(x)
+	log.Printf("xref index: built in %v", time.Since(start).Round(time.Millisecond))
+}
+
+// xref returns the cross-reference index, or nil if it is not available.
+func (c *Corpus) xref() *xref.Index {
+	x, _ := c.xrefIndex.Load().(*xref.Index)
+	return x
+}
+
+// A refsFile lists the references to an identifier in a single file.
+type refsFile struct {
+	File string
+	Refs []refLine
+}
+
+// A refLine is a reference to an identifier.
+type refLine struct {
+	URL  string
+	Line int
+	Text []byte // HTML of the source line, with the identifier selected
+}
+
+// serveRefs serves the list of uses of the identifier name
+// declared in the package in directory dir.
+func (p *Presentation) serveRefs(w http.ResponseWriter, r *http.Request, dir, name string) {
+	data := struct {
+		Name     string
+		Dir      string
+		Indexing bool // index not yet available
+		Found    bool
+		Def      *refLine
+		DefFile  string
+		Files    []refsFile
+		Count    int
+	}{Name: name, Dir: dir}
+
+	if x := p.Corpus.xref(); x == nil {
+		data.Indexing = true
+	} else if def, refs, ok := x.Refs(dir, name); ok {
+		data.Found = true
+		data.Count = len(refs)
+		lines := newLineCache(p.Corpus.fs)
+		data.Def = lines.refLine(def)
+		data.DefFile = def.File
+		for _, ref := range refs {
+			if n := len(data.Files); n == 0 || data.Files[n-1].File != ref.File {
+				data.Files = append(data.Files, refsFile{File: ref.File})
+			}
+			f := &data.Files[len(data.Files)-1]
+			f.Refs = append(f.Refs, *lines.refLine(ref))
+		}
+	}
+
+	p.ServePage(w, Page{
+		Title:    "References to " + name,
+		Tabtitle: name,
+		SrcPath:  dir[1:],
+		Body:     applyTemplate(p.RefsHTML, "refsHTML", data),
+		GoogleCN: p.googleCN(r),
+	})
+}
+
+// A lineCache holds the lines of the source files read while
+// listing references.
+type lineCache struct {
+	fsys  fs.FS
+	files map[string][][]byte
+}
+
+func newLineCache(fsys fs.FS) *lineCache {
+	return &lineCache{fsys: fsys, files: make(map[string][][]byte)}
+}
+
+// refLine returns the refLine for ref.
+func (c *lineCache) refLine(ref xref.Ref) *refLine {
+	l := &refLine{
+		URL:  fmt.Sprintf("%s?s=%d:%d#L%d", ref.File, ref.Start, ref.End, ref.Line),
+		Line: ref.Line,
+	}
+	lines, ok := c.files[ref.File]
+	if !ok {
+		src, err := fs.ReadFile(c.fsys, toFS(ref.File))
+		if err == nil {
+			lines = bytes.SplitAfter(src, []byte("\n"))
+		}
+		c.files[ref.File] = lines
+	}
+	if ref.Line < 1 || ref.Line > len(lines) {
+		return l
+	}
+	// Compute the offset of the identifier within its line.
+	offset := 0
+	for _, line := range lines[:ref.Line-1] {
+		offset += len(line)
+	}
+	line := bytes.TrimRight(lines[ref.Line-1], "\n")
+	sel := texthtml.Span{Start: ref.Start - offset, End: ref.End - offset}
+	l.Text = texthtml.Format(line, texthtml.Config{Selection: texthtml.Spans(sel)})
+	return l
+}
@@ -3,6 +3,28 @@
 // license that can be found in the LICENSE file.
 
 // Package history holds the Go project release history.
+//
+// The history is recorded in the YAML front matter at the top of the
+// release notes for each major release, such as _content/doc/go1.16.html:
+//
+//	---
+//	Title: Go 1.16 Release Notes
+//	Release:
+//	  Version: "1.16"
+//	  Date: "2021-02-16"
+//	Minor:
+//	- Version: 1.16.1
+//	  Date: "2021-03-10"
+//	  Security: true
+//	  Packages:
+//	  - archive/zip
+//	  - encoding/xml
+//	---
+//
+// Versions of the form N.M must be quoted so that YAML reads them as strings.
+//
+// Releases older than Go 1.9 omit information about minor versions,
+// which is instead hard-coded in _content/doc/devel/release.html.
 package history
 
 import (
@@ -10,6 +32,7 @@ import (
 	"html"
 	"html/template"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -18,15 +41,22 @@ import (
 type Release struct {
 	Version  Version
 	Date     Date
-	Security bool // whether this is a security release
-	Future   bool // if true, the release hasn't happened yet
+	Security bool `json:",omitempty"` // whether this is a security release
+	Future   bool `json:",omitempty"` // if true, the release hasn't happened yet
 
 	// Release content summary.
-	Quantifier    string          // Optional quantifier. Empty string for unspecified amount of fixes (typical), "a" for a single fix, "two", "three" for multiple fixes, etc.
-	Components    []template.HTML // Components involved. For example, "cgo", "the <code>go</code> command", "the runtime", etc.
-	Packages      []string        // Packages involved. For example, "net/http", "crypto/x509", etc.
-	More          template.HTML   // Additional release content.
-	CustomSummary template.HTML   // CustomSummary, if non-empty, replaces the entire release content summary with custom HTML.
+	Quantifier    string          `json:",omitempty"` // Optional quantifier. Empty string for unspecified amount of fixes (typical), "a" for a single fix, "two", "three" for multiple fixes, etc.
+	Components    []template.HTML `json:",omitempty"` // Components involved. For example, "cgo", "the <code>go</code> command", "the runtime", etc.
+	Packages      []string        `json:",omitempty"` // Packages involved. For example, "net/http", "crypto/x509", etc.
+	More          template.HTML   `json:",omitempty"` // Additional release content.
+	CustomSummary template.HTML   `json:",omitempty"` // CustomSummary, if non-empty, replaces the entire release content summary with custom HTML.
+}
+
+// A Download describes a file of a Go release available for download.
+type Download struct {
+	Filename string // "go1.16.4.src.tar.gz"
+	URL      string // "https://golang.org/dl/go1.16.4.src.tar.gz"
+	SHA256   string // hex-encoded SHA256 checksum of the file
 }
 
 // A Version is a Go release version.
@@ -57,6 +87,29 @@ func (v Version) String() string {
 	}
 }
 
+// MarshalText returns the version string, like "1.14.1".
+func (v Version) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// UnmarshalText parses a version string, like "1.14.1".
+func (v *Version) UnmarshalText(text []byte) error {
+	f := strings.Split(string(text), ".")
+	if len(f) > 3 {
+		return fmt.Errorf("invalid Go version %q", text)
+	}
+	var n [3]int
+	for i, s := range f {
+		x, err := strconv.Atoi(s)
+		if err != nil || x < 0 || i == 0 && x < 1 {
+			return fmt.Errorf("invalid Go version %q", text)
+		}
+		n[i] = x
+	}
+	*v = Version{n[0], n[1], n[2]}
+	return nil
+}
+
 func (v Version) Before(u Version) bool {
 	if v.X != u.X {
 		return v.X < u.X
@@ -90,7 +143,39 @@ func (d Date) String() string {
 }
 
 func (d Date) Format(format string) string {
-	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, time.UTC).Format(format)
+	return d.Time().Format(format)
+}
+
+// Time returns the start of the day d in UTC.
+func (d Date) Time() time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, time.UTC)
+}
+
+// MarshalText returns the date in the form "2006-01-02".
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText parses a date in the form "2006-01-02".
+func (d *Date) UnmarshalText(text []byte) error {
+	t, err := time.Parse("2006-01-02", string(text))
+	if err != nil {
+		return fmt.Errorf("invalid date %q", text)
+	}
+	*d = Date{t.Year(), t.Month(), t.Day()}
+	return nil
+}
+
+// Sort sorts releases by date, newest first,
+// breaking ties with newer versions first.
+func Sort(releases []*Release) {
+	sort.SliceStable(releases, func(i, j int) bool {
+		ti, tj := releases[i].Date.Time(), releases[j].Date.Time()
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return releases[j].Version.Before(releases[i].Version)
+	})
 }
 
 // A Major describes a major Go release and its minor revisions.
@@ -99,12 +184,11 @@ type Major struct {
 	Minor []*Release // oldest first
 }
 
-var Majors []*Major = majors() // major versions, newest first
-
-// majors returns a list of major versions, sorted newest first.
-func majors() []*Major {
+// Majors groups releases by major version
+// and returns the major versions, sorted newest first.
+func Majors(releases []*Release) []*Major {
 	byVersion := make(map[Version]*Major)
-	for _, r := range Releases {
+	for _, r := range releases {
 		v := r.Version
 		v.Z = 0 // make major version
 		m := byVersion[v]
@@ -208,3 +292,40 @@ func (r *Release) ComponentsAndPackages() template.HTML {
 
 	return template.HTML(buf.String())
 }
+
+// Summary returns the summary of the release content shown on
+// the release history page, such as
+// "includes security fixes to the net/http package. See the ...".
+func (r *Release) Summary() template.HTML {
+	if r.CustomSummary != "" {
+		return r.CustomSummary
+	}
+	var buf strings.Builder
+	if r.Future {
+		buf.WriteString("will include ")
+	} else {
+		buf.WriteString("includes ")
+	}
+	if r.Quantifier != "" {
+		buf.WriteString(html.EscapeString(r.Quantifier) + " ")
+	}
+	if r.Security {
+		buf.WriteString("security ")
+	}
+	if r.Quantifier == "a" {
+		buf.WriteString("fix")
+	} else {
+		buf.WriteString("fixes")
+	}
+	if cp := r.ComponentsAndPackages(); cp != "" {
+		buf.WriteString(" to " + string(cp))
+	}
+	buf.WriteString(".")
+	if r.More != "" {
+		buf.WriteString(" " + string(r.More))
+	}
+	if !r.Future {
+		fmt.Fprintf(&buf, ` See the <a href="https://github.com/golang/go/issues?q=milestone%%3AGo%s+label%%3ACherryPickApproved">Go %s milestone</a> on our issue tracker for details.`, r.Version, r.Version)
+	}
+	return template.HTML(buf.String())
+}
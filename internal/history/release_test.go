@@ -5,36 +5,89 @@
 package history
 
 import (
+	"encoding/json"
+	"html/template"
+	"strings"
 	"testing"
 	"time"
 )
 
-func dateTime(d Date) time.Time {
-	return time.Date(d.Year, time.Month(d.Month), d.Day, 0, 0, 0, 0, time.UTC)
-}
+func TestReleaseJSON(t *testing.T) {
+	const data = `{"Version": "1.16.1", "Date": "2021-03-10", "Security": true, "Packages": ["archive/zip", "encoding/xml"]}`
+	var r Release
+	if err := json.Unmarshal([]byte(data), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Version != (Version{1, 16, 1}) || r.Date != (Date{2021, time.March, 10}) || !r.Security || len(r.Packages) != 2 {
+		t.Errorf("Unmarshal = %+v", r)
+	}
+	out, err := json.Marshal(&r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"Version":"1.16.1","Date":"2021-03-10","Security":true,"Packages":["archive/zip","encoding/xml"]}`
+	if string(out) != want {
+		t.Errorf("Marshal = %s; want %s", out, want)
+	}
 
-func TestReleases(t *testing.T) {
-	seen := make(map[Version]bool)
-	lastMinor := make(map[Version]int)
-	var last time.Time
-	for _, r := range Releases {
-		if seen[r.Version] {
-			t.Errorf("version %v duplicated", r.Version)
-			continue
+	for _, bad := range []string{
+		`{"Version": "1.16.1.1"}`,
+		`{"Version": "go1.16"}`,
+		`{"Version": "0.1"}`,
+		`{"Date": "2021-02-30"}`,
+	} {
+		if err := json.Unmarshal([]byte(bad), new(Release)); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded; want error", bad)
 		}
-		seen[r.Version] = true
-		rt := dateTime(r.Date)
-		if !last.IsZero() && rt.After(last) {
-			t.Errorf("version %v out of order: %v vs %v", r.Version, r.Date, last.Format("2006-01-02"))
-		}
-		last = rt
-		major := r.Version
-		major.Z = 0
-		if z, ok := lastMinor[major]; ok && r.Version.Z != z-1 {
-			old := major
-			old.Z = z
-			t.Errorf("jumped from %v to %v", old, r.Version)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	for _, tt := range []struct {
+		r    Release
+		want string
+	}{
+		{
+			Release{Version: Version{1, 9, 4}, Security: true, Quantifier: "a", Components: []template.HTML{`"go get"`}},
+			`includes a security fix to "go get". See the <a href="https://github.com/golang/go/issues?q=milestone%3AGo1.9.4+label%3ACherryPickApproved">Go 1.9.4 milestone</a> on our issue tracker for details.`,
+		},
+		{
+			Release{Version: Version{1, 16, 3}, Future: true, Components: []template.HTML{"the compiler"}, Packages: []string{"time"}, More: "More."},
+			`will include fixes to the compiler and the <code>time</code> package. More.`,
+		},
+		{
+			Release{Version: Version{1, 16, 4}, CustomSummary: "custom"},
+			`custom`,
+		},
+	} {
+		if got := string(tt.r.Summary()); got != tt.want {
+			t.Errorf("%v Summary() = %q; want %q", tt.r.Version, got, tt.want)
 		}
-		lastMinor[major] = r.Version.Z
+	}
+}
+
+func TestMajors(t *testing.T) {
+	releases := []*Release{
+		{Version: Version{1, 15, 1}, Date: Date{2020, 9, 1}},
+		{Version: Version{1, 14, 8}, Date: Date{2020, 9, 1}},
+		{Version: Version{1, 15, 0}, Date: Date{2020, 8, 11}},
+		{Version: Version{1, 14, 7}, Date: Date{2020, 8, 6}},
+		{Version: Version{1, 14, 0}, Date: Date{2020, 2, 25}},
+	}
+	Sort(releases)
+	var order []string
+	for _, r := range releases {
+		order = append(order, r.Version.String())
+	}
+	if got, want := strings.Join(order, " "), "1.15.1 1.14.8 1.15 1.14.7 1.14"; got != want {
+		t.Errorf("Sort order = %s; want %s", got, want)
+	}
+
+	majors := Majors(releases)
+	if len(majors) != 2 || majors[0].Version != (Version{1, 15, 0}) || majors[1].Version != (Version{1, 14, 0}) {
+		t.Fatalf("Majors = %v", majors)
+	}
+	if m := majors[1].Minor; len(m) != 2 || m[0].Version != (Version{1, 14, 7}) {
+		t.Errorf("Majors[1].Minor = %v", m)
 	}
 }
@@ -0,0 +1,136 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package memcache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// An LRU is a Client storing values in the memory of the current process.
+// When the values exceed its size limit, it evicts the least recently used ones.
+type LRU struct {
+	maxBytes int64
+	maxAge   time.Duration
+	now      func() time.Time // for testing
+
+	mu    sync.Mutex
+	list  *list.List // of *lruEntry, most recently used first
+	items map[string]*list.Element
+	stats Stats
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time // zero for no expiry
+}
+
+// size returns the number of bytes counted against the size limit for e.
+func (e *lruEntry) size() int64 {
+	return int64(len(e.key) + len(e.value))
+}
+
+// Stats reports the activity and contents of an LRU.
+type Stats struct {
+	Hits      int64 // Get calls finding a value
+	Misses    int64 // Get calls reporting ErrCacheMiss
+	Evictions int64 // values removed to respect the size limit
+	Items     int   // values currently stored
+	Bytes     int64 // size of keys and values currently stored
+}
+
+// NewLRU returns an LRU holding at most maxBytes of keys and values.
+// If maxAge is non-zero, values are kept for at most maxAge,
+// even if set with a longer or no expiration.
+func NewLRU(maxBytes int64, maxAge time.Duration) *LRU {
+	return &LRU{
+		maxBytes: maxBytes,
+		maxAge:   maxAge,
+		now:      time.Now,
+		list:     list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *LRU) Get(ctx context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem := c.items[key]
+	if elem != nil {
+		e := elem.Value.(*lruEntry)
+		if e.expires.IsZero() || c.now().Before(e.expires) {
+			c.list.MoveToFront(elem)
+			c.stats.Hits++
+			return e.value, nil
+		}
+		c.remove(elem)
+	}
+	c.stats.Misses++
+	return nil, ErrCacheMiss
+}
+
+func (c *LRU) Set(ctx context.Context, item *Item) error {
+	if item.Value == nil {
+		return errors.New("nil item value")
+	}
+	e := &lruEntry{key: item.Key, value: item.Value}
+	exp := item.Expiration
+	if c.maxAge > 0 && (exp == 0 || exp > c.maxAge) {
+		exp = c.maxAge
+	}
+	if exp != 0 {
+		e.expires = c.now().Add(exp)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem := c.items[item.Key]; elem != nil {
+		c.remove(elem)
+	}
+	if e.size() > c.maxBytes {
+		// Too big to cache at all.
+		return nil
+	}
+	c.items[e.key] = c.list.PushFront(e)
+	c.stats.Items++
+	c.stats.Bytes += e.size()
+	for c.stats.Bytes > c.maxBytes {
+		c.remove(c.list.Back())
+		c.stats.Evictions++
+	}
+	return nil
+}
+
+func (c *LRU) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem := c.items[key]; elem != nil {
+		c.remove(elem)
+	}
+	return nil
+}
+
+// remove removes elem from the cache.
+// c.mu must be held.
+func (c *LRU) remove(elem *list.Element) {
+	e := c.list.Remove(elem).(*lruEntry)
+	delete(c.items, e.key)
+	c.stats.Items--
+	c.stats.Bytes -= e.size()
+}
+
+// Stats returns the cache's current statistics.
+func (c *LRU) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
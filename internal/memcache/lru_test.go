@@ -0,0 +1,137 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package memcache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	c := NewLRU(30, time.Hour)
+	c.now = func() time.Time { return now }
+
+	get := func(key string) string {
+		t.Helper()
+		b, err := c.Get(ctx, key)
+		if err == ErrCacheMiss {
+			return "<miss>"
+		}
+		if err != nil {
+			t.Fatalf("Get(%q): %v", key, err)
+		}
+		return string(b)
+	}
+	set := func(key, value string, exp time.Duration) {
+		t.Helper()
+		if err := c.Set(ctx, &Item{Key: key, Value: []byte(value), Expiration: exp}); err != nil {
+			t.Fatalf("Set(%q): %v", key, err)
+		}
+	}
+
+	set("a", "123456789", 0) // 10 bytes each
+	set("b", "123456789", time.Minute)
+	set("c", "123456789", 0)
+	if got := get("a"); got != "123456789" {
+		t.Errorf("Get(a) = %q, want value", got)
+	}
+
+	// Adding d must evict b, the least recently used.
+	set("d", "123456789", 0)
+	if got := get("b"); got != "<miss>" {
+		t.Errorf("Get(b) after eviction = %q, want miss", got)
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if got := get(key); got != "123456789" {
+			t.Errorf("Get(%s) = %q, want value", key, got)
+		}
+	}
+
+	// Expiration, and maxAge capping longer expirations.
+	set("e", "x", time.Minute)
+	set("f", "y", 2*time.Hour)
+	now = now.Add(2 * time.Minute)
+	if got := get("e"); got != "<miss>" {
+		t.Errorf("Get(e) after expiry = %q, want miss", got)
+	}
+	if got := get("f"); got != "y" {
+		t.Errorf("Get(f) = %q, want y", got)
+	}
+	now = now.Add(time.Hour)
+	if got := get("f"); got != "<miss>" {
+		t.Errorf("Get(f) after maxAge = %q, want miss", got)
+	}
+
+	// Values larger than the cache are not stored.
+	set("g", "this value is much too big to fit", 0)
+	if got := get("g"); got != "<miss>" {
+		t.Errorf("Get(g) = %q, want miss", got)
+	}
+
+	if err := c.Delete(ctx, "c"); err != nil {
+		t.Fatal(err)
+	}
+	if got := get("c"); got != "<miss>" {
+		t.Errorf("Get(c) after Delete = %q, want miss", got)
+	}
+
+	st := c.Stats()
+	want := Stats{Hits: 5, Misses: 5, Evictions: 2, Items: 1, Bytes: 10}
+	if st != want {
+		t.Errorf("Stats() = %+v, want %+v", st, want)
+	}
+}
+
+func TestTiered(t *testing.T) {
+	ctx := context.Background()
+	local, remote := NewLRU(1<<10, 0), NewLRU(1<<10, 0)
+	c := WithCodec(Tiered(local, remote), JSON)
+
+	if err := c.Set(ctx, &Item{Key: "k", Object: "v1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := local.Get(ctx, "k"); err != nil {
+		t.Errorf("local Get after Set: %v", err)
+	}
+
+	// A value set in the remote cache by another process
+	// is copied into the local cache when first read.
+	remote.Set(ctx, &Item{Key: "k2", Value: []byte(`"v2"`)})
+	var s string
+	if err := c.Get(ctx, "k2", &s); err != nil || s != "v2" {
+		t.Errorf("Get(k2) = %q, %v, want v2", s, err)
+	}
+	if b, err := local.Get(ctx, "k2"); err != nil || string(b) != `"v2"` {
+		t.Errorf("local Get(k2) = %q, %v, want copy", b, err)
+	}
+
+	if err := c.Delete(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(ctx, "k", &s); err != ErrCacheMiss {
+		t.Errorf("Get after Delete: %v, want ErrCacheMiss", err)
+	}
+	if _, err := remote.Get(ctx, "k"); err != ErrCacheMiss {
+		t.Errorf("remote Get after Delete: %v, want ErrCacheMiss", err)
+	}
+}
+
+func TestNilClient(t *testing.T) {
+	ctx := context.Background()
+	c := WithCodec(nil, Gob)
+	if err := c.Set(ctx, &Item{Key: "k", Object: 1}); err != nil {
+		t.Errorf("Set: %v", err)
+	}
+	var v int
+	if err := c.Get(ctx, "k", &v); err != ErrCacheMiss {
+		t.Errorf("Get: %v, want ErrCacheMiss", err)
+	}
+	if err := c.Delete(ctx, "k"); err != nil {
+		t.Errorf("Delete: %v", err)
+	}
+}
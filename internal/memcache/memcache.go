@@ -3,8 +3,9 @@
 // license that can be found in the LICENSE file.
 
 // Package memcache provides a minimally compatible interface for
-// google.golang.org/appengine/memcache
-// and stores the data in Redis (e.g., via Cloud Memorystore).
+// google.golang.org/appengine/memcache.
+// It stores the data in Redis (e.g., via Cloud Memorystore),
+// in the memory of the current process, or both.
 package memcache
 
 import (
@@ -14,30 +15,26 @@ import (
 	"encoding/json"
 	"errors"
 	"time"
-
-	"github.com/gomodule/redigo/redis"
 )
 
 var ErrCacheMiss = errors.New("memcache: cache miss")
 
-func New(addr string) *Client {
-	const maxConns = 20
+// A Client is a cache of byte values by string key.
+type Client interface {
+	// Get returns the value stored for key,
+	// or ErrCacheMiss if there is none.
+	Get(ctx context.Context, key string) ([]byte, error)
 
-	pool := redis.NewPool(func() (redis.Conn, error) {
-		return redis.Dial("tcp", addr)
-	}, maxConns)
+	// Set stores item.Value for item.Key.
+	Set(ctx context.Context, item *Item) error
 
-	return &Client{
-		pool: pool,
-	}
-}
-
-type Client struct {
-	pool *redis.Pool
+	// Delete deletes any value stored for key.
+	Delete(ctx context.Context, key string) error
 }
 
+// A CodecClient is a Client storing values encoded using a Codec.
 type CodecClient struct {
-	client *Client
+	client Client
 	codec  Codec
 }
 
@@ -48,34 +45,22 @@ type Item struct {
 	Expiration time.Duration // Read-only.
 }
 
-func (c *Client) WithCodec(codec Codec) *CodecClient {
+// WithCodec returns a client storing values in c encoded using codec.
+// If c is nil, the returned client stores nothing:
+// Get always reports ErrCacheMiss, and Set and Delete do nothing.
+func WithCodec(c Client, codec Codec) *CodecClient {
+	if c == nil {
+		c = noCache{}
+	}
 	return &CodecClient{
 		c, codec,
 	}
 }
 
-func (c *Client) Delete(ctx context.Context, key string) error {
-	conn, err := c.pool.GetContext(ctx)
-	if err != nil {
-		return err
-	}
-	defer conn.Close()
-
-	_, err = conn.Do("DEL", key)
-	return err
-}
-
 func (c *CodecClient) Delete(ctx context.Context, key string) error {
 	return c.client.Delete(ctx, key)
 }
 
-func (c *Client) Set(ctx context.Context, item *Item) error {
-	if item.Value == nil {
-		return errors.New("nil item value")
-	}
-	return c.set(ctx, item.Key, item.Value, item.Expiration)
-}
-
 func (c *CodecClient) Set(ctx context.Context, item *Item) error {
 	if item.Object == nil {
 		return errors.New("nil object value")
@@ -84,46 +69,7 @@ func (c *CodecClient) Set(ctx context.Context, item *Item) error {
 	if err != nil {
 		return err
 	}
-	return c.client.set(ctx, item.Key, b, item.Expiration)
-}
-
-func (c *Client) set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
-	conn, err := c.pool.GetContext(ctx)
-	if err != nil {
-		return err
-	}
-	defer conn.Close()
-
-	if expiration == 0 {
-		_, err := conn.Do("SET", key, value)
-		return err
-	}
-
-	// NOTE(cbro): redis does not support expiry in units more granular than a second.
-	exp := int64(expiration.Seconds())
-	if exp == 0 {
-		// Redis doesn't allow a zero expiration, delete the key instead.
-		_, err := conn.Do("DEL", key)
-		return err
-	}
-
-	_, err = conn.Do("SETEX", key, exp, value)
-	return err
-}
-
-// Get gets the item.
-func (c *Client) Get(ctx context.Context, key string) ([]byte, error) {
-	conn, err := c.pool.GetContext(ctx)
-	if err != nil {
-		return nil, err
-	}
-	defer conn.Close()
-
-	b, err := redis.Bytes(conn.Do("GET", key))
-	if err == redis.ErrNil {
-		err = ErrCacheMiss
-	}
-	return b, err
+	return c.client.Set(ctx, &Item{Key: item.Key, Value: b, Expiration: item.Expiration})
 }
 
 func (c *CodecClient) Get(ctx context.Context, key string, v interface{}) error {
@@ -134,6 +80,13 @@ func (c *CodecClient) Get(ctx context.Context, key string, v interface{}) error
 	return c.codec.Unmarshal(b, v)
 }
 
+// noCache is a Client that stores nothing.
+type noCache struct{}
+
+func (noCache) Get(ctx context.Context, key string) ([]byte, error) { return nil, ErrCacheMiss }
+func (noCache) Set(ctx context.Context, item *Item) error           { return nil }
+func (noCache) Delete(ctx context.Context, key string) error        { return nil }
+
 var (
 	Gob  = Codec{gobMarshal, gobUnmarshal}
 	JSON = Codec{json.Marshal, json.Unmarshal}
@@ -11,7 +11,7 @@ import (
 	"time"
 )
 
-func getClient(t *testing.T) *Client {
+func getClient(t *testing.T) Client {
 	t.Helper()
 
 	addr := os.Getenv("GOLANG_REDIS_ADDR")
@@ -19,7 +19,7 @@ func getClient(t *testing.T) *Client {
 		t.Skip("skipping because GOLANG_REDIS_ADDR is unset")
 	}
 
-	return New(addr)
+	return NewRedis(addr)
 }
 
 func TestCacheMiss(t *testing.T) {
@@ -32,7 +32,7 @@ func TestCacheMiss(t *testing.T) {
 }
 
 func TestExpiry(t *testing.T) {
-	c := getClient(t).WithCodec(Gob)
+	c := WithCodec(getClient(t), Gob)
 	ctx := context.Background()
 
 	key := "testexpiry"
@@ -63,7 +63,7 @@ func TestExpiry(t *testing.T) {
 }
 
 func TestShortExpiry(t *testing.T) {
-	c := getClient(t).WithCodec(Gob)
+	c := WithCodec(getClient(t), Gob)
 	ctx := context.Background()
 
 	key := "testshortexpiry"
@@ -0,0 +1,88 @@
+// Copyright 2018 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package memcache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// A Redis is a Client storing values in a Redis server.
+type Redis struct {
+	pool *redis.Pool
+}
+
+// NewRedis returns a Client storing values in the Redis server at addr.
+func NewRedis(addr string) *Redis {
+	const maxConns = 20
+
+	pool := redis.NewPool(func() (redis.Conn, error) {
+		return redis.Dial("tcp", addr)
+	}, maxConns)
+
+	return &Redis{
+		pool: pool,
+	}
+}
+
+func (c *Redis) Delete(ctx context.Context, key string) error {
+	conn, err := c.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Do("DEL", key)
+	return err
+}
+
+func (c *Redis) Set(ctx context.Context, item *Item) error {
+	if item.Value == nil {
+		return errors.New("nil item value")
+	}
+	return c.set(ctx, item.Key, item.Value, item.Expiration)
+}
+
+func (c *Redis) set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	conn, err := c.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if expiration == 0 {
+		_, err := conn.Do("SET", key, value)
+		return err
+	}
+
+	// NOTE(cbro): redis does not support expiry in units more granular than a second.
+	exp := int64(expiration.Seconds())
+	if exp == 0 {
+		// Redis doesn't allow a zero expiration, delete the key instead.
+		_, err := conn.Do("DEL", key)
+		return err
+	}
+
+	_, err = conn.Do("SETEX", key, exp, value)
+	return err
+}
+
+// Get gets the item.
+func (c *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	conn, err := c.pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	b, err := redis.Bytes(conn.Do("GET", key))
+	if err == redis.ErrNil {
+		err = ErrCacheMiss
+	}
+	return b, err
+}
@@ -0,0 +1,53 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package memcache
+
+import "context"
+
+// Tiered returns a Client that consults the fast local cache,
+// usually an LRU, before the shared remote cache, usually Redis.
+// Values found only in the remote cache are copied into the local one.
+// Set and Delete apply to both caches.
+//
+// Delete cannot remove values from the local caches of other processes,
+// so those processes may return a deleted value until it expires locally.
+// Give the local LRU a short maximum age to bound that delay.
+func Tiered(local, remote Client) Client {
+	return &tiered{local, remote}
+}
+
+type tiered struct {
+	local  Client
+	remote Client
+}
+
+func (c *tiered) Get(ctx context.Context, key string) ([]byte, error) {
+	if b, err := c.local.Get(ctx, key); err == nil {
+		return b, nil
+	}
+	b, err := c.remote.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	// The remote expiration is unknown;
+	// the local cache's maximum age limits how long the copy lives.
+	c.local.Set(ctx, &Item{Key: key, Value: b})
+	return b, nil
+}
+
+func (c *tiered) Set(ctx context.Context, item *Item) error {
+	if err := c.local.Set(ctx, item); err != nil {
+		return err
+	}
+	return c.remote.Set(ctx, item)
+}
+
+func (c *tiered) Delete(ctx context.Context, key string) error {
+	err := c.local.Delete(ctx, key)
+	if err1 := c.remote.Delete(ctx, key); err == nil {
+		err = err1
+	}
+	return err
+}
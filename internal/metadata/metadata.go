@@ -0,0 +1,122 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+// Package metadata parses the metadata block at the start of
+// the HTML and Markdown documents served by the site.
+//
+// The metadata is either a JSON object in an HTML comment:
+//
+//	<!--{
+//		"Title": "Release Notes",
+//		"Template": true
+//	}-->
+//
+// or YAML front matter between two --- lines:
+//
+//	---
+//	Title: Release Notes
+//	Template: true
+//	---
+//
+// Both forms are decoded with the JSON decoding rules of the
+// destination value, so they share field names and types.
+// In YAML, values that would otherwise read as numbers, such as
+// the version "1.10" or an all-digit revision, must be quoted.
+package metadata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	jsonStart = []byte("<!--{")
+	jsonEnd   = []byte("}-->")
+	yamlStart = []byte("---\n")
+	yamlEnd   = []byte("\n---\n")
+)
+
+// Parse decodes the metadata block at the start of data into v,
+// which must be a pointer, and returns the data following the block.
+// If data has no complete metadata block, or the block cannot be decoded,
+// Parse returns data unchanged.
+func Parse(data []byte, v interface{}) (tail []byte, err error) {
+	if bytes.HasPrefix(data, yamlStart) {
+		end := bytes.Index(data, yamlEnd)
+		if end < 0 {
+			return data, nil
+		}
+		if err := unmarshalYAML(data[len(yamlStart):end+1], v); err != nil {
+			return data, err
+		}
+		return data[end+len(yamlEnd):], nil
+	}
+	if !bytes.HasPrefix(data, jsonStart) {
+		return data, nil
+	}
+	end := bytes.Index(data, jsonEnd)
+	if end < 0 {
+		return data, nil
+	}
+	// Drop the leading <!-- and include the trailing }.
+	if err := json.Unmarshal(data[len(jsonStart)-1:end+1], v); err != nil {
+		return data, err
+	}
+	return data[end+len(jsonEnd):], nil
+}
+
+// unmarshalYAML decodes the YAML data into v using v's JSON decoding.
+func unmarshalYAML(data []byte, v interface{}) error {
+	var y interface{}
+	if err := yaml.Unmarshal(data, &y); err != nil {
+		return err
+	}
+	j, err := yamlToJSON(y)
+	if err != nil {
+		return err
+	}
+	js, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(js, v)
+}
+
+// yamlToJSON converts the YAML-decoded value y,
+// whose maps have interface{} keys, to a value json.Marshal accepts.
+func yamlToJSON(y interface{}) (interface{}, error) {
+	switch y := y.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(y))
+		for k, v := range y {
+			ks, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("non-string key %v", k)
+			}
+			jv, err := yamlToJSON(v)
+			if err != nil {
+				return nil, err
+			}
+			m[ks] = jv
+		}
+		return m, nil
+	case []interface{}:
+		l := make([]interface{}, len(y))
+		for i, v := range y {
+			jv, err := yamlToJSON(v)
+			if err != nil {
+				return nil, err
+			}
+			l[i] = jv
+		}
+		return l, nil
+	}
+	return y, nil
+}
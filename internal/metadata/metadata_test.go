@@ -0,0 +1,45 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package metadata
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	type meta struct {
+		Title string
+		Rev   string
+		Tags  []string
+	}
+	for _, tt := range []struct {
+		in   string
+		want meta
+		tail string
+		err  bool
+	}{
+		{in: "<!--{\n\t\"Title\": \"T\",\n\t\"Rev\": \"abc\"\n}-->\nbody", want: meta{Title: "T", Rev: "abc"}, tail: "\nbody"},
+		{in: "---\nTitle: T\nTags: [a, b]\n---\nbody", want: meta{Title: "T", Tags: []string{"a", "b"}}, tail: "body"},
+		{in: "---\nRev: \"0123\"\n---\n", want: meta{Rev: "0123"}},
+		{in: "---\nRev: 0123\n---\n", tail: "---\nRev: 0123\n---\n", err: true},
+		{in: "<!--{ \"Title\": 1 }-->", tail: "<!--{ \"Title\": 1 }-->", err: true},
+		{in: "<!--{ unterminated", tail: "<!--{ unterminated"},
+		{in: "---\nunterminated", tail: "---\nunterminated"},
+		{in: "no metadata", tail: "no metadata"},
+	} {
+		var m meta
+		tail, err := Parse([]byte(tt.in), &m)
+		if (err != nil) != tt.err {
+			t.Errorf("Parse(%q): err = %v, want error %v", tt.in, err, tt.err)
+		}
+		if string(tail) != tt.tail {
+			t.Errorf("Parse(%q): tail = %q, want %q", tt.in, tail, tt.tail)
+		}
+		if !tt.err && (m.Title != tt.want.Title || m.Rev != tt.want.Rev || len(m.Tags) != len(tt.want.Tags)) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.in, m, tt.want)
+		}
+	}
+}
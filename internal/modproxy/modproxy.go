@@ -0,0 +1,409 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package modproxy serves the module proxy protocol (see 'go help goproxy')
+// for the modules under the import paths a site owns, such as golang.org/x/...,
+// from a local module cache directory or an upstream proxy.
+// Every go.mod file and module zip is checked against a checksum
+// database or a go.sum file before it is served, so that the site can
+// be trusted as GOPROXY, including on a network without other access.
+//
+// Module zips are copied to a cache directory and checked there once;
+// later requests are served from the checked copy.
+package modproxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	modzip "golang.org/x/mod/zip"
+)
+
+// A Config configures a module proxy.
+type Config struct {
+	// Prefixes lists the module path prefixes served, like "golang.org/x/net".
+	// A prefix also covers the paths below it, such as major versions.
+	// Requests for other modules get a 404, as from a proxy without them.
+	Prefixes []string
+
+	Source   Source   // where to find the module files
+	Verifier Verifier // how to check them
+
+	// CacheDir is the directory holding the module zips
+	// that have been checked. If empty, the handler creates
+	// a temporary directory when it first serves a zip.
+	CacheDir string
+}
+
+// NewConfig returns a Config serving the modules under prefixes.
+// If source is an http or https URL, the module files are fetched from
+// the proxy there; otherwise source names a module cache directory.
+// If sums is an http or https URL, the files are checked against the
+// checksum database sum.golang.org served there; otherwise sums names
+// a go.sum file listing the only modules that can be served.
+func NewConfig(prefixes []string, source, sums string) (*Config, error) {
+	cfg := &Config{Prefixes: prefixes}
+	if isURL(source) {
+		cfg.Source = Upstream(source)
+	} else {
+		cfg.Source = Dir(source)
+	}
+	if isURL(sums) {
+		cfg.Verifier = SumDB(SumGolangOrgKey, sums)
+	} else {
+		v, err := SumFile(sums)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Verifier = v
+	}
+	return cfg, nil
+}
+
+// isURL reports whether s is an http or https URL.
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "http://")
+}
+
+// Handler returns a handler serving the module proxy protocol for cfg,
+// at URL paths like /golang.org/x/net/@v/list. To serve it below a
+// path prefix, use http.StripPrefix.
+func Handler(cfg *Config) http.Handler {
+	return &server{cfg: cfg, zips: make(map[string]bool)}
+}
+
+type server struct {
+	cfg *Config
+
+	mu   sync.Mutex
+	dir  string          // cache directory, once created
+	zips map[string]bool // zip files checked and copied to dir
+}
+
+// owns reports whether s serves the module path.
+func (s *server) owns(path string) bool {
+	for _, p := range s.cfg.Prefixes {
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	file := strings.TrimPrefix(r.URL.Path, "/")
+	var escPath, rest string
+	if i := strings.Index(file, "/@"); i >= 0 {
+		escPath, rest = file[:i], file[i+1:]
+	}
+	path, err := module.UnescapePath(escPath)
+	if err != nil || !s.owns(path) {
+		http.NotFound(w, r)
+		return
+	}
+
+	ctx := r.Context()
+	switch {
+	case rest == "@v/list":
+		s.serveList(w, r, file)
+	case rest == "@latest":
+		s.serveLatest(w, r, file)
+	case strings.HasPrefix(rest, "@v/") && strings.Contains(rest, "."):
+		ext := rest[strings.LastIndex(rest, "."):]
+		version, err := module.UnescapeVersion(strings.TrimSuffix(strings.TrimPrefix(rest, "@v/"), ext))
+		if err != nil || module.Check(path, version) != nil || version != semver.Canonical(version) && !strings.HasSuffix(version, "+incompatible") {
+			http.NotFound(w, r)
+			return
+		}
+		switch ext {
+		case ".info":
+			s.serveInfo(w, r, file)
+		case ".mod":
+			data, ok := s.fetch(w, r, file)
+			if !ok {
+				return
+			}
+			if err := verifyMod(ctx, s.cfg.Verifier, path, version, data); err != nil {
+				verifyError(w, file, err)
+				return
+			}
+			serve(w, r, ext, data)
+		case ".zip":
+			s.serveZip(w, r, file, path, version)
+		default:
+			http.NotFound(w, r)
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// fetch returns the named file from the source.
+// If the file cannot be fetched, fetch writes the error response
+// and returns ok == false.
+func (s *server) fetch(w http.ResponseWriter, r *http.Request, file string) (data []byte, ok bool) {
+	data, err := s.cfg.Source.Fetch(r.Context(), file)
+	if errors.Is(err, os.ErrNotExist) {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("ERROR modproxy: fetching %s: %v", file, err)
+		http.Error(w, "error fetching module", http.StatusBadGateway)
+		return nil, false
+	}
+	return data, true
+}
+
+// verifyError writes the response for a file that failed verification.
+func verifyError(w http.ResponseWriter, file string, err error) {
+	log.Printf("ERROR modproxy: %s: %v", file, err)
+	if errors.Is(err, ErrNotListed) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+	} else {
+		http.Error(w, "module failed verification", http.StatusInternalServerError)
+	}
+}
+
+// serveZip serves the zip file of path at version from the cache directory,
+// first copying it from the source and verifying it if it is not there.
+func (s *server) serveZip(w http.ResponseWriter, r *http.Request, file, path, version string) {
+	dir, err := s.cacheDir()
+	if err != nil {
+		log.Printf("ERROR modproxy: %v", err)
+		http.Error(w, "error caching module", http.StatusInternalServerError)
+		return
+	}
+	name := filepath.Join(dir, filepath.FromSlash(file))
+	s.mu.Lock()
+	ok := s.zips[file]
+	s.mu.Unlock()
+	if !ok {
+		tmp, ok := s.download(w, r, file, name)
+		if !ok {
+			return
+		}
+		defer os.Remove(tmp) // in case it is not renamed
+		if err := verifyZip(r.Context(), s.cfg.Verifier, path, version, tmp); err != nil {
+			verifyError(w, file, err)
+			return
+		}
+		if err := os.Rename(tmp, name); err != nil {
+			log.Printf("ERROR modproxy: %v", err)
+			http.Error(w, "error caching module", http.StatusInternalServerError)
+			return
+		}
+		s.mu.Lock()
+		s.zips[file] = true
+		s.mu.Unlock()
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		log.Printf("ERROR modproxy: %v", err)
+		http.Error(w, "error reading module", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		log.Printf("ERROR modproxy: %v", err)
+		http.Error(w, "error reading module", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentTypes[".zip"])
+	http.ServeContent(w, r, "", fi.ModTime(), f)
+}
+
+// cacheDir returns the directory holding the checked module zips,
+// creating a temporary one if the configuration has none.
+func (s *server) cacheDir() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dir != "" {
+		return s.dir, nil
+	}
+	dir := s.cfg.CacheDir
+	if dir == "" {
+		var err error
+		dir, err = ioutil.TempDir("", "modproxy")
+		if err != nil {
+			return "", err
+		}
+	}
+	s.dir = dir
+	return dir, nil
+}
+
+// download copies the named file from the source to a temporary file
+// next to name, which it returns. It reads at most modzip.MaxZipFile bytes,
+// the largest module zip the go command accepts.
+// If the file cannot be copied, download writes the error response
+// and returns ok == false.
+func (s *server) download(w http.ResponseWriter, r *http.Request, file, name string) (tmp string, ok bool) {
+	rc, err := s.cfg.Source.Open(r.Context(), file)
+	if errors.Is(err, os.ErrNotExist) {
+		http.NotFound(w, r)
+		return "", false
+	}
+	if err != nil {
+		log.Printf("ERROR modproxy: fetching %s: %v", file, err)
+		http.Error(w, "error fetching module", http.StatusBadGateway)
+		return "", false
+	}
+	defer rc.Close()
+
+	if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+		log.Printf("ERROR modproxy: %v", err)
+		http.Error(w, "error caching module", http.StatusInternalServerError)
+		return "", false
+	}
+	f, err := ioutil.TempFile(filepath.Dir(name), "*.tmp")
+	if err != nil {
+		log.Printf("ERROR modproxy: %v", err)
+		http.Error(w, "error caching module", http.StatusInternalServerError)
+		return "", false
+	}
+	n, err := io.Copy(f, io.LimitReader(rc, modzip.MaxZipFile+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > modzip.MaxZipFile {
+		err = fmt.Errorf("zip larger than %d bytes", modzip.MaxZipFile)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		log.Printf("ERROR modproxy: fetching %s: %v", file, err)
+		http.Error(w, "error fetching module", http.StatusBadGateway)
+		return "", false
+	}
+	return f.Name(), true
+}
+
+// serveList serves a @v/list file, keeping only the valid versions.
+func (s *server) serveList(w http.ResponseWriter, r *http.Request, file string) {
+	data, ok := s.fetch(w, r, file)
+	if !ok {
+		return
+	}
+	serve(w, r, ".list", []byte(strings.Join(versions(data), "\n")+"\n"))
+}
+
+// versions returns the valid versions in the @v/list file data.
+func versions(data []byte) []string {
+	var list []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if f := strings.Fields(line); len(f) > 0 && semver.IsValid(f[0]) {
+			list = append(list, f[0])
+		}
+	}
+	return list
+}
+
+// An info is the JSON in .info and @latest files.
+type info struct {
+	Version string
+	Time    string `json:",omitempty"`
+}
+
+// serveInfo serves a .info file, after checking that it is one.
+func (s *server) serveInfo(w http.ResponseWriter, r *http.Request, file string) {
+	data, ok := s.fetch(w, r, file)
+	if !ok {
+		return
+	}
+	serveInfoData(w, r, file, data)
+}
+
+func serveInfoData(w http.ResponseWriter, r *http.Request, file string, data []byte) {
+	var i info
+	if err := json.Unmarshal(data, &i); err != nil || !semver.IsValid(i.Version) {
+		log.Printf("ERROR modproxy: %s: invalid info %q", file, data)
+		http.Error(w, "invalid version info", http.StatusBadGateway)
+		return
+	}
+	serve(w, r, ".info", data)
+}
+
+// serveLatest serves a @latest file. Module caches have none,
+// so if the source does not have it either, serveLatest serves the info
+// of the latest version in the @v/list file, preferring releases
+// to pre-releases, as the go command does.
+func (s *server) serveLatest(w http.ResponseWriter, r *http.Request, file string) {
+	data, err := s.cfg.Source.Fetch(r.Context(), file)
+	if err == nil {
+		serveInfoData(w, r, file, data)
+		return
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		log.Printf("ERROR modproxy: fetching %s: %v", file, err)
+		http.Error(w, "error fetching module", http.StatusBadGateway)
+		return
+	}
+	escPath := strings.TrimSuffix(file, "/@latest")
+	list, ok := s.fetch(w, r, escPath+"/@v/list")
+	if !ok {
+		return
+	}
+	latest := latestVersion(versions(list))
+	if latest == "" {
+		http.NotFound(w, r)
+		return
+	}
+	escVersion, err := module.EscapeVersion(latest)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	s.serveInfo(w, r, escPath+"/@v/"+escVersion+".info")
+}
+
+// latestVersion returns the latest release in list,
+// or the latest pre-release if there are no releases.
+func latestVersion(list []string) string {
+	var latest, latestPre string
+	for _, v := range list {
+		if semver.Prerelease(v) == "" {
+			if latest == "" || semver.Compare(v, latest) > 0 {
+				latest = v
+			}
+		} else if latestPre == "" || semver.Compare(v, latestPre) > 0 {
+			latestPre = v
+		}
+	}
+	if latest == "" {
+		return latestPre
+	}
+	return latest
+}
+
+var contentTypes = map[string]string{
+	".list": "text/plain; charset=utf-8",
+	".info": "application/json",
+	".mod":  "text/plain; charset=utf-8",
+	".zip":  "application/zip",
+}
+
+func serve(w http.ResponseWriter, r *http.Request, ext string, data []byte) {
+	w.Header().Set("Content-Type", contentTypes[ext])
+	w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+	if r.Method != "HEAD" {
+		w.Write(data)
+	}
+}
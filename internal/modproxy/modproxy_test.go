@@ -0,0 +1,281 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modproxy
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/mod/sumdb"
+	"golang.org/x/mod/sumdb/dirhash"
+	"golang.org/x/mod/sumdb/note"
+)
+
+// writeProxy writes a proxy directory holding versions of example.com/mod,
+// and returns it along with the go.sum lines for the versions.
+// The files of v1.0.1 are changed after computing its checksums.
+func writeProxy(t *testing.T) (dir string, gosum []string) {
+	dir, err := ioutil.TempDir("", "modproxy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := filepath.Join(dir, "example.com/mod/@v")
+	if err := os.MkdirAll(v, 0777); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, data string) {
+		if err := ioutil.WriteFile(filepath.Join(v, name), []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("list", "v1.0.0\nv1.0.1\nv1.1.0-pre\nbogus\n")
+	for _, vers := range []string{"v1.0.0", "v1.0.1", "v1.1.0-pre"} {
+		mod := "module example.com/mod\n"
+		write(vers+".info", fmt.Sprintf(`{"Version": %q, "Time": "2021-01-01T00:00:00Z"}`, vers))
+		write(vers+".mod", mod)
+		write(vers+".zip", modZip(t, vers, mod, "package mod\n"))
+
+		zipHash, err := dirhash.HashZip(filepath.Join(v, vers+".zip"), dirhash.Hash1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		modHash, err := dirhash.Hash1([]string{"go.mod"}, func(string) (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader(mod)), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		gosum = append(gosum,
+			fmt.Sprintf("example.com/mod %s %s", vers, zipHash),
+			fmt.Sprintf("example.com/mod %s/go.mod %s", vers, modHash))
+	}
+	write("v1.0.1.mod", "module example.com/mod\n\nrequire evil.com/mod v1.0.0\n")
+	write("v1.0.1.zip", modZip(t, "v1.0.1", "module example.com/mod\n", "package mod // changed\n"))
+	return dir, gosum
+}
+
+// modZip returns a zip file for example.com/mod at vers.
+func modZip(t *testing.T, vers, mod, src string) string {
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	for _, f := range []struct{ name, data string }{{"go.mod", mod}, {"mod.go", src}} {
+		w, err := z.Create("example.com/mod@" + vers + "/" + f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, f.data)
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestHandler(t *testing.T) {
+	dir, gosum := writeProxy(t)
+	defer os.RemoveAll(dir)
+	sumFile := filepath.Join(dir, "go.sum")
+	// Leave v1.1.0-pre out of the checksums.
+	if err := ioutil.WriteFile(sumFile, []byte(strings.Join(gosum[:4], "\n")+"\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	sums, err := SumFile(sumFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstream := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer upstream.Close()
+
+	for _, src := range []struct {
+		name   string
+		source Source
+	}{
+		{"dir", Dir(dir)},
+		{"upstream", Upstream(upstream.URL)},
+	} {
+		h := Handler(&Config{
+			Prefixes: []string{"example.com/mod"},
+			Source:   src.source,
+			Verifier: sums,
+			CacheDir: filepath.Join(dir, "cache-"+src.name),
+		})
+		for _, tc := range []struct {
+			path string
+			code int
+			body string
+		}{
+			{"/example.com/mod/@v/list", 200, "v1.0.0\nv1.0.1\nv1.1.0-pre\n"},
+			{"/example.com/mod/@v/v1.0.0.info", 200, `{"Version": "v1.0.0", "Time": "2021-01-01T00:00:00Z"}`},
+			{"/example.com/mod/@v/v1.0.0.mod", 200, "module example.com/mod\n"},
+			{"/example.com/mod/@v/v1.0.0.zip", 200, ""},
+			{"/example.com/mod/@latest", 200, `{"Version": "v1.0.1", "Time": "2021-01-01T00:00:00Z"}`},
+			{"/example.com/mod/@v/v1.0.1.mod", 500, ""},
+			{"/example.com/mod/@v/v1.0.1.zip", 500, ""},
+			{"/example.com/mod/@v/v1.1.0-pre.zip", 404, ""},
+			{"/example.com/mod/@v/v1.2.0.info", 404, ""},
+			{"/example.com/mod/@v/v1.0.info", 404, ""},
+			{"/example.com/mod/@v/v1", 404, ""},
+			{"/example.com/mod/@v/v2.0.0.info", 404, ""},
+			{"/example.com/other/@v/list", 404, ""},
+			{"/example.com/mod/../other/@v/list", 404, ""},
+		} {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", tc.path, nil))
+			if w.Code != tc.code || tc.body != "" && w.Body.String() != tc.body {
+				t.Errorf("%s: GET %s: %d %q, want %d %q", src.name, tc.path, w.Code, w.Body, tc.code, tc.body)
+			}
+		}
+	}
+}
+
+func TestZipCache(t *testing.T) {
+	dir, gosum := writeProxy(t)
+	defer os.RemoveAll(dir)
+	sums := make(sumFile)
+	for _, line := range gosum {
+		f := strings.Fields(line)
+		sums[f[0]+" "+f[1]] = f[2]
+	}
+	cfg := &Config{
+		Prefixes: []string{"example.com/mod"},
+		Source:   Dir(dir),
+		Verifier: sums,
+		CacheDir: filepath.Join(dir, "cache"),
+	}
+	zipFile := filepath.Join(dir, "example.com/mod/@v/v1.0.0.zip")
+	want, err := ioutil.ReadFile(zipFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(h http.Handler, method string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, "/example.com/mod/@v/v1.0.0.zip", nil))
+		return w
+	}
+	h := Handler(cfg)
+	if w := get(h, "GET"); w.Code != 200 || !bytes.Equal(w.Body.Bytes(), want) {
+		t.Fatalf("GET: %d, %d bytes, want 200 and the zip's %d bytes", w.Code, w.Body.Len(), len(want))
+	}
+
+	// Once checked, the zip is served from the cache directory,
+	// without reading the source again.
+	if err := ioutil.WriteFile(zipFile, []byte(modZip(t, "v1.0.0", "module example.com/mod\n", "package mod // changed\n")), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if w := get(h, "GET"); w.Code != 200 || !bytes.Equal(w.Body.Bytes(), want) {
+		t.Errorf("GET after change: %d, %d bytes, want 200 and the original %d bytes", w.Code, w.Body.Len(), len(want))
+	}
+	if w := get(h, "HEAD"); w.Code != 200 || w.Body.Len() != 0 || w.Header().Get("Content-Length") != fmt.Sprint(len(want)) {
+		t.Errorf("HEAD: %d, %d bytes, Content-Length %s, want 200, no body, and %d", w.Code, w.Body.Len(), w.Header().Get("Content-Length"), len(want))
+	}
+
+	// A new handler checks the changed zip, which fails,
+	// and leaves no temporary files behind.
+	cfg.CacheDir = filepath.Join(dir, "cache2")
+	if w := get(Handler(cfg), "GET"); w.Code != 500 {
+		t.Errorf("GET changed zip: %d, want 500", w.Code)
+	}
+	files, err := ioutil.ReadDir(filepath.Join(cfg.CacheDir, "example.com/mod/@v"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Errorf("cache directory holds %d files after failed check, want 0", len(files))
+	}
+}
+
+func TestSumDB(t *testing.T) {
+	dir, gosum := writeProxy(t)
+	defer os.RemoveAll(dir)
+
+	skey, vkey, err := note.GenerateKey(rand.Reader, "sum.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Leave v1.1.0-pre out of the database.
+	db := sumdb.NewTestServer(skey, func(path, vers string) ([]byte, error) {
+		var lines []string
+		for _, line := range gosum[:4] {
+			if strings.HasPrefix(line, path+" "+vers+" ") || strings.HasPrefix(line, path+" "+vers+"/go.mod ") {
+				lines = append(lines, line+"\n")
+			}
+		}
+		if lines == nil {
+			return nil, os.ErrNotExist
+		}
+		return []byte(strings.Join(lines, "")), nil
+	})
+	srv := httptest.NewServer(sumdb.NewServer(db))
+	defer srv.Close()
+
+	h := Handler(&Config{
+		Prefixes: []string{"example.com"},
+		Source:   Dir(dir),
+		Verifier: SumDB(vkey, srv.URL),
+		CacheDir: filepath.Join(dir, "cache"),
+	})
+	for _, tc := range []struct {
+		path string
+		code int
+	}{
+		{"/example.com/mod/@v/v1.0.0.zip", 200},
+		{"/example.com/mod/@v/v1.0.0.mod", 200},
+		{"/example.com/mod/@v/v1.1.0-pre.zip", 404},
+		{"/example.com/mod/@v/v1.1.0-pre.mod", 404},
+		{"/example.com/mod/@v/v1.0.1.zip", 500},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", tc.path, nil))
+		if w.Code != tc.code {
+			t.Errorf("GET %s: %d %q, want %d", tc.path, w.Code, w.Body, tc.code)
+		}
+	}
+}
+
+func TestNewConfig(t *testing.T) {
+	dir, gosum := writeProxy(t)
+	defer os.RemoveAll(dir)
+	sums := filepath.Join(dir, "go.sum")
+	if err := ioutil.WriteFile(sums, []byte(strings.Join(gosum, "\n")+"\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := NewConfig([]string{"example.com/mod"}, dir, sums)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cfg.Source.(dirSource); !ok {
+		t.Errorf("NewConfig(%q).Source = %T, want directory", dir, cfg.Source)
+	}
+	if _, ok := cfg.Verifier.(sumFile); !ok {
+		t.Errorf("NewConfig(%q).Verifier = %T, want go.sum file", sums, cfg.Verifier)
+	}
+
+	cfg, err = NewConfig(nil, "https://proxy.golang.org", "https://sum.golang.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cfg.Source.(upstreamSource); !ok {
+		t.Errorf("NewConfig(URL).Source = %T, want upstream", cfg.Source)
+	}
+	if _, ok := cfg.Verifier.(*sumDB); !ok {
+		t.Errorf("NewConfig(URL).Verifier = %T, want checksum database", cfg.Verifier)
+	}
+
+	if _, err := NewConfig(nil, dir, filepath.Join(dir, "missing.sum")); err == nil {
+		t.Errorf("NewConfig with missing go.sum file succeeded")
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modproxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// A Source provides the files of the module proxy protocol.
+type Source interface {
+	// Fetch returns the contents of the named file, like
+	// "golang.org/x/net/@v/list" or "golang.org/x/net/@v/v0.1.0.zip",
+	// in which the module path and version are already escaped.
+	// If there is no such file, the error satisfies errors.Is(err, os.ErrNotExist).
+	Fetch(ctx context.Context, file string) ([]byte, error)
+
+	// Open is like Fetch but returns a reader of the file's contents,
+	// for files too large to read into memory, such as module zips.
+	// The caller must close it.
+	Open(ctx context.Context, file string) (io.ReadCloser, error)
+}
+
+// Dir returns a Source reading files from the local directory dir,
+// which is laid out like a module proxy, as are the module cache's
+// download directory, $GOMODCACHE/cache/download, and a directory
+// used with GOPROXY=file://dir.
+func Dir(dir string) Source {
+	return dirSource(dir)
+}
+
+type dirSource string
+
+func (d dirSource) Fetch(ctx context.Context, file string) ([]byte, error) {
+	if strings.Contains(file, "..") {
+		return nil, os.ErrNotExist
+	}
+	return ioutil.ReadFile(filepath.Join(string(d), filepath.FromSlash(file)))
+}
+
+func (d dirSource) Open(ctx context.Context, file string) (io.ReadCloser, error) {
+	if strings.Contains(file, "..") {
+		return nil, os.ErrNotExist
+	}
+	return os.Open(filepath.Join(string(d), filepath.FromSlash(file)))
+}
+
+// Upstream returns a Source fetching files from the module proxy at baseURL,
+// such as "https://proxy.golang.org".
+func Upstream(baseURL string) Source {
+	return upstreamSource(strings.TrimSuffix(baseURL, "/"))
+}
+
+type upstreamSource string
+
+var upstreamClient = &http.Client{Timeout: 5 * time.Minute}
+
+func (u upstreamSource) Fetch(ctx context.Context, file string) ([]byte, error) {
+	body, err := u.Open(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return ioutil.ReadAll(body)
+}
+
+func (u upstreamSource) Open(ctx context.Context, file string) (io.ReadCloser, error) {
+	req, err := http.NewRequest("GET", string(u)+"/"+file, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := upstreamClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound, http.StatusGone:
+		// The module proxy protocol's "not found".
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %w", file, os.ErrNotExist)
+	}
+	resp.Body.Close()
+	return nil, fmt.Errorf("%s: %s", file, resp.Status)
+}
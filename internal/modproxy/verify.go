@@ -0,0 +1,237 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modproxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// A Verifier knows the checksums of modules, as listed in go.sum files.
+type Verifier interface {
+	// Sum returns the go.sum hash, like "h1:...", of the module path
+	// at version, or of its go.mod file if version ends in "/go.mod".
+	// If the module is not known, the error satisfies errors.Is(err, ErrNotListed).
+	Sum(ctx context.Context, path, version string) (string, error)
+}
+
+// ErrNotListed is the error reported by a Verifier for unknown modules.
+var ErrNotListed = errors.New("module not listed in checksums")
+
+// verifyMod checks that data, the go.mod file of path at version,
+// has the checksum given by v.
+func verifyMod(ctx context.Context, v Verifier, path, version string, data []byte) error {
+	got, err := dirhash.Hash1([]string{"go.mod"}, func(string) (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	})
+	if err != nil {
+		return err
+	}
+	return checkSum(ctx, v, path, version+"/go.mod", got)
+}
+
+// verifyZip checks that the named file, the zip of path at version,
+// has the checksum given by v.
+func verifyZip(ctx context.Context, v Verifier, path, version, name string) error {
+	got, err := dirhash.HashZip(name, dirhash.Hash1)
+	if err != nil {
+		return err
+	}
+	return checkSum(ctx, v, path, version, got)
+}
+
+// checkSum checks that v gives the checksum got
+// for path at version, as written in go.sum files.
+func checkSum(ctx context.Context, v Verifier, path, version, got string) error {
+	want, err := v.Sum(ctx, path, version)
+	if err != nil {
+		return err
+	}
+	if got != want {
+		return fmt.Errorf("%s %s: checksum is %s, want %s", path, version, got, want)
+	}
+	return nil
+}
+
+// SumFile returns a Verifier using the checksums listed in the named
+// file, which has the format of a go.sum file.
+// Only the modules it lists can be served.
+func SumFile(file string) (Verifier, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	sums := make(sumFile)
+	for i, line := range strings.Split(string(data), "\n") {
+		f := strings.Fields(line)
+		if len(f) == 0 {
+			continue
+		}
+		if len(f) != 3 {
+			return nil, fmt.Errorf("%s:%d: malformed line", file, i+1)
+		}
+		sums[f[0]+" "+f[1]] = f[2]
+	}
+	return sums, nil
+}
+
+// A sumFile maps "path version" to hash.
+type sumFile map[string]string
+
+func (s sumFile) Sum(ctx context.Context, path, version string) (string, error) {
+	sum, ok := s[path+" "+version]
+	if !ok {
+		return "", fmt.Errorf("%s %s: %w", path, version, ErrNotListed)
+	}
+	return sum, nil
+}
+
+// SumGolangOrgKey is the verifier key of sum.golang.org,
+// the checksum database used by the go command.
+const SumGolangOrgKey = "sum.golang.org+033de0ae+Ac4zctda0e5eza+HJyk9SxEdh+s3Ctvd4u4tZyMthYsl"
+
+// SumDB returns a Verifier using the checksum database with the given
+// verifier key, served at baseURL, such as "https://sum.golang.org"
+// or a proxy's "https://proxy.golang.org/sumdb/sum.golang.org".
+// Like the go command, it checks the database's signed tree,
+// and it caches what it reads in memory.
+func SumDB(key, baseURL string) Verifier {
+	ops := &sumOps{
+		key:      key,
+		url:      strings.TrimSuffix(baseURL, "/"),
+		config:   make(map[string][]byte),
+		cache:    make(map[string][]byte),
+		notFound: make(map[string]bool),
+	}
+	return &sumDB{sumdb.NewClient(ops), ops}
+}
+
+type sumDB struct {
+	client *sumdb.Client
+	ops    *sumOps
+}
+
+func (s *sumDB) Sum(ctx context.Context, path, version string) (string, error) {
+	lines, err := s.client.Lookup(path, version)
+	if err != nil {
+		// The client reports errors as text, so ask sumOps
+		// whether the database said it has no such module.
+		if s.ops.lookupNotFound(path, version) {
+			return "", fmt.Errorf("%s %s: %w", path, version, ErrNotListed)
+		}
+		return "", err
+	}
+	prefix := path + " " + version + " "
+	for _, line := range lines {
+		if strings.HasPrefix(line, prefix) {
+			return strings.TrimPrefix(line, prefix), nil
+		}
+	}
+	return "", fmt.Errorf("%s %s: %w", path, version, ErrNotListed)
+}
+
+// sumOps implements sumdb.ClientOps, keeping the configuration
+// and cache in memory.
+type sumOps struct {
+	key string
+	url string
+
+	mu       sync.Mutex
+	config   map[string][]byte
+	cache    map[string][]byte
+	notFound map[string]bool // remote paths answered with 404 or 410
+}
+
+var sumClient = &http.Client{Timeout: time.Minute}
+
+func (o *sumOps) ReadRemote(path string) ([]byte, error) {
+	resp, err := sumClient.Get(o.url + path)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		o.mu.Lock()
+		o.notFound[path] = true
+		o.mu.Unlock()
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s%s: %s", o.url, path, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// lookupNotFound reports whether the database answered a lookup
+// of path at version, which may end in "/go.mod", with not found.
+func (o *sumOps) lookupNotFound(path, version string) bool {
+	epath, err := module.EscapePath(path)
+	if err != nil {
+		return false
+	}
+	evers, err := module.EscapeVersion(strings.TrimSuffix(version, "/go.mod"))
+	if err != nil {
+		return false
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.notFound["/lookup/"+epath+"@"+evers]
+}
+
+func (o *sumOps) ReadConfig(file string) ([]byte, error) {
+	if file == "key" {
+		return []byte(o.key), nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.config[file], nil
+}
+
+func (o *sumOps) WriteConfig(file string, old, new []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if !bytes.Equal(o.config[file], old) {
+		return sumdb.ErrWriteConflict
+	}
+	o.config[file] = new
+	return nil
+}
+
+func (o *sumOps) ReadCache(file string) ([]byte, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	data, ok := o.cache[file]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return data, nil
+}
+
+func (o *sumOps) WriteCache(file string, data []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.cache[file] = data
+}
+
+func (o *sumOps) Log(msg string) {
+	log.Print("modproxy: ", msg)
+}
+
+func (o *sumOps) SecurityError(msg string) {
+	log.Print("ERROR modproxy: checksum database: ", msg)
+}
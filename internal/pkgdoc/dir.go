@@ -259,7 +259,7 @@ func parseFile(fsys fs.FS, fset *token.FileSet, filename string, mode parser.Mod
 	return parser.ParseFile(fset, filename, src, mode)
 }
 
-func parseFiles(fsys fs.FS, fset *token.FileSet, relpath string, abspath string, localnames []string) (map[string]*ast.File, error) {
+func parseFiles(fsys fs.FS, fset *token.FileSet, abspath string, localnames []string) (map[string]*ast.File, error) {
 	files := make(map[string]*ast.File)
 	for _, f := range localnames {
 		absname := path.Join(abspath, f)
@@ -267,7 +267,7 @@ func parseFiles(fsys fs.FS, fset *token.FileSet, relpath string, abspath string,
 		if err != nil {
 			return nil, err
 		}
-		files[path.Join(relpath, f)] = file
+		files[absname] = file
 	}
 
 	return files, nil
@@ -22,25 +22,69 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"unicode"
 	"unicode/utf8"
+
+	"golang.org/x/mod/modfile"
 )
 
 type Docs struct {
-	fs   fs.FS
-	root *Dir
+	fs     fs.FS
+	root   *Dir
+	top    string // absolute path of the package tree, like "/src"
+	module string // module path of the tree, if it is not Go's
+
+	typesMu    sync.Mutex
+	typesCache map[Platform]*typesCache // imported packages, for ModeTypes
+
+	portsOnce sync.Once
+	ports     []Platform        // see Ports
+	portCgo   map[Platform]bool // port -> whether it supports cgo
+
+	platformsMu sync.Mutex
+	platforms   map[string]*PlatformSet // by absolute path
+
+	importsMu sync.Mutex
+	imports   map[Platform]*cachedGraph
 }
 
 func NewDocs(fsys fs.FS) *Docs {
-	src := newDir(fsys, token.NewFileSet(), "/src")
-	root := &Dir{
-		Path: "/",
-		Dirs: []*Dir{src},
+	return NewDocsAt(fsys, "/src")
+}
+
+// NewDocsAt returns the documentation for the package tree
+// rooted at the absolute path root in fsys, such as "/src"
+// or "/mod/golang.org/x/net@v0.1.0". The import path of a package
+// is its path relative to root, as passed to Doc.
+func NewDocsAt(fsys fs.FS, root string) *Docs {
+	var dirs []*Dir
+	if tree := newDir(fsys, token.NewFileSet(), root); tree != nil {
+		dirs = []*Dir{tree}
+	}
+	// Lookup walks down from "/" one element at a time,
+	// so add a directory for each of root's parents.
+	for dir := root; dir != "/"; {
+		dir = path.Dir(dir)
+		dirs = []*Dir{{Path: dir, Dirs: dirs}}
 	}
-	return &Docs{
+	d := &Docs{
 		fs:   fsys,
-		root: root,
+		root: dirs[0],
+		top:  root,
+	}
+	if data, err := fs.ReadFile(fsys, toFS(path.Join(root, "go.mod"))); err == nil {
+		if m := modfile.ModulePath(data); m != "std" {
+			d.module = m
+		}
 	}
+	return d
+}
+
+// Lookup returns the directory tree for the absolute path abspath,
+// such as "/src" or "/src/net/http", or nil if there is none.
+func (d *Docs) Lookup(abspath string) *Dir {
+	return d.root.Lookup(abspath)
 }
 
 type Page struct {
@@ -62,6 +106,19 @@ type Page struct {
 	// directory info
 	Dirs    *DirList // nil if no directory information
 	DirFlat bool     // if set, show directory in a flat (non-indented) manner
+
+	// type information, only computed in ModeTypes
+	Uses     map[*ast.Ident]Ref   // what the identifiers in the package refer to
+	TypeInfo map[string]*TypeInfo // exported type name -> information about it
+
+	// platform info
+	Platform  Platform     // GOOS and GOARCH documented
+	Platforms *PlatformSet // where the package's identifiers exist; nil unless set from Docs.Platforms
+
+	// version info
+	Module   string   // module path, if documenting a module instead of Go
+	Version  string   // Go or module version documented
+	Versions []string // Go or module versions available, newest first; nil if only one
 }
 
 func (info *Page) IsEmpty() bool {
@@ -76,6 +133,13 @@ const (
 	ModeMethods                  // show all embedded methods
 	ModeSrc                      // show source code, do not extract documentation
 	ModeBuiltin                  // don't associate consts, vars, and factory functions with types (not exposed via ?m= query parameter, used for package builtin, see issue 6645)
+	ModeJSON                     // serve the documentation as JSON (see JSONPage)
+	ModeTypes                    // type-check the package, to link identifiers and list method sets and interfaces
+	ModePlatforms                // show the platforms on which each exported identifier exists
+	ModeImports                  // show the packages the package imports, directly or not (see ImportList)
+	ModeImportedBy               // show the packages importing the package, directly or not
+	ModeDOT                      // serve the import graph of ModeImports or ModeImportedBy in the DOT language
+	ModeSVG                      // serve the import graph of ModeImports or ModeImportedBy as an SVG image
 )
 
 // modeNames defines names for each PageInfoMode flag.
@@ -85,6 +149,14 @@ var modeNames = []string{
 	"flat",
 	"methods",
 	"src",
+	"", // ModeBuiltin
+	"json",
+	"types",
+	"platforms",
+	"imports",
+	"importedby",
+	"dot",
+	"svg",
 }
 
 // generate a query string for persisting PageInfoMode between pages.
@@ -108,7 +180,7 @@ func ParseMode(text string) Mode {
 	for _, k := range strings.Split(text, ",") {
 		k = strings.TrimSpace(k)
 		for i, name := range modeNames {
-			if name == k {
+			if name == k && name != "" {
 				mode |= 1 << i
 			}
 		}
@@ -132,32 +204,7 @@ func Doc(d *Docs, abspath, relpath string, mode Mode, goos, goarch string) *Page
 	// jumble them all together.
 	// Note: If goos/goarch aren't set, the current binary's GOOS/GOARCH
 	// are used.
-	ctxt := build.Default
-	ctxt.IsAbsPath = path.IsAbs
-	ctxt.IsDir = func(path string) bool {
-		fi, err := fs.Stat(d.fs, toFS(filepath.ToSlash(path)))
-		return err == nil && fi.IsDir()
-	}
-	ctxt.ReadDir = func(dir string) ([]os.FileInfo, error) {
-		f, err := fs.ReadDir(d.fs, toFS(filepath.ToSlash(dir)))
-		filtered := make([]os.FileInfo, 0, len(f))
-		for _, i := range f {
-			if mode&ModeAll != 0 || i.Name() != "internal" {
-				info, err := i.Info()
-				if err == nil {
-					filtered = append(filtered, info)
-				}
-			}
-		}
-		return filtered, err
-	}
-	ctxt.OpenFile = func(name string) (r io.ReadCloser, err error) {
-		data, err := fs.ReadFile(d.fs, toFS(filepath.ToSlash(name)))
-		if err != nil {
-			return nil, err
-		}
-		return ioutil.NopCloser(bytes.NewReader(data)), nil
-	}
+	ctxt := d.buildContext(mode)
 
 	// Make the syscall/js package always visible by default.
 	// It defaults to the host's GOOS/GOARCH, and golang.org's
@@ -173,6 +220,7 @@ func Doc(d *Docs, abspath, relpath string, mode Mode, goos, goarch string) *Page
 	if goarch != "" {
 		ctxt.GOARCH = goarch
 	}
+	info.Platform = Platform{GOOS: ctxt.GOOS, GOARCH: ctxt.GOARCH}
 
 	pkginfo, err := ctxt.ImportDir(abspath, 0)
 	// continue if there are no Go source files; we still want the directory info
@@ -198,7 +246,7 @@ func Doc(d *Docs, abspath, relpath string, mode Mode, goos, goarch string) *Page
 	if len(pkgfiles) > 0 {
 		// build package AST
 		fset := token.NewFileSet()
-		files, err := parseFiles(d.fs, fset, relpath, abspath, pkgfiles)
+		files, err := parseFiles(d.fs, fset, abspath, pkgfiles)
 		if err != nil {
 			info.Err = err
 			return info
@@ -207,6 +255,11 @@ func Doc(d *Docs, abspath, relpath string, mode Mode, goos, goarch string) *Page
 		// ignore any errors - they are due to unresolved identifiers
 		pkg, _ := ast.NewPackage(fset, files, simpleImporter, nil)
 
+		// Type-check before the exports are filtered below.
+		if mode&ModeTypes != 0 && mode&ModeBuiltin == 0 {
+			d.typeCheck(info, &ctxt, fset, strings.TrimPrefix(path.Clean(relpath), "/"), files)
+		}
+
 		// extract package documentation
 		info.FSet = fset
 		if mode&ModeSrc == 0 {
@@ -235,7 +288,7 @@ func Doc(d *Docs, abspath, relpath string, mode Mode, goos, goarch string) *Page
 
 			// collect examples
 			testfiles := append(pkginfo.TestGoFiles, pkginfo.XTestGoFiles...)
-			files, err = parseFiles(d.fs, fset, relpath, abspath, testfiles)
+			files, err = parseFiles(d.fs, fset, abspath, testfiles)
 			if err != nil {
 				log.Println("parsing examples:", err)
 			}
@@ -259,6 +312,38 @@ func Doc(d *Docs, abspath, relpath string, mode Mode, goos, goarch string) *Page
 	return info
 }
 
+// buildContext returns a build context reading the files in d.
+// Unless mode has ModeAll, it omits internal directories.
+func (d *Docs) buildContext(mode Mode) build.Context {
+	ctxt := build.Default
+	ctxt.IsAbsPath = path.IsAbs
+	ctxt.IsDir = func(path string) bool {
+		fi, err := fs.Stat(d.fs, toFS(filepath.ToSlash(path)))
+		return err == nil && fi.IsDir()
+	}
+	ctxt.ReadDir = func(dir string) ([]os.FileInfo, error) {
+		f, err := fs.ReadDir(d.fs, toFS(filepath.ToSlash(dir)))
+		filtered := make([]os.FileInfo, 0, len(f))
+		for _, i := range f {
+			if mode&ModeAll != 0 || i.Name() != "internal" {
+				info, err := i.Info()
+				if err == nil {
+					filtered = append(filtered, info)
+				}
+			}
+		}
+		return filtered, err
+	}
+	ctxt.OpenFile = func(name string) (r io.ReadCloser, err error) {
+		data, err := fs.ReadFile(d.fs, toFS(filepath.ToSlash(name)))
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+	return ctxt
+}
+
 func (d *Docs) includePath(path string, mode Mode) (r bool) {
 	// if the path includes 'internal', don't list unless we are in the NoFiltering mode.
 	if mode&ModeAll != 0 {
@@ -350,14 +435,7 @@ func addNames(names map[string]bool, decl ast.Decl) {
 	case *ast.FuncDecl:
 		name := d.Name.Name
 		if d.Recv != nil {
-			var typeName string
-			switch r := d.Recv.List[0].Type.(type) {
-			case *ast.StarExpr:
-				typeName = r.X.(*ast.Ident).Name
-			case *ast.Ident:
-				typeName = r.Name
-			}
-			name = typeName + "_" + name
+			name = recvTypeName(d.Recv.List[0].Type) + "_" + name
 		}
 		names[name] = true
 	case *ast.GenDecl:
@@ -374,6 +452,25 @@ func addNames(names map[string]bool, decl ast.Decl) {
 	}
 }
 
+// recvTypeName returns the name of the base type of the receiver type x,
+// or the empty string if it cannot be determined.
+func recvTypeName(x ast.Expr) string {
+	switch x := x.(type) {
+	case *ast.StarExpr:
+		return recvTypeName(x.X)
+	case *ast.IndexExpr:
+		// Generic receiver type T[P].
+		return recvTypeName(x.X)
+	case *ast.Ident:
+		return x.Name
+	}
+	if base, ok := indexListBase(x); ok {
+		// Generic receiver type T[P, Q].
+		return recvTypeName(base)
+	}
+	return ""
+}
+
 func SplitExampleName(s string) (name, suffix string) {
 	i := strings.LastIndex(s, "_")
 	if 0 <= i && i < len(s)-1 && !startsWithUppercase(s[i+1:]) {
@@ -0,0 +1,404 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package pkgdoc
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// An ImportGraph records the imports among the packages in a
+// documentation tree, as built for one of its ports.
+type ImportGraph struct {
+	pkgs map[string]*importNode // by import path
+}
+
+type importNode struct {
+	path       string
+	dir        string   // absolute path of the package's directory; "" if outside the tree
+	cgo        bool     // the package has cgo files
+	imports    []string // import paths of the packages it imports, sorted
+	importedBy []string // import paths of the packages importing it, sorted
+}
+
+// A cachedGraph is an ImportGraph built on first use.
+type cachedGraph struct {
+	once sync.Once
+	g    *ImportGraph
+}
+
+// ImportGraph returns the graph of the imports among the packages in d
+// when built for p, or nil if p is not one of the Ports of d.
+// It reads every package in d the first time it is called for p.
+func (d *Docs) ImportGraph(p Platform) *ImportGraph {
+	if !d.IsPort(p) {
+		return nil
+	}
+	d.importsMu.Lock()
+	c := d.imports[p]
+	if c == nil {
+		if d.imports == nil {
+			d.imports = make(map[Platform]*cachedGraph)
+		}
+		c = new(cachedGraph)
+		d.imports[p] = c
+	}
+	d.importsMu.Unlock()
+
+	c.once.Do(func() { c.g = d.importGraph(p) })
+	return c.g
+}
+
+// importGraph reads the imports of every package in d, built for p
+// with cgo enabled if p supports it.
+func (d *Docs) importGraph(p Platform) *ImportGraph {
+	g := &ImportGraph{pkgs: make(map[string]*importNode)}
+	node := func(importPath, dir string) *importNode {
+		n := g.pkgs[importPath]
+		if n == nil {
+			n = &importNode{path: importPath}
+			g.pkgs[importPath] = n
+		}
+		if dir != "" {
+			n.dir = dir
+		}
+		return n
+	}
+
+	top := d.Lookup(d.top)
+	if top == nil {
+		return g
+	}
+	ctxt := d.buildContext(ModeAll)
+	ctxt.GOOS, ctxt.GOARCH = p.GOOS, p.GOARCH
+	ctxt.CgoEnabled = d.cgoEnabled(p)
+	top.walk(func(dir *Dir, depth int) {
+		if !dir.HasPkg {
+			return
+		}
+		pkginfo, err := ctxt.ImportDir(dir.Path, 0)
+		if err != nil {
+			return
+		}
+		n := node(d.dirImportPath(dir.Path), dir.Path)
+		n.cgo = len(pkginfo.CgoFiles) > 0
+		for _, imp := range pkginfo.Imports {
+			if imp == "C" {
+				continue
+			}
+			abspath, pkgPath := d.findPackage(imp, dir.Path)
+			if abspath == "" {
+				pkgPath = imp
+			}
+			m := node(pkgPath, abspath)
+			n.imports = append(n.imports, m.path)
+			m.importedBy = append(m.importedBy, n.path)
+		}
+	})
+	for _, n := range g.pkgs {
+		sort.Strings(n.imports)
+		sort.Strings(n.importedBy)
+	}
+	return g
+}
+
+// dirImportPath returns the import path of the package in the directory
+// abspath in d, matching the package paths returned by findPackage.
+func (d *Docs) dirImportPath(abspath string) string {
+	rel := strings.TrimPrefix(strings.TrimPrefix(abspath, d.top), "/")
+	if d.module == "" {
+		return rel
+	}
+	if i := strings.LastIndex("/"+rel, "/vendor/"); i >= 0 {
+		return ("/" + rel)[i+len("/vendor/"):]
+	}
+	return path.Join(d.module, rel)
+}
+
+// An ImportList lists the packages that a package imports,
+// or that import it, directly or indirectly.
+type ImportList struct {
+	Path     string             `json:"path"`              // import path of the package
+	Reverse  bool               `json:"reverse,omitempty"` // list the packages importing Path, not those it imports
+	Cgo      bool               `json:"cgo,omitempty"`     // the package has cgo files
+	Packages []*ImportedPackage `json:"packages"`          // sorted by Path
+
+	g *ImportGraph
+}
+
+// An ImportedPackage is a package in an ImportList.
+type ImportedPackage struct {
+	Path     string   `json:"path"`
+	Dir      string   `json:"-"`                  // absolute path of the package's directory; "" if outside the tree
+	Direct   bool     `json:"direct,omitempty"`   // it imports or is imported by the listed package directly
+	Cgo      bool     `json:"cgo,omitempty"`      // it has cgo files
+	External bool     `json:"external,omitempty"` // it is outside the tree, so its own imports are unknown
+	Via      []string `json:"via,omitempty"`      // the packages on a shortest chain of imports between it and the listed package, in import order
+}
+
+// Imports returns the packages imported by the package importPath,
+// directly or indirectly.
+func (g *ImportGraph) Imports(importPath string) *ImportList {
+	return g.list(importPath, false)
+}
+
+// ImportedBy returns the packages in the tree importing
+// the package importPath, directly or indirectly.
+func (g *ImportGraph) ImportedBy(importPath string) *ImportList {
+	return g.list(importPath, true)
+}
+
+func (g *ImportGraph) list(importPath string, reverse bool) *ImportList {
+	l := &ImportList{Path: importPath, Reverse: reverse, g: g}
+	root := g.pkgs[importPath]
+	if root == nil {
+		return l
+	}
+	l.Cgo = root.cgo
+
+	// Search breadth-first, to find the shortest chains.
+	parent := map[string]string{importPath: ""}
+	queue := []string{importPath}
+	for len(queue) > 0 {
+		n := g.pkgs[queue[0]]
+		queue = queue[1:]
+		next := n.imports
+		if reverse {
+			next = n.importedBy
+		}
+		for _, p := range next {
+			if _, ok := parent[p]; !ok {
+				parent[p] = n.path
+				queue = append(queue, p)
+			}
+		}
+	}
+	for p := range parent {
+		if p == importPath {
+			continue
+		}
+		n := g.pkgs[p]
+		pkg := &ImportedPackage{
+			Path:     p,
+			Dir:      n.dir,
+			Cgo:      n.cgo,
+			External: n.dir == "",
+		}
+		for q := parent[p]; q != importPath; q = parent[q] {
+			if reverse {
+				pkg.Via = append(pkg.Via, q)
+			} else {
+				pkg.Via = append([]string{q}, pkg.Via...)
+			}
+		}
+		pkg.Direct = pkg.Via == nil
+		l.Packages = append(l.Packages, pkg)
+	}
+	sort.Slice(l.Packages, func(i, j int) bool {
+		return l.Packages[i].Path < l.Packages[j].Path
+	})
+	return l
+}
+
+// graph returns the packages in l, including the listed package first,
+// and, for each, the packages in l it imports.
+func (l *ImportList) graph() (nodes []*ImportedPackage, edges map[string][]string) {
+	root := &ImportedPackage{Path: l.Path, Cgo: l.Cgo}
+	if n := l.g.pkgs[l.Path]; n != nil {
+		root.Dir = n.dir
+	}
+	nodes = append([]*ImportedPackage{root}, l.Packages...)
+	in := make(map[string]bool)
+	for _, n := range nodes {
+		in[n.Path] = true
+	}
+	edges = make(map[string][]string)
+	for _, n := range nodes {
+		if gn := l.g.pkgs[n.Path]; gn != nil {
+			for _, p := range gn.imports {
+				if in[p] {
+					edges[n.Path] = append(edges[n.Path], p)
+				}
+			}
+		}
+	}
+	return nodes, edges
+}
+
+// DOT returns the graph of the imports among the packages in l,
+// including the listed package, in the Graphviz DOT language.
+// Packages with cgo files are filled, and packages outside
+// the tree are dashed.
+func (l *ImportList) DOT() []byte {
+	nodes, edges := l.graph()
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "digraph %q {\n", l.Path)
+	fmt.Fprintf(&buf, "\tnode [shape=box];\n")
+	for _, n := range nodes {
+		var attrs []string
+		if n.Path == l.Path {
+			attrs = append(attrs, "penwidth=2")
+		}
+		if n.Cgo {
+			attrs = append(attrs, "style=filled", `fillcolor="#ffeef0"`)
+		}
+		if n.Dir == "" {
+			attrs = append(attrs, "style=dashed")
+		}
+		if attrs != nil {
+			fmt.Fprintf(&buf, "\t%q [%s];\n", n.Path, strings.Join(attrs, ", "))
+		}
+	}
+	for _, n := range nodes {
+		for _, p := range edges[n.Path] {
+			fmt.Fprintf(&buf, "\t%q -> %q;\n", n.Path, p)
+		}
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
+
+// Dimensions of the SVG drawing, in pixels.
+const (
+	svgCharWidth = 7  // width of a character of a package path
+	svgPadding   = 8  // horizontal space around a path in its box
+	svgBoxHeight = 24 // height of a package's box
+	svgGap       = 16 // horizontal space between boxes
+	svgLayerGap  = 64 // vertical space between layers of boxes
+	svgMargin    = 8  // space around the drawing
+)
+
+// SVG returns a drawing of the graph of the imports among the packages
+// in l, including the listed package, as an SVG image. Each package is
+// drawn below the packages importing it, and links to link(dir), where dir
+// is its directory, unless it is outside the tree.
+func (l *ImportList) SVG(link func(dir string) string) []byte {
+	nodes, edges := l.graph()
+
+	// Assign each package to the layer below
+	// the lowest of the packages importing it.
+	indegree := make(map[string]int)
+	for _, list := range edges {
+		for _, p := range list {
+			indegree[p]++
+		}
+	}
+	layer := make(map[string]int)
+	var queue []string
+	for _, n := range nodes {
+		if indegree[n.Path] == 0 {
+			queue = append(queue, n.Path)
+		}
+	}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, q := range edges[p] {
+			if layer[q] < layer[p]+1 {
+				layer[q] = layer[p] + 1
+			}
+			if indegree[q]--; indegree[q] == 0 {
+				queue = append(queue, q)
+			}
+		}
+	}
+	var layers [][]*ImportedPackage
+	for _, n := range nodes {
+		i := layer[n.Path]
+		for len(layers) <= i {
+			layers = append(layers, nil)
+		}
+		layers[i] = append(layers[i], n)
+	}
+
+	// Lay out each layer in turn, ordering its packages by
+	// the mean position of the packages importing them,
+	// to keep edges short.
+	type box struct{ x, y, w int }
+	boxes := make(map[string]box)
+	importers := make(map[string][]string)
+	for p, list := range edges {
+		for _, q := range list {
+			importers[q] = append(importers[q], p)
+		}
+	}
+	width := 0
+	for i, list := range layers {
+		center := make(map[string]float64)
+		for _, n := range list {
+			sum, count := 0.0, 0
+			for _, p := range importers[n.Path] {
+				if b, ok := boxes[p]; ok {
+					sum += float64(b.x) + float64(b.w)/2
+					count++
+				}
+			}
+			if count > 0 {
+				center[n.Path] = sum / float64(count)
+			}
+		}
+		sort.SliceStable(list, func(i, j int) bool {
+			return center[list[i].Path] < center[list[j].Path]
+		})
+		x := svgMargin
+		for _, n := range list {
+			w := len(n.Path)*svgCharWidth + 2*svgPadding
+			boxes[n.Path] = box{x, svgMargin + i*(svgBoxHeight+svgLayerGap), w}
+			x += w + svgGap
+		}
+		if x-svgGap+svgMargin > width {
+			width = x - svgGap + svgMargin
+		}
+	}
+	height := 2*svgMargin + len(layers)*(svgBoxHeight+svgLayerGap) - svgLayerGap
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="%d" height="%d" viewBox="0 0 %d %d" font-family="monospace" font-size="12">`+"\n", width, height, width, height)
+	buf.WriteString(`<defs><marker id="arrow" viewBox="0 0 8 8" refX="8" refY="4" markerWidth="8" markerHeight="8" orient="auto"><path d="M0,0 L8,4 L0,8 z" fill="#888"/></marker></defs>` + "\n")
+	for _, n := range nodes {
+		from := boxes[n.Path]
+		for _, p := range edges[n.Path] {
+			to := boxes[p]
+			x1, y1 := from.x+from.w/2, from.y+svgBoxHeight
+			x2, y2 := to.x+to.w/2, to.y
+			fmt.Fprintf(&buf, `<path d="M%d,%d C%d,%d %d,%d %d,%d" fill="none" stroke="#888" marker-end="url(#arrow)"/>`+"\n",
+				x1, y1, x1, y1+svgLayerGap/2, x2, y2-svgLayerGap/2, x2, y2)
+		}
+	}
+	for _, n := range nodes {
+		b := boxes[n.Path]
+		fill, stroke, dash, sw := "#fff", "#375eab", "", 1
+		if n.Cgo {
+			fill = "#ffeef0"
+		}
+		if n.Dir == "" {
+			stroke, dash = "#888", ` stroke-dasharray="4,2"`
+		}
+		if n.Path == l.Path {
+			sw = 2
+		}
+		if n.Dir != "" {
+			fmt.Fprintf(&buf, `<a xlink:href="%s">`, html.EscapeString(link(n.Dir)))
+		}
+		fmt.Fprintf(&buf, `<rect x="%d" y="%d" width="%d" height="%d" rx="3" fill="%s" stroke="%s" stroke-width="%d"%s/>`,
+			b.x, b.y, b.w, svgBoxHeight, fill, stroke, sw, dash)
+		fmt.Fprintf(&buf, `<text x="%d" y="%d" text-anchor="middle">%s</text>`,
+			b.x+b.w/2, b.y+svgBoxHeight/2+4, html.EscapeString(n.Path))
+		if n.Dir != "" {
+			buf.WriteString("</a>")
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString("</svg>\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,95 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package pkgdoc
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestImportGraph(t *testing.T) {
+	fs := fstest.MapFS{
+		"src/a/a.go":                    {Data: []byte("package a\n\nimport (\n\t\"b\"\n\t\"example.com/x\"\n)\n")},
+		"src/a/a_windows.go":            {Data: []byte("package a\n\nimport \"w\"\n")},
+		"src/b/b.go":                    {Data: []byte("package b\n\nimport \"c\"\n")},
+		"src/c/c.go":                    {Data: []byte("package c\n\n// #include <stdio.h>\nimport \"C\"\n")},
+		"src/d/d.go":                    {Data: []byte("package d\n\nimport \"a\"\n")},
+		"src/w/w.go":                    {Data: []byte("package w\n")},
+		"src/vendor/example.com/x/x.go": {Data: []byte("package x\n\nimport \"c\"\n")},
+	}
+	d := NewDocs(fs)
+	g := d.ImportGraph(Platform{"linux", "amd64"})
+	if g2 := d.ImportGraph(Platform{"linux", "amd64"}); g2 != g {
+		t.Errorf("ImportGraph not cached")
+	}
+	if g := d.ImportGraph(Platform{"linux", "bogus"}); g != nil {
+		t.Errorf("ImportGraph(linux/bogus) = %v, want nil", g)
+	}
+
+	l := g.Imports("a")
+	want := []*ImportedPackage{
+		{Path: "b", Dir: "/src/b", Direct: true},
+		{Path: "c", Dir: "/src/c", Cgo: true, Via: []string{"b"}},
+		{Path: "vendor/example.com/x", Dir: "/src/vendor/example.com/x", Direct: true},
+	}
+	if !reflect.DeepEqual(l.Packages, want) {
+		t.Errorf("Imports(a) = %v, want %v", l.Packages, want)
+	}
+
+	l = g.ImportedBy("c")
+	want = []*ImportedPackage{
+		{Path: "a", Dir: "/src/a", Via: []string{"b"}},
+		{Path: "b", Dir: "/src/b", Direct: true},
+		{Path: "d", Dir: "/src/d", Via: []string{"a", "b"}},
+		{Path: "vendor/example.com/x", Dir: "/src/vendor/example.com/x", Direct: true},
+	}
+	if !l.Cgo || !l.Reverse || !reflect.DeepEqual(l.Packages, want) {
+		t.Errorf("ImportedBy(c) = %+v, want %v", l, want)
+	}
+
+	if l := d.ImportGraph(Platform{"windows", "amd64"}).Imports("a"); len(l.Packages) != 4 || l.Packages[3].Path != "w" {
+		t.Errorf("Imports(a) on windows = %v, want b, c, x, w", l.Packages)
+	}
+
+	dot := string(g.Imports("b").DOT())
+	for _, s := range []string{`digraph "b" {`, `"b" -> "c";`, `"c" [style=filled, fillcolor="#ffeef0"];`} {
+		if !strings.Contains(dot, s) {
+			t.Errorf("DOT() = %s, want %s", dot, s)
+		}
+	}
+
+	svg := g.ImportedBy("b").SVG(func(dir string) string { return dir + "/" })
+	if err := xml.Unmarshal(svg, new(struct{})); err != nil {
+		t.Errorf("SVG() is not XML: %v\n%s", err, svg)
+	}
+	for _, s := range []string{`<a xlink:href="/src/d/">`, `>a</text>`, `>b</text>`} {
+		if !strings.Contains(string(svg), s) {
+			t.Errorf("SVG() = %s, want %s", svg, s)
+		}
+	}
+}
+
+func TestModuleImportGraph(t *testing.T) {
+	fs := fstest.MapFS{
+		"mod/example.com/m@v1.0.0/go.mod":   {Data: []byte("module example.com/m\n")},
+		"mod/example.com/m@v1.0.0/m.go":     {Data: []byte("package m\n\nimport (\n\t\"example.com/m/sub\"\n\t\"fmt\"\n)\n")},
+		"mod/example.com/m@v1.0.0/sub/s.go": {Data: []byte("package sub\n")},
+	}
+	d := NewDocsAt(fs, "/mod/example.com/m@v1.0.0")
+	l := d.ImportGraph(Platform{"linux", "amd64"}).Imports("example.com/m")
+	want := []*ImportedPackage{
+		{Path: "example.com/m/sub", Dir: "/mod/example.com/m@v1.0.0/sub", Direct: true},
+		{Path: "fmt", Direct: true, External: true},
+	}
+	if !reflect.DeepEqual(l.Packages, want) {
+		t.Errorf("Imports(example.com/m) = %v, want %v", l.Packages, want)
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package pkgdoc
+
+import (
+	"bytes"
+	"go/ast"
+	"go/doc"
+	"go/format"
+	"go/printer"
+	"go/token"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// A JSONPage is the documentation of a directory,
+// as served by /pkg/path/?m=json.
+//
+// The field names and meanings are stable:
+// fields may be added, but not renamed or removed.
+type JSONPage struct {
+	Path     string       `json:"path"`               // import path, like "net/http" or "cmd/go"
+	Module   string       `json:"module,omitempty"`   // module path, for /mod/ pages
+	Version  string       `json:"version,omitempty"`  // Go or module version documented, if the site serves several
+	Versions []string     `json:"versions,omitempty"` // Go or module versions served, newest first, if several
+	Package  *JSONPackage `json:"package,omitempty"`  // nil if the directory has no package
+	Subdirs  []*JSONDir   `json:"subdirs,omitempty"`  // directories below, in tree order
+}
+
+// A JSONPackage is the documentation of a package.
+type JSONPackage struct {
+	Name       string         `json:"name"`
+	ImportPath string         `json:"importPath"`
+	IsCommand  bool           `json:"isCommand,omitempty"`
+	Synopsis   string         `json:"synopsis"`
+	Doc        string         `json:"doc"` // package comment, as plain text
+	Consts     []*JSONValue   `json:"consts,omitempty"`
+	Vars       []*JSONValue   `json:"vars,omitempty"`
+	Funcs      []*JSONFunc    `json:"funcs,omitempty"`
+	Types      []*JSONType    `json:"types,omitempty"`
+	Examples   []*JSONExample `json:"examples,omitempty"`
+	Bugs       []string       `json:"bugs,omitempty"`
+}
+
+// A JSONValue is a const or var declaration, possibly of several names.
+type JSONValue struct {
+	Names []string          `json:"names"`
+	Doc   string            `json:"doc"`
+	Decl  string            `json:"decl"`            // declaration source, formatted
+	Since map[string]string `json:"since,omitempty"` // name -> Go version that added it, if after Go 1
+}
+
+// A JSONFunc is a function or method.
+type JSONFunc struct {
+	Name  string `json:"name"`
+	Recv  string `json:"recv,omitempty"` // receiver type, like "*Buffer", for methods
+	Doc   string `json:"doc"`
+	Decl  string `json:"decl"`
+	Since string `json:"since,omitempty"` // Go version that added it, if after Go 1
+}
+
+// A JSONType is a type, along with the declarations associated with it.
+type JSONType struct {
+	Name        string            `json:"name"`
+	Doc         string            `json:"doc"`
+	Decl        string            `json:"decl"`
+	Since       string            `json:"since,omitempty"`
+	FieldsSince map[string]string `json:"fieldsSince,omitempty"` // struct field -> Go version that added it, if later than the type
+	Consts      []*JSONValue      `json:"consts,omitempty"`
+	Vars        []*JSONValue      `json:"vars,omitempty"`
+	Funcs       []*JSONFunc       `json:"funcs,omitempty"` // functions returning the type
+	Methods     []*JSONFunc       `json:"methods,omitempty"`
+}
+
+// A JSONExample is an example. Its name identifies what it is an example of:
+// "" for the package, "F" for function or type F, "T_M" for method T.M,
+// each optionally followed by a lower-case suffix, like "F_second".
+type JSONExample struct {
+	Name   string `json:"name"`
+	Doc    string `json:"doc"`
+	Code   string `json:"code"`
+	Output string `json:"output"`
+	Play   string `json:"play,omitempty"` // complete runnable program, if any
+}
+
+// A JSONDir is a directory below the page's directory.
+type JSONDir struct {
+	Path     string `json:"path"`  // import path
+	Depth    int    `json:"depth"` // depth below the page's directory: 1 for its children
+	HasPkg   bool   `json:"hasPkg"`
+	Synopsis string `json:"synopsis,omitempty"`
+}
+
+// JSON returns the documentation in info, for the directory with the
+// given import path, as a JSONPage.
+// The since function reports which Go version added a symbol
+// to the package, as api.DB.Func does; it may be nil.
+func JSON(info *Page, importPath string, since func(kind, receiver, name, pkg string) string) *JSONPage {
+	if since == nil {
+		since = func(kind, receiver, name, pkg string) string { return "" }
+	}
+	pg := &JSONPage{
+		Path:     importPath,
+		Module:   info.Module,
+		Version:  info.Version,
+		Versions: info.Versions,
+	}
+	if info.Dirs != nil {
+		for _, d := range info.Dirs.List {
+			pg.Subdirs = append(pg.Subdirs, &JSONDir{
+				Path:     path.Join(importPath, d.Path),
+				Depth:    d.Depth,
+				HasPkg:   d.HasPkg,
+				Synopsis: d.Synopsis,
+			})
+		}
+	}
+
+	pdoc := info.PDoc
+	if pdoc == nil {
+		return pg
+	}
+	j := &jsonWriter{fset: info.FSet, since: since, pkg: pdoc.ImportPath}
+	p := &JSONPackage{
+		Name:       pdoc.Name,
+		ImportPath: strings.TrimPrefix(pdoc.ImportPath, "/"), // "/cmd/go" for commands
+		IsCommand:  info.IsMain,
+		Synopsis:   doc.Synopsis(pdoc.Doc),
+		Doc:        pdoc.Doc,
+		Consts:     j.values("const", pdoc.Consts),
+		Vars:       j.values("var", pdoc.Vars),
+		Funcs:      j.funcs(pdoc.Funcs),
+	}
+	for _, t := range pdoc.Types {
+		p.Types = append(p.Types, j.typ(t))
+	}
+	for _, ex := range info.Examples {
+		p.Examples = append(p.Examples, j.example(ex))
+	}
+	for _, b := range info.Bugs {
+		p.Bugs = append(p.Bugs, b.Body)
+	}
+	pg.Package = p
+	return pg
+}
+
+type jsonWriter struct {
+	fset  *token.FileSet
+	since func(kind, receiver, name, pkg string) string
+	pkg   string
+}
+
+// node returns the formatted source text for node.
+func (j *jsonWriter) node(node interface{}) string {
+	var buf bytes.Buffer
+	cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+	cfg.Fprint(&buf, j.fset, node)
+	return buf.String()
+}
+
+func (j *jsonWriter) values(kind string, list []*doc.Value) []*JSONValue {
+	var out []*JSONValue
+	for _, v := range list {
+		decl := *v.Decl
+		decl.Doc = nil
+		jv := &JSONValue{Names: v.Names, Doc: v.Doc, Decl: j.node(&decl)}
+		for _, name := range v.Names {
+			if s := j.since(kind, "", name, j.pkg); s != "" {
+				if jv.Since == nil {
+					jv.Since = make(map[string]string)
+				}
+				jv.Since[name] = s
+			}
+		}
+		out = append(out, jv)
+	}
+	return out
+}
+
+func (j *jsonWriter) funcs(list []*doc.Func) []*JSONFunc {
+	var out []*JSONFunc
+	for _, fn := range list {
+		decl := *fn.Decl
+		decl.Doc = nil
+		decl.Body = nil
+		jf := &JSONFunc{Name: fn.Name, Recv: fn.Recv, Doc: fn.Doc, Decl: j.node(&decl)}
+		if fn.Recv != "" {
+			jf.Since = j.since("method", fn.Recv, fn.Name, j.pkg)
+		} else {
+			jf.Since = j.since("func", "", fn.Name, j.pkg)
+		}
+		out = append(out, jf)
+	}
+	return out
+}
+
+func (j *jsonWriter) typ(t *doc.Type) *JSONType {
+	decl := *t.Decl
+	decl.Doc = nil
+	jt := &JSONType{
+		Name:    t.Name,
+		Doc:     t.Doc,
+		Decl:    j.node(&decl),
+		Since:   j.since("type", "", t.Name, j.pkg),
+		Consts:  j.values("const", t.Consts),
+		Vars:    j.values("var", t.Vars),
+		Funcs:   j.funcs(t.Funcs),
+		Methods: j.funcs(t.Methods),
+	}
+	for _, spec := range t.Decl.Specs {
+		ts, ok := spec.(*ast.TypeSpec)
+		if !ok || ts.Name.Name != t.Name {
+			continue
+		}
+		st, ok := ts.Type.(*ast.StructType)
+		if !ok {
+			continue
+		}
+		for _, field := range st.Fields.List {
+			for _, name := range field.Names {
+				// Like the HTML page, note only fields added after the type.
+				if s := j.since("field", t.Name, name.Name, j.pkg); s != "" && s != jt.Since {
+					if jt.FieldsSince == nil {
+						jt.FieldsSince = make(map[string]string)
+					}
+					jt.FieldsSince[name.Name] = s
+				}
+			}
+		}
+	}
+	return jt
+}
+
+var exampleOutputRx = regexp.MustCompile(`(?i)//[[:space:]]*(unordered )?output:`)
+
+func (j *jsonWriter) example(ex *doc.Example) *JSONExample {
+	code := j.node(&printer.CommentedNode{Node: ex.Code, Comments: ex.Comments})
+	if n := len(code); n >= 2 && code[0] == '{' && code[n-1] == '}' {
+		// Show a function body without its braces and indentation.
+		lines := strings.Split(strings.TrimSpace(code[1:n-1]), "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimPrefix(line, "\t")
+		}
+		code = strings.Join(lines, "\n")
+		if loc := exampleOutputRx.FindStringIndex(code); loc != nil {
+			code = strings.TrimSpace(code[:loc[0]])
+		}
+	}
+	je := &JSONExample{Name: ex.Name, Doc: ex.Doc, Code: code, Output: ex.Output}
+	if ex.Play != nil {
+		var buf bytes.Buffer
+		if err := format.Node(&buf, j.fset, ex.Play); err == nil {
+			je.Play = buf.String()
+		}
+	}
+	return je
+}
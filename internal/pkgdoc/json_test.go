@@ -0,0 +1,124 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package pkgdoc
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestJSON(t *testing.T) {
+	d := NewDocs(fstest.MapFS{
+		"src/p/p.go": {Data: []byte(`// Package p is a test.
+package p
+
+// Max is the maximum.
+const Max = 10
+
+// A T is a thing.
+type T struct {
+	A int
+	B int // new
+}
+
+// NewT returns a T.
+func NewT() *T { return nil }
+
+// M is a method.
+func (t *T) M() {}
+
+// BUG(rsc): Everything is broken.
+
+func F(x int) {}
+`)},
+		"src/p/example_test.go": {Data: []byte(`package p_test
+
+import "fmt"
+
+func ExampleF() {
+	fmt.Println("hi")
+	// Output: hi
+}
+`)},
+		"src/p/sub/sub.go": {Data: []byte("// Package sub is below.\npackage sub\n")},
+	})
+	since := func(kind, receiver, name, pkg string) string {
+		if pkg != "p" {
+			t.Errorf("since(%q, %q, %q, %q): wrong package", kind, receiver, name, pkg)
+		}
+		return map[string]string{
+			"type  T":     "1.2",
+			"field T B":   "1.3",
+			"field T A":   "1.2",
+			"method *T M": "1.4",
+		}[kind+" "+receiver+" "+name]
+	}
+	info := Doc(d, "/src/p", "p", 0, "", "")
+	if info.Err != nil {
+		t.Fatal(info.Err)
+	}
+	js, err := json.Marshal(JSON(info, "p", since))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(js, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"path": "p",
+		"package": map[string]interface{}{
+			"name":       "p",
+			"importPath": "p",
+			"synopsis":   "Package p is a test.",
+			"doc":        "Package p is a test.\n",
+			"consts": []interface{}{
+				map[string]interface{}{"names": []interface{}{"Max"}, "doc": "Max is the maximum.\n", "decl": "const Max = 10"},
+			},
+			"funcs": []interface{}{
+				map[string]interface{}{"name": "F", "doc": "", "decl": "func F(x int)"},
+			},
+			"types": []interface{}{
+				map[string]interface{}{
+					"name":        "T",
+					"doc":         "A T is a thing.\n",
+					"decl":        "type T struct {\n\tA int\n\tB int // new\n}",
+					"since":       "1.2",
+					"fieldsSince": map[string]interface{}{"B": "1.3"},
+					"funcs": []interface{}{
+						map[string]interface{}{"name": "NewT", "doc": "NewT returns a T.\n", "decl": "func NewT() *T"},
+					},
+					"methods": []interface{}{
+						map[string]interface{}{"name": "M", "recv": "*T", "doc": "M is a method.\n", "decl": "func (t *T) M()", "since": "1.4"},
+					},
+				},
+			},
+			"examples": []interface{}{
+				map[string]interface{}{
+					"name":   "F",
+					"doc":    "",
+					"code":   `fmt.Println("hi")`,
+					"output": "hi\n",
+					"play":   "package main\n\nimport (\n\t\"fmt\"\n)\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n",
+				},
+			},
+			"bugs": []interface{}{"Everything is broken.\n"},
+		},
+		"subdirs": []interface{}{
+			map[string]interface{}{"path": "p/sub", "depth": 1.0, "hasPkg": true, "synopsis": "Package sub is below."},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		gotJS, _ := json.MarshalIndent(got, "", "\t")
+		wantJS, _ := json.MarshalIndent(want, "", "\t")
+		t.Errorf("JSON:\n%s\nwant:\n%s", gotJS, wantJS)
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package pkgdoc
+
+import (
+	"archive/zip"
+	"container/list"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// A ModuleCache provides the documentation of the module versions
+// whose zip files are in a local directory laid out like a module proxy,
+// as are the module cache's download directory, $GOMODCACHE/cache/download,
+// and a directory used with GOPROXY=file://dir.
+type ModuleCache struct {
+	dir string
+	max int // number of modules to keep open
+
+	mu   sync.Mutex
+	lru  *list.List               // of *Module, most recently used first
+	mods map[string]*list.Element // "path@version" -> element of lru
+}
+
+// maxCachedModules is the number of modules a ModuleCache keeps open.
+// Each open module holds a file descriptor, the zip's central directory,
+// and the documentation computed so far; the files themselves
+// are read from disk as needed.
+const maxCachedModules = 32
+
+// NewModuleCache returns a ModuleCache reading the module zips in dir.
+// If dir is a module cache, like $GOMODCACHE, the zips are read
+// from its cache/download directory.
+func NewModuleCache(dir string) *ModuleCache {
+	if fi, err := os.Stat(filepath.Join(dir, "cache/download")); err == nil && fi.IsDir() {
+		dir = filepath.Join(dir, "cache/download")
+	}
+	return &ModuleCache{
+		dir:  dir,
+		max:  maxCachedModules,
+		lru:  list.New(),
+		mods: make(map[string]*list.Element),
+	}
+}
+
+// A Module is the documentation of a module version.
+type Module struct {
+	Path    string
+	Version string
+	FS      fs.FS // the module's files, with go.mod at the root
+	Docs    *Docs // packages, rooted at Root
+}
+
+// Root returns the absolute path of the module's documentation tree,
+// like "/mod/golang.org/x/net@v0.1.0".
+func (m *Module) Root() string {
+	return "/mod/" + m.Path + "@" + m.Version
+}
+
+// Modules returns the paths of the modules that have
+// at least one version in the cache, in sorted order.
+func (c *ModuleCache) Modules() []string {
+	var list []string
+	filepath.Walk(c.dir, func(file string, fi os.FileInfo, err error) error {
+		if err != nil || !fi.IsDir() || fi.Name() != "@v" {
+			return nil
+		}
+		rel, err := filepath.Rel(c.dir, filepath.Dir(file))
+		if err != nil {
+			return filepath.SkipDir
+		}
+		if p, err := module.UnescapePath(filepath.ToSlash(rel)); err == nil && len(c.Versions(p)) > 0 {
+			list = append(list, p)
+		}
+		return filepath.SkipDir
+	})
+	sort.Strings(list)
+	return list
+}
+
+// Versions returns the versions of the module path
+// that have a zip file in the cache, newest first.
+func (c *ModuleCache) Versions(path string) []string {
+	escPath, err := module.EscapePath(path)
+	if err != nil {
+		return nil
+	}
+	files, err := ioutil.ReadDir(filepath.Join(c.dir, filepath.FromSlash(escPath), "@v"))
+	if err != nil {
+		return nil
+	}
+	var list []string
+	for _, fi := range files {
+		name := fi.Name()
+		if !strings.HasSuffix(name, ".zip") {
+			continue
+		}
+		v, err := module.UnescapeVersion(strings.TrimSuffix(name, ".zip"))
+		if err == nil && module.Check(path, v) == nil {
+			list = append(list, v)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return semver.Compare(list[i], list[j]) > 0
+	})
+	return list
+}
+
+// Latest returns the latest version of the module path in the cache,
+// preferring releases to pre-releases, as the go command does,
+// or "" if there is none.
+func (c *ModuleCache) Latest(path string) string {
+	list := c.Versions(path)
+	for _, v := range list {
+		if semver.Prerelease(v) == "" {
+			return v
+		}
+	}
+	if len(list) > 0 {
+		return list[0]
+	}
+	return ""
+}
+
+// Module returns the documentation of the module path at version.
+// If the cache has no such module, the error satisfies
+// errors.Is(err, fs.ErrNotExist).
+func (c *ModuleCache) Module(path, version string) (*Module, error) {
+	key := path + "@" + version
+	c.mu.Lock()
+	if elem := c.mods[key]; elem != nil {
+		c.lru.MoveToFront(elem)
+		c.mu.Unlock()
+		return elem.Value.(*Module), nil
+	}
+	c.mu.Unlock()
+
+	m, err := c.open(path, version)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem := c.mods[key]; elem != nil {
+		// Opened concurrently; use the module already cached.
+		c.lru.MoveToFront(elem)
+		return elem.Value.(*Module), nil
+	}
+	c.mods[key] = c.lru.PushFront(m)
+	for c.lru.Len() > c.max {
+		// The evicted module may still be in use by a request,
+		// so its zip file is not closed here: it is closed
+		// when the module is garbage collected.
+		old := c.lru.Remove(c.lru.Back()).(*Module)
+		delete(c.mods, old.Path+"@"+old.Version)
+	}
+	return m, nil
+}
+
+// open opens the zip file of the module path at version,
+// reading only its directory into memory.
+func (c *ModuleCache) open(path, version string) (*Module, error) {
+	if err := module.Check(path, version); err != nil {
+		return nil, fmt.Errorf("%w: %v", fs.ErrNotExist, err)
+	}
+	escPath, err := module.EscapePath(path)
+	if err != nil {
+		return nil, err
+	}
+	escVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	file := filepath.Join(c.dir, filepath.FromSlash(escPath), "@v", escVersion+".zip")
+	z, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s@%s: %w", path, version, err)
+	}
+	// Files in module zips are named path@version/file.
+	fsys, err := fs.Sub(z, escPath+"@"+escVersion)
+	if err != nil {
+		return nil, err
+	}
+	m := &Module{Path: path, Version: version, FS: fsys}
+	m.Docs = NewDocsAt(&subdirFS{dir: toFS(m.Root()), fsys: fsys}, m.Root())
+	return m, nil
+}
+
+// A subdirFS presents the files of fsys in the directory dir.
+type subdirFS struct {
+	dir  string
+	fsys fs.FS
+}
+
+func (s *subdirFS) Open(name string) (fs.File, error) {
+	if name == s.dir {
+		return s.fsys.Open(".")
+	}
+	if strings.HasPrefix(name, s.dir+"/") {
+		return s.fsys.Open(strings.TrimPrefix(name, s.dir+"/"))
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ModulePath splits the absolute path abspath in a module's documentation
+// tree, like "/mod/golang.org/x/net@v0.1.0/http2", into the module path,
+// version, and path within the module.
+// If abspath is not in such a tree, ModulePath returns ok == false.
+func ModulePath(abspath string) (modPath, version, dir string, ok bool) {
+	rest := strings.TrimPrefix(abspath, "/mod/")
+	i := strings.Index(rest, "@")
+	if rest == abspath || i < 0 {
+		return "", "", "", false
+	}
+	modPath, version = rest[:i], rest[i+1:]
+	if j := strings.Index(version, "/"); j >= 0 {
+		version, dir = version[:j], version[j+1:]
+	}
+	return modPath, version, path.Clean("/" + dir)[1:], true
+}
@@ -0,0 +1,143 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package pkgdoc
+
+import (
+	"archive/zip"
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeModule writes the zip file of the module path at version
+// to the module proxy directory dir.
+func writeModule(t *testing.T, dir, escPath, escVersion string, files map[string]string) {
+	v := filepath.Join(dir, escPath, "@v")
+	if err := os.MkdirAll(v, 0777); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(v, escVersion+".zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	z := zip.NewWriter(f)
+	for name, data := range files {
+		w, err := z.Create(escPath + "@" + escVersion + "/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(data))
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestModuleCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "modcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	download := filepath.Join(dir, "cache/download")
+	writeModule(t, download, "example.com/!m", "v1.0.0", map[string]string{
+		"go.mod":   "module example.com/M\n",
+		"m.go":     "// Package m is a test.\npackage m\n\nfunc F() {}\n",
+		"sub/s.go": "// Package s is below m.\npackage s\n",
+	})
+	writeModule(t, download, "example.com/!m", "v1.1.0", map[string]string{"go.mod": "module example.com/M\n", "m.go": "package m\n"})
+	writeModule(t, download, "example.com/!m", "v1.2.0-pre", map[string]string{"go.mod": "module example.com/M\n", "m.go": "package m\n"})
+	writeModule(t, download, "example.com/other", "v0.1.0", map[string]string{"go.mod": "module example.com/other\n", "o.go": "package other\n"})
+	ioutil.WriteFile(filepath.Join(download, "example.com/!m/@v/list"), []byte("v1.0.0\nv1.1.0\n"), 0666)
+
+	c := NewModuleCache(dir)
+	if got, want := c.Modules(), []string{"example.com/M", "example.com/other"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Modules() = %v, want %v", got, want)
+	}
+	if got, want := c.Versions("example.com/M"), []string{"v1.2.0-pre", "v1.1.0", "v1.0.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Versions() = %v, want %v", got, want)
+	}
+	if got, want := c.Latest("example.com/M"), "v1.1.0"; got != want {
+		t.Errorf("Latest() = %q, want %q", got, want)
+	}
+	if got := c.Latest("example.com/missing"); got != "" {
+		t.Errorf("Latest(missing) = %q, want \"\"", got)
+	}
+
+	m, err := c.Module("example.com/M", "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat(m.FS, "sub/s.go"); err != nil {
+		t.Error(err)
+	}
+	info := Doc(m.Docs, m.Root(), "example.com/M", 0, "", "")
+	if info.Err != nil {
+		t.Fatal(info.Err)
+	}
+	if info.PDoc == nil || info.PDoc.ImportPath != "example.com/M" || len(info.PDoc.Funcs) != 1 {
+		t.Fatalf("Doc(%s) = %+v, want package with F", m.Root(), info.PDoc)
+	}
+	if got, want := info.PDoc.Filenames, []string{"/mod/example.com/M@v1.0.0/m.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Filenames = %v, want %v", got, want)
+	}
+	if info.Dirs == nil || len(info.Dirs.List) != 1 || info.Dirs.List[0].Path != "sub" || info.Dirs.List[0].Synopsis != "Package s is below m." {
+		t.Errorf("Dirs = %+v, want sub", info.Dirs)
+	}
+
+	for _, vers := range []string{"v1.3.0", "v1", "latest"} {
+		if _, err := c.Module("example.com/M", vers); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Module(%s) error = %v, want fs.ErrNotExist", vers, err)
+		}
+	}
+
+	// With room for two modules, the least recently used is evicted.
+	c.max = 2
+	use := func(version string) *Module {
+		m, err := c.Module("example.com/M", version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	m0 := use("v1.0.0")
+	use("v1.1.0")
+	if use("v1.0.0") != m0 {
+		t.Errorf("v1.0.0 opened again, want cached module")
+	}
+	use("v1.2.0-pre") // evicts v1.1.0
+	if c.lru.Len() != 2 || c.mods["example.com/M@v1.1.0"] != nil || c.mods["example.com/M@v1.0.0"] == nil {
+		t.Errorf("after using v1.0.0, v1.1.0, v1.0.0, v1.2.0-pre, cached %v, want v1.0.0 and v1.2.0-pre", c.mods)
+	}
+}
+
+func TestModulePath(t *testing.T) {
+	for _, tc := range []struct {
+		abspath               string
+		modPath, version, dir string
+		ok                    bool
+	}{
+		{"/mod/example.com/m@v1.0.0", "example.com/m", "v1.0.0", "", true},
+		{"/mod/example.com/m@v1.0.0/", "example.com/m", "v1.0.0", "", true},
+		{"/mod/example.com/m@v1.0.0/a/b.go", "example.com/m", "v1.0.0", "a/b.go", true},
+		{"/mod/example.com/m", "", "", "", false},
+		{"/src/net@v1", "", "", "", false},
+	} {
+		modPath, version, dir, ok := ModulePath(tc.abspath)
+		if modPath != tc.modPath || version != tc.version || dir != tc.dir || ok != tc.ok {
+			t.Errorf("ModulePath(%q) = %q, %q, %q, %v, want %q, %q, %q, %v", tc.abspath, modPath, version, dir, ok, tc.modPath, tc.version, tc.dir, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,425 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package pkgdoc
+
+import (
+	"go/ast"
+	"go/token"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A Platform is a target operating system and architecture.
+type Platform struct {
+	GOOS   string
+	GOARCH string
+}
+
+func (p Platform) String() string {
+	return p.GOOS + "/" + p.GOARCH
+}
+
+// defaultPorts maps the ports of Go 1.16 to whether they support cgo,
+// as cmd/dist's cgoEnabled does. It is used for trees without
+// cmd/dist, like modules.
+var defaultPorts = map[string]bool{
+	"aix/ppc64":       true,
+	"android/386":     true,
+	"android/amd64":   true,
+	"android/arm":     true,
+	"android/arm64":   true,
+	"darwin/amd64":    true,
+	"darwin/arm64":    true,
+	"dragonfly/amd64": true,
+	"freebsd/386":     true,
+	"freebsd/amd64":   true,
+	"freebsd/arm":     true,
+	"freebsd/arm64":   true,
+	"illumos/amd64":   true,
+	"ios/amd64":       true,
+	"ios/arm64":       true,
+	"js/wasm":         false,
+	"linux/386":       true,
+	"linux/amd64":     true,
+	"linux/arm":       true,
+	"linux/arm64":     true,
+	"linux/mips":      true,
+	"linux/mips64":    true,
+	"linux/mips64le":  true,
+	"linux/mipsle":    true,
+	"linux/ppc64":     false,
+	"linux/ppc64le":   true,
+	"linux/riscv64":   true,
+	"linux/s390x":     true,
+	"netbsd/386":      true,
+	"netbsd/amd64":    true,
+	"netbsd/arm":      true,
+	"netbsd/arm64":    true,
+	"openbsd/386":     true,
+	"openbsd/amd64":   true,
+	"openbsd/arm":     true,
+	"openbsd/arm64":   true,
+	"openbsd/mips64":  false,
+	"plan9/386":       false,
+	"plan9/amd64":     false,
+	"plan9/arm":       false,
+	"solaris/amd64":   true,
+	"windows/386":     true,
+	"windows/amd64":   true,
+	"windows/arm":     false,
+}
+
+// Ports returns the ports of Go on which the packages in d are evaluated
+// to find platform-specific identifiers, sorted by GOOS, then GOARCH.
+// They are the ports the tree's cmd/dist lists, as 'go tool dist list'
+// prints them, or, for trees without cmd/dist, like modules,
+// the ports of Go 1.16.
+func (d *Docs) Ports() []Platform {
+	d.portsOnce.Do(d.readPorts)
+	return d.ports
+}
+
+// IsPort reports whether p is one of the Ports of d.
+func (d *Docs) IsPort(p Platform) bool {
+	d.portsOnce.Do(d.readPorts)
+	_, ok := d.portCgo[p]
+	return ok
+}
+
+// cgoEnabled reports whether the port p supports cgo.
+func (d *Docs) cgoEnabled(p Platform) bool {
+	d.portsOnce.Do(d.readPorts)
+	return d.portCgo[p]
+}
+
+// readPorts sets d.ports and d.portCgo.
+func (d *Docs) readPorts() {
+	ports := defaultPorts
+	if d.module == "" {
+		if m := distPorts(d.fs, path.Join(d.top, "cmd/dist/build.go")); m != nil {
+			ports = m
+		}
+	}
+	d.portCgo = make(map[Platform]bool)
+	for key, cgo := range ports {
+		i := strings.Index(key, "/")
+		if i < 0 {
+			continue
+		}
+		p := Platform{GOOS: key[:i], GOARCH: key[i+1:]}
+		d.portCgo[p] = cgo
+		d.ports = append(d.ports, p)
+	}
+	sort.Slice(d.ports, func(i, j int) bool {
+		if d.ports[i].GOOS != d.ports[j].GOOS {
+			return d.ports[i].GOOS < d.ports[j].GOOS
+		}
+		return d.ports[i].GOARCH < d.ports[j].GOARCH
+	})
+}
+
+// distPorts returns the cgoEnabled map declared in filename,
+// the source of cmd/dist, less the ports it declares broken
+// or incomplete, or nil if there is no such map.
+func distPorts(fsys fs.FS, filename string) map[string]bool {
+	f, err := parseFile(fsys, token.NewFileSet(), filename, 0)
+	if err != nil {
+		return nil
+	}
+	maps := make(map[string]map[string]bool)
+	for _, decl := range f.Decls {
+		d, ok := decl.(*ast.GenDecl)
+		if !ok || d.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range d.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, name := range vs.Names {
+				if i >= len(vs.Values) {
+					break
+				}
+				lit, ok := vs.Values[i].(*ast.CompositeLit)
+				if !ok {
+					continue
+				}
+				m := make(map[string]bool)
+				for _, elt := range lit.Elts {
+					kv, ok := elt.(*ast.KeyValueExpr)
+					if !ok {
+						continue
+					}
+					k, ok := kv.Key.(*ast.BasicLit)
+					if !ok || k.Kind != token.STRING {
+						continue
+					}
+					key, err := strconv.Unquote(k.Value)
+					if err != nil {
+						continue
+					}
+					v, _ := kv.Value.(*ast.Ident)
+					m[key] = v != nil && v.Name == "true"
+				}
+				maps[name.Name] = m
+			}
+		}
+	}
+	ports := maps["cgoEnabled"]
+	if len(ports) == 0 {
+		return nil
+	}
+	for key := range maps["broken"] {
+		delete(ports, key)
+	}
+	for key := range maps["incomplete"] {
+		delete(ports, key)
+	}
+	return ports
+}
+
+// A PlatformSet records the platforms on which a package
+// and each of its exported identifiers exist.
+type PlatformSet struct {
+	Platforms []Platform // platforms on which the package builds, in the order of Docs.Ports
+	Names     []string   // exported identifiers, like "F", "T", and "T.M", sorted
+
+	where    map[string][]Platform // identifier -> platforms on which it exists
+	portable bool                  // the package has the same files on every platform
+}
+
+// Platforms returns the platforms on which the package in the
+// directory abspath and its exported identifiers exist.
+// It evaluates the package on each of the Ports of d
+// the first time it is called for abspath.
+func (d *Docs) Platforms(abspath string) *PlatformSet {
+	d.platformsMu.Lock()
+	s := d.platforms[abspath]
+	d.platformsMu.Unlock()
+	if s != nil {
+		return s
+	}
+
+	s = d.platformSet(abspath)
+	d.platformsMu.Lock()
+	defer d.platformsMu.Unlock()
+	if d.platforms == nil {
+		d.platforms = make(map[string]*PlatformSet)
+	}
+	d.platforms[abspath] = s
+	return s
+}
+
+// platformSet evaluates the package in abspath on each of the Ports of d,
+// with cgo enabled if the port supports it.
+func (d *Docs) platformSet(abspath string) *PlatformSet {
+	s := &PlatformSet{
+		where:    make(map[string][]Platform),
+		portable: true,
+	}
+	ctxt := d.buildContext(0)
+	fset := token.NewFileSet()
+	declared := make(map[string][]string) // file name -> exported identifiers it declares
+	var first string
+	for _, p := range d.Ports() {
+		ctxt.GOOS, ctxt.GOARCH = p.GOOS, p.GOARCH
+		ctxt.CgoEnabled = d.cgoEnabled(p)
+		pkginfo, err := ctxt.ImportDir(abspath, 0)
+		if err != nil {
+			continue
+		}
+		files := append(pkginfo.GoFiles, pkginfo.CgoFiles...)
+		if len(files) == 0 {
+			continue
+		}
+		if key := strings.Join(files, " "); s.Platforms == nil {
+			first = key
+		} else if key != first {
+			s.portable = false
+		}
+		s.Platforms = append(s.Platforms, p)
+
+		seen := make(map[string]bool)
+		for _, file := range files {
+			names, ok := declared[file]
+			if !ok {
+				names = exportedNames(d.fs, fset, path.Join(abspath, file))
+				declared[file] = names
+			}
+			for _, name := range names {
+				if !seen[name] {
+					seen[name] = true
+					s.where[name] = append(s.where[name], p)
+				}
+			}
+		}
+	}
+	for name := range s.where {
+		s.Names = append(s.Names, name)
+	}
+	sort.Strings(s.Names)
+	return s
+}
+
+// exportedNames returns the exported identifiers declared in the file,
+// with methods of exported types named like "T.M".
+func exportedNames(fsys fs.FS, fset *token.FileSet, filename string) []string {
+	f, err := parseFile(fsys, fset, filename, 0)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if !d.Name.IsExported() {
+				continue
+			}
+			if d.Recv == nil {
+				names = append(names, d.Name.Name)
+			} else if recv := recvTypeName(d.Recv.List[0].Type); ast.IsExported(recv) {
+				names = append(names, recv+"."+d.Name.Name)
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if s.Name.IsExported() {
+						names = append(names, s.Name.Name)
+					}
+				case *ast.ValueSpec:
+					for _, id := range s.Names {
+						if id.IsExported() {
+							names = append(names, id.Name)
+						}
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+// Portable reports whether the package is built from the same files
+// on every platform on which it builds, so that its documentation
+// does not depend on the platform.
+func (s *PlatformSet) Portable() bool {
+	return s.portable || len(s.Platforms) < 2
+}
+
+// Note describes the platforms on which any of the identifiers exist,
+// like "darwin, linux only" or "all but plan9, windows",
+// or returns "" if they exist on every platform the package does.
+func (s *PlatformSet) Note(names ...string) string {
+	in := make(map[Platform]bool)
+	for _, name := range names {
+		for _, p := range s.where[name] {
+			in[p] = true
+		}
+	}
+	if len(in) == 0 || len(in) == len(s.Platforms) {
+		return ""
+	}
+
+	// Name an operating system instead of
+	// listing all its platforms.
+	var only, except []string
+	for _, g := range s.byGOOS() {
+		var yes, no []string
+		for _, p := range g {
+			if in[p] {
+				yes = append(yes, p.String())
+			} else {
+				no = append(no, p.String())
+			}
+		}
+		switch {
+		case no == nil:
+			only = append(only, g[0].GOOS)
+		case yes == nil:
+			except = append(except, g[0].GOOS)
+		default:
+			only = append(only, yes...)
+			except = append(except, no...)
+		}
+	}
+	if len(except) < len(only) {
+		return "all but " + strings.Join(except, ", ")
+	}
+	return strings.Join(only, ", ") + " only"
+}
+
+// byGOOS splits s.Platforms into runs with the same GOOS.
+func (s *PlatformSet) byGOOS() [][]Platform {
+	var groups [][]Platform
+	for i, p := range s.Platforms {
+		if i == 0 || p.GOOS != s.Platforms[i-1].GOOS {
+			groups = append(groups, nil)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], p)
+	}
+	return groups
+}
+
+// A PlatformTable shows the platforms on which each exported
+// identifier of a package exists, with a column for each GOOS.
+type PlatformTable struct {
+	GOOS []string
+	Rows []*PlatformRow
+}
+
+// A PlatformRow is the row of a PlatformTable for an identifier.
+type PlatformRow struct {
+	Name     string
+	Platform Platform        // platform on which to view it
+	Cells    []*PlatformCell // by GOOS
+}
+
+// A PlatformCell records the architectures of a GOOS
+// on which an identifier exists.
+type PlatformCell struct {
+	All    bool     // it exists on every architecture the package builds on
+	Arches []string // otherwise, the architectures on which it exists
+}
+
+// Table returns the table of the platforms on which
+// each exported identifier exists. Each identifier is viewed
+// on the preferred platform if it exists there.
+func (s *PlatformSet) Table(prefer Platform) *PlatformTable {
+	groups := s.byGOOS()
+	t := new(PlatformTable)
+	for _, g := range groups {
+		t.GOOS = append(t.GOOS, g[0].GOOS)
+	}
+	for _, name := range s.Names {
+		where := s.where[name]
+		in := make(map[Platform]bool)
+		for _, p := range where {
+			in[p] = true
+		}
+		row := &PlatformRow{Name: name, Platform: where[0]}
+		if in[prefer] {
+			row.Platform = prefer
+		}
+		for _, g := range groups {
+			cell := new(PlatformCell)
+			for _, p := range g {
+				if in[p] {
+					cell.Arches = append(cell.Arches, p.GOARCH)
+				}
+			}
+			if len(cell.Arches) == len(g) {
+				cell.All, cell.Arches = true, nil
+			}
+			row.Cells = append(row.Cells, cell)
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t
+}
@@ -0,0 +1,130 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package pkgdoc
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPlatforms(t *testing.T) {
+	fs := fstest.MapFS{
+		"src/p/p.go":             {Data: []byte("package p\n\nfunc F() {}\n\ntype T int\n")},
+		"src/p/p_unix.go":        {Data: []byte("//go:build !windows && !plan9 && !js\n\npackage p\n\nfunc (T) Fd() uintptr { return 0 }\n")},
+		"src/p/p_linux.go":       {Data: []byte("package p\n\nconst Epoll = 1\n")},
+		"src/p/p_darwin.go":      {Data: []byte("package p\n\nconst Kqueue = 1\n")},
+		"src/p/p_linux_amd64.go": {Data: []byte("package p\n\nfunc Arch() {}\n\nfunc unexported() {}\n")},
+		"src/p/p_windows.go":     {Data: []byte("package p\n\nconst Kqueue = 2\n\ntype handle int\n\nfunc (handle) Close() {}\n")},
+		"src/q/q.go":             {Data: []byte("package q\n")},
+		"src/js/js.go":           {Data: []byte("//go:build js\n\npackage js\n\nfunc F() {}\n")},
+	}
+	d := NewDocs(fs)
+
+	s := d.Platforms("/src/p")
+	if d.Platforms("/src/p") != s {
+		t.Errorf("Platforms(/src/p) not cached")
+	}
+	if len(s.Platforms) != len(d.Ports()) || s.Portable() {
+		t.Errorf("Platforms = %d, Portable() = %v, want %d, false", len(s.Platforms), s.Portable(), len(d.Ports()))
+	}
+	if want := []string{"Arch", "Epoll", "F", "Kqueue", "T", "T.Fd"}; !reflect.DeepEqual(s.Names, want) {
+		t.Errorf("Names = %v, want %v", s.Names, want)
+	}
+	for _, tc := range []struct {
+		names []string
+		note  string
+	}{
+		{[]string{"F"}, ""},
+		{[]string{"T"}, ""},
+		{[]string{"Epoll"}, "android, linux only"}, // android implies linux
+		{[]string{"Kqueue"}, "darwin, ios, windows only"},
+		{[]string{"Epoll", "Kqueue"}, "android, darwin, ios, linux, windows only"},
+		{[]string{"Arch"}, "android/amd64, linux/amd64 only"},
+		{[]string{"T.Fd"}, "all but js, plan9, windows"},
+		{[]string{"missing"}, ""},
+	} {
+		if note := s.Note(tc.names...); note != tc.note {
+			t.Errorf("Note(%v) = %q, want %q", tc.names, note, tc.note)
+		}
+	}
+
+	table := s.Table(Platform{"linux", "amd64"})
+	var arch *PlatformRow
+	for _, row := range table.Rows {
+		if row.Name == "Arch" {
+			arch = row
+		}
+	}
+	if arch == nil || len(arch.Cells) != len(table.GOOS) {
+		t.Fatalf("Table() = %+v, want row for Arch", table)
+	}
+	for i, goos := range table.GOOS {
+		cell := arch.Cells[i]
+		if goos == "linux" || goos == "android" {
+			if cell.All || !reflect.DeepEqual(cell.Arches, []string{"amd64"}) {
+				t.Errorf("Arch cell for %s = %+v, want amd64", goos, cell)
+			}
+		} else if cell.All || cell.Arches != nil {
+			t.Errorf("Arch cell for %s = %+v, want empty", goos, cell)
+		}
+	}
+	if got := table.Rows[0].Platform; got != (Platform{"linux", "amd64"}) {
+		t.Errorf("Arch row platform = %v, want linux/amd64", got)
+	}
+
+	if s := d.Platforms("/src/q"); !s.Portable() || len(s.Names) != 0 {
+		t.Errorf("Platforms(/src/q) = %+v, want portable and empty", s)
+	}
+	if s := d.Platforms("/src/js"); !s.Portable() || !reflect.DeepEqual(s.Platforms, []Platform{{"js", "wasm"}}) || s.Note("F") != "" {
+		t.Errorf("Platforms(/src/js) = %+v, want only js/wasm", s)
+	}
+}
+
+func TestDistPorts(t *testing.T) {
+	fs := fstest.MapFS{
+		"src/cmd/dist/build.go": {Data: []byte(`package main
+
+var cgoEnabled = map[string]bool{
+	"linux/amd64":   true,
+	"linux/sparc64": true,
+	"plan9/386":     false,
+	"windows/arm64": true,
+}
+
+var broken = map[string]bool{
+	"linux/sparc64": true,
+}
+`)},
+		"src/p/p.go":       {Data: []byte("package p\n")},
+		"src/p/p_cgo.go":   {Data: []byte("//go:build cgo\n\npackage p\n\nfunc Cgo() {}\n")},
+		"src/p/p_arm64.go": {Data: []byte("package p\n\nfunc Arm64() {}\n")},
+	}
+	d := NewDocs(fs)
+	want := []Platform{{"linux", "amd64"}, {"plan9", "386"}, {"windows", "arm64"}}
+	if got := d.Ports(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Ports() = %v, want %v", got, want)
+	}
+	if d.IsPort(Platform{"linux", "sparc64"}) || !d.IsPort(Platform{"plan9", "386"}) {
+		t.Errorf("IsPort(linux/sparc64), IsPort(plan9/386) = %v, %v, want false, true",
+			d.IsPort(Platform{"linux", "sparc64"}), d.IsPort(Platform{"plan9", "386"}))
+	}
+
+	s := d.Platforms("/src/p")
+	if note := s.Note("Cgo"); note != "all but plan9" {
+		t.Errorf("Note(Cgo) = %q, want %q", note, "all but plan9")
+	}
+	if note := s.Note("Arm64"); note != "windows only" {
+		t.Errorf("Note(Arm64) = %q, want %q", note, "windows only")
+	}
+
+	// Modules have no cmd/dist.
+	if got := NewDocs(fstest.MapFS{"src/p/p.go": fs["src/p/p.go"]}).Ports(); len(got) != len(defaultPorts) {
+		t.Errorf("Ports() without cmd/dist = %d ports, want %d", len(got), len(defaultPorts))
+	}
+}
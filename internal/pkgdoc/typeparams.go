@@ -0,0 +1,33 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package pkgdoc
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+// isGeneric reports whether named has type parameters.
+func isGeneric(named *types.Named) bool {
+	return named.TypeParams().Len() > 0
+}
+
+// isMethodSet reports whether iface is an ordinary interface,
+// not a constraint with a type set, like ~int | ~string.
+func isMethodSet(iface *types.Interface) bool {
+	return iface.IsMethodSet()
+}
+
+// indexListBase returns the generic type of x, if x is
+// an instantiation with several type arguments, like T[K, V].
+func indexListBase(x ast.Expr) (ast.Expr, bool) {
+	if x, ok := x.(*ast.IndexListExpr); ok {
+		return x.X, true
+	}
+	return nil, false
+}
@@ -0,0 +1,21 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16 && !go1.18
+// +build go1.16,!go1.18
+
+package pkgdoc
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+// Before Go 1.18, there are no type parameters.
+
+func isGeneric(named *types.Named) bool { return false }
+
+func isMethodSet(iface *types.Interface) bool { return true }
+
+func indexListBase(x ast.Expr) (ast.Expr, bool) { return nil, false }
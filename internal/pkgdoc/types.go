@@ -0,0 +1,433 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package pkgdoc
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// A Ref describes what an identifier refers to: the package-level
+// object Name in the package with import path Path, the method or field
+// Name = "T.M" of a package-level type T, or, if Name is empty, the
+// package itself. The zero Ref refers to nothing worth linking to,
+// such as a type parameter, function parameter, or struct field.
+type Ref struct {
+	Path string
+	Name string
+}
+
+// A TypeInfo is the information about an exported type
+// that only type checking provides.
+type TypeInfo struct {
+	// Implements lists the interfaces the type implements,
+	// from the package and the packages it imports.
+	Implements []*Interface
+
+	// Promoted lists the methods promoted to the type from fields
+	// embedding types of other packages, which ModeMethods cannot show.
+	Promoted []*PromotedMethod
+}
+
+// An Interface is an interface a type implements.
+type Interface struct {
+	Ref
+	Text    string // qualified name, like "io.Reader"
+	Pointer bool   // only a pointer to the type implements it
+}
+
+// A PromotedMethod is a method promoted from an embedded field.
+type PromotedMethod struct {
+	Name string
+	Recv string // receiver, like "T" or "*T"
+	Decl string // declaration, like "func (*T) Read(p []byte) (n int, err error)"
+	From Ref    // the method promoted, like {"bufio", "Reader.Read"}
+	Text string // qualified name of the method, like "bufio.Reader.Read"
+}
+
+// A typesCache holds the packages imported by the packages
+// type-checked for one port, for use by later type checks.
+type typesCache struct {
+	fset *token.FileSet
+	pkgs map[string]*types.Package // by package path; replaced, never modified, by addTypes
+}
+
+// typeCheck type-checks the package with import path importPath,
+// whose files were parsed into fset, and records the results in info.
+// Imported packages are type-checked from the source files in d,
+// using ctxt to select their files, and kept for use by later calls
+// for the same port. If ctxt is not for a port of Go, typeCheck
+// records nothing.
+func (d *Docs) typeCheck(info *Page, ctxt *build.Context, fset *token.FileSet, importPath string, files map[string]*ast.File) {
+	p := Platform{ctxt.GOOS, ctxt.GOARCH}
+	if !d.IsPort(p) {
+		return
+	}
+	d.typesMu.Lock()
+	if d.typesCache == nil {
+		d.typesCache = make(map[Platform]*typesCache)
+	}
+	c := d.typesCache[p]
+	if c == nil {
+		c = &typesCache{fset: token.NewFileSet(), pkgs: make(map[string]*types.Package)}
+		d.typesCache[p] = c
+	}
+	cached := c.pkgs
+	d.typesMu.Unlock()
+
+	// Check without holding d.typesMu, using the packages cached so far,
+	// then add the packages imported that were not.
+	imp := &typesImporter{
+		d:         d,
+		ctxt:      ctxt,
+		fset:      c.fset,
+		cached:    cached,
+		pkgs:      make(map[string]*types.Package),
+		importing: make(map[string]bool),
+	}
+
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var list []*ast.File
+	for _, name := range names {
+		list = append(list, files[name])
+	}
+	conf := &types.Config{
+		Importer:    imp,
+		FakeImportC: true,
+		Error:       func(error) {}, // report what can be checked
+	}
+	tinfo := &types.Info{Uses: make(map[*ast.Ident]types.Object)}
+	pkg, _ := conf.Check(importPath, fset, list, tinfo)
+	d.addTypes(c, imp.checked)
+	if pkg == nil {
+		return
+	}
+
+	info.Uses = make(map[*ast.Ident]Ref)
+	for id, obj := range tinfo.Uses {
+		info.Uses[id] = objRef(obj)
+	}
+	info.TypeInfo = make(map[string]*TypeInfo)
+	ifaces := interfaces(pkg)
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || !obj.Exported() || obj.IsAlias() {
+			continue
+		}
+		named, ok := obj.Type().(*types.Named)
+		if !ok || isGeneric(named) {
+			continue
+		}
+		t := &TypeInfo{
+			Implements: implements(named, ifaces),
+			Promoted:   promoted(pkg, named),
+		}
+		if t.Implements != nil || t.Promoted != nil {
+			info.TypeInfo[name] = t
+		}
+	}
+}
+
+// objRef returns the Ref for obj.
+func objRef(obj types.Object) Ref {
+	switch obj := obj.(type) {
+	case *types.PkgName:
+		return Ref{Path: obj.Imported().Path()}
+	case *types.Func:
+		if sig, ok := obj.Type().(*types.Signature); ok && sig.Recv() != nil {
+			if named := recvNamed(sig.Recv().Type()); named != nil && isPackageLevel(named.Obj()) {
+				return Ref{Path: obj.Pkg().Path(), Name: named.Obj().Name() + "." + obj.Name()}
+			}
+			return Ref{}
+		}
+	}
+	if obj.Pkg() == nil {
+		return Ref{Path: "builtin", Name: obj.Name()}
+	}
+	if isPackageLevel(obj) {
+		return Ref{Path: obj.Pkg().Path(), Name: obj.Name()}
+	}
+	return Ref{}
+}
+
+// isPackageLevel reports whether obj is declared at package level.
+func isPackageLevel(obj types.Object) bool {
+	return obj.Pkg() != nil && obj.Parent() == obj.Pkg().Scope()
+}
+
+// recvNamed returns the named type of the receiver type t,
+// or nil if there is none.
+func recvNamed(t types.Type) *types.Named {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	named, _ := t.(*types.Named)
+	return named
+}
+
+// interfaces returns the exported, non-empty interfaces
+// declared in pkg and the packages it imports, directly or not,
+// except for internal packages.
+func interfaces(pkg *types.Package) []*types.TypeName {
+	var list []*types.TypeName
+	seen := make(map[*types.Package]bool)
+	var visit func(*types.Package)
+	visit = func(p *types.Package) {
+		if seen[p] {
+			return
+		}
+		seen[p] = true
+		for _, imp := range p.Imports() {
+			visit(imp)
+		}
+		if p != pkg && isInternal(p.Path()) {
+			return
+		}
+		scope := p.Scope()
+		for _, name := range scope.Names() {
+			obj, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || !obj.Exported() || obj.IsAlias() {
+				continue
+			}
+			named, ok := obj.Type().(*types.Named)
+			if !ok || isGeneric(named) {
+				continue
+			}
+			if iface, ok := named.Underlying().(*types.Interface); ok && iface.NumMethods() > 0 && isMethodSet(iface) {
+				list = append(list, obj)
+			}
+		}
+	}
+	visit(pkg)
+	sort.Slice(list, func(i, j int) bool {
+		if pi, pj := list[i].Pkg().Path(), list[j].Pkg().Path(); pi != pj {
+			return pi < pj
+		}
+		return list[i].Name() < list[j].Name()
+	})
+	return list
+}
+
+// isInternal reports whether the package path
+// cannot be imported by other packages.
+func isInternal(p string) bool {
+	for _, elem := range strings.Split(p, "/") {
+		if elem == "internal" || elem == "vendor" {
+			return true
+		}
+	}
+	return false
+}
+
+// implements returns the interfaces in ifaces that named,
+// or a pointer to it, implements.
+func implements(named *types.Named, ifaces []*types.TypeName) []*Interface {
+	_, isIface := named.Underlying().(*types.Interface)
+	ptr := types.NewPointer(named)
+	var list []*Interface
+	for _, obj := range ifaces {
+		if obj == named.Obj() {
+			continue
+		}
+		iface := obj.Type().Underlying().(*types.Interface)
+		x := &Interface{
+			Ref:  Ref{Path: obj.Pkg().Path(), Name: obj.Name()},
+			Text: obj.Pkg().Name() + "." + obj.Name(),
+		}
+		switch {
+		case types.Implements(named, iface):
+			list = append(list, x)
+		case !isIface && types.Implements(ptr, iface):
+			x.Pointer = true
+			list = append(list, x)
+		}
+	}
+	return list
+}
+
+// promoted returns the exported methods of named, or a pointer to it,
+// that are promoted from embedded fields and declared outside pkg.
+func promoted(pkg *types.Package, named *types.Named) []*PromotedMethod {
+	if _, ok := named.Underlying().(*types.Struct); !ok {
+		return nil
+	}
+	qual := func(p *types.Package) string {
+		if p == pkg {
+			return ""
+		}
+		return p.Name()
+	}
+	values := types.NewMethodSet(named)
+	all := types.NewMethodSet(types.NewPointer(named))
+	var list []*PromotedMethod
+	for i := 0; i < all.Len(); i++ {
+		sel := all.At(i)
+		m, ok := sel.Obj().(*types.Func)
+		if !ok || len(sel.Index()) < 2 || !m.Exported() || m.Pkg() == pkg {
+			continue
+		}
+		recv := "*" + named.Obj().Name()
+		if values.Lookup(m.Pkg(), m.Name()) != nil {
+			recv = recv[1:]
+		}
+		sig := types.TypeString(m.Type(), qual)
+		pm := &PromotedMethod{
+			Name: m.Name(),
+			Recv: recv,
+			Decl: fmt.Sprintf("func (%s) %s%s", recv, m.Name(), strings.TrimPrefix(sig, "func")),
+			From: objRef(m),
+			Text: m.Pkg().Name() + "." + m.Name(),
+		}
+		if pm.From.Name != "" {
+			pm.Text = m.Pkg().Name() + "." + pm.From.Name
+		}
+		list = append(list, pm)
+	}
+	return list
+}
+
+// addTypes adds to c the packages in list, which imports only
+// packages in c or earlier in list. So that the cached packages
+// keep importing each other, it skips the packages already in c,
+// as checked by a concurrent call, and those importing a package
+// other than the one in c.
+func (d *Docs) addTypes(c *typesCache, list []*types.Package) {
+	if len(list) == 0 {
+		return
+	}
+	d.typesMu.Lock()
+	defer d.typesMu.Unlock()
+	pkgs := make(map[string]*types.Package, len(c.pkgs)+len(list))
+	for path, pkg := range c.pkgs {
+		pkgs[path] = pkg
+	}
+List:
+	for _, pkg := range list {
+		if pkgs[pkg.Path()] != nil {
+			continue
+		}
+		for _, dep := range pkg.Imports() {
+			if dep != types.Unsafe && pkgs[dep.Path()] != dep {
+				continue List
+			}
+		}
+		pkgs[pkg.Path()] = pkg
+	}
+	c.pkgs = pkgs
+}
+
+// A typesImporter type-checks imported packages
+// from the source files in a documentation tree.
+type typesImporter struct {
+	d         *Docs
+	ctxt      *build.Context
+	fset      *token.FileSet
+	cached    map[string]*types.Package // by package path; checked by earlier calls, read-only
+	pkgs      map[string]*types.Package // by package path; checked by this importer
+	checked   []*types.Package          // pkgs, in the order checked
+	importing map[string]bool           // absolute paths being imported, to catch cycles
+}
+
+func (imp *typesImporter) Import(path string) (*types.Package, error) {
+	return imp.ImportFrom(path, "", 0)
+}
+
+func (imp *typesImporter) ImportFrom(importPath, dir string, mode types.ImportMode) (*types.Package, error) {
+	if importPath == "unsafe" {
+		return types.Unsafe, nil
+	}
+	abspath, pkgPath := imp.d.findPackage(importPath, dir)
+	if abspath == "" {
+		return nil, fmt.Errorf("cannot find package %q", importPath)
+	}
+	if pkg := imp.cached[pkgPath]; pkg != nil {
+		return pkg, nil
+	}
+	if pkg := imp.pkgs[pkgPath]; pkg != nil {
+		return pkg, nil
+	}
+	if imp.importing[abspath] {
+		return nil, fmt.Errorf("import cycle through %q", importPath)
+	}
+	imp.importing[abspath] = true
+	defer delete(imp.importing, abspath)
+
+	bp, err := imp.ctxt.ImportDir(abspath, 0)
+	if err != nil {
+		return nil, err
+	}
+	var files []*ast.File
+	for _, name := range append(bp.GoFiles, bp.CgoFiles...) {
+		f, err := parseFile(imp.d.fs, imp.fset, path.Join(abspath, name), 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	conf := &types.Config{
+		Importer:    imp,
+		FakeImportC: true,
+		Error:       func(error) {},
+	}
+	pkg, _ := conf.Check(pkgPath, imp.fset, files, nil)
+	if pkg == nil {
+		return nil, fmt.Errorf("cannot type-check %q", importPath)
+	}
+	imp.pkgs[pkgPath] = pkg
+	imp.checked = append(imp.checked, pkg)
+	return pkg, nil
+}
+
+// findPackage returns the absolute path of the directory holding the
+// package importPath, as imported from the directory dir, and the
+// package's path for documentation links, which differs from
+// importPath for vendored packages.
+// If there is no such package in d, findPackage returns "", "".
+func (d *Docs) findPackage(importPath, dir string) (abspath, pkgPath string) {
+	isDir := func(name string) bool {
+		fi, err := fs.Stat(d.fs, toFS(name))
+		return err == nil && fi.IsDir()
+	}
+	rel := func(name string) string {
+		return strings.TrimPrefix(strings.TrimPrefix(name, d.top), "/")
+	}
+
+	// Look for vendored packages in dir and its parents within the tree.
+	for dir := path.Clean(dir); dir == d.top || strings.HasPrefix(dir, d.top+"/"); dir = path.Dir(dir) {
+		if name := path.Join(dir, "vendor", importPath); isDir(name) {
+			if d.module != "" {
+				return name, importPath
+			}
+			return name, rel(name)
+		}
+	}
+
+	switch {
+	case d.module == "":
+		if name := path.Join(d.top, importPath); isDir(name) {
+			return name, importPath
+		}
+	case importPath == d.module || strings.HasPrefix(importPath, d.module+"/"):
+		if name := path.Join(d.top, strings.TrimPrefix(importPath, d.module)); isDir(name) {
+			return name, importPath
+		}
+	}
+	return "", ""
+}
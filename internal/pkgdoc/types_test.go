@@ -0,0 +1,129 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package pkgdoc
+
+import (
+	"go/ast"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestTypeCheck(t *testing.T) {
+	fs := fstest.MapFS{
+		"src/io/io.go": {Data: []byte(`package io
+
+type Reader interface{ Read(p []byte) (int, error) }
+type Closer interface{ Close() error }
+`)},
+		"src/vendor/example.com/r/r.go": {Data: []byte(`package r
+
+type R struct{}
+
+func (*R) Read(p []byte) (int, error) { return 0, nil }
+`)},
+		"src/p/p.go": {Data: []byte(`package p
+
+import (
+	"example.com/r"
+	"io"
+)
+
+// T embeds r.R, which has a Read method.
+type T struct {
+	*r.R
+	in io.Reader
+}
+
+func (T) Close() error { return nil }
+
+// U has only pointer methods.
+type U struct{}
+
+func (*U) Close() error { return nil }
+
+func Use(t T, x io.Reader) T { return t }
+`)},
+	}
+	d := NewDocs(fs)
+	info := Doc(d, "/src/p", "p", ModeTypes, "", "")
+	if info.Err != nil {
+		t.Fatal(info.Err)
+	}
+
+	if got, want := info.TypeInfo["T"], (&TypeInfo{
+		Implements: []*Interface{
+			{Ref: Ref{"io", "Closer"}, Text: "io.Closer"},
+			{Ref: Ref{"io", "Reader"}, Text: "io.Reader"},
+		},
+		Promoted: []*PromotedMethod{{
+			Name: "Read",
+			Recv: "T",
+			Decl: "func (T) Read(p []byte) (int, error)",
+			From: Ref{"vendor/example.com/r", "R.Read"},
+			Text: "r.R.Read",
+		}},
+	}); !reflect.DeepEqual(got, want) {
+		t.Errorf("TypeInfo[T] = %+v\nwant %+v", got, want)
+	}
+	if got, want := info.TypeInfo["U"], (&TypeInfo{
+		Implements: []*Interface{{Ref: Ref{"io", "Closer"}, Text: "io.Closer", Pointer: true}},
+	}); !reflect.DeepEqual(got, want) {
+		t.Errorf("TypeInfo[U] = %+v\nwant %+v", got, want)
+	}
+
+	// Find the uses in Use's declaration.
+	// Use returns a T, so go/doc lists it with T.
+	uses := make(map[string]Ref)
+	for _, typ := range info.PDoc.Types {
+		if typ.Name != "T" || len(typ.Funcs) != 1 {
+			continue
+		}
+		f := typ.Funcs[0]
+		ast.Inspect(f.Decl, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok {
+				if ref, ok := info.Uses[id]; ok {
+					uses[id.Name] = ref
+				}
+			}
+			return true
+		})
+	}
+	if want := map[string]Ref{
+		"T":      {"p", "T"},
+		"io":     {"io", ""},
+		"Reader": {"io", "Reader"},
+	}; !reflect.DeepEqual(uses, want) {
+		t.Errorf("uses in Use = %v, want %v", uses, want)
+	}
+
+	// The imported packages are cached for the host's port only.
+	if len(d.typesCache) != 1 {
+		t.Errorf("cached packages for %d ports, want 1", len(d.typesCache))
+	}
+	for _, c := range d.typesCache {
+		for _, path := range []string{"io", "vendor/example.com/r"} {
+			if c.pkgs[path] == nil {
+				t.Errorf("package %s not cached", path)
+			}
+		}
+	}
+	// Packages are not type-checked for platforms that are not ports.
+	if info := Doc(d, "/src/p", "p", ModeTypes, "linux", "bogus"); info.TypeInfo != nil {
+		t.Errorf("type-checked for linux/bogus")
+	}
+}
+
+func TestParseModeEmpty(t *testing.T) {
+	if m := ParseMode(""); m != 0 {
+		t.Errorf("ParseMode(\"\") = %v, want 0", m)
+	}
+	if m := ParseMode("types,json"); m != ModeTypes|ModeJSON {
+		t.Errorf("ParseMode(\"types,json\") = %v, want %v", m, ModeTypes|ModeJSON)
+	}
+}
@@ -0,0 +1,288 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package pkgdoc
+
+import (
+	"bytes"
+	"go/ast"
+	"go/doc"
+	"go/printer"
+	"go/token"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A VersionSet holds the package documentation for several versions of Go,
+// each read from its own GOROOT.
+// The documentation tree for a version is built the first time it is needed.
+type VersionSet struct {
+	mu   sync.Mutex
+	list []*version // newest first
+}
+
+type version struct {
+	name string
+	fs   fs.FS
+	once sync.Once
+	docs *Docs
+}
+
+// Add adds the version with the given name, such as go1.15,
+// whose GOROOT is fsys.
+func (s *VersionSet) Add(name string, fsys fs.FS) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.list = append(s.list, &version{name: name, fs: fsys})
+	sort.Slice(s.list, func(i, j int) bool {
+		return versionLess(s.list[j].name, s.list[i].name)
+	})
+}
+
+// AddDocs adds the version with the given name,
+// whose documentation tree is docs.
+func (s *VersionSet) AddDocs(name string, docs *Docs) {
+	s.Add(name, docs.fs)
+	v := s.lookup(name)
+	v.once.Do(func() { v.docs = docs })
+}
+
+// Names returns the names of the versions in s, newest first.
+func (s *VersionSet) Names() []string {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for _, v := range s.list {
+		names = append(names, v.name)
+	}
+	return names
+}
+
+// Docs returns the documentation tree for the named version,
+// or nil if there is no such version.
+func (s *VersionSet) Docs(name string) *Docs {
+	if s == nil {
+		return nil
+	}
+	v := s.lookup(name)
+	if v == nil {
+		return nil
+	}
+	v.once.Do(func() { v.docs = NewDocs(v.fs) })
+	return v.docs
+}
+
+func (s *VersionSet) lookup(name string) *version {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, v := range s.list {
+		if v.name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// versionLess reports whether the Go version a, such as go1.9 or go1.16rc1,
+// precedes b. Names that are not Go versions sort before all versions.
+func versionLess(a, b string) bool {
+	va, vb := parseVersion(a), parseVersion(b)
+	for i := range va {
+		if va[i] != vb[i] {
+			return va[i] < vb[i]
+		}
+	}
+	return a < b
+}
+
+// parseVersion returns the major, minor and patch numbers of the Go version v
+// and the number of its beta or release candidate, or a large number
+// for a final release.
+func parseVersion(v string) [4]int {
+	const final = 1 << 20
+	var n [4]int
+	if !strings.HasPrefix(v, "go") {
+		return n
+	}
+	v = v[len("go"):]
+	n[3] = final
+	for _, pre := range []string{"beta", "rc"} {
+		if i := strings.Index(v, pre); i >= 0 {
+			n[3], _ = strconv.Atoi(v[i+len(pre):])
+			if pre == "rc" {
+				n[3] += final / 2
+			}
+			v = v[:i]
+			break
+		}
+	}
+	for i, f := range strings.SplitN(v, ".", 3) {
+		n[i], _ = strconv.Atoi(f)
+	}
+	return n
+}
+
+// An APIDiff lists the changes to the exported API of a package
+// between two versions.
+type APIDiff struct {
+	From, To string   // version names
+	Added    []string // features in To but not in From
+	Removed  []string // features in From but not in To
+}
+
+// DiffAPI returns the changes to the exported API of the package
+// documented by from and to, which may be nil if the package
+// does not exist in that version.
+func DiffAPI(fromName string, from *Page, toName string, to *Page) *APIDiff {
+	d := &APIDiff{From: fromName, To: toName}
+	old := make(map[string]bool)
+	for _, f := range API(from) {
+		old[f] = true
+	}
+	for _, f := range API(to) {
+		if old[f] {
+			delete(old, f)
+		} else {
+			d.Added = append(d.Added, f)
+		}
+	}
+	for f := range old {
+		d.Removed = append(d.Removed, f)
+	}
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	return d
+}
+
+// API returns the exported API of the package documented by info,
+// one line per feature, in the style of the files in $GOROOT/api:
+//
+//	const MaxInt8 = 127
+//	func Println(...interface{}) (int, error)
+//	method (*Buffer) Len() int
+//	type Buffer struct
+//	type Buffer struct, embedded io.Reader
+//	type Stringer interface, String() string
+//
+// It returns nil if info has no package documentation.
+func API(info *Page) []string {
+	if info == nil || info.PDoc == nil {
+		return nil
+	}
+	a := &apiWriter{fset: info.FSet}
+	pdoc := info.PDoc
+	a.values("const", pdoc.Consts)
+	a.values("var", pdoc.Vars)
+	a.funcs(pdoc.Funcs)
+	for _, t := range pdoc.Types {
+		a.typ(t)
+		a.values("const", t.Consts)
+		a.values("var", t.Vars)
+		a.funcs(t.Funcs)
+		a.funcs(t.Methods)
+	}
+	sort.Strings(a.features)
+	return a.features
+}
+
+type apiWriter struct {
+	fset     *token.FileSet
+	features []string
+}
+
+func (a *apiWriter) add(f string) {
+	a.features = append(a.features, f)
+}
+
+// node returns the source text for node on a single line.
+func (a *apiWriter) node(node interface{}) string {
+	var buf bytes.Buffer
+	printer.Fprint(&buf, a.fset, node)
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+func (a *apiWriter) values(kind string, list []*doc.Value) {
+	for _, v := range list {
+		for _, spec := range v.Decl.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for _, name := range vs.Names {
+				if !ast.IsExported(name.Name) {
+					continue
+				}
+				f := kind + " " + name.Name
+				if vs.Type != nil {
+					f += " " + a.node(vs.Type)
+				}
+				a.add(f)
+			}
+		}
+	}
+}
+
+func (a *apiWriter) funcs(list []*doc.Func) {
+	for _, fn := range list {
+		if !ast.IsExported(fn.Name) {
+			continue
+		}
+		decl := *fn.Decl
+		decl.Doc = nil
+		decl.Body = nil
+		f := a.node(&decl)
+		if decl.Recv != nil {
+			f = "method " + strings.TrimPrefix(f, "func ")
+		}
+		a.add(f)
+	}
+}
+
+func (a *apiWriter) typ(t *doc.Type) {
+	for _, spec := range t.Decl.Specs {
+		ts := spec.(*ast.TypeSpec)
+		if ts.Name.Name != t.Name {
+			continue
+		}
+		prefix := "type " + t.Name
+		switch typ := ts.Type.(type) {
+		case *ast.StructType:
+			prefix += " struct"
+			a.add(prefix)
+			for _, field := range typ.Fields.List {
+				if len(field.Names) == 0 {
+					a.add(prefix + ", embedded " + a.node(field.Type))
+					continue
+				}
+				for _, name := range field.Names {
+					if ast.IsExported(name.Name) {
+						a.add(prefix + ", " + name.Name + " " + a.node(field.Type))
+					}
+				}
+			}
+		case *ast.InterfaceType:
+			prefix += " interface"
+			a.add(prefix)
+			for _, m := range typ.Methods.List {
+				if len(m.Names) == 0 {
+					a.add(prefix + ", embedded " + a.node(m.Type))
+					continue
+				}
+				for _, name := range m.Names {
+					if ast.IsExported(name.Name) {
+						a.add(prefix + ", " + name.Name + strings.TrimPrefix(a.node(m.Type), "func"))
+					}
+				}
+			}
+		default:
+			a.add(prefix + " " + a.node(ts.Type))
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package pkgdoc
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestVersionSet(t *testing.T) {
+	var s VersionSet
+	for _, v := range []string{"go1.9", "go1.16rc1", "go1.15.2", "go1.16", "go1.16beta1", "go1.15"} {
+		s.Add(v, fstest.MapFS{})
+	}
+	got := strings.Join(s.Names(), " ")
+	want := "go1.16 go1.16rc1 go1.16beta1 go1.15.2 go1.15 go1.9"
+	if got != want {
+		t.Errorf("Names() = %q; want %q", got, want)
+	}
+	if s.Docs("go1.15") == nil {
+		t.Errorf("Docs(go1.15) = nil")
+	}
+	if s.Docs("go1.14") != nil {
+		t.Errorf("Docs(go1.14) != nil")
+	}
+}
+
+func TestDiffAPI(t *testing.T) {
+	old := NewDocs(fstest.MapFS{
+		"src/p/p.go": {Data: []byte(`package p
+
+const C = 1
+
+type T struct {
+	A int
+	b int
+}
+
+func (T) M() {}
+
+func F(x int) {}
+
+type I interface {
+	M()
+}
+`)},
+	})
+	new := NewDocs(fstest.MapFS{
+		"src/p/p.go": {Data: []byte(`package p
+
+const C = 1
+
+var V string
+
+type T struct {
+	A int
+	B []byte
+	c int
+}
+
+func (*T) M() {}
+
+func F(x int, y string) {}
+
+func NewT() *T { return nil }
+
+type I interface {
+	M()
+	N() error
+}
+`)},
+	})
+
+	diff := DiffAPI("go1.1", Doc(old, "/src/p", "p", 0, "", ""), "go1.2", Doc(new, "/src/p", "p", 0, "", ""))
+	added := strings.Join(diff.Added, "\n")
+	wantAdded := strings.Join([]string{
+		"func F(x int, y string)",
+		"func NewT() *T",
+		"method (*T) M()",
+		"type I interface, N() error",
+		"type T struct, B []byte",
+		"var V string",
+	}, "\n")
+	if added != wantAdded {
+		t.Errorf("Added:\n%s\nwant:\n%s", added, wantAdded)
+	}
+	removed := strings.Join(diff.Removed, "\n")
+	wantRemoved := strings.Join([]string{
+		"func F(x int)",
+		"method (T) M()",
+	}, "\n")
+	if removed != wantRemoved {
+		t.Errorf("Removed:\n%s\nwant:\n%s", removed, wantRemoved)
+	}
+
+	// A package missing in the old version has its whole API added.
+	diff = DiffAPI("go1.0", nil, "go1.1", Doc(old, "/src/p", "p", 0, "", ""))
+	if len(diff.Added) != 7 || len(diff.Removed) != 0 {
+		t.Errorf("DiffAPI(nil, old) = +%v -%v; want 7 added", diff.Added, diff.Removed)
+	}
+}
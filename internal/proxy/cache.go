@@ -0,0 +1,102 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"golang.org/x/website/internal/memcache"
+)
+
+// compileTimeout limits a backend compile shared by several requests,
+// which is not canceled when any one of them is.
+const compileTimeout = 60 * time.Second
+
+// compileCacheKey returns the cache key for the result
+// of compiling body for a response of the given version.
+func compileCacheKey(version, body string) string {
+	return fmt.Sprintf("compile-%x", sha256.Sum256([]byte(version+"\n"+body)))
+}
+
+// cachedCompile returns the result of compiling req for a response of
+// the given version, from the cache if possible. Concurrent calls for
+// the same program and version share a single backend call.
+func (s server) cachedCompile(ctx context.Context, version string, req *Request) (*Response, error) {
+	key := compileCacheKey(version, req.Body)
+	res := new(Response)
+	if err := s.memcache.Get(ctx, key, res); err == nil {
+		return res, nil
+	} else if err != memcache.ErrCacheMiss {
+		log.Printf("ERROR getting compile result from cache: %v", err)
+	}
+
+	return s.flights.do(ctx, key, func() (*Response, error) {
+		// Use a new context so that the call is not canceled
+		// if the request that started it goes away.
+		ctx, cancel := context.WithTimeout(context.Background(), compileTimeout)
+		defer cancel()
+		res, err := s.backend.Compile(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		// A program that times out might finish when the backend is less busy.
+		if res.Errors != timeoutError {
+			item := &memcache.Item{Key: key, Object: res, Expiration: expires}
+			if err := s.memcache.Set(ctx, item); err != nil {
+				log.Printf("ERROR caching compile result: %v", err)
+			}
+		}
+		return res, nil
+	})
+}
+
+// A flightGroup collapses concurrent calls with the same key into one.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+// A flight is a call in progress.
+type flight struct {
+	done chan bool // closed when res and err are set
+	res  *Response
+	err  error
+}
+
+// do returns the result of fn, calling it only if no call with the
+// same key is in progress and otherwise waiting for that call.
+// If ctx is canceled while waiting, do returns early with ctx.Err(),
+// leaving the call to finish for the others waiting for it.
+func (g *flightGroup) do(ctx context.Context, key string, fn func() (*Response, error)) (*Response, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flight)
+	}
+	f, ok := g.calls[key]
+	if !ok {
+		f = &flight{done: make(chan bool)}
+		g.calls[key] = f
+		go func() {
+			f.res, f.err = fn()
+			g.mu.Lock()
+			delete(g.calls, key)
+			g.mu.Unlock()
+			close(f.done)
+		}()
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.res, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/website/internal/memcache"
+)
+
+// countingBackend counts compiles, blocking each until release is closed.
+type countingBackend struct {
+	mu       sync.Mutex
+	compiles map[string]int
+	started  chan bool
+	release  chan bool
+	timeout  bool
+}
+
+func (b *countingBackend) Compile(ctx context.Context, req *Request) (*Response, error) {
+	b.mu.Lock()
+	b.compiles[req.Body]++
+	b.mu.Unlock()
+	b.started <- true
+	<-b.release
+	res := &Response{Events: []Event{{Message: "ran " + req.Body, Kind: "stdout"}}}
+	if b.timeout {
+		res.Errors = timeoutError
+	}
+	return res, nil
+}
+
+func (b *countingBackend) Share(ctx context.Context, body []byte) (string, error) {
+	return "", nil
+}
+
+func TestCompileCache(t *testing.T) {
+	b := &countingBackend{compiles: make(map[string]int), started: make(chan bool, 100), release: make(chan bool)}
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, b, memcache.NewLRU(1<<20, 0))
+	compile := func(body, version string) string {
+		req := httptest.NewRequest("POST", "/compile", strings.NewReader(url.Values{"body": {body}, "version": {version}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	// Concurrent requests share one backend call.
+	const n = 10
+	var wg sync.WaitGroup
+	out := make([]string, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			out[i] = compile("hello", "2")
+		}(i)
+	}
+	<-b.started
+	close(b.release)
+	wg.Wait()
+	want := `{"Errors":"","Events":[{"Message":"ran hello","Kind":"stdout","Delay":0}]}`
+	for i, o := range out {
+		if o != want {
+			t.Errorf("response %d = %s, want %s", i, o, want)
+		}
+	}
+
+	// Later requests are served from the cache,
+	// but other versions and programs are compiled again.
+	compile("hello", "2")
+	if got := compile("hello", "1"); got != `{"compile_errors":"","output":"ran hello"}` {
+		t.Errorf("version 1 response = %s", got)
+	}
+	compile("hello", "")
+	compile("bye", "2")
+	b.timeout = true
+	compile("slow", "2")
+	compile("slow", "2")
+
+	want2 := map[string]int{"hello": 2, "bye": 1, "slow": 2}
+	for body, n := range want2 {
+		if b.compiles[body] != n {
+			t.Errorf("%s compiled %d times, want %d", body, b.compiles[body], n)
+		}
+	}
+}
+
+func TestFlightGroupCancel(t *testing.T) {
+	var g flightGroup
+	release := make(chan bool)
+	fn := func() (*Response, error) {
+		<-release
+		return &Response{Errors: "done"}, nil
+	}
+
+	// A canceled caller stops waiting, but the call goes on for others.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := g.do(ctx, "k", fn); err != context.Canceled {
+		t.Errorf("canceled do returned %v, want context.Canceled", err)
+	}
+	g.mu.Lock()
+	_, running := g.calls["k"]
+	g.mu.Unlock()
+	if !running {
+		t.Errorf("call stopped when its caller was canceled")
+	}
+	close(release)
+	res, err := g.do(context.Background(), "k", fn)
+	if err != nil || res.Errors != "done" {
+		t.Errorf("do = %+v, %v, want done", res, err)
+	}
+}
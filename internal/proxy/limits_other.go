@@ -0,0 +1,21 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build windows || plan9
+// +build windows plan9
+
+package proxy
+
+import "os/exec"
+
+// The local playground needs Unix resource limits and process groups.
+const limitsSupported = false
+
+func limitedCommand(bin string) *exec.Cmd {
+	panic("unreachable")
+}
+
+func killLimited(cmd *exec.Cmd) {
+	panic("unreachable")
+}
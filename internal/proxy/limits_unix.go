@@ -0,0 +1,29 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package proxy
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+const limitsSupported = true
+
+// limitedCommand returns a command running bin, a program built with
+// the limits package (see limitsSource), in its own process group so
+// that killLimited can kill any processes it starts too.
+func limitedCommand(bin string) *exec.Cmd {
+	cmd := exec.Command(bin)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return cmd
+}
+
+// killLimited kills the process group started by cmd.
+func killLimited(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
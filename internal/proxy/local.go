@@ -0,0 +1,394 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/website/internal/storage"
+)
+
+// A LocalConfig configures a Local backend.
+// Zero fields take the defaults shown.
+type LocalConfig struct {
+	GoCmd       string        // go command used to build programs ("go")
+	BuildTime   time.Duration // limit on build time (30s)
+	RunTime     time.Duration // limit on run time (10s)
+	CPUTime     time.Duration // limit on CPU time used by a run (RunTime)
+	Memory      int64         // limit on memory used by a run, in bytes (512 MB)
+	MaxOutput   int           // limit on output of a run, in bytes (1 MB)
+	Concurrency int           // number of programs built or run at once (4)
+
+	// DB stores shared programs as "Snippet" entities.
+	// If nil, they are kept in memory.
+	DB storage.DB
+}
+
+// A Local backend builds and runs programs on this machine,
+// each in a subprocess limited in time, CPU time, memory and output,
+// and stores shared programs in a storage.DB.
+//
+// Where the system allows it (see Isolated), each program is also
+// isolated from the machine: it runs as an unprivileged user in its own
+// Linux namespaces, with no network and only its own directory as its
+// file system. Otherwise the limits protect the server from runaway
+// programs, not from malicious ones: programs run as the server's user,
+// with its file system and network access. Serve such a Local backend
+// only to users of the server's machine, on a loopback address.
+type Local struct {
+	cfg      LocalConfig
+	sem      chan bool
+	isolated bool
+}
+
+// A Snippet is a shared program, stored keyed by its ID.
+type Snippet struct {
+	Body []byte `datastore:",noindex"`
+}
+
+// NewLocal returns a Local backend configured by cfg.
+func NewLocal(cfg LocalConfig) (*Local, error) {
+	if !limitsSupported {
+		return nil, errors.New("local playground not supported on this system")
+	}
+	if cfg.GoCmd == "" {
+		cfg.GoCmd = "go"
+	}
+	if _, err := exec.LookPath(cfg.GoCmd); err != nil {
+		return nil, err
+	}
+	if cfg.BuildTime == 0 {
+		cfg.BuildTime = 30 * time.Second
+	}
+	if cfg.RunTime == 0 {
+		cfg.RunTime = 10 * time.Second
+	}
+	if cfg.CPUTime == 0 {
+		cfg.CPUTime = cfg.RunTime
+	}
+	if cfg.Memory == 0 {
+		cfg.Memory = 512 << 20
+	}
+	if cfg.MaxOutput == 0 {
+		cfg.MaxOutput = 1 << 20
+	}
+	if cfg.Concurrency == 0 {
+		cfg.Concurrency = 4
+	}
+	if cfg.DB == nil {
+		cfg.DB = storage.NewMemory()
+	}
+	return &Local{cfg: cfg, sem: make(chan bool, cfg.Concurrency), isolated: canIsolate()}, nil
+}
+
+// Isolated reports whether l isolates the programs it runs from
+// this machine's files, network and processes.
+func (l *Local) Isolated() bool {
+	return l.isolated
+}
+
+// limitsSource is the source of a package built into each program
+// to set its resource limits. The packages a program imports are
+// initialized before it, so the limits apply before any of the
+// program's own code runs, and without privileges the program cannot
+// raise them again.
+//
+// The memory limit applies to the data segment, not the address space:
+// Go programs reserve far more address space than they use.
+const limitsSource = `// Package limits sets the resource limits of the program.
+package limits
+
+import "syscall"
+
+func init() {
+	set(syscall.RLIMIT_CPU, &syscall.Rlimit{Cur: %[1]d, Max: %[1]d})
+	set(syscall.RLIMIT_DATA, &syscall.Rlimit{Cur: %[2]d, Max: %[2]d})
+	set(syscall.RLIMIT_FSIZE, &syscall.Rlimit{Cur: %[3]d, Max: %[3]d})
+}
+
+func set(resource int, lim *syscall.Rlimit) {
+	if err := syscall.Setrlimit(resource, lim); err != nil {
+		panic(err)
+	}
+}
+`
+
+// timeoutError is the Response.Errors reported for a program
+// that runs too long, as by play.golang.org.
+const timeoutError = "process took too long"
+
+// Compile builds the program in req and, if it builds, runs it.
+func (l *Local) Compile(ctx context.Context, req *Request) (*Response, error) {
+	select {
+	case l.sem <- true:
+		defer func() { <-l.sem }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	dir, err := ioutil.TempDir("", "play")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	cpu := int64((l.cfg.CPUTime + 999e6) / 1e9) // round up to whole seconds
+	files := map[string]string{
+		"go.mod":                    "module play\n",
+		"prog.go":                   req.Body,
+		"limits.go":                 "package main\n\nimport _ \"play/internal/limits\"\n",
+		"internal/limits/limits.go": fmt.Sprintf(limitsSource, cpu, l.cfg.Memory, l.cfg.MaxOutput),
+	}
+	for name, data := range files {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(name, []byte(data), 0666); err != nil {
+			return nil, err
+		}
+	}
+
+	bin := filepath.Join(dir, "prog")
+	if msg, err := l.build(ctx, dir, bin); err != nil || msg != "" {
+		return &Response{Errors: msg}, err
+	}
+	return l.run(ctx, dir, bin)
+}
+
+// build builds the program in dir into bin.
+// It returns the compiler's error messages if the build fails.
+func (l *Local) build(ctx context.Context, dir, bin string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, l.cfg.BuildTime)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, l.cfg.GoCmd, "build", "-o", bin, ".")
+	cmd.Dir = dir
+	// Build without network access or cgo,
+	// but share the build cache with the server's user.
+	cmd.Env = append(os.Environ(), "GO111MODULE=on", "GOPROXY=off", "GOFLAGS=-mod=mod", "CGO_ENABLED=0")
+	out, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", errors.New("build took too long")
+	}
+	if _, ok := err.(*exec.ExitError); ok {
+		msg := strings.TrimPrefix(string(out), "# play\n")
+		return strings.ReplaceAll(msg, dir+string(filepath.Separator), ""), nil
+	}
+	return "", err
+}
+
+// run runs the program bin built in dir, isolated if possible,
+// recording its output as timed events.
+func (l *Local) run(ctx context.Context, dir, bin string) (*Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, l.cfg.RunTime)
+	defer cancel()
+
+	rec := &eventRecorder{start: time.Now(), max: l.cfg.MaxOutput, overflow: cancel}
+	cmd := limitedCommand(bin)
+	cmd.Dir = dir
+	cmd.Env = []string{"HOME=" + dir, "TMPDIR=" + dir, "PATH=" + os.Getenv("PATH")}
+	if l.isolated {
+		if err := isolate(cmd, dir); err != nil {
+			return nil, err
+		}
+	}
+	cmd.Stdout = rec.writer("stdout")
+	cmd.Stderr = rec.writer("stderr")
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	done := make(chan bool)
+	go func() {
+		select {
+		case <-ctx.Done():
+			killLimited(cmd)
+		case <-done:
+		}
+	}()
+	err := cmd.Wait()
+	close(done)
+
+	res := &Response{Events: rec.events}
+	switch {
+	case rec.truncated:
+		res.Events = append(res.Events, Event{Message: "\n[output truncated]\n", Kind: "stderr"})
+		res.Status = 1
+	case ctx.Err() == context.DeadlineExceeded:
+		res.Errors = timeoutError
+	case err != nil:
+		ee, ok := err.(*exec.ExitError)
+		if !ok {
+			return nil, err
+		}
+		res.Status = ee.ExitCode()
+		if res.Status < 0 {
+			// Killed by a signal, such as SIGXCPU for too much CPU time.
+			res.Events = append(res.Events, Event{Message: "\n" + ee.Error() + "\n", Kind: "stderr"})
+			res.Status = 1
+		}
+	}
+	return res, nil
+}
+
+// An eventRecorder records the output of a program as Events,
+// timing each write since the previous one.
+type eventRecorder struct {
+	mu        sync.Mutex
+	start     time.Time // time of the last event
+	events    []Event
+	size      int
+	max       int
+	truncated bool
+	overflow  func() // called when output exceeds max
+}
+
+func (r *eventRecorder) writer(kind string) *eventWriter {
+	return &eventWriter{r, kind}
+}
+
+type eventWriter struct {
+	r    *eventRecorder
+	kind string
+}
+
+func (w *eventWriter) Write(b []byte) (int, error) {
+	r := w.r
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.truncated {
+		return len(b), nil
+	}
+	msg := b
+	if r.size+len(msg) > r.max {
+		msg = msg[:r.max-r.size]
+		r.truncated = true
+		r.overflow()
+	}
+	r.size += len(msg)
+
+	now := time.Now()
+	delay := now.Sub(r.start)
+	n := len(r.events)
+	if n > 0 && r.events[n-1].Kind == w.kind && delay < time.Millisecond {
+		// Merge writes made together, like the playground.
+		r.events[n-1].Message += string(msg)
+	} else {
+		r.events = append(r.events, Event{Message: string(msg), Kind: w.kind, Delay: delay})
+		r.start = now
+	}
+	return len(b), nil
+}
+
+// Share stores body under an ID derived from its SHA256,
+// so that sharing the same program again returns the same ID.
+func (l *Local) Share(ctx context.Context, body []byte) (string, error) {
+	sum := sha256.Sum256(body)
+	hash := base64.RawURLEncoding.EncodeToString(sum[:])
+	// Use the shortest prefix of at least 11 characters
+	// (like play.golang.org) that is not already taken
+	// by a different program.
+	for n := 11; n <= len(hash); n++ {
+		id := hash[:n]
+		var old Snippet
+		switch err := l.cfg.DB.Get(ctx, "Snippet", id, &old); {
+		case err == storage.ErrNotFound:
+			if err := l.cfg.DB.Put(ctx, "Snippet", id, &Snippet{Body: body}); err != nil {
+				return "", err
+			}
+			return id, nil
+		case err != nil:
+			return "", err
+		case bytes.Equal(old.Body, body):
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("all IDs for %s taken", hash)
+}
+
+// serveSnippet serves /play/p/{id}.go, the source of a shared program,
+// and /play/p/{id}, a page for editing and running it.
+func (l *Local) serveSnippet(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/play/p/")
+	if !validSnippetID.MatchString(name) {
+		http.NotFound(w, r)
+		return
+	}
+	id := strings.TrimSuffix(name, ".go")
+	var snip Snippet
+	if err := l.cfg.DB.Get(r.Context(), "Snippet", id, &snip); err == storage.ErrNotFound {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		log.Printf("ERROR reading snippet %s: %v", id, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if strings.HasSuffix(name, ".go") {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write(snip.Body)
+		return
+	}
+	data := struct {
+		ID   string
+		Body string
+	}{id, string(snip.Body)}
+	if err := snippetTemplate.Execute(w, &data); err != nil {
+		log.Printf("ERROR snippetTemplate: %v", err)
+	}
+}
+
+var snippetTemplate = template.Must(template.New("snippet").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Go Playground - {{.ID}}</title>
+<link type="text/css" rel="stylesheet" href="/lib/godoc/style.css">
+<script src="/lib/godoc/jquery.js"></script>
+<script src="/lib/godoc/playground.js"></script>
+<style>
+#code { width: 100%; height: 24em; font-family: Menlo, monospace; font-size: 0.875rem; }
+#output { white-space: pre-wrap; font-family: Menlo, monospace; font-size: 0.875rem; }
+#output .stderr { color: #a00; }
+#output .system { color: #888; }
+</style>
+</head>
+<body>
+<h1>Go Playground</h1>
+<p>Shared program {{.ID}} (<a href="/play/p/{{.ID}}.go">source</a>), run on this server.</p>
+<textarea id="code" spellcheck="false">{{.Body}}</textarea>
+<p>
+<button id="run">Run</button>
+<button id="fmt">Format</button>
+<button id="share">Share</button>
+</p>
+<div id="output"></div>
+<script>
+playground({
+	codeEl: '#code',
+	outputEl: '#output',
+	runEl: '#run',
+	fmtEl: '#fmt',
+	shareEl: '#share',
+	shareRedirect: '/play/p/',
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,282 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLocal(t *testing.T) *Local {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+	l, err := NewLocal(LocalConfig{RunTime: 2 * time.Second})
+	if err != nil {
+		t.Skip(err)
+	}
+	return l
+}
+
+func TestLocalCompile(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds programs")
+	}
+	l := newTestLocal(t)
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		desc   string
+		body   string
+		errors string // substring of Response.Errors
+		output string // concatenated output
+		status int
+	}{
+		{
+			desc: "hello",
+			body: `package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+func main() {
+	fmt.Println("hello")
+	time.Sleep(200 * time.Millisecond)
+	fmt.Fprintln(os.Stderr, "world")
+}`,
+			output: "hello\nworld\n",
+		},
+		{
+			desc:   "build error",
+			body:   "package main\n\nfunc main() { x }\n",
+			errors: "./prog.go:3:15: undefined: x",
+		},
+		{
+			desc:   "exit status",
+			body:   "package main\n\nimport \"os\"\n\nfunc main() { os.Exit(3) }\n",
+			status: 3,
+		},
+		{
+			desc:   "timeout",
+			body:   "package main\n\nimport \"time\"\n\nfunc main() { println(\"start\"); time.Sleep(time.Hour) }\n",
+			errors: timeoutError,
+			output: "start\n",
+		},
+	} {
+		res, err := l.Compile(ctx, &Request{Body: tc.body})
+		if err != nil {
+			t.Errorf("%s: %v", tc.desc, err)
+			continue
+		}
+		if !strings.Contains(res.Errors, tc.errors) || tc.errors == "" && res.Errors != "" {
+			t.Errorf("%s: Errors = %q, want %q", tc.desc, res.Errors, tc.errors)
+		}
+		if out := flatten(res.Events); out != tc.output {
+			t.Errorf("%s: output = %q, want %q", tc.desc, out, tc.output)
+		}
+		if res.Status != tc.status {
+			t.Errorf("%s: Status = %d, want %d", tc.desc, res.Status, tc.status)
+		}
+		if tc.desc == "hello" {
+			if len(res.Events) != 2 || res.Events[0].Kind != "stdout" || res.Events[1].Kind != "stderr" || res.Events[1].Delay < 200*time.Millisecond {
+				t.Errorf("%s: events = %+v, want stdout then stderr after 200ms", tc.desc, res.Events)
+			}
+		}
+	}
+}
+
+func TestLocalIsolation(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds programs")
+	}
+	l := newTestLocal(t)
+	if !l.Isolated() {
+		t.Skip("cannot isolate programs on this system")
+	}
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		desc   string
+		body   string
+		output string // concatenated output, unless fail
+		fail   bool   // program must exit with an error
+	}{
+		{
+			desc: "files",
+			body: `package main
+
+import (
+	"fmt"
+	"io/ioutil"
+)
+
+func main() {
+	_, err := ioutil.ReadFile("/etc/passwd")
+	fmt.Println(err != nil)
+}`,
+			output: "true\n",
+		},
+		{
+			desc: "network",
+			body: `package main
+
+import (
+	"fmt"
+	"net"
+)
+
+func main() {
+	_, err := net.Dial("tcp", "8.8.8.8:53")
+	fmt.Println(err != nil)
+}`,
+			output: "true\n",
+		},
+		{
+			desc: "user",
+			body: `package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() { fmt.Println(os.Getuid(), os.Getpid()) }`,
+			output: "65534 1\n",
+		},
+		{
+			desc: "memory",
+			body: `package main
+
+import "fmt"
+
+var b []byte
+
+func main() {
+	b = make([]byte, 1<<30)
+	fmt.Println("allocated")
+}`,
+			fail: true,
+		},
+	} {
+		res, err := l.Compile(ctx, &Request{Body: tc.body})
+		if err != nil {
+			t.Errorf("%s: %v", tc.desc, err)
+			continue
+		}
+		if tc.fail {
+			if res.Status == 0 {
+				t.Errorf("%s: program succeeded, want failure", tc.desc)
+			}
+			continue
+		}
+		if res.Errors != "" {
+			t.Errorf("%s: Errors = %q", tc.desc, res.Errors)
+		}
+		if out := flatten(res.Events); out != tc.output {
+			t.Errorf("%s: output = %q, want %q", tc.desc, out, tc.output)
+		}
+	}
+}
+
+func TestLocalShare(t *testing.T) {
+	l := newTestLocal(t)
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, l, nil)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	src := "package main\n\nfunc main() { println(\"<hi>\") }\n"
+	w := do("POST", "/share", src)
+	id := w.Body.String()
+	if w.Code != 200 || len(id) != 11 {
+		t.Fatalf("share: %d %q", w.Code, id)
+	}
+	if w := do("POST", "/share", src); w.Body.String() != id {
+		t.Errorf("sharing again returned %q, want %q", w.Body, id)
+	}
+	if w := do("GET", "/play/p/"+id+".go", ""); w.Body.String() != src {
+		t.Errorf("source = %q, want %q", w.Body, src)
+	}
+	if w := do("GET", "/play/p/"+id, ""); w.Code != 200 || !strings.Contains(w.Body.String(), "println(&#34;&lt;hi&gt;&#34;)") {
+		t.Errorf("snippet page: %d\n%s", w.Code, w.Body)
+	}
+	for _, path := range []string{"/play/p/nosuchsnippet", "/play/p/a.b"} {
+		if w := do("GET", path, ""); w.Code != 404 {
+			t.Errorf("GET %s = %d, want 404", path, w.Code)
+		}
+	}
+	if w := do("POST", "/share", strings.Repeat("x", maxShareSize+1)); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("share of large snippet = %d, want 413", w.Code)
+	}
+}
+
+func TestRemote(t *testing.T) {
+	play := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/compile":
+			var req Request
+			json.NewDecoder(r.Body).Decode(&req)
+			json.NewEncoder(w).Encode(&Response{Events: []Event{{Message: req.Body, Kind: "stdout"}}})
+		case "/share":
+			if r.Header.Get("Content-Type") != "text/plain" {
+				http.Error(w, "bad content type", http.StatusUnsupportedMediaType)
+				return
+			}
+			w.Write([]byte("abc123"))
+		}
+	}))
+	defer play.Close()
+
+	mux := http.NewServeMux()
+	RegisterHandlers(mux, Remote(play.URL), nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/compile", strings.NewReader(url.Values{"body": {"hi"}}.Encode())))
+	// The request has no form content type, so the body is empty.
+	if want := `{"compile_errors":"","output":""}`; w.Body.String() != want {
+		t.Errorf("compile: %s, want %s", w.Body, want)
+	}
+
+	req := httptest.NewRequest("POST", "/compile", strings.NewReader(url.Values{"body": {"hi"}, "version": {"2"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if want := `{"Errors":"","Events":[{"Message":"hi","Kind":"stdout","Delay":0}]}`; w.Body.String() != want {
+		t.Errorf("compile: %s, want %s", w.Body, want)
+	}
+
+	// Shares are passed through, with the request's content type
+	// and the playground's status.
+	req = httptest.NewRequest("POST", "/share", strings.NewReader("package main"))
+	req.Header.Set("Content-Type", "text/plain")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != 200 || w.Body.String() != "abc123" {
+		t.Errorf("share: %d %q, want 200 abc123", w.Code, w.Body)
+	}
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/share", strings.NewReader("package main")))
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("share without content type: %d, want %d", w.Code, http.StatusUnsupportedMediaType)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/play/p/abc123", nil))
+	if loc := w.Header().Get("Location"); loc != "https://play.golang.org/p/abc123" {
+		t.Errorf("snippet redirect to %q", loc)
+	}
+}
@@ -2,8 +2,10 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Package proxy proxies requests to the playground's compile and share handlers.
-// It is designed to run only on the instance of godoc that serves golang.org.
+// Package proxy serves the playground's compile and share handlers
+// used by the Run and Share buttons of examples, codewalks and the tour.
+// By default it proxies them to play.golang.org; a Local backend instead
+// compiles and runs programs on this machine and stores shares itself.
 package proxy
 
 import (
@@ -15,10 +17,12 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"regexp"
 	"strings"
 	"time"
 
 	"golang.org/x/website/internal/env"
+	"golang.org/x/website/internal/memcache"
 )
 
 const playgroundURL = "https://play.golang.org"
@@ -30,6 +34,7 @@ type Request struct {
 type Response struct {
 	Errors string
 	Events []Event
+	Status int `json:",omitempty"` // exit status of the program, if not zero
 }
 
 type Event struct {
@@ -41,12 +46,51 @@ type Event struct {
 const expires = 7 * 24 * time.Hour // 1 week
 var cacheControlHeader = fmt.Sprintf("public, max-age=%d", int(expires.Seconds()))
 
-func RegisterHandlers(mux *http.ServeMux) {
-	mux.HandleFunc("/compile", compile)
-	mux.HandleFunc("/share", share)
+// A Backend compiles and runs programs and stores shared programs
+// for the /compile and /share handlers.
+type Backend interface {
+	// Compile compiles and runs the program in req.
+	// Compilation errors and timeouts are reported in the Response;
+	// an error means the backend itself failed.
+	Compile(ctx context.Context, req *Request) (*Response, error)
+
+	// Share stores the program source body and returns its ID,
+	// as used in /play/p/{id} and https://play.golang.org/p/{id}.
+	Share(ctx context.Context, body []byte) (id string, err error)
 }
 
-func compile(w http.ResponseWriter, r *http.Request) {
+// maxShareSize is the maximum size of a program shared
+// with a backend other than Remote, as play.golang.org enforces.
+const maxShareSize = 64 << 10
+
+// RegisterHandlers registers the /compile and /share handlers on mux,
+// served by the backend b and caching compile results in mc.
+// If b is nil, requests are proxied to play.golang.org.
+// The memcache client mc may be nil, to disable caching.
+//
+// It also registers /play/p/, where shared programs can be viewed:
+// a Local backend serves them itself, and others redirect to play.golang.org.
+func RegisterHandlers(mux *http.ServeMux, b Backend, mc memcache.Client) {
+	if b == nil {
+		b = Remote(playgroundURL)
+	}
+	s := server{b, memcache.WithCodec(mc, memcache.Gob), new(flightGroup)}
+	mux.HandleFunc("/compile", s.compile)
+	mux.HandleFunc("/share", s.share)
+	if l, ok := b.(*Local); ok {
+		mux.HandleFunc("/play/p/", l.serveSnippet)
+	} else {
+		mux.Handle("/play/p/", http.StripPrefix("/play/p/", http.HandlerFunc(redirectSnippet)))
+	}
+}
+
+type server struct {
+	backend  Backend
+	memcache *memcache.CodecClient
+	flights  *flightGroup
+}
+
+func (s server) compile(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		http.Error(w, "I only answer to POST requests.", http.StatusMethodNotAllowed)
 		return
@@ -55,16 +99,20 @@ func compile(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	body := r.FormValue("body")
-	res := &Response{}
+	version := r.FormValue("version")
+	if version != "2" {
+		version = "1"
+	}
 	req := &Request{Body: body}
-	if err := makeCompileRequest(ctx, req, res); err != nil {
+	res, err := s.cachedCompile(ctx, version, req)
+	if err != nil {
 		log.Printf("ERROR compile error: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
 	var out interface{}
-	switch r.FormValue("version") {
+	switch version {
 	case "2":
 		out = res
 	default: // "1"
@@ -86,52 +134,70 @@ func compile(w http.ResponseWriter, r *http.Request) {
 	w.Write(b)
 }
 
-// makePlaygroundRequest sends the given Request to the playground compile
-// endpoint and stores the response in the given Response.
-func makeCompileRequest(ctx context.Context, req *Request, res *Response) error {
+// Remote returns a Backend that proxies requests to the playground
+// at baseURL, such as "https://play.golang.org".
+func Remote(baseURL string) Backend {
+	return remote(strings.TrimSuffix(baseURL, "/"))
+}
+
+type remote string
+
+// Compile sends the given Request to the playground compile endpoint.
+func (b remote) Compile(ctx context.Context, req *Request) (*Response, error) {
 	reqJ, err := json.Marshal(req)
 	if err != nil {
-		return fmt.Errorf("marshalling request: %v", err)
+		return nil, fmt.Errorf("marshalling request: %v", err)
 	}
-	hReq, _ := http.NewRequest("POST", playgroundURL+"/compile", bytes.NewReader(reqJ))
+	hReq, _ := http.NewRequest("POST", string(b)+"/compile", bytes.NewReader(reqJ))
 	hReq.Header.Set("Content-Type", "application/json")
 	hReq = hReq.WithContext(ctx)
 
 	r, err := http.DefaultClient.Do(hReq)
 	if err != nil {
-		return fmt.Errorf("making request: %v", err)
+		return nil, fmt.Errorf("making request: %v", err)
 	}
 	defer r.Body.Close()
 
 	if r.StatusCode != http.StatusOK {
 		b, _ := ioutil.ReadAll(r.Body)
-		return fmt.Errorf("bad status: %v body:\n%s", r.Status, b)
+		return nil, fmt.Errorf("bad status: %v body:\n%s", r.Status, b)
 	}
 
+	res := new(Response)
 	if err := json.NewDecoder(r.Body).Decode(res); err != nil {
-		return fmt.Errorf("unmarshalling response: %v", err)
+		return nil, fmt.Errorf("unmarshalling response: %v", err)
 	}
-	return nil
+	return res, nil
 }
 
-// flatten takes a sequence of Events and returns their contents, concatenated.
-func flatten(seq []Event) string {
-	var buf bytes.Buffer
-	for _, e := range seq {
-		buf.WriteString(e.Message)
+// Share sends body to the playground share endpoint.
+// The /share handler proxies requests with serveShare instead,
+// passing the playground's response through unchanged.
+func (b remote) Share(ctx context.Context, body []byte) (string, error) {
+	req, _ := http.NewRequest("POST", string(b)+"/share", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req = req.WithContext(ctx)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
 	}
-	return buf.String()
-}
-
-func share(w http.ResponseWriter, r *http.Request) {
-	if googleCN(r) {
-		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
-		return
+	defer resp.Body.Close()
+	id, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return "", err
 	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("bad status: %v body:\n%s", resp.Status, id)
+	}
+	return string(id), nil
+}
 
+// serveShare proxies the share request r to the playground,
+// copying its response, status and content type to w.
+func (b remote) serveShare(w http.ResponseWriter, r *http.Request) {
 	// HACK(cbro): use a simple proxy rather than httputil.ReverseProxy because of Issue #28168.
 	// TODO: investigate using ReverseProxy with a Director, unsetting whatever's necessary to make that work.
-	req, _ := http.NewRequest("POST", playgroundURL+"/share", r.Body)
+	req, _ := http.NewRequest("POST", string(b)+"/share", r.Body)
 	req.Header.Set("Content-Type", r.Header.Get("Content-Type"))
 	req = req.WithContext(r.Context())
 	resp, err := http.DefaultClient.Do(req)
@@ -152,6 +218,67 @@ func share(w http.ResponseWriter, r *http.Request) {
 	io.Copy(w, resp.Body)
 }
 
+// redirectSnippet redirects /play/p/{id} to the program on play.golang.org.
+func redirectSnippet(w http.ResponseWriter, r *http.Request) {
+	if !validSnippetID.MatchString(r.URL.Path) {
+		http.NotFound(w, r)
+		return
+	}
+	http.Redirect(w, r, playgroundURL+"/p/"+r.URL.Path, http.StatusFound)
+}
+
+var validSnippetID = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.go)?$`)
+
+// flatten takes a sequence of Events and returns their contents, concatenated.
+func flatten(seq []Event) string {
+	var buf bytes.Buffer
+	for _, e := range seq {
+		buf.WriteString(e.Message)
+	}
+	return buf.String()
+}
+
+func (s server) share(w http.ResponseWriter, r *http.Request) {
+	if b, ok := s.backend.(remote); ok {
+		// Pass the request and response through as they are:
+		// the playground checks the request's size and content type.
+		if googleCN(r) {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		b.serveShare(w, r)
+		return
+	}
+
+	if r.Method != "POST" {
+		http.Error(w, "I only answer to POST requests.", http.StatusMethodNotAllowed)
+		return
+	}
+	// Read the body before googleCN parses the form,
+	// which would consume a form-encoded body.
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxShareSize+1))
+	if err != nil {
+		http.Error(w, "error reading request", http.StatusBadRequest)
+		return
+	}
+	if googleCN(r) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	if len(body) > maxShareSize {
+		http.Error(w, "Snippet is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	id, err := s.backend.Share(r.Context(), body)
+	if err != nil {
+		log.Printf("ERROR share error: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, id)
+}
+
 // googleCN reports whether request r is considered
 // to be served from golang.google.cn.
 func googleCN(r *http.Request) bool {
@@ -0,0 +1,72 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package proxy
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+)
+
+// sandboxUID is the user and group ID of isolated programs
+// inside their user namespace, and outside it if the server runs as root.
+const sandboxUID = 65534 // nobody
+
+// isolate changes cmd, which runs a program in the directory dir,
+// to run it isolated from this machine: in new user, network, mount,
+// PID, IPC and UTS namespaces, with dir as its root directory,
+// as an unprivileged user without capabilities.
+// The program sees only the files in dir and has no network.
+//
+// If the server runs as root, the program runs as the user nobody,
+// which is given dir. Otherwise it runs as the server's user,
+// but cannot reach any of its files outside dir.
+func isolate(cmd *exec.Cmd, dir string) error {
+	rel, err := filepath.Rel(dir, cmd.Path)
+	if err != nil {
+		return err
+	}
+	host := os.Getuid()
+	if host == 0 {
+		host = sandboxUID
+		if err := os.Chown(dir, host, host); err != nil {
+			return err
+		}
+	}
+	cmd.Path = "/" + filepath.ToSlash(rel)
+	cmd.Dir = "/"
+	cmd.Env = []string{"HOME=/", "TMPDIR=/"}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = new(syscall.SysProcAttr)
+	}
+	attr := cmd.SysProcAttr
+	attr.Cloneflags = syscall.CLONE_NEWUSER | syscall.CLONE_NEWNET | syscall.CLONE_NEWNS |
+		syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+	attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: sandboxUID, HostID: host, Size: 1}}
+	attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: sandboxUID, HostID: host, Size: 1}}
+	attr.Chroot = dir
+	attr.Credential = &syscall.Credential{Uid: sandboxUID, Gid: sandboxUID, NoSetGroups: true}
+	return nil
+}
+
+// canIsolate reports whether isolate works on this system,
+// which may not allow user namespaces. It runs a missing program
+// isolated: the program is found missing only if the namespaces
+// and root directory were set up.
+func canIsolate() bool {
+	dir, err := ioutil.TempDir("", "play")
+	if err != nil {
+		return false
+	}
+	defer os.RemoveAll(dir)
+	cmd := exec.Command(filepath.Join(dir, "missing"))
+	if err := isolate(cmd, dir); err != nil {
+		return false
+	}
+	return errors.Is(cmd.Run(), syscall.ENOENT)
+}
@@ -0,0 +1,23 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package proxy
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// Programs can only be isolated on Linux; see sandbox_linux.go.
+
+func isolate(cmd *exec.Cmd, dir string) error {
+	return errors.New("isolation not supported on this system")
+}
+
+func canIsolate() bool {
+	return false
+}
@@ -45,6 +45,23 @@ func Register(mux *http.ServeMux) {
 	mux.HandleFunc("/design/", designHandler)
 }
 
+// Redirects returns the fixed path redirects registered by Register,
+// as a map from source path to target URL. It does not include the
+// redirects computed from the request, such as /issue/NNN or /cl/NNN.
+func Redirects() map[string]string {
+	m := make(map[string]string)
+	for prefix, table := range map[string]map[string]string{"/pkg/": pkgRedirects, "/cmd/": cmdRedirects} {
+		for source, target := range table {
+			m[prefix+source] = prefix + target + "/"
+			m[prefix+source+"/"] = prefix + target + "/"
+		}
+	}
+	for path, target := range redirects {
+		m[path] = target
+	}
+	return m
+}
+
 func handlePathRedirects(mux *http.ServeMux, redirects map[string]string, prefix string) {
 	for source, target := range redirects {
 		h := Handler(prefix + target + "/")
@@ -0,0 +1,250 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package search implements a simple in-memory full-text index
+// over website documents, with stemming for English and Russian.
+package search
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/website/internal/texthtml"
+)
+
+// Document kinds.
+const (
+	KindDoc   = "doc"   // site document (_content)
+	KindPkg   = "pkg"   // package or command documentation
+	KindIdent = "ident" // exported identifier in a package
+	KindBlog  = "blog"  // blog article
+)
+
+// A Doc is a searchable document.
+type Doc struct {
+	Kind  string // one of the Kind constants
+	Title string
+	URL   string
+	Text  string // plain text body, also used for snippets
+}
+
+// A Result is a single search result.
+type Result struct {
+	*Doc
+	Score   float64
+	Snippet []byte // HTML excerpt of Text with the matches highlighted
+}
+
+type posting struct {
+	doc   int32
+	count int32 // number of occurrences in the text
+	title bool  // term occurs in the title
+}
+
+// An Index is an inverted index of documents.
+// An Index must not be modified while it is being searched.
+type Index struct {
+	docs  []*Doc
+	terms map[string][]posting // postings sorted by doc
+}
+
+// NewIndex returns a new, empty index.
+func NewIndex() *Index {
+	return &Index{terms: make(map[string][]posting)}
+}
+
+// Len returns the number of documents in the index.
+func (x *Index) Len() int {
+	return len(x.docs)
+}
+
+// Add adds d to the index.
+func (x *Index) Add(d *Doc) {
+	id := int32(len(x.docs))
+	x.docs = append(x.docs, d)
+
+	counts := make(map[string]int32)
+	inTitle := make(map[string]bool)
+	for _, t := range Terms(d.Title) {
+		inTitle[t] = true
+		if _, ok := counts[t]; !ok {
+			counts[t] = 0
+		}
+	}
+	for _, t := range Terms(d.Text) {
+		counts[t]++
+	}
+	for t, n := range counts {
+		x.terms[t] = append(x.terms[t], posting{doc: id, count: n, title: inTitle[t]})
+	}
+}
+
+// Search returns up to max documents matching all the words in query,
+// best matches first.
+func (x *Index) Search(query string, max int) []*Result {
+	terms := Terms(query)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	scores := make(map[int32]float64)
+	for i, t := range terms {
+		list := x.terms[t]
+		if len(list) == 0 {
+			return nil
+		}
+		idf := math.Log(1 + float64(len(x.docs))/float64(len(list)))
+		next := make(map[int32]float64)
+		for _, p := range list {
+			prev, ok := scores[p.doc]
+			if i > 0 && !ok {
+				continue
+			}
+			s := idf * (1 + math.Log(1+float64(p.count)))
+			if p.title {
+				s *= 5
+			}
+			next[p.doc] = prev + s
+		}
+		scores = next
+	}
+
+	var results []*Result
+	for id, score := range scores {
+		results = append(results, &Result{Doc: x.docs[id], Score: score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].URL < results[j].URL
+	})
+	if len(results) > max {
+		results = results[:max]
+	}
+
+	stems := make(map[string]bool)
+	for _, t := range terms {
+		stems[t] = true
+	}
+	for _, r := range results {
+		r.Snippet = snippet(r.Text, stems)
+	}
+	return results
+}
+
+// snippetLen is the approximate length in runes of a result snippet.
+const snippetLen = 200
+
+// snippet returns an HTML excerpt of text around the first word
+// whose stem is in stems, with all such words highlighted.
+func snippet(text string, stems map[string]bool) []byte {
+	words := splitWords(text)
+	first := -1
+	var matched []string
+	seen := make(map[string]bool)
+	for _, w := range words {
+		word := text[w.start:w.end]
+		if !stems[stem(strings.ToLower(word))] {
+			continue
+		}
+		if first < 0 {
+			first = w.start
+		}
+		if !seen[word] {
+			seen[word] = true
+			matched = append(matched, regexp.QuoteMeta(word))
+		}
+	}
+
+	// Choose a window of about snippetLen runes,
+	// starting a little before the first match.
+	start := 0
+	if first > 0 {
+		start = first
+		for n := 0; start > 0 && n < snippetLen/4; n++ {
+			_, size := utf8.DecodeLastRuneInString(text[:start])
+			start -= size
+		}
+	}
+	end := start
+	for n := 0; end < len(text) && n < snippetLen; n++ {
+		_, size := utf8.DecodeRuneInString(text[end:])
+		end += size
+	}
+	// Trim partial words at either end.
+	for _, w := range words {
+		if w.start < start && start < w.end {
+			start = w.end
+		}
+		if w.start < end && end < w.end {
+			end = w.start
+		}
+	}
+	excerpt := strings.TrimSpace(text[start:end])
+	if start > 0 {
+		excerpt = "… " + excerpt
+	}
+	if end < len(text) {
+		excerpt += " …"
+	}
+
+	var cfg texthtml.Config
+	if len(matched) > 0 {
+		// Longest first so that the regexp prefers whole words.
+		sort.Slice(matched, func(i, j int) bool { return len(matched[i]) > len(matched[j]) })
+		cfg.Highlight = "(" + strings.Join(matched, "|") + ")"
+	}
+	return texthtml.Format([]byte(excerpt), cfg)
+}
+
+type span struct{ start, end int }
+
+// splitWords returns the byte offsets of the words in s.
+// A word is a maximal run of letters and digits.
+func splitWords(s string) []span {
+	var words []span
+	start := -1
+	for i, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			words = append(words, span{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, span{start, len(s)})
+	}
+	return words
+}
+
+// Terms returns the index terms for the words in s:
+// each word is lower-cased and stemmed.
+func Terms(s string) []string {
+	var terms []string
+	for _, w := range splitWords(s) {
+		terms = append(terms, stem(strings.ToLower(s[w.start:w.end])))
+	}
+	return terms
+}
+
+// stem returns the stem of the lower-case word w,
+// using the Russian stemmer for words in Cyrillic script.
+func stem(w string) string {
+	for _, r := range w {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return stemRussian(w)
+		}
+	}
+	return stemEnglish(w)
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStemRussian(t *testing.T) {
+	for _, tt := range []struct {
+		in, want string
+	}{
+		// From the Snowball sample vocabulary.
+		{"вавилон", "вавилон"},
+		{"вавилона", "вавилон"},
+		{"вагонами", "вагон"},
+		{"важнейшие", "важн"},
+		{"важного", "важн"},
+		{"вдохновенно", "вдохновен"},
+		{"вдохновение", "вдохновен"},
+		{"взглянул", "взглянул"},
+		{"возвращался", "возвраща"},
+		{"всё", "все"},
+		{"гуляющий", "гуля"},
+		{"значительность", "значительн"},
+		{"книги", "книг"},
+		{"программирования", "программирован"},
+		{"пакеты", "пакет"},
+		{"пакетов", "пакет"},
+	} {
+		if got := stemRussian(tt.in); got != tt.want {
+			t.Errorf("stemRussian(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTerms(t *testing.T) {
+	got := strings.Join(Terms("Пакеты стандартной библиотеки: Packages, strings.Builder и io/fs"), " ")
+	want := "пакет стандартн библиотек package string builder и io fs"
+	if got != want {
+		t.Errorf("Terms = %q; want %q", got, want)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	x := NewIndex()
+	x.Add(&Doc{Kind: KindDoc, Title: "Установка Go", URL: "/doc/install", Text: "Скачайте архив и распакуйте его. Установка занимает минуту."})
+	x.Add(&Doc{Kind: KindDoc, Title: "Effective Go", URL: "/doc/effective_go", Text: "Tips for writing clear, idiomatic Go code. Packages are installed with go install."})
+	x.Add(&Doc{Kind: KindPkg, Title: "net/http", URL: "/pkg/net/http/", Text: "Package http provides HTTP client and server implementations."})
+	x.Add(&Doc{Kind: KindIdent, Title: "http.Client", URL: "/pkg/net/http/#Client", Text: "A Client is an HTTP client."})
+
+	check := func(query string, urls ...string) []*Result {
+		t.Helper()
+		res := x.Search(query, 10)
+		var got []string
+		for _, r := range res {
+			got = append(got, r.URL)
+		}
+		if strings.Join(got, " ") != strings.Join(urls, " ") {
+			t.Errorf("Search(%q) = %v; want %v", query, got, urls)
+		}
+		return res
+	}
+
+	res := check("установки", "/doc/install")
+	if s := string(res[0].Snippet); !strings.Contains(s, `<span class="highlight">Установка</span>`) {
+		t.Errorf("snippet %q does not highlight Установка", s)
+	}
+	check("http client", "/pkg/net/http/#Client", "/pkg/net/http/")
+	check("package", "/doc/effective_go", "/pkg/net/http/")
+	check("http server", "/pkg/net/http/")
+	check("nonexistent")
+	check("")
+}
@@ -0,0 +1,165 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package search
+
+import "strings"
+
+// This file implements the Snowball stemmer for Russian
+// (https://snowballstem.org/algorithms/russian/stemmer.html)
+// and a deliberately light stemmer for English.
+
+// Endings that must follow а or я, which are kept.
+// Each list is ordered longest first so that the first match is the longest.
+var (
+	perfectiveGerund1 = []string{"вшись", "вши", "в"}
+	perfectiveGerund2 = []string{"ившись", "ывшись", "ивши", "ывши", "ив", "ыв"}
+
+	reflexive = []string{"ся", "сь"}
+
+	adjective = []string{
+		"ими", "ыми", "его", "ого", "ему", "ому",
+		"ее", "ие", "ые", "ое", "ей", "ий", "ый", "ой", "ем", "им", "ым", "ом",
+		"их", "ых", "ую", "юю", "ая", "яя", "ою", "ею",
+	}
+	participle1 = []string{"ем", "нн", "вш", "ющ", "щ"}
+	participle2 = []string{"ивш", "ывш", "ующ"}
+
+	verb1 = []string{"ете", "йте", "ешь", "нно", "ла", "на", "ли", "ем", "ло", "но", "ет", "ют", "ны", "ть", "й", "л", "н"}
+	verb2 = []string{
+		"ейте", "уйте",
+		"ила", "ыла", "ена", "ите", "или", "ыли", "ило", "ыло", "ено", "ует", "уют", "ены", "ить", "ыть", "ишь",
+		"ей", "уй", "ил", "ыл", "им", "ым", "ен", "ят", "ит", "ыт", "ую", "ю",
+	}
+
+	noun = []string{
+		"иями", "ями", "ами", "ией", "иям", "ием", "иях",
+		"ев", "ов", "ие", "ье", "еи", "ии", "ей", "ой", "ий", "ям", "ем", "ам", "ом", "ах", "ях", "ию", "ью", "ия", "ья",
+		"а", "е", "и", "й", "о", "у", "ы", "ь", "ю", "я",
+	}
+
+	superlative   = []string{"ейше", "ейш"}
+	derivational  = []string{"ость", "ост"}
+	russianVowels = "аеиоуыэюя"
+)
+
+func isRussianVowel(r rune) bool {
+	return strings.ContainsRune(russianVowels, r)
+}
+
+// stemRussian returns the stem of the lower-case Russian word w.
+func stemRussian(w string) string {
+	word := []rune(strings.ReplaceAll(w, "ё", "е"))
+
+	// RV is the region after the first vowel.
+	// R1 is the region after the first non-vowel following a vowel,
+	// and R2 is the R1 region within R1.
+	rv, r1, r2 := len(word), len(word), len(word)
+	for i, r := range word {
+		if isRussianVowel(r) {
+			rv = i + 1
+			break
+		}
+	}
+	for i := 1; i < len(word); i++ {
+		if !isRussianVowel(word[i]) && isRussianVowel(word[i-1]) {
+			r1 = i + 1
+			break
+		}
+	}
+	for i := r1 + 1; i < len(word); i++ {
+		if !isRussianVowel(word[i]) && isRussianVowel(word[i-1]) {
+			r2 = i + 1
+			break
+		}
+	}
+
+	// Step 1.
+	if n, ok := removeSuffix(word, rv, perfectiveGerund2, false); ok {
+		word = word[:n]
+	} else if n, ok := removeSuffix(word, rv, perfectiveGerund1, true); ok {
+		word = word[:n]
+	} else {
+		if n, ok := removeSuffix(word, rv, reflexive, false); ok {
+			word = word[:n]
+		}
+		if n, ok := removeSuffix(word, rv, adjective, false); ok {
+			word = word[:n]
+			// An adjective may be preceded by a participle.
+			if n, ok := removeSuffix(word, rv, participle2, false); ok {
+				word = word[:n]
+			} else if n, ok := removeSuffix(word, rv, participle1, true); ok {
+				word = word[:n]
+			}
+		} else if n, ok := removeSuffix(word, rv, verb2, false); ok {
+			word = word[:n]
+		} else if n, ok := removeSuffix(word, rv, verb1, true); ok {
+			word = word[:n]
+		} else if n, ok := removeSuffix(word, rv, noun, false); ok {
+			word = word[:n]
+		}
+	}
+
+	// Step 2.
+	if n, ok := removeSuffix(word, rv, []string{"и"}, false); ok {
+		word = word[:n]
+	}
+
+	// Step 3.
+	if n, ok := removeSuffix(word, r2, derivational, false); ok {
+		word = word[:n]
+	}
+
+	// Step 4.
+	undouble := func() {
+		if n, ok := removeSuffix(word, rv, []string{"нн"}, false); ok {
+			word = word[:n+1]
+		}
+	}
+	if n, ok := removeSuffix(word, rv, superlative, false); ok {
+		word = word[:n]
+		undouble()
+	} else if n, ok := removeSuffix(word, rv, []string{"нн"}, false); ok {
+		word = word[:n+1]
+	} else if n, ok := removeSuffix(word, rv, []string{"ь"}, false); ok {
+		word = word[:n]
+	}
+
+	return string(word)
+}
+
+// removeSuffix reports whether word ends in one of the suffixes
+// lying entirely within the region starting at start, and if so,
+// returns the length of word without the longest such suffix.
+// If afterA is set, the suffix must also be preceded by а or я.
+func removeSuffix(word []rune, start int, suffixes []string, afterA bool) (int, bool) {
+	best := -1
+	for _, s := range suffixes {
+		suf := []rune(s)
+		n := len(word) - len(suf)
+		if n < start || n < 0 || string(word[n:]) != s {
+			continue
+		}
+		if afterA && (n == 0 || n-1 < start || word[n-1] != 'а' && word[n-1] != 'я') {
+			continue
+		}
+		if best < 0 || n < best {
+			best = n
+		}
+	}
+	return best, best >= 0
+}
+
+// stemEnglish returns a light stem of the lower-case English word w,
+// folding the common plural and possessive forms.
+func stemEnglish(w string) string {
+	w = strings.TrimSuffix(w, "'s")
+	switch {
+	case len(w) > 4 && strings.HasSuffix(w, "ies"):
+		return w[:len(w)-3] + "y"
+	case len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") && !strings.HasSuffix(w, "us") && !strings.HasSuffix(w, "is"):
+		return w[:len(w)-1]
+	}
+	return w
+}
@@ -0,0 +1,288 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package short
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/website/internal/memcache"
+	"golang.org/x/website/internal/storage"
+)
+
+// AdminHandler serves an administrative interface for managing shortener entries.
+// Be careful. It is the caller’s responsibility to ensure that the handler is
+// only exposed to authorized users, for example by serving it through
+// auth.Auth.Handler, which also records changes in the audit log.
+func AdminHandler(db storage.DB, mc memcache.Client) http.HandlerFunc {
+	s := newServer(db, mc)
+	return s.adminHandler
+}
+
+var adminTemplate = template.Must(template.New("admin").Parse(templateHTML))
+
+// dateLayout is the format of expiry dates in the admin form and CSV files.
+const dateLayout = "2006-01-02"
+
+// adminHandler serves an administrative interface.
+// Be careful. Ensure that this handler is only be exposed to authorized users.
+//
+// The q parameter limits the list to links containing q in their key, target,
+// owner or description, and the sort parameter orders it by one of
+// the columns (see sortLinks). With format=csv, the list is served as a CSV file,
+// in the format accepted by the Import action.
+func (h server) adminHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var newLink *Link
+	var doErr error
+	var message string
+	if r.Method == "POST" {
+		key := r.FormValue("key")
+		switch r.FormValue("do") {
+		case "Add":
+			newLink = &Link{
+				Key:         key,
+				Target:      r.FormValue("target"),
+				Owner:       r.FormValue("owner"),
+				Description: r.FormValue("description"),
+			}
+			newLink.Expires, doErr = parseExpires(r.FormValue("expires"))
+			if doErr == nil {
+				doErr = h.putLink(ctx, newLink)
+			}
+		case "Delete":
+			doErr = h.deleteLink(ctx, key)
+		case "Import":
+			var n int
+			n, doErr = h.importCSV(ctx, r)
+			if doErr == nil {
+				message = fmt.Sprintf("Imported %d links.", n)
+			}
+		default:
+			http.Error(w, "unknown action", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var links []*Link
+	if err := h.db.GetAll(ctx, kind, &links); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Printf("ERROR %v", err)
+		return
+	}
+
+	// Put the new link in the list if it's not there already.
+	// (Eventual consistency means that it might not show up
+	// immediately, which might be confusing for the user.)
+	if newLink != nil && doErr == nil {
+		found := false
+		for i := range links {
+			if links[i].Key == newLink.Key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			links = append([]*Link{newLink}, links...)
+		}
+		newLink = nil
+	}
+
+	query := r.FormValue("q")
+	links = searchLinks(links, query)
+	sortBy := r.FormValue("sort")
+	sortLinks(links, sortBy)
+
+	if r.FormValue("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="links.csv"`)
+		if err := writeCSV(w, links); err != nil {
+			log.Printf("ERROR writing CSV: %v", err)
+		}
+		return
+	}
+
+	var data = struct {
+		BaseURL string
+		Prefix  string
+		Links   []*Link
+		New     *Link
+		Error   error
+		Message string
+		Query   string
+		Sort    string
+	}{baseURL, prefix, links, newLink, doErr, message, query, sortBy}
+	if err := adminTemplate.Execute(w, &data); err != nil {
+		log.Printf("ERROR adminTemplate: %v", err)
+	}
+}
+
+// parseExpires parses an expiry date from the admin form or a CSV file.
+// An empty string means the link does not expire.
+func parseExpires(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("bad expiry date %q: want YYYY-MM-DD", s)
+	}
+	return t, nil
+}
+
+// searchLinks returns the links containing query, ignoring case,
+// in their key, target, owner or description.
+func searchLinks(links []*Link, query string) []*Link {
+	if query == "" {
+		return links
+	}
+	query = strings.ToLower(query)
+	var out []*Link
+	for _, l := range links {
+		for _, s := range []string{l.Key, l.Target, l.Owner, l.Description} {
+			if strings.Contains(strings.ToLower(s), query) {
+				out = append(out, l)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// sortLinks sorts links by the named column: key (the default), target,
+// owner, created, expires or visits. The names created, expires and visits
+// sort newest or most visited first. A leading "-" reverses the order.
+func sortLinks(links []*Link, by string) {
+	desc := strings.HasPrefix(by, "-")
+	by = strings.TrimPrefix(by, "-")
+	var less func(a, b *Link) bool
+	switch by {
+	case "target":
+		less = func(a, b *Link) bool { return a.Target < b.Target }
+	case "owner":
+		less = func(a, b *Link) bool { return a.Owner < b.Owner }
+	case "created":
+		less = func(a, b *Link) bool { return a.Created.After(b.Created) }
+	case "expires":
+		less = func(a, b *Link) bool { return a.Expires.After(b.Expires) }
+	case "visits":
+		less = func(a, b *Link) bool { return a.Visits > b.Visits }
+	default:
+		less = func(a, b *Link) bool { return a.Key < b.Key }
+	}
+	sort.SliceStable(links, func(i, j int) bool {
+		if desc {
+			return less(links[j], links[i])
+		}
+		return less(links[i], links[j])
+	})
+}
+
+// csvHeader lists the columns of the CSV form of the links.
+var csvHeader = []string{"key", "target", "owner", "description", "created", "expires", "visits"}
+
+// writeCSV writes links to w in CSV form.
+func writeCSV(w io.Writer, links []*Link) error {
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+	for _, l := range links {
+		var expires string
+		if !l.Expires.IsZero() {
+			expires = l.Expires.Format(dateLayout)
+		}
+		cw.Write([]string{
+			l.Key,
+			l.Target,
+			l.Owner,
+			l.Description,
+			l.Created.Format(time.RFC3339),
+			expires,
+			strconv.FormatInt(l.Visits, 10),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// importCSV adds or replaces the links in the CSV file uploaded as
+// the csv form field. The file's first line names its columns,
+// as written by writeCSV; only key and target are required,
+// and the created and visits columns are ignored.
+// It returns the number of links imported.
+func (h server) importCSV(ctx context.Context, r *http.Request) (int, error) {
+	f, _, err := r.FormFile("csv")
+	if err != nil {
+		return 0, errors.New("missing CSV file")
+	}
+	defer f.Close()
+	links, err := readCSV(f)
+	if err != nil {
+		return 0, err
+	}
+	for i, l := range links {
+		if err := h.putLink(ctx, l); err != nil {
+			return i, fmt.Errorf("link %q: %v", l.Key, err)
+		}
+	}
+	return len(links), nil
+}
+
+// readCSV reads links in the CSV form written by writeCSV.
+func readCSV(r io.Reader) ([]*Link, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("empty CSV file")
+	}
+	col := make(map[string]int)
+	for i, name := range rows[0] {
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := col["key"]; !ok {
+		return nil, errors.New("CSV file has no key column")
+	}
+	if _, ok := col["target"]; !ok {
+		return nil, errors.New("CSV file has no target column")
+	}
+	field := func(row []string, name string) string {
+		if i, ok := col[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var links []*Link
+	for n, row := range rows[1:] {
+		l := &Link{
+			Key:         field(row, "key"),
+			Target:      field(row, "target"),
+			Owner:       field(row, "owner"),
+			Description: field(row, "description"),
+		}
+		if !validKey.MatchString(l.Key) || l.Target == "" {
+			return nil, fmt.Errorf("line %d: invalid key %q or missing target", n+2, l.Key)
+		}
+		if l.Expires, err = parseExpires(field(row, "expires")); err != nil {
+			return nil, fmt.Errorf("line %d: %v", n+2, err)
+		}
+		links = append(links, l)
+	}
+	return links, nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package short
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/website/internal/storage"
+)
+
+func TestSearchSort(t *testing.T) {
+	links := []*Link{
+		{Key: "b", Target: "https://golang.org/b", Owner: "rsc", Visits: 10, Created: time.Unix(2, 0)},
+		{Key: "a", Target: "https://golang.org/z", Owner: "adg", Visits: 5, Created: time.Unix(3, 0)},
+		{Key: "c", Target: "https://example.com/", Owner: "rsc", Description: "Go stuff", Visits: 7, Created: time.Unix(1, 0)},
+	}
+	keys := func(links []*Link) string {
+		var s []string
+		for _, l := range links {
+			s = append(s, l.Key)
+		}
+		return strings.Join(s, ",")
+	}
+	for _, tc := range []struct {
+		query, sort, want string
+	}{
+		{"", "", "a,b,c"},
+		{"", "-key", "c,b,a"},
+		{"", "target", "c,b,a"},
+		{"", "visits", "b,c,a"},
+		{"", "created", "a,b,c"},
+		{"", "-created", "c,b,a"},
+		{"RSC", "", "b,c"},
+		{"golang.org", "visits", "b,a"},
+		{"go stuff", "", "c"},
+	} {
+		list := searchLinks(append([]*Link(nil), links...), tc.query)
+		sortLinks(list, tc.sort)
+		if got := keys(list); got != tc.want {
+			t.Errorf("q=%q sort=%q: got %s, want %s", tc.query, tc.sort, got, tc.want)
+		}
+	}
+}
+
+func TestCSV(t *testing.T) {
+	db := storage.NewMemory()
+	h := AdminHandler(db, nil)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("do", "Import")
+	fw, _ := mw.CreateFormFile("csv", "links.csv")
+	fw.Write([]byte("key,target,owner,expires\n" +
+		"go1,https://golang.org/doc/go1,gopher,\n" +
+		"tour,https://tour.golang.org/,,2030-01-02\n"))
+	mw.Close()
+	req := httptest.NewRequest("POST", "/", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	h(w, req)
+	if !strings.Contains(w.Body.String(), "Imported 2 links.") {
+		t.Fatalf("import response does not report 2 links:\n%s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "/?format=csv", nil))
+	links, err := readCSV(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 2 || links[0].Key != "go1" || links[0].Owner != "gopher" ||
+		links[1].Key != "tour" || links[1].Expires.Format(dateLayout) != "2030-01-02" {
+		t.Errorf("exported links = %+v %+v", links[0], links[1])
+	}
+
+	if _, err := readCSV(strings.NewReader("key,owner\nx,y\n")); err == nil {
+		t.Errorf("readCSV without target column succeeded")
+	}
+	if _, err := readCSV(strings.NewReader("key,target,expires\nx,https://golang.org/,tomorrow\n")); err == nil {
+		t.Errorf("readCSV with bad expiry succeeded")
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package short
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"golang.org/x/website/internal/memcache"
+	"golang.org/x/website/internal/storage"
+)
+
+// apiPrefix is the path at which the handler returned by APIHandler expects to be served.
+const apiPrefix = "/api/links"
+
+// APIHandler serves a JSON API for managing shortener entries:
+//
+//	GET    /api/links        list all links
+//	POST   /api/links        create the link in the request body
+//	GET    /api/links/{key}  get a link
+//	PUT    /api/links/{key}  create or replace a link
+//	DELETE /api/links/{key}  delete a link
+//
+// Links are encoded as JSON objects with the fields of Link.
+// The created and visits fields are maintained by the server
+// and ignored in requests.
+//
+// Be careful. It is the caller’s responsibility to ensure that the handler is
+// only exposed to authorized users, at both /api/links and /api/links/,
+// for example by serving it through auth.Auth.Handler.
+func APIHandler(db storage.DB, mc memcache.Client) http.HandlerFunc {
+	s := newServer(db, mc)
+	return s.apiHandler
+}
+
+func (h server) apiHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	path := strings.TrimPrefix(r.URL.Path, apiPrefix)
+
+	if path == "" || path == "/" {
+		switch r.Method {
+		case "GET":
+			var links []*Link
+			if err := h.db.GetAll(ctx, kind, &links); err != nil {
+				log.Printf("ERROR %v", err)
+				apiError(w, "could not list links", http.StatusInternalServerError)
+				return
+			}
+			if links == nil {
+				links = []*Link{}
+			}
+			apiReply(w, http.StatusOK, links)
+		case "POST":
+			link, ok := readLink(w, r)
+			if !ok {
+				return
+			}
+			var old Link
+			if err := h.db.Get(ctx, kind, link.Key, &old); err == nil {
+				apiError(w, "link already exists", http.StatusConflict)
+				return
+			}
+			if err := h.putLink(ctx, link); err != nil {
+				apiError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			apiReply(w, http.StatusCreated, link)
+		default:
+			apiError(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	key := strings.TrimPrefix(path, "/")
+	if !validKey.MatchString(key) {
+		apiError(w, "invalid key", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case "GET":
+		var link Link
+		if err := h.db.Get(ctx, kind, key, &link); err == storage.ErrNotFound {
+			apiError(w, "no such link", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Printf("ERROR %q: %v", key, err)
+			apiError(w, "could not read link", http.StatusInternalServerError)
+			return
+		}
+		apiReply(w, http.StatusOK, &link)
+	case "PUT":
+		link, ok := readLink(w, r)
+		if !ok {
+			return
+		}
+		if link.Key == "" {
+			link.Key = key
+		}
+		if link.Key != key {
+			apiError(w, "key in body does not match URL", http.StatusBadRequest)
+			return
+		}
+		if err := h.putLink(ctx, link); err != nil {
+			apiError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		apiReply(w, http.StatusOK, link)
+	case "DELETE":
+		if err := h.deleteLink(ctx, key); err != nil {
+			log.Printf("ERROR %q: %v", key, err)
+			apiError(w, "could not delete link", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		apiError(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// readLink decodes the link in the body of r.
+// If the body is invalid, it replies with an error and returns ok == false.
+func readLink(w http.ResponseWriter, r *http.Request) (link *Link, ok bool) {
+	link = new(Link)
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(link); err != nil {
+		apiError(w, "invalid link JSON: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return link, true
+}
+
+func apiReply(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(v); err != nil {
+		log.Printf("ERROR encoding JSON: %v", err)
+	}
+}
+
+func apiError(w http.ResponseWriter, msg string, code int) {
+	apiReply(w, code, struct {
+		Error string `json:"error"`
+	}{msg})
+}
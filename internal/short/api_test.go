@@ -0,0 +1,59 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package short
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/website/internal/storage"
+)
+
+func TestAPI(t *testing.T) {
+	h := APIHandler(storage.NewMemory(), nil)
+	do := func(method, path, body string) (int, string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w.Code, w.Body.String()
+	}
+
+	for _, tc := range []struct {
+		method, path, body string
+		code               int
+	}{
+		{"GET", "/api/links", "", 200},
+		{"POST", "/api/links", `{"key": "go1", "target": "https://golang.org/doc/go1", "owner": "gopher"}`, 201},
+		{"POST", "/api/links", `{"key": "go1", "target": "https://golang.org/"}`, 409},
+		{"POST", "/api/links", `{"key": "bad key", "target": "https://golang.org/"}`, 400},
+		{"POST", "/api/links", `{"key": "x"`, 400},
+		{"PUT", "/api/links/tour", `{"target": "https://tour.golang.org/", "expires": "2030-01-01T00:00:00Z"}`, 200},
+		{"PUT", "/api/links/tour", `{"key": "other", "target": "https://tour.golang.org/"}`, 400},
+		{"GET", "/api/links/missing", "", 404},
+		{"DELETE", "/api/links/go1", "", 204},
+		{"PATCH", "/api/links/tour", "", 405},
+	} {
+		if code, body := do(tc.method, tc.path, tc.body); code != tc.code {
+			t.Errorf("%s %s = %d %s, want %d", tc.method, tc.path, code, body, tc.code)
+		}
+	}
+
+	code, body := do("GET", "/api/links/tour", "")
+	var link Link
+	if err := json.Unmarshal([]byte(body), &link); code != 200 || err != nil {
+		t.Fatalf("GET /api/links/tour = %d %s (%v)", code, body, err)
+	}
+	if link.Key != "tour" || link.Target != "https://tour.golang.org/" || link.Expires.Year() != 2030 || link.Created.IsZero() {
+		t.Errorf("GET /api/links/tour = %+v", link)
+	}
+
+	code, body = do("GET", "/api/links", "")
+	var links []Link
+	if err := json.Unmarshal([]byte(body), &links); code != 200 || err != nil || len(links) != 1 || links[0].Key != "tour" {
+		t.Errorf("GET /api/links = %d %s, want only tour", code, body)
+	}
+}
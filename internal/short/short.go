@@ -3,24 +3,23 @@
 // license that can be found in the LICENSE file.
 
 // Package short implements a simple URL shortener, serving shortened urls
-// from /s/key. An administrative handler is provided for other services to use.
+// from /s/key. An administrative handler and a JSON API for managing the
+// links are provided for other services to use.
 package short
 
-// TODO(adg): collect statistics on URL visits
-
 import (
 	"context"
 	"errors"
 	"fmt"
-	"html/template"
 	"log"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
+	"time"
 
-	"cloud.google.com/go/datastore"
 	"golang.org/x/website/internal/memcache"
+	"golang.org/x/website/internal/storage"
 )
 
 const (
@@ -31,31 +30,58 @@ const (
 
 // Link represents a short link.
 type Link struct {
-	Key, Target string
+	Key         string    `json:"key"`
+	Target      string    `json:"target"`
+	Owner       string    `json:"owner"`
+	Description string    `json:"description" datastore:",noindex"`
+	Created     time.Time `json:"created"`
+	Expires     time.Time `json:"expires"` // zero if the link does not expire
+	Visits      int64     `json:"visits"`  // as last flushed to storage
+}
+
+// Expired reports whether the link has expired.
+func (l *Link) Expired() bool {
+	return !l.Expires.IsZero() && !timeNow().Before(l.Expires)
 }
 
 var validKey = regexp.MustCompile(`^[a-zA-Z0-9-_.]+$`)
 
+var timeNow = time.Now // for testing
+
 type server struct {
-	datastore *datastore.Client
-	memcache  *memcache.CodecClient
+	db       storage.DB
+	memcache *memcache.CodecClient
+	visits   *visitCounter
 }
 
-func newServer(dc *datastore.Client, mc *memcache.Client) *server {
+func newServer(db storage.DB, mc memcache.Client) *server {
 	return &server{
-		datastore: dc,
-		memcache:  mc.WithCodec(memcache.JSON),
+		db:       db,
+		memcache: memcache.WithCodec(mc, memcache.JSON),
+		visits:   newVisitCounter(),
 	}
 }
 
-func RegisterHandlers(mux *http.ServeMux, dc *datastore.Client, mc *memcache.Client) {
-	s := newServer(dc, mc)
+// RegisterHandlers registers the short link handler on mux,
+// looking up Link entities in db and caching them in mc.
+// The memcache client mc may be nil, to disable caching.
+// Visits to each link are counted in memory and periodically
+// added to the link's Visits in db.
+//
+// RegisterHandlers returns a function that adds the visits
+// counted since the last flush to db. The server must call it
+// when shutting down, after it has stopped serving requests,
+// so that those visits are not lost.
+func RegisterHandlers(mux *http.ServeMux, db storage.DB, mc memcache.Client) (flush func(context.Context)) {
+	s := newServer(db, mc)
 	mux.HandleFunc(prefix+"/", s.linkHandler)
+	go s.flushVisitsLoop(visitFlushInterval)
+	return s.flushVisits
 }
 
 // linkHandler services requests to short URLs.
 //   http://golang.org/s/key[/remaining/path]
-// It consults memcache and datastore for the Link for key.
+// It consults memcache and storage for the Link for key.
 // It then sends a redirects or an error message.
 // If the remaining path part is not empty, the redirects
 // will be the relative path from the resolved Link.
@@ -70,10 +96,9 @@ func (h server) linkHandler(w http.ResponseWriter, r *http.Request) {
 
 	var link Link
 	if err := h.memcache.Get(ctx, cacheKey(key), &link); err != nil {
-		k := datastore.NameKey(kind, key, nil)
-		err = h.datastore.Get(ctx, k, &link)
+		err = h.db.Get(ctx, kind, key, &link)
 		switch err {
-		case datastore.ErrNoSuchEntity:
+		case storage.ErrNotFound:
 			http.Error(w, "not found", http.StatusNotFound)
 			return
 		default: // != nil
@@ -91,6 +116,12 @@ func (h server) linkHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if link.Expired() {
+		http.Error(w, "link expired", http.StatusGone)
+		return
+	}
+	h.visits.add(key, 1)
+
 	target := link.Target
 	if remainingPath != "" {
 		target += remainingPath
@@ -115,89 +146,52 @@ func extractKey(r *http.Request) (key, remainingPath string, err error) {
 	return key, remainingPath, nil
 }
 
-// AdminHandler serves an administrative interface for managing shortener entries.
-// Be careful. It is the caller’s responsibility to ensure that the handler is
-// only exposed to authorized users.
-func AdminHandler(dc *datastore.Client, mc *memcache.Client) http.HandlerFunc {
-	s := newServer(dc, mc)
-	return s.adminHandler
-}
-
-var adminTemplate = template.Must(template.New("admin").Parse(templateHTML))
-
-// adminHandler serves an administrative interface.
-// Be careful. Ensure that this handler is only be exposed to authorized users.
-func (h server) adminHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-
-	var newLink *Link
-	var doErr error
-	if r.Method == "POST" {
-		key := r.FormValue("key")
-		switch r.FormValue("do") {
-		case "Add":
-			newLink = &Link{key, r.FormValue("target")}
-			doErr = h.putLink(ctx, newLink)
-		case "Delete":
-			k := datastore.NameKey(kind, key, nil)
-			doErr = h.datastore.Delete(ctx, k)
-		default:
-			http.Error(w, "unknown action", http.StatusBadRequest)
-		}
-		err := h.memcache.Delete(ctx, cacheKey(key))
-		if err != nil && err != memcache.ErrCacheMiss {
-			log.Printf("WARNING %q: %v", key, err)
-		}
+// putLink validates the provided link and puts it into storage,
+// replacing any existing link with the same key.
+// The creation time and visit count of an existing link are kept,
+// and a new link is created now.
+func (h server) putLink(ctx context.Context, link *Link) error {
+	if !validKey.MatchString(link.Key) {
+		return errors.New("invalid key; must match " + validKey.String())
 	}
-
-	var links []*Link
-	q := datastore.NewQuery(kind).Order("Key")
-	if _, err := h.datastore.GetAll(ctx, q, &links); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		log.Printf("ERROR %v", err)
-		return
+	if link.Target == "" {
+		return errors.New("missing target")
 	}
-
-	// Put the new link in the list if it's not there already.
-	// (Eventual consistency means that it might not show up
-	// immediately, which might be confusing for the user.)
-	if newLink != nil && doErr == nil {
-		found := false
-		for i := range links {
-			if links[i].Key == newLink.Key {
-				found = true
-				break
-			}
-		}
-		if !found {
-			links = append([]*Link{newLink}, links...)
-		}
-		newLink = nil
+	if _, err := url.Parse(link.Target); err != nil {
+		return fmt.Errorf("bad target: %v", err)
 	}
-
-	var data = struct {
-		BaseURL string
-		Prefix  string
-		Links   []*Link
-		New     *Link
-		Error   error
-	}{baseURL, prefix, links, newLink, doErr}
-	if err := adminTemplate.Execute(w, &data); err != nil {
-		log.Printf("ERROR adminTemplate: %v", err)
+	var old Link
+	switch err := h.db.Get(ctx, kind, link.Key, &old); err {
+	case nil:
+		link.Created = old.Created
+		link.Visits = old.Visits
+	case storage.ErrNotFound:
+		link.Created = timeNow().UTC()
+		link.Visits = 0
+	default:
+		return err
 	}
+	if err := h.db.Put(ctx, kind, link.Key, link); err != nil {
+		return err
+	}
+	h.clearCache(ctx, link.Key)
+	return nil
 }
 
-// putLink validates the provided link and puts it into the datastore.
-func (h server) putLink(ctx context.Context, link *Link) error {
-	if !validKey.MatchString(link.Key) {
-		return errors.New("invalid key; must match " + validKey.String())
+// deleteLink deletes the link with the given key.
+func (h server) deleteLink(ctx context.Context, key string) error {
+	if err := h.db.Delete(ctx, kind, key); err != nil {
+		return err
 	}
-	if _, err := url.Parse(link.Target); err != nil {
-		return fmt.Errorf("bad target: %v", err)
+	h.clearCache(ctx, key)
+	return nil
+}
+
+func (h server) clearCache(ctx context.Context, key string) {
+	err := h.memcache.Delete(ctx, cacheKey(key))
+	if err != nil && err != memcache.ErrCacheMiss {
+		log.Printf("WARNING %q: %v", key, err)
 	}
-	k := datastore.NameKey(kind, link.Key, nil)
-	_, err := h.datastore.Put(ctx, k, link)
-	return err
 }
 
 // cacheKey returns a short URL key as a memcache key.
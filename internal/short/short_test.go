@@ -5,8 +5,13 @@
 package short
 
 import (
+	"context"
+	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+
+	"golang.org/x/website/internal/storage"
 )
 
 func TestExtractKey(t *testing.T) {
@@ -35,3 +40,78 @@ func TestExtractKey(t *testing.T) {
 		}
 	}
 }
+
+func TestLinkHandler(t *testing.T) {
+	db := storage.NewMemory()
+	s := newServer(db, nil)
+	ctx := context.Background()
+	if err := s.putLink(ctx, &Link{Key: "go1", Target: "https://golang.org/doc/go1"}); err != nil {
+		t.Fatal(err)
+	}
+	old := &Link{Key: "old", Target: "https://golang.org/", Expires: time.Now().Add(-time.Hour)}
+	if err := s.putLink(ctx, old); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		path   string
+		status int
+		target string
+	}{
+		{"/s/go1", http.StatusFound, "https://golang.org/doc/go1"},
+		{"/s/go1/more", http.StatusFound, "https://golang.org/doc/go1/more"},
+		{"/s/missing", http.StatusNotFound, ""},
+		{"/s/bad*key", http.StatusNotFound, ""},
+		{"/s/old", http.StatusGone, ""},
+	}
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		s.linkHandler(w, httptest.NewRequest("GET", tc.path, nil))
+		if w.Code != tc.status || w.Header().Get("Location") != tc.target {
+			t.Errorf("GET %s = %d %q, want %d %q", tc.path, w.Code, w.Header().Get("Location"), tc.status, tc.target)
+		}
+	}
+
+	// The two visits to go1 are added to storage when flushed.
+	s.flushVisits(ctx)
+	var link Link
+	if err := db.Get(ctx, kind, "go1", &link); err != nil {
+		t.Fatal(err)
+	}
+	if link.Visits != 2 || link.Created.IsZero() {
+		t.Errorf("after flush, go1 = %+v, want 2 visits and creation time", link)
+	}
+	s.visits.add("go1", 1)
+	if err := s.putLink(ctx, &Link{Key: "go1", Target: "https://golang.org/doc/go1compat"}); err != nil {
+		t.Fatal(err)
+	}
+	s.flushVisits(ctx)
+	if err := db.Get(ctx, kind, "go1", &link); err != nil {
+		t.Fatal(err)
+	}
+	if link.Visits != 3 || link.Target != "https://golang.org/doc/go1compat" {
+		t.Errorf("after edit and flush, go1 = %+v, want 3 visits and new target", link)
+	}
+}
+
+func TestRegisterHandlersFlush(t *testing.T) {
+	db := storage.NewMemory()
+	ctx := context.Background()
+	if err := newServer(db, nil).putLink(ctx, &Link{Key: "go1", Target: "https://golang.org/doc/go1"}); err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	flush := RegisterHandlers(mux, db, nil)
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/s/go1", nil))
+
+	// The visit is in storage after the flush returned for shutdown,
+	// well before the periodic flush.
+	flush(ctx)
+	var link Link
+	if err := db.Get(ctx, kind, "go1", &link); err != nil {
+		t.Fatal(err)
+	}
+	if link.Visits != 1 {
+		t.Errorf("after flush, go1 has %d visits, want 1", link.Visits)
+	}
+}
@@ -43,44 +43,90 @@ table {
 	margin-left: auto;
 	margin-right: auto;
 }
+input.short {
+	width: 160px;
+}
+form.inline {
+	display: inline;
+	margin-right: 20px;
+}
+.expired td {
+	color: #999;
+	text-decoration: line-through;
+}
 </style>
 <table>
 
 {{with .Error}}
 	<tr>
-		<th colspan="3">Error</th>
+		<th colspan="7">Error</th>
 	</tr>
 	<tr>
-		<td class="error" colspan="3">{{.}}</td>
+		<td class="error" colspan="7">{{.}}</td>
+	</tr>
+{{end}}
+{{with .Message}}
+	<tr>
+		<td colspan="7">{{.}}</td>
 	</tr>
 {{end}}
 
 <tr>
 	<th>Key</th>
 	<th>Target</th>
-	<th></th>
+	<th>Owner</th>
+	<th>Description</th>
+	<th>Expires</th>
+	<th colspan="2"></th>
 </tr>
 
 <form method="POST">
 <tr>
 	<td><input type="text" name="key"{{with .New}} value="{{.Key}}"{{end}} required></td>
 	<td><input type="url" name="target"{{with .New}} value="{{.Target}}"{{end}} required></td>
-	<td><input type="submit" name="do" value="Add">
+	<td><input type="text" name="owner" class="short"{{with .New}} value="{{.Owner}}"{{end}}></td>
+	<td><input type="text" name="description" class="short"{{with .New}} value="{{.Description}}"{{end}}></td>
+	<td><input type="date" name="expires"{{with .New}}{{if not .Expires.IsZero}} value="{{.Expires.Format "2006-01-02"}}"{{end}}{{end}}></td>
+	<td colspan="2"><input type="submit" name="do" value="Add"></td>
 </tr>
 </form>
 
+<tr>
+	<td colspan="7">
+		<form method="GET" class="inline">
+			<input type="search" name="q" value="{{.Query}}" placeholder="Search links">
+			<input type="hidden" name="sort" value="{{.Sort}}">
+			<input type="submit" value="Search">
+		</form>
+		<a href="?q={{.Query}}&amp;sort={{.Sort}}&amp;format=csv">Export CSV</a>
+		<form method="POST" enctype="multipart/form-data" class="inline">
+			<input type="file" name="csv" accept=".csv,text/csv" required>
+			<input type="submit" name="do" value="Import">
+		</form>
+	</td>
+</tr>
+
 {{with .Links}}
 	<tr>
-		<th>Short Link</th>
-		<th>&nbsp;</th>
-		<th>&nbsp;</th>
+		<th><a href="?q={{$.Query}}&amp;sort={{if eq $.Sort "key"}}-{{end}}key">Short Link</a></th>
+		<th><a href="?q={{$.Query}}&amp;sort={{if eq $.Sort "target"}}-{{end}}target">Target</a></th>
+		<th><a href="?q={{$.Query}}&amp;sort={{if eq $.Sort "owner"}}-{{end}}owner">Owner</a></th>
+		<th>Description</th>
+		<th><a href="?q={{$.Query}}&amp;sort={{if eq $.Sort "expires"}}-{{end}}expires">Expires</a></th>
+		<th><a href="?q={{$.Query}}&amp;sort={{if eq $.Sort "visits"}}-{{end}}visits">Visits</a></th>
+		<th><a href="?q={{$.Query}}&amp;sort={{if eq $.Sort "created"}}-{{end}}created">Created</a></th>
 	</tr>
 	{{range .}}
-		<tr>
+		<tr{{if .Expired}} class="expired"{{end}}>
 			<td><input class="autoselect" type="text" value="{{$.BaseURL}}/{{.Key}}" readonly></td>
 			<td><input class="autoselect" type="text" value="{{.Target}}" readonly></td>
+			<td>{{.Owner}}</td>
+			<td>{{.Description}}</td>
+			<td>{{if not .Expires.IsZero}}{{.Expires.Format "2006-01-02"}}{{end}}</td>
+			<td>{{.Visits}}</td>
 			<td>
-				<form method="POST">
+				{{if not .Created.IsZero}}{{.Created.Format "2006-01-02"}}{{end}}
+				<form method="POST" class="inline">
 					<input type="hidden" name="key" value="{{.Key}}">
 					<input type="submit" name="do" value="Delete" class="delete">
 				</form>
@@ -0,0 +1,74 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package short
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"golang.org/x/website/internal/storage"
+)
+
+// visitFlushInterval is how often visit counts are added to storage.
+const visitFlushInterval = time.Minute
+
+// A visitCounter counts visits to links in memory,
+// so that serving a link does not require a storage write.
+type visitCounter struct {
+	mu     sync.Mutex
+	counts map[string]int64 // link key -> visits not yet flushed
+}
+
+func newVisitCounter() *visitCounter {
+	return &visitCounter{counts: make(map[string]int64)}
+}
+
+func (c *visitCounter) add(key string, n int64) {
+	c.mu.Lock()
+	c.counts[key] += n
+	c.mu.Unlock()
+}
+
+// take returns the counts accumulated since the last call and resets them.
+func (c *visitCounter) take() map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts := c.counts
+	c.counts = make(map[string]int64)
+	return counts
+}
+
+// flushVisitsLoop calls flushVisits every interval, forever.
+func (h server) flushVisitsLoop(interval time.Duration) {
+	for range time.Tick(interval) {
+		h.flushVisits(context.Background())
+	}
+}
+
+// flushVisits adds the visits counted in memory to the links in storage.
+// It is called every visitFlushInterval and once more at shutdown.
+//
+// Storage has no transactions, so a flush racing with an edit of the same link
+// or with the flush of another server process can lose the visits counted
+// by one of them. Since flushes are infrequent, the counts are close enough
+// for analytics.
+func (h server) flushVisits(ctx context.Context) {
+	for key, n := range h.visits.take() {
+		var link Link
+		if err := h.db.Get(ctx, kind, key, &link); err != nil {
+			if err != storage.ErrNotFound {
+				log.Printf("ERROR flushing visits to %q: %v", key, err)
+			}
+			continue
+		}
+		link.Visits += n
+		if err := h.db.Put(ctx, kind, key, &link); err != nil {
+			log.Printf("ERROR flushing visits to %q: %v", key, err)
+			h.visits.add(key, n) // try again next time
+		}
+	}
+}
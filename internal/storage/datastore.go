@@ -0,0 +1,70 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"context"
+
+	"cloud.google.com/go/datastore"
+)
+
+// Datastore is a DB that keeps entities in Cloud Datastore,
+// each entity named by its key.
+type Datastore struct {
+	Client *datastore.Client
+
+	// Parents maps entity kinds to the ancestor key of all their
+	// entities, if any. Queries for a kind with a parent are
+	// strongly consistent.
+	Parents map[string]*datastore.Key
+}
+
+// NewDatastore returns a DB storing entities using dc.
+func NewDatastore(dc *datastore.Client) *Datastore {
+	return &Datastore{Client: dc}
+}
+
+func (d *Datastore) key(kind, key string) *datastore.Key {
+	return datastore.NameKey(kind, key, d.Parents[kind])
+}
+
+func (d *Datastore) Get(ctx context.Context, kind, key string, v interface{}) error {
+	err := d.Client.Get(ctx, d.key(kind, key), v)
+	if err == datastore.ErrNoSuchEntity {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (d *Datastore) Put(ctx context.Context, kind, key string, v interface{}) error {
+	_, err := d.Client.Put(ctx, d.key(kind, key), v)
+	return err
+}
+
+func (d *Datastore) Delete(ctx context.Context, kind, key string) error {
+	return d.Client.Delete(ctx, d.key(kind, key))
+}
+
+func (d *Datastore) GetAll(ctx context.Context, kind string, dst interface{}) error {
+	q := datastore.NewQuery(kind)
+	if parent := d.Parents[kind]; parent != nil {
+		q = q.Ancestor(parent)
+	}
+	_, err := d.Client.GetAll(ctx, q, dst)
+	return err
+}
+
+func (d *Datastore) GetLast(ctx context.Context, kind, field string, value interface{}, n int, dst interface{}) error {
+	q := datastore.NewQuery(kind).Order("-__key__").Limit(n)
+	if parent := d.Parents[kind]; parent != nil {
+		q = q.Ancestor(parent)
+	}
+	if field != "" {
+		// Needs a composite index on field and descending __key__.
+		q = q.Filter(field+" =", value)
+	}
+	_, err := d.Client.GetAll(ctx, q, dst)
+	return err
+}
@@ -0,0 +1,153 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Dir is a DB that keeps each entity in a file in a directory tree,
+// at dir/kind/key.gob.
+// It is meant for a server running on a single machine.
+type Dir struct {
+	dir string
+	mu  sync.RWMutex // serializes writes against reads within the process
+}
+
+// OpenDir returns a DB storing its entities in dir,
+// creating the directory if necessary.
+func OpenDir(dir string) (*Dir, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	return &Dir{dir: dir}, nil
+}
+
+// file returns the name of the file holding the given entity.
+// Kinds and keys are escaped so that any string is a valid key.
+func (d *Dir) file(kind, key string) string {
+	return filepath.Join(d.dir, url.PathEscape(kind), url.PathEscape(key)+".gob")
+}
+
+func (d *Dir) Get(ctx context.Context, kind, key string, v interface{}) error {
+	d.mu.RLock()
+	data, err := ioutil.ReadFile(d.file(kind, key))
+	d.mu.RUnlock()
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return decode(data, v)
+}
+
+func (d *Dir) Put(ctx context.Context, kind, key string, v interface{}) error {
+	data, err := encode(v)
+	if err != nil {
+		return err
+	}
+	name := d.file(kind, key)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+		return err
+	}
+	// Write to a temporary file and rename it into place,
+	// so that a crash never leaves a partly written entity.
+	f, err := ioutil.TempFile(filepath.Dir(name), ".tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+func (d *Dir) Delete(ctx context.Context, kind, key string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	err := os.Remove(d.file(kind, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (d *Dir) GetAll(ctx context.Context, kind string, dst interface{}) error {
+	add, err := appendFunc(dst)
+	if err != nil {
+		return err
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	keys, err := d.keys(kind)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		data, err := ioutil.ReadFile(d.file(kind, key))
+		if err != nil {
+			return err
+		}
+		if err := decode(data, add()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Dir) GetLast(ctx context.Context, kind, field string, value interface{}, n int, dst interface{}) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	keys, err := d.keys(kind)
+	if err != nil {
+		return err
+	}
+	read := func(key string) ([]byte, error) { return ioutil.ReadFile(d.file(kind, key)) }
+	return getLast(keys, read, field, value, n, dst)
+}
+
+// keys returns the keys of the entities of the given kind, in key order.
+// The caller must hold d.mu.
+func (d *Dir) keys(kind string) ([]string, error) {
+	list, err := ioutil.ReadDir(filepath.Join(d.dir, url.PathEscape(kind)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, fi := range list {
+		name := fi.Name()
+		if fi.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, ".gob") {
+			continue
+		}
+		key, err := url.PathUnescape(strings.TrimSuffix(name, ".gob"))
+		if err != nil {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
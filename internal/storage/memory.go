@@ -0,0 +1,96 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// Memory is a DB that keeps entities in memory,
+// for tests and for servers that need not keep their data.
+// The zero Memory is an empty DB ready to use.
+type Memory struct {
+	mu   sync.Mutex
+	data map[string]map[string][]byte // kind -> key -> encoded entity
+}
+
+// NewMemory returns a new, empty in-memory DB.
+func NewMemory() *Memory {
+	return new(Memory)
+}
+
+func (m *Memory) Get(ctx context.Context, kind, key string, v interface{}) error {
+	m.mu.Lock()
+	data, ok := m.data[kind][key]
+	m.mu.Unlock()
+	if !ok {
+		return ErrNotFound
+	}
+	return decode(data, v)
+}
+
+func (m *Memory) Put(ctx context.Context, kind, key string, v interface{}) error {
+	// Store the encoding, not v itself, so that later changes
+	// to v by the caller do not change the stored entity.
+	data, err := encode(v)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.data == nil {
+		m.data = make(map[string]map[string][]byte)
+	}
+	if m.data[kind] == nil {
+		m.data[kind] = make(map[string][]byte)
+	}
+	m.data[kind][key] = data
+	return nil
+}
+
+func (m *Memory) Delete(ctx context.Context, kind, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.data[kind], key)
+	return nil
+}
+
+func (m *Memory) GetAll(ctx context.Context, kind string, dst interface{}) error {
+	add, err := appendFunc(dst)
+	if err != nil {
+		return err
+	}
+	keys, data := m.list(kind)
+	for _, key := range keys {
+		if err := decode(data[key], add()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Memory) GetLast(ctx context.Context, kind, field string, value interface{}, n int, dst interface{}) error {
+	keys, data := m.list(kind)
+	read := func(key string) ([]byte, error) { return data[key], nil }
+	return getLast(keys, read, field, value, n, dst)
+}
+
+// list returns the keys of the entities of the given kind, in key order,
+// and a copy of the map from their keys to their encodings.
+func (m *Memory) list(kind string) (keys []string, data map[string][]byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entities := m.data[kind]
+	keys = make([]string, 0, len(entities))
+	data = make(map[string][]byte, len(entities))
+	for key, d := range entities {
+		keys = append(keys, key)
+		data[key] = d
+	}
+	sort.Strings(keys)
+	return keys, data
+}
@@ -0,0 +1,129 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package storage provides the entity storage used by the download
+// server and the link shortener.
+//
+// An entity is a struct value of some kind, such as "File" or "Link",
+// identified by a string key. The production server stores entities
+// in Cloud Datastore; a single machine can instead keep them in a
+// directory on disk or in memory.
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrNotFound is returned by Get for an entity that does not exist.
+var ErrNotFound = errors.New("storage: no such entity")
+
+// A DB stores entities by kind and key.
+type DB interface {
+	// Get loads the entity with the given kind and key into v,
+	// which must be a pointer to a struct.
+	// It returns ErrNotFound if there is no such entity.
+	Get(ctx context.Context, kind, key string, v interface{}) error
+
+	// Put stores v, a pointer to a struct, as the entity
+	// with the given kind and key, replacing any existing one.
+	Put(ctx context.Context, kind, key string, v interface{}) error
+
+	// Delete deletes the entity with the given kind and key.
+	// Deleting an entity that does not exist is not an error.
+	Delete(ctx context.Context, kind, key string) error
+
+	// GetAll loads all entities of the given kind, in key order,
+	// into dst, which must be a pointer to a slice of structs
+	// or of pointers to structs.
+	GetAll(ctx context.Context, kind string, dst interface{}) error
+
+	// GetLast loads into dst, as GetAll does, the last n entities
+	// of the given kind in key order, the entity with the greatest key
+	// first. If field is not empty, it considers only the entities
+	// whose field named field equals value.
+	GetLast(ctx context.Context, kind, field string, value interface{}, n int, dst interface{}) error
+}
+
+// appendFunc checks that dst is a pointer to a slice of structs
+// or of pointers to structs and returns a function that appends
+// a new zero element to the slice and returns a pointer to it.
+func appendFunc(dst interface{}) (func() interface{}, error) {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return nil, fmt.Errorf("storage: GetAll of %T, not pointer to slice", dst)
+	}
+	slice := v.Elem()
+	elem := slice.Type().Elem()
+	isPtr := elem.Kind() == reflect.Ptr
+	if isPtr {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("storage: GetAll of %T, not slice of structs", dst)
+	}
+	return func() interface{} {
+		p := reflect.New(elem)
+		if isPtr {
+			slice.Set(reflect.Append(slice, p))
+		} else {
+			slice.Set(reflect.Append(slice, p.Elem()))
+			p = slice.Index(slice.Len() - 1).Addr()
+		}
+		return p.Interface()
+	}, nil
+}
+
+// getLast implements GetLast for the Memory and Dir implementations.
+// The keys of the entities of the kind are keys, in key order,
+// and read returns the encoding of the entity with a given key.
+func getLast(keys []string, read func(key string) ([]byte, error), field string, value interface{}, n int, dst interface{}) error {
+	add, err := appendFunc(dst)
+	if err != nil {
+		return err
+	}
+	slice := reflect.ValueOf(dst).Elem()
+	for i := len(keys) - 1; i >= 0 && slice.Len() < n; i-- {
+		data, err := read(keys[i])
+		if err != nil {
+			return err
+		}
+		p := add()
+		if err := decode(data, p); err != nil {
+			return err
+		}
+		if field == "" {
+			continue
+		}
+		f := reflect.ValueOf(p).Elem().FieldByName(field)
+		if !f.IsValid() {
+			return fmt.Errorf("storage: GetLast of %T, which has no field %s", dst, field)
+		}
+		if !reflect.DeepEqual(f.Interface(), value) {
+			slice.SetLen(slice.Len() - 1)
+		}
+	}
+	return nil
+}
+
+// encode returns the encoding of the entity v
+// stored by the Memory and Dir implementations.
+// It uses gob rather than JSON so that all exported fields are kept,
+// including those omitted from the entity's JSON form.
+func encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decode decodes an entity encoded by encode into v.
+func decode(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
@@ -0,0 +1,131 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type entity struct {
+	Key     string
+	Target  string
+	Created time.Time `json:"-"`
+}
+
+func TestMemory(t *testing.T) {
+	testDB(t, NewMemory())
+}
+
+func TestDir(t *testing.T) {
+	dir := t.TempDir()
+	db, err := OpenDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testDB(t, db)
+
+	// Entities persist across reopening the directory.
+	db, err = OpenDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e entity
+	if err := db.Get(context.Background(), "Link", "a/b", &e); err != nil || e.Target != "slash" {
+		t.Errorf("after reopen: Get(a/b) = %+v, %v, want Target slash", e, err)
+	}
+}
+
+func testDB(t *testing.T, db DB) {
+	ctx := context.Background()
+	created := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	var e entity
+	if err := db.Get(ctx, "Link", "x", &e); err != ErrNotFound {
+		t.Fatalf("Get of missing entity: %v, want ErrNotFound", err)
+	}
+	for _, e := range []*entity{
+		{Key: "go", Target: "https://golang.org/", Created: created},
+		{Key: "a/b", Target: "slash"},
+		{Key: "b", Target: "old"},
+		{Key: "b", Target: "new"},
+	} {
+		if err := db.Put(ctx, "Link", e.Key, e); err != nil {
+			t.Fatalf("Put(%q): %v", e.Key, err)
+		}
+	}
+	if err := db.Put(ctx, "Other", "z", &entity{Key: "z"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Get(ctx, "Link", "go", &e); err != nil {
+		t.Fatal(err)
+	}
+	if want := (entity{Key: "go", Target: "https://golang.org/", Created: created}); !reflect.DeepEqual(e, want) {
+		t.Errorf("Get(go) = %+v, want %+v", e, want)
+	}
+
+	var list []entity
+	if err := db.GetAll(ctx, "Link", &list); err != nil {
+		t.Fatal(err)
+	}
+	var keys, targets []string
+	for _, e := range list {
+		keys = append(keys, e.Key)
+		targets = append(targets, e.Target)
+	}
+	if want := []string{"a/b", "b", "go"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("GetAll keys = %q, want %q", keys, want)
+	}
+	if want := []string{"slash", "new", "https://golang.org/"}; !reflect.DeepEqual(targets, want) {
+		t.Errorf("GetAll targets = %q, want %q", targets, want)
+	}
+
+	lastKeys := func(field string, value interface{}, n int) []string {
+		t.Helper()
+		var list []*entity
+		if err := db.GetLast(ctx, "Link", field, value, n, &list); err != nil {
+			t.Fatal(err)
+		}
+		var keys []string
+		for _, e := range list {
+			keys = append(keys, e.Key)
+		}
+		return keys
+	}
+	if keys, want := lastKeys("", nil, 2), []string{"go", "b"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("GetLast(2) keys = %q, want %q", keys, want)
+	}
+	if keys, want := lastKeys("Target", "slash", 2), []string{"a/b"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("GetLast(Target=slash, 2) keys = %q, want %q", keys, want)
+	}
+	var list2 []*entity
+	if err := db.GetLast(ctx, "Link", "Missing", "x", 1, &list2); err == nil {
+		t.Errorf("GetLast by missing field succeeded, want error")
+	}
+
+	if err := db.Delete(ctx, "Link", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(ctx, "Link", "b"); err != nil {
+		t.Errorf("Delete of missing entity: %v", err)
+	}
+	if err := db.Get(ctx, "Link", "b", &e); err != ErrNotFound {
+		t.Errorf("Get after Delete: %v, want ErrNotFound", err)
+	}
+
+	var ptrs []*entity
+	if err := db.GetAll(ctx, "Link", &ptrs); err != nil {
+		t.Fatal(err)
+	}
+	if len(ptrs) != 2 || ptrs[0].Key != "a/b" || ptrs[1].Key != "go" {
+		t.Errorf("GetAll after Delete returned %d entities", len(ptrs))
+	}
+	if err := db.GetAll(ctx, "Link", &e); err == nil {
+		t.Errorf("GetAll into %T succeeded, want error", &e)
+	}
+}
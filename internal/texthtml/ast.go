@@ -11,6 +11,7 @@ import (
 	"go/doc"
 	"go/token"
 	"strconv"
+	"text/template"
 	"unicode"
 	"unicode/utf8"
 )
@@ -20,10 +21,13 @@ import (
 type goLink struct {
 	path, name string // package path, identifier name
 	isVal      bool   // identifier is defined in a const or var declaration
+	url        string // if set, link to url instead
 }
 
 func (l *goLink) tags() (start, end string) {
 	switch {
+	case l.url != "":
+		return `<a href="` + template.HTMLEscapeString(l.url) + `">`, `</a>`
 	case l.path != "" && l.name == "":
 		// package path
 		return `<a href="/pkg/` + l.path + `/">`, `</a>`
@@ -44,7 +48,8 @@ func (l *goLink) tags() (start, end string) {
 
 // goLinksFor returns the list of links for the identifiers used
 // by node in the same order as they appear in the source.
-func goLinksFor(node ast.Node) (links []goLink) {
+// If identURL is not nil, it overrides the links found syntactically.
+func goLinksFor(node ast.Node, identURL IdentURL) (links []goLink) {
 	// linkMap tracks link information for each ast.Ident node. Entries may
 	// be created out of source order (for example, when we visit a parent
 	// definition node). These links are appended to the returned slice when
@@ -141,6 +146,12 @@ func goLinksFor(node ast.Node) (links []goLink) {
 				}
 			}
 		case *ast.Ident:
+			if identURL != nil {
+				if url, ok := identURL(n); ok {
+					links = append(links, goLink{url: url})
+					return true
+				}
+			}
 			if l, ok := linkMap[n]; ok {
 				links = append(links, l)
 			} else {
@@ -148,6 +159,13 @@ func goLinksFor(node ast.Node) (links []goLink) {
 				if n.Obj == nil && doc.IsPredeclared(n.Name) {
 					l.path = "builtin"
 				}
+				if n.Obj != nil {
+					if _, ok := n.Obj.Decl.(*ast.Field); ok {
+						// A use of a type parameter or function parameter,
+						// not of a package-level declaration.
+						l = goLink{}
+					}
+				}
 				links = append(links, l)
 			}
 		}
@@ -37,6 +37,18 @@ type Config struct {
 	Highlight  string    // highlight matches for this regexp with <span class="highlight">
 	Selection  Selection // mark selected spans with <span class="selection">
 	AST        ast.Node  // link uses to declarations, assuming text is formatting of AST
+	IdentURL   IdentURL  // with AST, link identifiers to the URLs it returns, if any
+	Links      []Link    // link these spans, which must be sorted and non-overlapping; ignored if AST is set
+}
+
+// An IdentURL returns the URL to link the identifier id to, or "" for no link.
+// If ok is false, the identifier is linked as if there were no IdentURL.
+type IdentURL func(id *ast.Ident) (url string, ok bool)
+
+// A Link describes a hyperlink from the text span [Start, End) to URL.
+type Link struct {
+	Start, End int
+	URL        string
 }
 
 // Format formats text to HTML according to the configuration cfg.
@@ -54,7 +66,15 @@ func Format(text []byte, cfg Config) (html []byte) {
 	var goLinks []goLink
 	if cfg.AST != nil {
 		idents = tokenSelection(text, token.IDENT)
-		goLinks = goLinksFor(cfg.AST)
+		goLinks = goLinksFor(cfg.AST, cfg.IdentURL)
+	} else if len(cfg.Links) > 0 {
+		spans := make([]Span, len(cfg.Links))
+		goLinks = make([]goLink, len(cfg.Links))
+		for i, l := range cfg.Links {
+			spans[i] = Span{l.Start, l.End}
+			goLinks[i] = goLink{url: l.URL}
+		}
+		idents = Spans(spans...)
 	}
 
 	formatSelections(&buf, text, goLinks, comments, highlights, cfg.Selection, idents)
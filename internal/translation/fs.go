@@ -0,0 +1,230 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+// Package translation serves translated website content layered
+// over the English originals, and reports the translation status
+// of individual documents.
+package translation
+
+import (
+	"io/fs"
+	"log"
+	"path"
+	"strings"
+	"sync"
+)
+
+// A Layer identifies one of the file systems that make up an FS.
+type Layer int
+
+const (
+	LayerNone        Layer = iota // file not found in any layer
+	LayerTranslation              // translated overlay
+	LayerContent                  // English website content (_content)
+	LayerGOROOT                   // Go distribution ($GOROOT)
+)
+
+var layerNames = []string{
+	LayerNone:        "none",
+	LayerTranslation: "translation",
+	LayerContent:     "content",
+	LayerGOROOT:      "goroot",
+}
+
+func (l Layer) String() string {
+	if 0 <= l && int(l) < len(layerNames) {
+		return layerNames[l]
+	}
+	return "unknown"
+}
+
+// Status describes where a served document came from.
+type Status struct {
+	Layer      Layer // layer providing the served file
+	Translated bool  // file is served from the translated overlay
+	Stale      bool  // English source has changed since the translation's UpstreamRev
+}
+
+// Untranslated reports whether the document is served in English
+// from a file system that carries a translated overlay.
+func (s Status) Untranslated() bool {
+	return !s.Translated && s.Layer != LayerNone
+}
+
+var _ fs.ReadDirFS = (*FS)(nil)
+
+// An FS is a file system presenting a translated overlay
+// on top of the English website content and GOROOT.
+// If multiple layers provide a particular file, Open uses the
+// topmost one: the translation, then the content, then GOROOT.
+// If multiple layers provide a particular directory, ReadDir presents
+// the concatenation of all the directories (with duplicates removed).
+type FS struct {
+	layers [LayerGOROOT + 1]fs.FS // indexed by Layer; nil if absent
+
+	reportOnce sync.Once
+	report     map[string]*Doc // by name in the FS; see readReport
+}
+
+// ReportFile is the name of the translation status report in the
+// translated overlay, as written by "transtatus -json".
+const ReportFile = "translations.json"
+
+// NewFS returns a new FS layering translation over content over goroot.
+// Any of the file systems may be nil, in which case that layer is omitted.
+func NewFS(translation, content, goroot fs.FS) *FS {
+	fsys := new(FS)
+	fsys.layers[LayerTranslation] = translation
+	fsys.layers[LayerContent] = content
+	fsys.layers[LayerGOROOT] = goroot
+	return fsys
+}
+
+// HasTranslation reports whether fsys has a translated overlay.
+func (fsys *FS) HasTranslation() bool {
+	return fsys.layers[LayerTranslation] != nil
+}
+
+// English returns the file system fsys presents without its
+// translated overlay: the English content over GOROOT.
+func (fsys *FS) English() *FS {
+	return NewFS(nil, fsys.layers[LayerContent], fsys.layers[LayerGOROOT])
+}
+
+func (fsys *FS) Open(name string) (fs.File, error) {
+	var errOut error
+	for _, sub := range fsys.layers {
+		if sub == nil {
+			continue
+		}
+		f, err := sub.Open(name)
+		if err == nil {
+			// Note: Should technically check for directory
+			// and return a synthetic directory that merges
+			// reads from all the matching directories,
+			// but all the directory reads in internal/godoc
+			// come from fsys.ReadDir, which does that for us.
+			// So we can ignore direct f.ReadDir calls.
+			return f, nil
+		}
+		if errOut == nil {
+			errOut = err
+		}
+	}
+	if errOut == nil {
+		errOut = &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return nil, errOut
+}
+
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	var all []fs.DirEntry
+	var seen map[string]bool // seen[name] is true if name is listed in all; lazily initialized
+	var errOut error
+	for _, sub := range fsys.layers {
+		if sub == nil {
+			continue
+		}
+		list, err := fs.ReadDir(sub, toFS(name))
+		if err != nil {
+			errOut = err
+		}
+		if len(all) == 0 {
+			all = append(all, list...)
+		} else {
+			if seen == nil {
+				// Initialize seen only after we get two different directory listings.
+				seen = make(map[string]bool)
+				for _, d := range all {
+					seen[d.Name()] = true
+				}
+			}
+			for _, d := range list {
+				name := d.Name()
+				if !seen[name] {
+					seen[name] = true
+					all = append(all, d)
+				}
+			}
+		}
+	}
+	if len(all) > 0 {
+		return all, nil
+	}
+	return nil, errOut
+}
+
+// Status reports which layer serves the named file and,
+// for translated files, whether the translation is stale.
+//
+// A translation is stale if the English file it shadows has changed
+// since the UpstreamRev recorded in the translation, as reported by the
+// ReportFile in the translated overlay. Without a report, or a document
+// in it, Status reports no translation as stale.
+func (fsys *FS) Status(name string) Status {
+	name = toFS(name)
+	var st Status
+	for l, sub := range fsys.layers {
+		if sub == nil {
+			continue
+		}
+		if _, err := fs.Stat(sub, name); err != nil {
+			continue
+		}
+		switch {
+		case Layer(l) == LayerTranslation:
+			st.Layer = LayerTranslation
+			st.Translated = true
+		case st.Translated:
+			// First English file shadowed by the translation.
+			if d := fsys.reportDoc(name); d != nil {
+				st.Stale = d.State() == StateOutdated
+			}
+			return st
+		default:
+			st.Layer = Layer(l)
+			return st
+		}
+	}
+	return st
+}
+
+// reportDoc returns the status in the translated overlay's ReportFile
+// of the named file, or nil if there is none.
+func (fsys *FS) reportDoc(name string) *Doc {
+	fsys.reportOnce.Do(fsys.readReport)
+	return fsys.report[name]
+}
+
+// readReport reads the translated overlay's ReportFile into fsys.report.
+// The report names documents relative to the repository root;
+// the FS serves the "_content" directory.
+func (fsys *FS) readReport() {
+	data, err := fs.ReadFile(fsys.layers[LayerTranslation], ReportFile)
+	if err != nil {
+		return
+	}
+	r, err := ReadReport(data)
+	if err != nil {
+		log.Printf("reading %s: %v", ReportFile, err)
+		return
+	}
+	fsys.report = make(map[string]*Doc)
+	for _, d := range r.Docs {
+		if name := strings.TrimPrefix(d.Path, "_content/"); name != d.Path {
+			fsys.report[name] = d
+		}
+	}
+}
+
+// toFS returns the io/fs name for name (no leading slash).
+func toFS(name string) string {
+	if name == "/" || name == "" {
+		return "."
+	}
+	return path.Clean(strings.TrimPrefix(name, "/"))
+}
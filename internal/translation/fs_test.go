@@ -0,0 +1,92 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package translation
+
+import (
+	"io/fs"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFS(t *testing.T) {
+	// Staleness comes from the report, not modification times,
+	// which embedded content does not have.
+	report := `{"docs": [
+		{"path": "_content/doc/fresh.html", "translated": true, "upstreamRev": "abc", "diffLines": 0},
+		{"path": "_content/doc/stale.html", "translated": true, "upstreamRev": "abc", "diffLines": 3},
+		{"path": "_content/doc/norev.html", "translated": true, "diffLines": -1}
+	]}`
+	fsys := NewFS(
+		fstest.MapFS{
+			"doc/fresh.html":    {Data: []byte("свежий")},
+			"doc/stale.html":    {Data: []byte("старый")},
+			"doc/norev.html":    {Data: []byte("без версии")},
+			"translations.json": {Data: []byte(report)},
+		},
+		fstest.MapFS{
+			"doc/fresh.html":   {Data: []byte("fresh")},
+			"doc/stale.html":   {Data: []byte("stale")},
+			"doc/norev.html":   {Data: []byte("no rev")},
+			"doc/english.html": {Data: []byte("english")},
+			"lib/godoc/x.html": {Data: []byte("template")},
+			"src/fmt/print.go": {Data: []byte("content copy")},
+		},
+		fstest.MapFS{
+			"src/fmt/print.go": {Data: []byte("package fmt")},
+			"src/fmt/scan.go":  {Data: []byte("package fmt")},
+		},
+	)
+
+	for _, tt := range []struct {
+		name string
+		data string
+		want Status
+	}{
+		{"/doc/fresh.html", "свежий", Status{Layer: LayerTranslation, Translated: true}},
+		{"doc/stale.html", "старый", Status{Layer: LayerTranslation, Translated: true, Stale: true}},
+		{"doc/norev.html", "без версии", Status{Layer: LayerTranslation, Translated: true}},
+		{"doc/english.html", "english", Status{Layer: LayerContent}},
+		{"src/fmt/print.go", "content copy", Status{Layer: LayerContent}},
+		{"src/fmt/scan.go", "package fmt", Status{Layer: LayerGOROOT}},
+		{"doc/missing.html", "", Status{}},
+	} {
+		if got := fsys.Status(tt.name); got != tt.want {
+			t.Errorf("Status(%q) = %+v; want %+v", tt.name, got, tt.want)
+		}
+		if tt.data == "" {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, strings.TrimPrefix(tt.name, "/"))
+		if err != nil {
+			t.Errorf("ReadFile(%q): %v", tt.name, err)
+			continue
+		}
+		if string(data) != tt.data {
+			t.Errorf("ReadFile(%q) = %q; want %q", tt.name, data, tt.data)
+		}
+	}
+
+	list, err := fs.ReadDir(fsys, "src/fmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, d := range list {
+		names = append(names, d.Name())
+	}
+	sort.Strings(names)
+	if got, want := strings.Join(names, " "), "print.go scan.go"; got != want {
+		t.Errorf("ReadDir(src/fmt) = %s; want %s", got, want)
+	}
+
+	if st := fsys.Status("doc/english.html"); !st.Untranslated() {
+		t.Errorf("Status(doc/english.html).Untranslated() = false; want true")
+	}
+}
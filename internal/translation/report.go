@@ -0,0 +1,217 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package translation
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/website/internal/metadata"
+)
+
+// A Source describes a tree of translatable documents
+// in the website repository.
+type Source struct {
+	Dir     string // directory relative to the repository root
+	Recurse bool   // whether to descend into subdirectories
+	Exts    []string
+}
+
+// Sources lists the translatable documents in the website repository:
+// the site content, the tour and the blog.
+var Sources = []Source{
+	{Dir: "_content", Recurse: true, Exts: []string{".html", ".md"}},
+	{Dir: "tour/content", Exts: []string{".article"}},
+	{Dir: "blog/_content", Exts: []string{".article"}},
+}
+
+func (s Source) match(name string) bool {
+	ext := path.Ext(name)
+	for _, x := range s.Exts {
+		if x == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// A Report summarizes the translation status of the website.
+type Report struct {
+	Time time.Time `json:"time"`
+	Docs []*Doc    `json:"docs"`
+}
+
+// A Doc is the translation status of a single document.
+type Doc struct {
+	Path        string `json:"path"`                  // path relative to the repository root
+	Translated  bool   `json:"translated"`            // whether a translation exists
+	UpstreamRev string `json:"upstreamRev,omitempty"` // English revision the translation was synced to
+	DiffLines   int    `json:"diffLines"`             // lines changed upstream since UpstreamRev; -1 if unknown
+}
+
+// Document states reported by Doc.State.
+const (
+	StateMissing  = "missing"  // no translation
+	StateUnknown  = "unknown"  // translated, but not anchored to an upstream revision
+	StateCurrent  = "current"  // translated, no upstream changes since
+	StateOutdated = "outdated" // translated, upstream has changed since
+)
+
+// State returns a summary of the document's translation status.
+func (d *Doc) State() string {
+	switch {
+	case !d.Translated:
+		return StateMissing
+	case d.UpstreamRev == "" || d.DiffLines < 0:
+		return StateUnknown
+	case d.DiffLines == 0:
+		return StateCurrent
+	}
+	return StateOutdated
+}
+
+// A DiffFunc returns the number of lines changed in the named file
+// of the English repository since the revision rev.
+type DiffFunc func(rev, name string) (int, error)
+
+// Scan compares the English documents listed by Sources in repo
+// with their translations in trans, which mirrors the repository layout.
+// If diff is non-nil, it is used to measure the upstream changes
+// since each translation's UpstreamRev.
+func Scan(repo, trans fs.FS, diff DiffFunc) (*Report, error) {
+	r := &Report{Time: time.Now().UTC()}
+	for _, src := range Sources {
+		err := fs.WalkDir(repo, src.Dir, func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if name != src.Dir && !src.Recurse {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if !src.match(name) {
+				return nil
+			}
+			doc := &Doc{Path: name, DiffLines: -1}
+			if data, err := fs.ReadFile(trans, name); err == nil {
+				doc.Translated = true
+				doc.UpstreamRev = UpstreamRev(data)
+				if doc.UpstreamRev != "" && diff != nil {
+					n, err := diff(doc.UpstreamRev, name)
+					if err != nil {
+						return fmt.Errorf("%s: %v", name, err)
+					}
+					doc.DiffLines = n
+				}
+			}
+			r.Docs = append(r.Docs, doc)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(r.Docs, func(i, j int) bool { return r.Docs[i].Path < r.Docs[j].Path })
+	return r, nil
+}
+
+// Count returns the number of documents in r with the given state.
+func (r *Report) Count(state string) int {
+	n := 0
+	for _, d := range r.Docs {
+		if d.State() == state {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteText writes r to w as a plain text table.
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "PATH\tSTATE\tREV\tDIFF\n")
+	for _, d := range r.Docs {
+		diff := "-"
+		if d.DiffLines >= 0 {
+			diff = fmt.Sprint(d.DiffLines)
+		}
+		rev := d.UpstreamRev
+		if rev == "" {
+			rev = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", d.Path, d.State(), rev, diff)
+	}
+	fmt.Fprintf(tw, "\n%d documents: %d current, %d outdated, %d unknown, %d missing\n",
+		len(r.Docs), r.Count(StateCurrent), r.Count(StateOutdated), r.Count(StateUnknown), r.Count(StateMissing))
+	return tw.Flush()
+}
+
+// ReadReport decodes a JSON-encoded report, as written by cmd/transtatus.
+func ReadReport(data []byte) (*Report, error) {
+	r := new(Report)
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// UpstreamRev returns the English revision recorded in a translated document,
+// or the empty string if there is none.
+//
+// HTML and Markdown documents record it in their metadata block,
+// as parsed by package metadata:
+//
+//	<!--{
+//		"Title": "...",
+//		"UpstreamRev": "2e4b3a7"
+//	}-->
+//
+// or in their YAML front matter:
+//
+//	---
+//	Title: ...
+//	UpstreamRev: "2e4b3a7"
+//	---
+//
+// Articles record it as a header line before the first section:
+//
+//	UpstreamRev: 2e4b3a7
+func UpstreamRev(data []byte) string {
+	var meta struct{ UpstreamRev string }
+	tail, err := metadata.Parse(data, &meta)
+	if err != nil {
+		return ""
+	}
+	if len(tail) < len(data) {
+		// The document has a metadata block, so the revision is there or nowhere.
+		return meta.UpstreamRev
+	}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "*") || strings.HasPrefix(line, "##") {
+			// Start of the article body.
+			break
+		}
+		if rev := strings.TrimPrefix(line, "UpstreamRev:"); rev != line {
+			return strings.TrimSpace(rev)
+		}
+	}
+	return ""
+}
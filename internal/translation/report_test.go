@@ -0,0 +1,85 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package translation
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestUpstreamRev(t *testing.T) {
+	for _, tt := range []struct {
+		in, want string
+	}{
+		{"<!--{\n\t\"Title\": \"Установка\",\n\t\"UpstreamRev\": \"abc123\"\n}-->\nТекст", "abc123"},
+		{"<!--{\n\t\"Title\": \"Установка\"\n}-->\nТекст", ""},
+		{"<!--{ broken", ""},
+		{"---\nTitle: Установка\nUpstreamRev: abc123\n---\nТекст", "abc123"},
+		{"---\nTitle: Установка\n---\nUpstreamRev: late\n", ""},
+		{"---\nUpstreamRev: \"1234567\"\n---\n", "1234567"},
+		{"Пакеты\nUpstreamRev: abc123\n\nThe Go Authors\n\n* Пакеты\n", "abc123"},
+		{"# Go исполняется 10\n8 Nov 2019\nSummary: С днём рождения!\nUpstreamRev:  def456 \n\n##\n", "def456"},
+		{"Пакеты\n\n* Пакеты\n\nUpstreamRev: late\n", ""},
+	} {
+		if got := UpstreamRev([]byte(tt.in)); got != tt.want {
+			t.Errorf("UpstreamRev(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	repo := fstest.MapFS{
+		"_content/doc/a.html":           {},
+		"_content/doc/b.md":             {},
+		"_content/doc/c.html":           {},
+		"_content/doc/d.html":           {},
+		"_content/doc/logo.png":         {},
+		"tour/content/basics.article":   {},
+		"tour/content/img/x.article":    {},
+		"blog/_content/gos10.article":   {},
+		"blog/_content/gos10/main.go":   {},
+		"cmd/golangorg/ignored.article": {},
+	}
+	trans := fstest.MapFS{
+		"_content/doc/a.html":         {Data: []byte("<!--{\"UpstreamRev\": \"old\"}-->")},
+		"_content/doc/b.md":           {Data: []byte("<!--{\"UpstreamRev\": \"new\"}-->")},
+		"_content/doc/c.html":         {Data: []byte("без метаданных")},
+		"tour/content/basics.article": {Data: []byte("Пакеты\nUpstreamRev: new\n")},
+	}
+	diff := func(rev, name string) (int, error) {
+		if rev == "old" {
+			return 7, nil
+		}
+		return 0, nil
+	}
+	r, err := Scan(repo, trans, diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"_content/doc/a.html":         StateOutdated,
+		"_content/doc/b.md":           StateCurrent,
+		"_content/doc/c.html":         StateUnknown,
+		"_content/doc/d.html":         StateMissing,
+		"tour/content/basics.article": StateCurrent,
+		"blog/_content/gos10.article": StateMissing,
+	}
+	if len(r.Docs) != len(want) {
+		t.Errorf("Scan found %d documents; want %d", len(r.Docs), len(want))
+	}
+	for _, d := range r.Docs {
+		if got, ok := want[d.Path]; !ok {
+			t.Errorf("Scan found unexpected document %s", d.Path)
+		} else if d.State() != got {
+			t.Errorf("%s: State() = %s; want %s", d.Path, d.State(), got)
+		}
+	}
+	if got := r.Count(StateMissing); got != 2 {
+		t.Errorf("Count(StateMissing) = %d; want 2", got)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vanity
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"gopkg.in/yaml.v2"
+)
+
+// LoadConfig reads a Config from the named YAML or JSON file, such as:
+//
+//	host: google.golang.org
+//	index: https://cloud.google.com/go/google.golang.org
+//	redirect_browsers: true
+//	paths:
+//	- prefix: google.golang.org/grpc
+//	  vcs: git
+//	  repo: https://github.com/grpc/grpc-go
+//	  source:
+//	    home: https://github.com/grpc/grpc-go
+//	    dir: https://github.com/grpc/grpc-go/tree/master{/dir}
+//	    file: https://github.com/grpc/grpc-go/blob/master{/dir}/{file}#L{line}
+//
+// Unknown keys are an error.
+func LoadConfig(file string) (*Config, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is YAML too.
+	cfg := new(Config)
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return cfg, nil
+}
+
+var vcsNames = map[string]bool{
+	"bzr":    true,
+	"fossil": true,
+	"git":    true,
+	"hg":     true,
+	"mod":    true,
+	"svn":    true,
+}
+
+// placeholder matches the {name} placeholders in URL templates.
+var placeholder = regexp.MustCompile(`{[^{}]*}`)
+
+// Check checks that cfg is valid: that every Path has an import path
+// under cfg.Host that is a valid module path, a known VCS, absolute
+// repository and documentation URLs, and source templates using only
+// the placeholders the go-source meta tag defines.
+// It also checks that no prefix is listed twice, and that prefixes
+// are nested only to serve major versions from their own repositories.
+// Check reports all the problems it finds, one per line.
+func (cfg *Config) Check() error {
+	var errs []string
+	report := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+
+	host := strings.TrimSuffix(cfg.Host, "/")
+	if err := module.CheckImportPath(host); err != nil {
+		report("host: %v", err)
+	}
+	if cfg.Index != "" {
+		if err := checkURL(cfg.Index, nil); err != nil {
+			report("index: %v", err)
+		}
+	}
+	if len(cfg.Paths) == 0 {
+		report("no paths")
+	}
+
+	seen := make(map[string]bool)
+	for i, p := range cfg.Paths {
+		name := p.Prefix
+		if name == "" {
+			name = fmt.Sprintf("paths[%d]", i)
+		}
+		if !strings.HasPrefix(p.Prefix, host+"/") {
+			report("%s: prefix not under host %s", name, host)
+		} else if err := module.CheckPath(p.Prefix); err != nil {
+			report("%s: %v", name, err)
+		}
+		if seen[p.Prefix] {
+			report("%s: listed twice", name)
+		}
+		seen[p.Prefix] = true
+		if !vcsNames[p.VCS] {
+			report("%s: unknown vcs %q", name, p.VCS)
+		}
+		if err := checkURL(p.Repo, nil); err != nil {
+			report("%s: repo: %v", name, err)
+		}
+		if p.Doc != "" {
+			if err := checkURL(p.Doc, []string{"{path}", "{module}"}); err != nil {
+				report("%s: doc: %v", name, err)
+			}
+		}
+		if s := p.Source; s != nil {
+			if err := checkURL(s.Home, nil); err != nil {
+				report("%s: source home: %v", name, err)
+			}
+			if err := checkURL(s.Dir, []string{"{dir}", "{/dir}"}); err != nil {
+				report("%s: source dir: %v", name, err)
+			}
+			if err := checkURL(s.File, []string{"{dir}", "{/dir}", "{file}", "{line}"}); err != nil {
+				report("%s: source file: %v", name, err)
+			} else if !strings.Contains(s.File, "{file}") {
+				report("%s: source file: missing {file}", name)
+			}
+		}
+	}
+
+	for _, p := range cfg.Paths {
+		for _, q := range cfg.Paths {
+			if !strings.HasPrefix(q.Prefix, p.Prefix+"/") {
+				continue
+			}
+			if prefix, major, ok := module.SplitPathVersion(q.Prefix); !ok || major == "" || prefix != p.Prefix {
+				report("%s: nested in %s, but not as a major version", q.Prefix, p.Prefix)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// checkURL checks that u, with its {name} placeholders
+// replaced, is an absolute URL, and that it uses only the
+// placeholders listed in allowed.
+func checkURL(u string, allowed []string) error {
+	if u == "" {
+		return errors.New("missing")
+	}
+	for _, ph := range placeholder.FindAllString(u, -1) {
+		ok := false
+		for _, a := range allowed {
+			if ph == a {
+				ok = true
+			}
+		}
+		if !ok {
+			return fmt.Errorf("unknown placeholder %s in %s", ph, u)
+		}
+	}
+	parsed, err := url.Parse(placeholder.ReplaceAllString(u, "x"))
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return fmt.Errorf("%s is not an absolute URL", u)
+	}
+	return nil
+}
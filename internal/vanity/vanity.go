@@ -0,0 +1,176 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package vanity serves vanity import paths, like golang.org/x/tools
+// or google.golang.org/grpc: it tells the go command which repository
+// holds the code for an import path, and it sends people visiting
+// the import path in a browser to its documentation.
+//
+// See 'go help importpath' for the mechanics.
+package vanity
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/module"
+)
+
+// A Config maps the import paths under a host to repositories.
+// It is usually loaded from a file by LoadConfig.
+type Config struct {
+	// Host is the import path prefix served, like "google.golang.org".
+	// A request for /grpc/codes is for the import path Host+"/grpc/codes".
+	Host string `yaml:"host"`
+
+	// Index, if set, is the URL to which requests for the root are redirected.
+	Index string `yaml:"index"`
+
+	// RedirectBrowsers reports whether requests not made by the go command
+	// (without ?go-get=1) are redirected to the documentation with an HTTP
+	// redirect, rather than served a page with go-import meta tags that
+	// redirects when loaded.
+	RedirectBrowsers bool `yaml:"redirect_browsers"`
+
+	// Paths lists the mappings.
+	// A request is served by the one with the longest matching prefix.
+	Paths []*Path `yaml:"paths"`
+}
+
+// A Path maps an import path prefix to a repository.
+type Path struct {
+	// Prefix is the import path prefix, like "google.golang.org/grpc",
+	// which is also the path of the module at the root of the repository.
+	// It may end in a major version suffix, like "example.com/mod/v2",
+	// for a major version kept in a repository of its own.
+	Prefix string `yaml:"prefix"`
+
+	VCS  string `yaml:"vcs"`  // version control system: "git", "hg", "svn", "bzr", "fossil" or "mod"
+	Repo string `yaml:"repo"` // repository URL
+
+	// Source, if set, is served in a go-source meta tag,
+	// which tells documentation sites how to link to source files.
+	Source *Source `yaml:"source"`
+
+	// Doc is the documentation URL, in which {path} is replaced by
+	// the requested import path and {module} by its module path.
+	// The default is "https://pkg.go.dev/{path}".
+	Doc string `yaml:"doc"`
+}
+
+// A Source holds the URL templates of a go-source meta tag.
+// In Dir and File, {dir} or {/dir} is replaced by the directory
+// within the repository, {file} by the file name,
+// and in File, {line} by the line number.
+type Source struct {
+	Home string `yaml:"home"` // home page of the repository
+	Dir  string `yaml:"dir"`  // directory listing
+	File string `yaml:"file"` // file, at a line
+}
+
+// DefaultDoc is the documentation URL used when a Path does not set Doc.
+const DefaultDoc = "https://pkg.go.dev/{path}"
+
+// Handler returns a handler serving the import paths in cfg.
+// Requests for unknown import paths get a 404.
+func Handler(cfg *Config) http.Handler {
+	s := &server{cfg: cfg, paths: append([]*Path(nil), cfg.Paths...)}
+	sort.SliceStable(s.paths, func(i, j int) bool {
+		return len(s.paths[i].Prefix) > len(s.paths[j].Prefix)
+	})
+	return s
+}
+
+type server struct {
+	cfg   *Config
+	paths []*Path // longest prefix first
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/" || r.URL.Path == "" {
+		if s.cfg.Index == "" {
+			http.NotFound(w, r)
+			return
+		}
+		http.Redirect(w, r, s.cfg.Index, http.StatusTemporaryRedirect)
+		return
+	}
+	importPath := strings.TrimSuffix(s.cfg.Host, "/") + r.URL.Path
+	p := s.lookup(importPath)
+	if p == nil {
+		http.NotFound(w, r)
+		return
+	}
+	doc := p.doc(importPath)
+	if s.cfg.RedirectBrowsers && r.FormValue("go-get") == "" {
+		http.Redirect(w, r, doc, http.StatusFound)
+		return
+	}
+	data := struct {
+		*Path
+		Doc string
+	}{p, doc}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.Execute(w, &data); err != nil {
+		log.Printf("vanity: %s: %v", importPath, err)
+	}
+}
+
+// lookup returns the Path serving importPath, or nil if there is none.
+func (s *server) lookup(importPath string) *Path {
+	for _, p := range s.paths {
+		if importPath == p.Prefix || strings.HasPrefix(importPath, p.Prefix+"/") {
+			return p
+		}
+	}
+	return nil
+}
+
+// doc returns the documentation URL for importPath.
+func (p *Path) doc(importPath string) string {
+	doc := p.Doc
+	if doc == "" {
+		doc = DefaultDoc
+	}
+	return strings.NewReplacer("{path}", importPath, "{module}", p.module(importPath)).Replace(doc)
+}
+
+// module returns the module path of importPath: the prefix,
+// followed by the major version suffix that starts the rest of
+// importPath, if any. The go command finds the modules for major
+// versions 2 and up in the same repository, either in a subdirectory
+// or on a branch, so the go-import meta tag is the same for all.
+func (p *Path) module(importPath string) string {
+	rest := strings.TrimPrefix(importPath, p.Prefix)
+	if _, major, _ := module.SplitPathVersion(p.Prefix); major != "" || rest == "" {
+		return p.Prefix
+	}
+	elem := rest
+	if i := strings.Index(rest[1:], "/"); i >= 0 {
+		elem = rest[:1+i]
+	}
+	if _, major, ok := module.SplitPathVersion(p.Prefix + elem); ok && major != "" {
+		return p.Prefix + major
+	}
+	return p.Prefix
+}
+
+var tmpl = template.Must(template.New("vanity").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta http-equiv="Content-Type" content="text/html; charset=utf-8"/>
+<meta name="go-import" content="{{.Prefix}} {{.VCS}} {{.Repo}}">
+{{with .Source -}}
+<meta name="go-source" content="{{$.Prefix}} {{.Home}} {{.Dir}} {{.File}}">
+{{end -}}
+<meta http-equiv="refresh" content="0; url={{.Doc}}">
+</head>
+<body>
+<a href="{{.Doc}}">Redirecting to documentation...</a>
+</body>
+</html>
+`))
@@ -0,0 +1,194 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vanity
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testConfig = &Config{
+	Host:  "example.com",
+	Index: "https://example.com/about",
+	Paths: []*Path{
+		{
+			Prefix: "example.com/mod",
+			VCS:    "git",
+			Repo:   "https://github.com/example/mod",
+			Source: &Source{
+				Home: "https://github.com/example/mod",
+				Dir:  "https://github.com/example/mod/tree/main{/dir}",
+				File: "https://github.com/example/mod/blob/main{/dir}/{file}#L{line}",
+			},
+			Doc: "https://docs.example.com/{module}?path={path}",
+		},
+		{
+			Prefix: "example.com/mod/v3",
+			VCS:    "hg",
+			Repo:   "https://hg.example.com/mod-v3",
+		},
+	},
+}
+
+func TestHandler(t *testing.T) {
+	h := Handler(testConfig)
+	for _, tc := range []struct {
+		path   string
+		code   int
+		prefix string // go-import prefix
+		repo   string
+		doc    string
+	}{
+		{"/", 307, "", "", ""},
+		{"/mod", 200, "example.com/mod", "git https://github.com/example/mod", "example.com/mod?path=example.com/mod"},
+		{"/mod/sub/pkg", 200, "example.com/mod", "git https://github.com/example/mod", "example.com/mod?path=example.com/mod/sub/pkg"},
+		{"/mod/v2", 200, "example.com/mod", "git https://github.com/example/mod", "example.com/mod/v2?path=example.com/mod/v2"},
+		{"/mod/v2/sub", 200, "example.com/mod", "git https://github.com/example/mod", "example.com/mod/v2?path=example.com/mod/v2/sub"},
+		{"/mod/v1/sub", 200, "example.com/mod", "git https://github.com/example/mod", "example.com/mod?path=example.com/mod/v1/sub"},
+		{"/mod/v3/sub", 200, "example.com/mod/v3", "hg https://hg.example.com/mod-v3", ""},
+		{"/modx", 404, "", "", ""},
+		{"/other", 404, "", "", ""},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", tc.path+"?go-get=1", nil))
+		if w.Code != tc.code {
+			t.Errorf("%s: code %d, want %d", tc.path, w.Code, tc.code)
+			continue
+		}
+		if tc.code != 200 {
+			continue
+		}
+		body := w.Body.String()
+		if want := `<meta name="go-import" content="` + tc.prefix + ` ` + tc.repo + `">`; !strings.Contains(body, want) {
+			t.Errorf("%s: missing %s in:\n%s", tc.path, want, body)
+		}
+		if tc.doc == "" {
+			if want := `url=https://pkg.go.dev/example.com` + tc.path + `"`; !strings.Contains(body, want) {
+				t.Errorf("%s: missing %s in:\n%s", tc.path, want, body)
+			}
+		} else if want := `url=https://docs.example.com/` + tc.doc; !strings.Contains(body, want) {
+			t.Errorf("%s: missing %s in:\n%s", tc.path, want, body)
+		}
+		if hasSource := strings.Contains(body, `name="go-source"`); hasSource != (tc.prefix == "example.com/mod") {
+			t.Errorf("%s: go-source meta = %v, want %v", tc.path, hasSource, !hasSource)
+		}
+	}
+}
+
+func TestRedirectBrowsers(t *testing.T) {
+	cfg := *testConfig
+	cfg.RedirectBrowsers = true
+	h := Handler(&cfg)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/mod/v3/x", nil))
+	if loc := w.Header().Get("Location"); w.Code != 302 || loc != "https://pkg.go.dev/example.com/mod/v3/x" {
+		t.Errorf("browser: %d %q", w.Code, loc)
+	}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/mod/v3/x?go-get=1", nil))
+	if w.Code != 200 {
+		t.Errorf("go command: %d, want 200", w.Code)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vanity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"config.yaml": `
+host: example.com
+paths:
+- prefix: example.com/mod
+  vcs: git
+  repo: https://github.com/example/mod
+`,
+		"config.json": `{
+	"host": "example.com",
+	"paths": [{"prefix": "example.com/mod", "vcs": "git", "repo": "https://github.com/example/mod"}]
+}`,
+		"unknown.yaml": `
+host: example.com
+paths:
+- prefix: example.com/mod
+  url: https://github.com/example/mod
+`,
+	}
+	for name, data := range files {
+		file := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(file, []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := LoadConfig(file)
+		if name == "unknown.yaml" {
+			if err == nil || !strings.Contains(err.Error(), "url") {
+				t.Errorf("%s: err = %v, want unknown field url", name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if err := cfg.Check(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		if len(cfg.Paths) != 1 || cfg.Paths[0].Repo != "https://github.com/example/mod" {
+			t.Errorf("%s: paths = %+v", name, cfg.Paths)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if err := testConfig.Check(); err != nil {
+		t.Errorf("testConfig: %v", err)
+	}
+
+	cfg := &Config{
+		Host:  "example.com",
+		Index: "/about",
+		Paths: []*Path{
+			{Prefix: "other.com/mod", VCS: "git", Repo: "https://github.com/example/mod"},
+			{Prefix: "example.com/mod", VCS: "cvs", Repo: "github.com/example/mod"},
+			{Prefix: "example.com/mod", VCS: "git", Repo: "https://github.com/example/mod", Doc: "https://pkg.go.dev/{pkg}"},
+			{Prefix: "example.com/mod/v1", VCS: "git", Repo: "https://github.com/example/mod"},
+			{Prefix: "example.com/mod/sub", VCS: "git", Repo: "https://github.com/example/sub"},
+			{
+				Prefix: "example.com/src",
+				VCS:    "git",
+				Repo:   "https://github.com/example/src",
+				Source: &Source{Home: "https://github.com/example/src", Dir: "{dir}", File: "https://github.com/example/src/blob/main{/dir}#L{line}"},
+			},
+		},
+	}
+	err := cfg.Check()
+	if err == nil {
+		t.Fatal("Check succeeded on bad config")
+	}
+	for _, want := range []string{
+		"index: /about is not an absolute URL",
+		"other.com/mod: prefix not under host example.com",
+		`example.com/mod: unknown vcs "cvs"`,
+		"example.com/mod: repo: github.com/example/mod is not an absolute URL",
+		"example.com/mod: doc: unknown placeholder {pkg}",
+		"example.com/mod: listed twice",
+		"example.com/mod/v1: malformed module path",
+		"example.com/mod/sub: nested in example.com/mod, but not as a major version",
+		"example.com/src: source dir: {dir} is not an absolute URL",
+		"example.com/src: source file: missing {file}",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Check error does not report %q:\n%v", want, err)
+		}
+	}
+}
@@ -0,0 +1,453 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+// Package xref builds an identifier cross-reference index
+// for a tree of type-checked Go packages.
+package xref
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/website/internal/texthtml"
+)
+
+// An Index records, for every identifier in the indexed Go files,
+// the declaration it refers to, and for every package-level declaration,
+// method and struct field, the identifiers that refer to it.
+//
+// File names in an Index are absolute slash-separated paths,
+// such as /src/fmt/print.go, naming files in the indexed file system.
+type Index struct {
+	files  []string         // files[id] is the name of the file with that id
+	fileID map[string]int32 // file name -> id
+	links  [][]link         // links[file] lists the identifiers in file, sorted by offset
+	objs   []object
+	keys   map[string]int32 // dir + "." + key -> object
+}
+
+// A link records an identifier in a file.
+type link struct {
+	start, end int32
+	obj        int32 // index in objs
+	def        bool  // identifier is the declaration of obj
+}
+
+// An object is a declaration.
+type object struct {
+	file, line int32  // position of the declaration; file < 0 if not indexed
+	url        string // if file < 0, URL for the declaration, such as /pkg/builtin/#int
+	key        string // name within its package, such as T.Method; empty for local declarations
+	refs       []ref  // uses, recorded only if key is set
+}
+
+type ref struct {
+	file, start, line int32
+}
+
+// A Ref is the location of an identifier in a source file.
+type Ref struct {
+	File       string // file name, such as /src/fmt/print.go
+	Line       int    // line number, starting at 1
+	Start, End int    // byte offsets of the identifier in the file
+}
+
+// Build type-checks every package in the tree rooted at root in fsys,
+// such as /src, and returns the resulting index.
+// It resolves imports in the tree the way the go command does for the
+// standard library, looking first in the vendor directories that enclose
+// the importing package and then in root itself.
+// Only the files used when building for the default GOOS and GOARCH
+// with cgo disabled are indexed; test files are not indexed.
+// Type errors are ignored: the identifiers that could be resolved are
+// indexed nonetheless.
+func Build(fsys fs.FS, root string) *Index {
+	b := &builder{
+		fsys:   fsys,
+		root:   root,
+		fset:   token.NewFileSet(),
+		pkgs:   make(map[string]*types.Package),
+		errs:   make(map[string]error),
+		dirs:   make(map[*types.Package]string),
+		defs:   make(map[token.Pos]int32),
+		extern: make(map[string]int32),
+		x: &Index{
+			fileID: make(map[string]int32),
+			keys:   make(map[string]int32),
+		},
+	}
+
+	ctxt := build.Default
+	ctxt.CgoEnabled = false
+	ctxt.IsAbsPath = path.IsAbs
+	ctxt.IsDir = func(name string) bool {
+		fi, err := fs.Stat(fsys, toFS(filepath.ToSlash(name)))
+		return err == nil && fi.IsDir()
+	}
+	ctxt.ReadDir = func(dir string) ([]os.FileInfo, error) {
+		list, err := fs.ReadDir(fsys, toFS(filepath.ToSlash(dir)))
+		infos := make([]os.FileInfo, 0, len(list))
+		for _, d := range list {
+			if info, err := d.Info(); err == nil {
+				infos = append(infos, info)
+			}
+		}
+		return infos, err
+	}
+	ctxt.OpenFile = func(name string) (io.ReadCloser, error) {
+		data, err := fs.ReadFile(fsys, toFS(filepath.ToSlash(name)))
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+	b.ctxt = &ctxt
+
+	fs.WalkDir(fsys, toFS(root), func(name string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if elem := d.Name(); elem == "testdata" || strings.HasPrefix(elem, ".") || strings.HasPrefix(elem, "_") {
+			return fs.SkipDir
+		}
+		b.load("/" + name)
+		return nil
+	})
+
+	x := b.x
+	for _, obj := range x.objs {
+		sort.Slice(obj.refs, func(i, j int) bool {
+			ri, rj := obj.refs[i], obj.refs[j]
+			if ri.file != rj.file {
+				return x.files[ri.file] < x.files[rj.file]
+			}
+			return ri.start < rj.start
+		})
+	}
+	return x
+}
+
+// toFS returns the io/fs name for path (no leading slash).
+func toFS(name string) string {
+	if name == "/" {
+		return "."
+	}
+	return path.Clean(strings.TrimPrefix(name, "/"))
+}
+
+// Links returns links for the identifiers in the named file:
+// uses link to their declarations, and package-level declarations
+// link to a list of their uses, ?refs=Name.
+// Links returns nil if the file is not in the index.
+func (x *Index) Links(file string) []texthtml.Link {
+	id, ok := x.fileID[file]
+	if !ok {
+		return nil
+	}
+	var links []texthtml.Link
+	for _, l := range x.links[id] {
+		obj := &x.objs[l.obj]
+		var url string
+		switch {
+		case l.def:
+			if obj.key == "" {
+				continue
+			}
+			url = "?refs=" + obj.key
+		case obj.file < 0:
+			url = obj.url
+		case obj.file == id:
+			url = "#L" + strconv.Itoa(int(obj.line))
+		default:
+			url = x.files[obj.file] + "#L" + strconv.Itoa(int(obj.line))
+		}
+		links = append(links, texthtml.Link{Start: int(l.start), End: int(l.end), URL: url})
+	}
+	return links
+}
+
+// Refs returns the declaration of name in the package in directory dir,
+// such as /src/fmt, and the list of its uses, sorted by file and offset.
+// The name is either a package-level name, such as Println, or a method
+// or field name qualified by its type name, such as Buffer.Write.
+// The result ok reports whether the declaration was found.
+func (x *Index) Refs(dir, name string) (def Ref, refs []Ref, ok bool) {
+	id, ok := x.keys[dir+"."+name]
+	if !ok {
+		return Ref{}, nil, false
+	}
+	obj := &x.objs[id]
+	def = Ref{File: x.files[obj.file], Line: int(obj.line)}
+	for _, l := range x.links[obj.file] {
+		if l.obj == id && l.def {
+			def.Start, def.End = int(l.start), int(l.end)
+			break
+		}
+	}
+	for _, r := range obj.refs {
+		refs = append(refs, Ref{
+			File:  x.files[r.file],
+			Line:  int(r.line),
+			Start: int(r.start),
+			End:   int(r.start) + len(name[strings.LastIndex(name, ".")+1:]),
+		})
+	}
+	return def, refs, true
+}
+
+// A builder type-checks packages, serving as their importer,
+// and records the identifiers of each package in the index.
+type builder struct {
+	fsys fs.FS
+	root string
+	ctxt *build.Context
+	fset *token.FileSet
+	x    *Index
+
+	pkgs   map[string]*types.Package // dir -> package; nil while being loaded
+	errs   map[string]error          // dir -> error loading package
+	dirs   map[*types.Package]string // package -> dir
+	extern map[string]int32          // package dir or builtin name -> object
+	defs   map[token.Pos]int32       // declaration position -> object
+}
+
+func (b *builder) Import(path string) (*types.Package, error) {
+	return b.ImportFrom(path, b.root, 0)
+}
+
+func (b *builder) ImportFrom(importPath, srcDir string, mode types.ImportMode) (*types.Package, error) {
+	if importPath == "unsafe" {
+		return types.Unsafe, nil
+	}
+	srcDir = filepath.ToSlash(srcDir)
+	for dir := srcDir; dir == b.root || strings.HasPrefix(dir, b.root+"/"); dir = path.Dir(dir) {
+		vendored := path.Join(dir, "vendor", importPath)
+		if b.ctxt.IsDir(vendored) {
+			return b.load(vendored)
+		}
+		if dir == b.root {
+			break
+		}
+	}
+	return b.load(path.Join(b.root, importPath))
+}
+
+// load type-checks the package in dir, if it has not been loaded already,
+// and indexes its files.
+func (b *builder) load(dir string) (*types.Package, error) {
+	if pkg, ok := b.pkgs[dir]; ok {
+		if pkg == nil {
+			return nil, fmt.Errorf("import cycle through %s", dir)
+		}
+		return pkg, nil
+	}
+	if err, ok := b.errs[dir]; ok {
+		return nil, err
+	}
+	b.pkgs[dir] = nil
+	pkg, err := b.check(dir)
+	if err != nil {
+		delete(b.pkgs, dir)
+		b.errs[dir] = err
+		return nil, err
+	}
+	b.pkgs[dir] = pkg
+	b.dirs[pkg] = dir
+	return pkg, nil
+}
+
+func (b *builder) check(dir string) (*types.Package, error) {
+	bp, err := b.ctxt.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		name = path.Join(dir, name)
+		src, err := fs.ReadFile(b.fsys, toFS(name))
+		if err != nil {
+			return nil, err
+		}
+		f, err := parser.ParseFile(b.fset, name, src, 0)
+		if f == nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	conf := types.Config{
+		Importer: b,
+		Error:    func(error) {}, // report as many identifiers as possible
+	}
+	info := &types.Info{
+		Defs: make(map[*ast.Ident]types.Object),
+		Uses: make(map[*ast.Ident]types.Object),
+	}
+	pkg, _ := conf.Check(strings.TrimPrefix(dir, b.root+"/"), b.fset, files, info)
+	b.index(dir, pkg, files, info)
+	return pkg, nil
+}
+
+// index records the declarations and uses in the files of pkg.
+func (b *builder) index(dir string, pkg *types.Package, files []*ast.File, info *types.Info) {
+	x := b.x
+	keys := memberKeys(pkg)
+
+	// Record declarations first, so that uses can refer to them
+	// regardless of the order of the files.
+	fileLinks := make(map[*token.File][]link)
+	for id, obj := range info.Defs {
+		if obj == nil || id.Pos() != obj.Pos() {
+			continue
+		}
+		f, pos := b.position(id.Pos())
+		objID := int32(len(x.objs))
+		x.objs = append(x.objs, object{file: f, line: int32(pos.Line), key: keys[obj]})
+		b.defs[obj.Pos()] = objID
+		if key := keys[obj]; key != "" {
+			x.keys[dir+"."+key] = objID
+		}
+		tf := b.fset.File(id.Pos())
+		fileLinks[tf] = append(fileLinks[tf], link{
+			start: int32(pos.Offset),
+			end:   int32(pos.Offset + len(id.Name)),
+			obj:   objID,
+			def:   true,
+		})
+	}
+	for id, obj := range info.Uses {
+		objID, ok := b.use(obj)
+		if !ok {
+			continue
+		}
+		f, pos := b.position(id.Pos())
+		// The name of an embedded field both declares the field
+		// and uses the embedded type. Link it once, as the declaration,
+		// but still count the use.
+		if info.Defs[id] == nil {
+			tf := b.fset.File(id.Pos())
+			fileLinks[tf] = append(fileLinks[tf], link{
+				start: int32(pos.Offset),
+				end:   int32(pos.Offset + len(id.Name)),
+				obj:   objID,
+			})
+		}
+		if o := &x.objs[objID]; o.key != "" {
+			o.refs = append(o.refs, ref{file: f, start: int32(pos.Offset), line: int32(pos.Line)})
+		}
+	}
+	for tf, list := range fileLinks {
+		sort.Slice(list, func(i, j int) bool { return list[i].start < list[j].start })
+		x.links[b.fileID(tf.Name())] = list
+	}
+}
+
+// use returns the object for a use of obj.
+func (b *builder) use(obj types.Object) (int32, bool) {
+	switch obj := obj.(type) {
+	case *types.PkgName:
+		dir, ok := b.dirs[obj.Imported()]
+		if !ok {
+			return 0, false
+		}
+		return b.urlObject(dir, dir+"/"), true
+	}
+	if obj.Pkg() == nil {
+		// Predeclared identifier, such as int or error.
+		if !obj.Pos().IsValid() {
+			return b.urlObject("builtin."+obj.Name(), "/pkg/builtin/#"+obj.Name()), true
+		}
+		return 0, false
+	}
+	// Uses of methods and fields of instantiated generic types
+	// refer to distinct objects at the same position as the originals.
+	id, ok := b.defs[obj.Pos()]
+	return id, ok
+}
+
+// urlObject returns the object, recorded under key, for a declaration
+// outside the indexed files that is documented at url.
+func (b *builder) urlObject(key, url string) int32 {
+	if id, ok := b.extern[key]; ok {
+		return id
+	}
+	id := int32(len(b.x.objs))
+	b.x.objs = append(b.x.objs, object{file: -1, url: url})
+	b.extern[key] = id
+	return id
+}
+
+// position returns the file id and the position of pos.
+func (b *builder) position(pos token.Pos) (int32, token.Position) {
+	p := b.fset.Position(pos)
+	return b.fileID(p.Filename), p
+}
+
+func (b *builder) fileID(name string) int32 {
+	x := b.x
+	if id, ok := x.fileID[name]; ok {
+		return id
+	}
+	id := int32(len(x.files))
+	x.files = append(x.files, name)
+	x.links = append(x.links, nil)
+	x.fileID[name] = id
+	return id
+}
+
+// memberKeys returns the names by which the package-level declarations
+// of pkg, and the methods and fields of its named types, can be looked up.
+func memberKeys(pkg *types.Package) map[types.Object]string {
+	keys := make(map[types.Object]string)
+	if pkg == nil {
+		return keys
+	}
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		keys[obj] = name
+		tn, ok := obj.(*types.TypeName)
+		if !ok {
+			continue
+		}
+		named, ok := tn.Type().(*types.Named)
+		if !ok {
+			continue
+		}
+		for i := 0; i < named.NumMethods(); i++ {
+			m := named.Method(i)
+			keys[m] = name + "." + m.Name()
+		}
+		switch t := named.Underlying().(type) {
+		case *types.Struct:
+			for i := 0; i < t.NumFields(); i++ {
+				f := t.Field(i)
+				keys[f] = name + "." + f.Name()
+			}
+		case *types.Interface:
+			for i := 0; i < t.NumExplicitMethods(); i++ {
+				m := t.ExplicitMethod(i)
+				keys[m] = name + "." + m.Name()
+			}
+		}
+	}
+	return keys
+}
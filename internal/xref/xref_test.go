@@ -0,0 +1,140 @@
+// Copyright 2021 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package xref
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"golang.org/x/website/internal/texthtml"
+)
+
+var testFS = fstest.MapFS{
+	"src/a/a.go": {Data: []byte(`package a
+
+import "b"
+
+type T struct {
+	F int
+}
+
+func (t *T) M() int { return t.F }
+
+func Use() {
+	var t T
+	t.M()
+	b.B()
+}
+`)},
+	"src/a/a_test.go": {Data: []byte("package a\n\nfunc TestUse() { Use() }\n")},
+	"src/b/b.go":      {Data: []byte("package b\n\n// B is vendored.\nfunc B() {}\n")},
+	"src/vendor/b/b.go": {Data: []byte(`package b
+
+import "c"
+
+func B() { c.C(); c.C() }
+`)},
+	"src/c/c.go":          {Data: []byte("package c\n\nfunc C() error { return nil }\n")},
+	"src/c/testdata/x.go": {Data: []byte("package x\n")},
+}
+
+func TestRefs(t *testing.T) {
+	x := Build(testFS, "/src")
+
+	for _, tt := range []struct {
+		dir, name string
+		def       string
+		refs      []string
+	}{
+		{"/src/a", "T", "/src/a/a.go:5", []string{"/src/a/a.go:9", "/src/a/a.go:12"}},
+		{"/src/a", "T.F", "/src/a/a.go:6", []string{"/src/a/a.go:9"}},
+		{"/src/a", "T.M", "/src/a/a.go:9", []string{"/src/a/a.go:13"}},
+		{"/src/a", "Use", "/src/a/a.go:11", nil},
+		{"/src/vendor/b", "B", "/src/vendor/b/b.go:5", []string{"/src/a/a.go:14"}},
+		{"/src/b", "B", "/src/b/b.go:4", nil},
+		{"/src/c", "C", "/src/c/c.go:3", []string{"/src/vendor/b/b.go:5", "/src/vendor/b/b.go:5"}},
+	} {
+		def, refs, ok := x.Refs(tt.dir, tt.name)
+		if !ok {
+			t.Errorf("Refs(%q, %q) not found", tt.dir, tt.name)
+			continue
+		}
+		if got := fmt.Sprintf("%s:%d", def.File, def.Line); got != tt.def {
+			t.Errorf("Refs(%q, %q) def = %s; want %s", tt.dir, tt.name, got, tt.def)
+		}
+		var got []string
+		for _, r := range refs {
+			got = append(got, fmt.Sprintf("%s:%d", r.File, r.Line))
+			if src := string(testFS[r.File[1:]].Data); src[r.Start:r.End] != tt.name[strings.LastIndex(tt.name, ".")+1:] {
+				t.Errorf("Refs(%q, %q): ref at %s:%d is %q", tt.dir, tt.name, r.File, r.Line, src[r.Start:r.End])
+			}
+		}
+		if strings.Join(got, " ") != strings.Join(tt.refs, " ") {
+			t.Errorf("Refs(%q, %q) = %v; want %v", tt.dir, tt.name, got, tt.refs)
+		}
+	}
+
+	if _, _, ok := x.Refs("/src/a", "TestUse"); ok {
+		t.Errorf("Refs found declaration in test file")
+	}
+}
+
+func TestLinks(t *testing.T) {
+	x := Build(testFS, "/src")
+	src := string(testFS["src/a/a.go"].Data)
+	var got []string
+	for _, l := range x.Links("/src/a/a.go") {
+		got = append(got, src[l.Start:l.End]+"->"+l.URL)
+	}
+	want := []string{
+		"T->?refs=T",
+		"F->?refs=T.F",
+		"int->/pkg/builtin/#int",
+		"T->#L5",
+		"M->?refs=T.M",
+		"int->/pkg/builtin/#int",
+		"t->#L9",
+		"F->#L6",
+		"Use->?refs=Use",
+		"T->#L5",
+		"t->#L12",
+		"M->#L9",
+		"b->/src/vendor/b/",
+		"B->/src/vendor/b/b.go#L5",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Links:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if links := x.Links("/src/a/a_test.go"); links != nil {
+		t.Errorf("Links(a_test.go) = %v; want nil", links)
+	}
+}
+
+func TestEmbeddedField(t *testing.T) {
+	fsys := fstest.MapFS{
+		"src/sync/sync.go": {Data: []byte("package sync\n\ntype Mutex struct{}\n")},
+		"src/a/a.go":       {Data: []byte("package a\n\nimport \"sync\"\n\ntype T struct {\n\tsync.Mutex\n}\n")},
+	}
+	x := Build(fsys, "/src")
+	src := fsys["src/a/a.go"].Data
+	html := string(texthtml.Format(src, texthtml.Config{Links: x.Links("/src/a/a.go")}))
+	if n := strings.Count(html, ">Mutex<"); n != 1 {
+		t.Errorf("rendered source has Mutex %d times, want 1:\n%s", n, html)
+	}
+	if !strings.Contains(html, `<a href="?refs=T.Mutex">Mutex</a>`) {
+		t.Errorf("rendered source does not link the embedded field:\n%s", html)
+	}
+
+	// The embedded field still counts as a use of the type.
+	_, refs, ok := x.Refs("/src/sync", "Mutex")
+	if !ok || len(refs) != 1 || refs[0].File != "/src/a/a.go" || refs[0].Line != 6 {
+		t.Errorf("Refs(sync, Mutex) = %v, %v; want one ref at /src/a/a.go:6", refs, ok)
+	}
+}